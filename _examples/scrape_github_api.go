@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/paginate"
+)
+
+func main() {
+	config := &scrape.ScrapeConfig{
+		Fetcher: &scrape.APIFetcher{
+			Token:    os.Getenv("GITHUB_TOKEN"),
+			Interval: 500 * time.Millisecond,
+		},
+
+		// The API returns a list of repositories, which becomes an <ol>.
+		DividePage: scrape.DividePageBySelector("body > ol > li"),
+
+		Pieces: []scrape.Piece{
+			{Name: "name", Selector: ".full_name", Extractor: extract.Text{}},
+			{Name: "description", Selector: ".description", Extractor: extract.Text{}},
+			{Name: "stars", Selector: ".stargazers_count", Extractor: extract.Number{}},
+		},
+
+		// The next page is given in the response's Link header.
+		Paginator: paginate.BySelector(`link[rel="next"]`, "href"),
+	}
+
+	scraper, err := scrape.New(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating scraper: %s\n", err)
+		os.Exit(1)
+	}
+
+	results, err := scraper.ScrapeWithOpts(
+		"https://api.github.com/users/andrew-d/repos?per_page=50",
+		scrape.ScrapeOptions{MaxPages: 3},
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scraping: %s\n", err)
+		os.Exit(1)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(results)
+}
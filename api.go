@@ -0,0 +1,347 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// APIFetcher is a Fetcher for JSON APIs, which allows them to be harvested
+// with the same Pieces, Paginators, limits and sinks as a regular website.
+// Each response is converted into an HTML document (see JSONToHTML) so that
+// its fields can be selected with CSS selectors, and the URLs in its Link
+// header (as used by e.g. GitHub's API) are added to the document's <head> as
+// <link> elements.
+//
+// A typical configuration for an API that returns a list of items under
+// "data", with the next page in the Link header, is:
+//
+//	&ScrapeConfig{
+//		Fetcher:    &APIFetcher{Token: token, Interval: time.Second},
+//		Paginator:  paginate.BySelector(`link[rel="next"]`, "href"),
+//		DividePage: DividePageBySelector(".data > li"),
+//		Pieces: []Piece{
+//			{Name: "id", Selector: ".id", Extractor: extract.Text{}},
+//			{Name: "name", Selector: ".name", Extractor: extract.Text{}},
+//		},
+//	}
+//
+// For APIs that return a cursor in the body instead, use paginate.ByCursor.
+// For GraphQL endpoints, see GraphQLFetcher.
+//
+// An APIFetcher is safe to use concurrently.
+type APIFetcher struct {
+	// Client is the http.Client used to make requests.  If this is nil, then
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// If Token is set, then it is sent in the Authorization header of each
+	// request as a bearer token.
+	Token string
+
+	// Header contains additional headers to send with each request.
+	Header http.Header
+
+	// Interval is the minimum time between the start of each request, which
+	// can be used to stay within an API's rate limit.
+	Interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (f *APIFetcher) Prepare() error {
+	return nil
+}
+
+func (f *APIFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *APIFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := f.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return renderDocument(doc)
+}
+
+// do sends the given request, and returns the response converted into an HTML
+// document.
+func (f *APIFetcher) do(ctx context.Context, req *http.Request) (*html.Node, error) {
+	req = req.WithContext(ctx)
+	url := req.URL.String()
+
+	for name, values := range f.Header {
+		req.Header[name] = values
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	if f.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.Token)
+	}
+
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("request to %s failed: %s", url, resp.Status)
+	}
+
+	doc, err := JSONToHTML(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing response from %s: %s", url, err)
+	}
+	addLinkHeaders(doc, resp.Request.URL, resp.Header["Link"])
+	return doc, nil
+}
+
+func renderDocument(doc *html.Node) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(&buf), nil
+}
+
+// wait blocks until the next request may be made.
+func (f *APIFetcher) wait(ctx context.Context) error {
+	if f.Interval <= 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	now := time.Now()
+	start := f.next
+	if start.Before(now) {
+		start = now
+	}
+	f.next = start.Add(f.Interval)
+	f.mu.Unlock()
+
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *APIFetcher) Close() {
+}
+
+// JSONToHTML converts a JSON document into an HTML document, so that it can be
+// scraped with CSS selectors.  The value is placed in the document's <body>,
+// and converted as follows:
+//
+//   - Each field of an object becomes an element with the field's name in its
+//     class (unless the name contains whitespace) and data-key attributes.
+//   - Objects become <div> elements containing their fields.
+//   - Arrays become <ol> elements with an <li> for each item.  An item that is
+//     an object contains its fields directly.
+//   - Strings, numbers and booleans become <span> elements containing their
+//     text, exactly as written in the JSON.  Null becomes an empty <span>.
+//
+// For example, {"data": [{"id": 1, "tags": ["a"]}], "next": null} becomes:
+//
+//	<ol class="data" data-key="data">
+//	  <li><span class="id" data-key="id">1</span><ol class="tags" data-key="tags"><li>a</li></ol></li>
+//	</ol>
+//	<span class="next" data-key="next"></span>
+//
+// The fields are kept in the same order as in the JSON.
+func JSONToHTML(r io.Reader) (*html.Node, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	doc := &html.Node{Type: html.DocumentNode}
+	root := newElement(atom.Html)
+	head := newElement(atom.Head)
+	body := newElement(atom.Body)
+	doc.AppendChild(root)
+	root.AppendChild(head)
+	root.AppendChild(body)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		err = jsonFields(dec, body)
+	default:
+		var n *html.Node
+		if n, err = jsonValue(dec, tok, ""); err == nil {
+			body.AppendChild(n)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return doc, nil
+}
+
+// jsonFields appends an element for each field of the object being decoded to
+// the given parent, up to and including the object's closing brace.
+func jsonFields(dec *json.Decoder, parent *html.Node) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+
+		if tok, err = dec.Token(); err != nil {
+			return err
+		}
+		n, err := jsonValue(dec, tok, key)
+		if err != nil {
+			return err
+		}
+		parent.AppendChild(n)
+	}
+
+	_, err := dec.Token()
+	return err
+}
+
+// jsonValue returns the element for the value that starts with the given
+// token, labelled with the given key (if any).
+func jsonValue(dec *json.Decoder, tok json.Token, key string) (*html.Node, error) {
+	var n *html.Node
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			n = newElement(atom.Div)
+			if err := jsonFields(dec, n); err != nil {
+				return nil, err
+			}
+
+		case '[':
+			n = newElement(atom.Ol)
+			for dec.More() {
+				item, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				li := newElement(atom.Li)
+				switch item {
+				case json.Delim('{'):
+					err = jsonFields(dec, li)
+				default:
+					var child *html.Node
+					if child, err = jsonValue(dec, item, ""); err == nil {
+						if child.DataAtom == atom.Span {
+							// Put scalars directly in the <li>.
+							for c := child.FirstChild; c != nil; c = child.FirstChild {
+								child.RemoveChild(c)
+								li.AppendChild(c)
+							}
+						} else {
+							li.AppendChild(child)
+						}
+					}
+				}
+				if err != nil {
+					return nil, err
+				}
+				n.AppendChild(li)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		n = newElement(atom.Span)
+		if tok != nil {
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: fmt.Sprint(tok)})
+		}
+	}
+
+	if key != "" {
+		if !strings.ContainsAny(key, " \t\r\n\f") {
+			n.Attr = append(n.Attr, html.Attribute{Key: "class", Val: key})
+		}
+		n.Attr = append(n.Attr, html.Attribute{Key: "data-key", Val: key})
+	}
+	return n, nil
+}
+
+func newElement(a atom.Atom) *html.Node {
+	return &html.Node{Type: html.ElementNode, DataAtom: a, Data: a.String()}
+}
+
+// addLinkHeaders adds a <link> element to the document's <head> for each link
+// in the given Link headers, e.g. `<https://api.example.com/items?page=2>;
+// rel="next"`.  Relative URLs are resolved against base.
+func addLinkHeaders(doc *html.Node, base *neturl.URL, headers []string) {
+	head := doc.FirstChild.FirstChild
+
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			ref, err := neturl.Parse(target[1 : len(target)-1])
+			if err != nil {
+				continue
+			}
+
+			n := newElement(atom.Link)
+			n.Attr = append(n.Attr, html.Attribute{Key: "href", Val: base.ResolveReference(ref).String()})
+			for _, param := range parts[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) == 2 {
+					n.Attr = append(n.Attr, html.Attribute{
+						Key: strings.ToLower(kv[0]),
+						Val: strings.Trim(kv[1], `"`),
+					})
+				}
+			}
+			head.AppendChild(n)
+		}
+	}
+}
+
+// Static type assertion
+var _ ContextFetcher = &APIFetcher{}
@@ -0,0 +1,86 @@
+package scrape_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/paginate"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestJSONToHTML(t *testing.T) {
+	doc, err := scrape.JSONToHTML(strings.NewReader(`{
+		"data": [{"id": 1, "tags": ["a", "b"]}, {"id": 2.50, "tags": []}],
+		"next": null,
+		"has more": true
+	}`))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, html.Render(&buf, doc))
+	assert.Equal(t, `<html><head></head><body>`+
+		`<ol class="data" data-key="data">`+
+		`<li><span class="id" data-key="id">1</span><ol class="tags" data-key="tags"><li>a</li><li>b</li></ol></li>`+
+		`<li><span class="id" data-key="id">2.50</span><ol class="tags" data-key="tags"></ol></li>`+
+		`</ol>`+
+		`<span class="next" data-key="next"></span>`+
+		`<span data-key="has more">true</span>`+
+		`</body></html>`, buf.String())
+
+	_, err = scrape.JSONToHTML(strings.NewReader(`{"a": 1} {"b": 2}`))
+	assert.Error(t, err)
+	_, err = scrape.JSONToHTML(strings.NewReader(`<html>`))
+	assert.Error(t, err)
+}
+
+func TestAPIFetcher(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			w.Header().Set("Link", `</items?page=2>; rel="next", </items?page=2>; rel="last"`)
+			page = "1"
+		}
+		fmt.Fprintf(w, `{"data": [{"name": "item %s-a"}, {"name": "item %s-b"}]}`, page, page)
+	}))
+	defer ts.Close()
+
+	config := &scrape.ScrapeConfig{
+		Fetcher:    &scrape.APIFetcher{Token: "secret", Interval: 20 * time.Millisecond},
+		Paginator:  paginate.BySelector(`link[rel="next"]`, "href"),
+		DividePage: scrape.DividePageBySelector(".data > li"),
+		Pieces: []scrape.Piece{
+			{Name: "name", Selector: ".name", Extractor: extract.Text{}},
+		},
+	}
+	start := time.Now()
+	results, err := mustNew(config).Scrape(ts.URL + "/items")
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+	assert.Equal(t, []string{ts.URL + "/items", ts.URL + "/items?page=2"}, results.URLs)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "item 1-a"},
+		{"name": "item 1-b"},
+		{"name": "item 2-a"},
+		{"name": "item 2-b"},
+	}, results.AllBlocks())
+	assert.Equal(t, 2, requests)
+
+	config.Fetcher = &scrape.APIFetcher{Token: "wrong"}
+	_, err = mustNew(config).Scrape(ts.URL + "/items")
+	assert.EqualError(t, err, "request to "+ts.URL+"/items failed: 401 Unauthorized")
+}
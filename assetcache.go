@@ -0,0 +1,170 @@
+package scrape
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// The default AssetCache.MaxBytes.
+const defaultAssetCacheBytes = 32 << 20
+
+// AssetCache is an http.RoundTripper that keeps static assets - stylesheets,
+// scripts, images and fonts - in memory once they have been downloaded, so
+// that assets referenced by many pages are only requested once, as a browser
+// would do.  This reduces the load on the target site when assets are fetched
+// through the same client as the pages (e.g. by an extractor that downloads
+// images).  Set HttpClientFetcher.CacheAssets to use one with that fetcher.
+//
+// Only successful GET requests are cached, and responses with a Cache-Control
+// header of "no-store" are never cached.  Cached assets are kept for the life
+// of the AssetCache, regardless of their expiry time.
+//
+// An AssetCache is safe for concurrent use.
+type AssetCache struct {
+	// The RoundTripper used to make requests that aren't cached.  If this is
+	// nil, then http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// MaxBytes is the maximum total size of the cached assets.  Once it is
+	// reached, the oldest assets are removed to make room.  If this is 0, then
+	// 32 MiB is used.
+	MaxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*cachedAsset
+	order   []string
+	size    int64
+}
+
+type cachedAsset struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (c *AssetCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.String()
+	if req.Method == "GET" {
+		if asset := c.get(key); asset != nil {
+			return asset.response(req), nil
+		}
+	}
+
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil || req.Method != "GET" || resp.StatusCode != http.StatusOK || !isStaticAsset(req, resp) {
+		return resp, err
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	asset := &cachedAsset{
+		status: resp.StatusCode,
+		header: resp.Header,
+		body:   body,
+	}
+	c.put(key, asset)
+	return asset.response(req), nil
+}
+
+func (c *AssetCache) get(key string) *cachedAsset {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key]
+}
+
+func (c *AssetCache) put(key string, asset *cachedAsset) {
+	max := c.MaxBytes
+	if max == 0 {
+		max = defaultAssetCacheBytes
+	}
+	n := int64(len(asset.body))
+	if n > max {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]*cachedAsset{}
+	}
+	if old, found := c.entries[key]; found {
+		// Another request for the same asset finished first.
+		c.size -= int64(len(old.body))
+		for i, k := range c.order {
+			if k == key {
+				c.order = append(c.order[:i], c.order[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for c.size+n > max && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		c.size -= int64(len(c.entries[oldest].body))
+		delete(c.entries, oldest)
+	}
+
+	c.entries[key] = asset
+	c.order = append(c.order, key)
+	c.size += n
+}
+
+func (a *cachedAsset) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(a.status),
+		StatusCode:    a.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        a.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(a.body)),
+		ContentLength: int64(len(a.body)),
+		Request:       req,
+	}
+}
+
+// Extensions of static assets, for responses without a useful Content-Type.
+var assetExtensions = map[string]bool{
+	".css": true, ".js": true, ".mjs": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	".svg": true, ".ico": true, ".avif": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+}
+
+// isStaticAsset reports whether the given response is a static asset.
+func isStaticAsset(req *http.Request, resp *http.Response) bool {
+	if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		switch {
+		case mt == "text/css",
+			mt == "text/javascript",
+			mt == "application/javascript",
+			strings.HasPrefix(mt, "image/"),
+			strings.HasPrefix(mt, "font/"),
+			strings.HasPrefix(mt, "application/font-"):
+			return true
+		case mt != "application/octet-stream":
+			return false
+		}
+	}
+	return assetExtensions[strings.ToLower(path.Ext(req.URL.Path))]
+}
+
+// Static type assertion
+var _ http.RoundTripper = &AssetCache{}
@@ -0,0 +1,95 @@
+package scrape_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetCache(t *testing.T) {
+	hits := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte("body { color: red }"))
+		case "/private.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Cache-Control", "private, no-store")
+			w.Write([]byte("png"))
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("alert(1)"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer ts.Close()
+
+	var client *http.Client
+	hf, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	hf.CacheAssets = true
+	hf.PrepareClient = func(c *http.Client) error {
+		client = c
+		return nil
+	}
+
+	// Preparing twice should not wrap the transport twice.
+	assert.NoError(t, hf.Prepare())
+	assert.NoError(t, hf.Prepare())
+	_, ok := client.Transport.(*scrape.AssetCache)
+	assert.True(t, ok)
+	assert.Nil(t, client.Transport.(*scrape.AssetCache).Transport)
+
+	get := func(path string) string {
+		resp, err := client.Get(ts.URL + path)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "body { color: red }", get("/style.css"))
+		assert.Equal(t, "png", get("/private.png"))
+		assert.Equal(t, "alert(1)", get("/app.js"))
+		assert.Equal(t, "<html></html>", get("/page"))
+	}
+
+	assert.Equal(t, map[string]int{
+		"/style.css":   1,
+		"/private.png": 3,
+		"/app.js":      1,
+		"/page":        3,
+	}, hits)
+}
+
+func TestAssetCacheMaxBytes(t *testing.T) {
+	hits := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write([]byte("0123456789"))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &scrape.AssetCache{MaxBytes: 25}}
+	for _, path := range []string{"/a.gif", "/b.gif", "/c.gif", "/c.gif", "/b.gif", "/a.gif"} {
+		resp, err := client.Get(ts.URL + path)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}
+
+	// Fetching c evicts a, so only a is requested twice.
+	assert.Equal(t, map[string]int{"/a.gif": 2, "/b.gif": 1, "/c.gif": 1}, hits)
+}
@@ -0,0 +1,129 @@
+package scrape
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultBlockIDName is the name under which a block's ID is stored if
+// BlockIDConfig.Name is empty.
+const DefaultBlockIDName = "_id"
+
+// BlockIDConfig adds an identifier to each block of a scrape, so that sinks,
+// deduplication and merging all use the same primary key for a block rather
+// than each choosing their own.  The ID is stored in the block under Name,
+// like the value of a Piece, and so can be used as the DedupConfig.Key or the
+// key Piece passed to MergeResults.
+type BlockIDConfig struct {
+	// Name is the name under which each block's ID is stored.  It must not be
+	// the name of a Piece.  If this is empty, then DefaultBlockIDName is used.
+	Name string
+
+	// Generator returns the ID of each block.  Required.
+	Generator BlockIDGenerator
+}
+
+func (c *BlockIDConfig) name() string {
+	if c.Name == "" {
+		return DefaultBlockIDName
+	}
+	return c.Name
+}
+
+func (c *BlockIDConfig) validate(pieces []Piece) error {
+	if c.Generator == nil {
+		return errors.New("no generator provided for block IDs")
+	}
+	for _, piece := range pieces {
+		if piece.Name == c.name() {
+			return fmt.Errorf("block ID name %q is the name of a piece", c.name())
+		}
+	}
+	return nil
+}
+
+// The BlockIDGenerator interface represents a strategy for identifying the
+// blocks of a scrape.  See HashBlockID, UUIDBlockID and SequenceBlockID.
+type BlockIDGenerator interface {
+	// BlockID returns the ID of the given block, which is passed the values of
+	// every Piece.  If it returns an empty string, then the block has no ID.
+	// If it returns an error, then the scrape is aborted.
+	BlockID(ctx ExtractContext, block map[string]interface{}) (string, error)
+}
+
+// The BlockIDFunc type is an adapter to allow the use of ordinary functions as
+// BlockIDGenerators.
+type BlockIDFunc func(ctx ExtractContext, block map[string]interface{}) (string, error)
+
+// BlockID calls f(ctx, block).
+func (f BlockIDFunc) BlockID(ctx ExtractContext, block map[string]interface{}) (string, error) {
+	return f(ctx, block)
+}
+
+// HashBlockID returns a BlockIDGenerator whose IDs are a hash of the values of
+// the given Pieces - i.e. the same content always has the same ID, even across
+// scrapes.  If no Pieces are given, then every Piece is used.  Blocks that have
+// none of the given Pieces have no ID.
+func HashBlockID(pieces ...string) BlockIDGenerator {
+	return BlockIDFunc(func(ctx ExtractContext, block map[string]interface{}) (string, error) {
+		key := block
+		if len(pieces) > 0 {
+			key = map[string]interface{}{}
+			for _, name := range pieces {
+				if val, found := block[name]; found {
+					key[name] = val
+				}
+			}
+		}
+		if len(key) == 0 {
+			return "", nil
+		}
+
+		// encoding/json sorts map keys, so this is deterministic.
+		data, err := json.Marshal(key)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:16]), nil
+	})
+}
+
+// UUIDBlockID returns a BlockIDGenerator whose IDs are random (version 4)
+// UUIDs, which are unique but differ every time a block is scraped.
+func UUIDBlockID() BlockIDGenerator {
+	return BlockIDFunc(func(ctx ExtractContext, block map[string]interface{}) (string, error) {
+		var u [16]byte
+		if _, err := rand.Read(u[:]); err != nil {
+			return "", err
+		}
+		u[6] = u[6]&0x0f | 0x40
+		u[8] = u[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+	})
+}
+
+// SequenceBlockID is a BlockIDGenerator whose IDs are consecutive numbers,
+// starting from 1, with the given prefix - e.g. "item-1", "item-2".  The
+// sequence continues across scrapes using the same SequenceBlockID, but is
+// not persisted, and so IDs are only unique within a single process.
+//
+// A SequenceBlockID is safe to use concurrently.
+type SequenceBlockID struct {
+	Prefix string
+
+	last uint64
+}
+
+func (s *SequenceBlockID) BlockID(ctx ExtractContext, block map[string]interface{}) (string, error) {
+	return fmt.Sprintf("%s%d", s.Prefix, atomic.AddUint64(&s.last, 1)), nil
+}
+
+// Static type assertion
+var _ BlockIDGenerator = BlockIDFunc(nil)
+var _ BlockIDGenerator = &SequenceBlockID{}
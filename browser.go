@@ -0,0 +1,97 @@
+package scrape
+
+// BrowserScripter is an optional interface that can be implemented by a
+// PieceExtractor that needs a JavaScript expression to be evaluated in the
+// rendered page by a browser-based Fetcher, such as extract.BrowserEval.
+type BrowserScripter interface {
+	PieceExtractor
+
+	// BrowserScript returns the JavaScript to evaluate in each page.
+	BrowserScript() string
+}
+
+// The WrappingExtractor interface can optionally be implemented by a
+// PieceExtractor that runs other extractors - such as WithCache or
+// extract.Chain - so that the extractors it wraps can be found.  In
+// particular, the scripts of every BrowserScripter that a Piece's extractor
+// wraps, however deeply, are given to a ScriptEvaluator.
+type WrappingExtractor interface {
+	PieceExtractor
+
+	// Unwrap returns the extractors that this one runs.
+	Unwrap() []PieceExtractor
+}
+
+// ScriptEvaluator is an optional interface that can be implemented by a
+// browser-based Fetcher that can evaluate JavaScript in each page it fetches.
+//
+// Before the Fetcher is prepared, SetScripts is called with the scripts of
+// every Piece whose extractor implements BrowserScripter, or wraps one that
+// does (see WrappingExtractor).  Each script is
+// evaluated in each page once it has rendered, and the result is added to the
+// returned document as an element of the form:
+//
+//	<script type="application/json" data-goscrape-eval="SCRIPT">RESULT</script>
+//
+// where SCRIPT is the script's source, and RESULT is a JSON object with either
+// a "value" field containing the script's result, or an "error" field
+// containing the message of the error that it threw.
+type ScriptEvaluator interface {
+	Fetcher
+
+	SetScripts(scripts []string)
+}
+
+// prepareFetcher prepares the Fetcher for a scrape.
+func (s *Scraper) prepareFetcher() error {
+	if se, ok := s.config.Fetcher.(ScriptEvaluator); ok {
+		scripts := []string{}
+		for _, piece := range s.config.Pieces {
+			scripts = appendBrowserScripts(scripts, piece.Extractor)
+		}
+		se.SetScripts(scripts)
+	}
+
+	return s.config.Fetcher.Prepare()
+}
+
+// appendBrowserScripts appends the scripts of the given extractor, and of any
+// extractors it wraps, that implement BrowserScripter.
+func appendBrowserScripts(scripts []string, e PieceExtractor) []string {
+	if bs, ok := e.(BrowserScripter); ok {
+		scripts = append(scripts, bs.BrowserScript())
+	}
+	if we, ok := e.(WrappingExtractor); ok {
+		for _, inner := range we.Unwrap() {
+			scripts = appendBrowserScripts(scripts, inner)
+		}
+	}
+	return scripts
+}
+
+// BrowserSession is the state of a browser that can be saved and restored
+// between scrapes, such as the session of a user who has logged in.  It can be
+// saved and loaded with encoding/json.  See PhantomJSFetcher.Session.
+type BrowserSession struct {
+	Cookies []BrowserCookie
+
+	// The contents of localStorage and sessionStorage, keyed by origin (e.g.
+	// "https://example.com") and then by item.
+	LocalStorage   map[string]map[string]string `json:",omitempty"`
+	SessionStorage map[string]map[string]string `json:",omitempty"`
+}
+
+// BrowserCookie is a single cookie in a BrowserSession.
+type BrowserCookie struct {
+	Name   string
+	Value  string
+	Domain string
+	Path   string
+
+	// The time at which the cookie expires, in seconds since the Unix epoch,
+	// or 0 for a session cookie.
+	Expires int64 `json:",omitempty"`
+
+	HTTPOnly bool `json:",omitempty"`
+	Secure   bool `json:",omitempty"`
+}
@@ -0,0 +1,116 @@
+package scrape
+
+import (
+	"log"
+)
+
+// ConfigBuilder builds a ScrapeConfig with a fluent interface.  Create one with
+// the Builder function - e.g.:
+//
+//	scraper, err := scrape.Builder().
+//		Divide(".item").
+//		Piece("title", "h2", extract.Text{}).
+//		Piece("link", "a", extract.Attr{Attr: "href"}).
+//		Paginate(paginate.BySelector("a.next", "href")).
+//		New()
+//
+// Each method modifies the builder and returns it, so calls can be chained.
+type ConfigBuilder struct {
+	config ScrapeConfig
+}
+
+// Builder returns a new, empty ConfigBuilder.
+func Builder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// Fetcher sets the Fetcher used to fetch documents.
+func (b *ConfigBuilder) Fetcher(f Fetcher) *ConfigBuilder {
+	b.config.Fetcher = f
+	return b
+}
+
+// Divide splits each page into blocks using the given CSS selector.  See
+// DividePageBySelector for more information.
+func (b *ConfigBuilder) Divide(sel string) *ConfigBuilder {
+	b.config.DividePage = DividePageBySelector(sel)
+	return b
+}
+
+// DivideFunc splits each page into blocks using the given function.
+func (b *ConfigBuilder) DivideFunc(f DividePageFunc) *ConfigBuilder {
+	b.config.DividePage = f
+	return b
+}
+
+// Piece adds a Piece with the given name, selector and extractor.
+func (b *ConfigBuilder) Piece(name, selector string, e PieceExtractor) *ConfigBuilder {
+	b.config.Pieces = append(b.config.Pieces, Piece{
+		Name:      name,
+		Selector:  selector,
+		Extractor: e,
+	})
+	return b
+}
+
+// Compute adds a computed Piece with the given name, whose value is computed
+// from the Pieces added before it.  See Piece.Compute for more information.
+func (b *ConfigBuilder) Compute(name string, f func(map[string]interface{}) (interface{}, error)) *ConfigBuilder {
+	b.config.Pieces = append(b.config.Pieces, Piece{
+		Name:    name,
+		Compute: f,
+	})
+	return b
+}
+
+// Paginate sets the Paginator used to find the next page.
+func (b *ConfigBuilder) Paginate(p Paginator) *ConfigBuilder {
+	b.config.Paginator = p
+	return b
+}
+
+// StopWhen sets the function used to end a scrape early.
+func (b *ConfigBuilder) StopWhen(f StopConditionFunc) *ConfigBuilder {
+	b.config.StopCondition = f
+	return b
+}
+
+// Dedup enables deduplication of blocks across scrapes, keyed by the value of
+// the Piece with the given name.
+func (b *ConfigBuilder) Dedup(store Store, key string) *ConfigBuilder {
+	b.config.Dedup = &DedupConfig{Store: store, Key: key}
+	return b
+}
+
+// Sink sets the Sink that receives each page as it is scraped.
+func (b *ConfigBuilder) Sink(s Sink) *ConfigBuilder {
+	b.config.Sink = s
+	return b
+}
+
+// Logger sets the Logger used to log the progress of each scrape.
+func (b *ConfigBuilder) Logger(l *log.Logger) *ConfigBuilder {
+	b.config.Logger = l
+	return b
+}
+
+// Build validates and returns the configuration.  The returned configuration
+// is a copy, so the builder can continue to be used afterwards.
+func (b *ConfigBuilder) Build() (*ScrapeConfig, error) {
+	ret := b.config.clone()
+	ret.Pieces = append([]Piece{}, b.config.Pieces...)
+
+	if err := ret.Validate(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// New builds the configuration and creates a Scraper from it.
+func (b *ConfigBuilder) New() (*Scraper, error) {
+	config, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return New(config)
+}
@@ -0,0 +1,253 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotCached is returned by a CachingFetcher in offline mode for pages that
+// aren't in its cache.
+var ErrNotCached = errors.New("page is not cached")
+
+// CachingFetcher is a Fetcher that wraps another Fetcher, and stores the
+// pages that it fetches on disk, so that a scrape can be re-run (e.g. while
+// working on its selectors) without downloading every page again.
+//
+// Cached pages are used until they are older than TTL.  After that, if the
+// page was sent with an ETag or Last-Modified header, then it is
+// revalidated - i.e. only downloaded again if it has changed.  Revalidation
+// requires a Fetcher that sends conditional requests, which HttpClientFetcher
+// does.
+//
+// Only successful (200 OK) responses to GET requests are cached, and
+// responses with a Cache-Control header of "no-store" are never cached.  If
+// the wrapped Fetcher doesn't report responses' status codes with
+// ResponseMetadata, then every page is assumed to be successful.
+//
+// A CachingFetcher is safe to use concurrently if the underlying Fetcher is.
+type CachingFetcher struct {
+	// The Fetcher to make requests with.
+	Fetcher Fetcher
+
+	// The directory in which the cache is stored.  It is created if it doesn't
+	// exist.
+	Dir string
+
+	// TTL is how long cached pages are used for before they are revalidated.
+	// If this is 0, then they are revalidated every time; if it is negative,
+	// then they never expire.
+	TTL time.Duration
+
+	// If Offline is true, then no requests are made: cached pages are used,
+	// however old they are, and other pages fail with ErrNotCached.
+	Offline bool
+
+	// Used to get the current time, so that tests can override it.
+	now func() time.Time
+}
+
+// cacheEntry is the metadata of a cached page, which is stored alongside its
+// body.
+type cacheEntry struct {
+	Response *Response
+	Stored   time.Time
+}
+
+func (f *CachingFetcher) Prepare() error {
+	if f.Fetcher == nil {
+		return errors.New("no fetcher to cache")
+	}
+	if f.Dir == "" {
+		return errors.New("no cache directory given")
+	}
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	if f.Offline {
+		return nil
+	}
+	return f.Fetcher.Prepare()
+}
+
+func (f *CachingFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *CachingFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	if method != "GET" {
+		if f.Offline {
+			return nil, fmt.Errorf("%w: %s %s", ErrNotCached, method, url)
+		}
+		return fetchFunc(f.Fetcher)(ctx, method, url)
+	}
+
+	now := time.Now
+	if f.now != nil {
+		now = f.now
+	}
+
+	path := entryPath(f.Dir, url)
+	entry, body := loadEntry(path)
+	if entry != nil && (f.Offline || f.TTL < 0 || now().Sub(entry.Stored) < f.TTL) {
+		return entry.body(body), nil
+	}
+	if f.Offline {
+		return nil, fmt.Errorf("%w: %s", ErrNotCached, url)
+	}
+
+	if entry != nil {
+		ctx = context.WithValue(ctx, validatorsKey{}, entry.Response.Header)
+	}
+	rc, err := fetchFunc(f.Fetcher)(ctx, method, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &Response{StatusCode: http.StatusOK, URL: url}
+	if rm, ok := rc.(ResponseMetadata); ok {
+		resp = rm.Response()
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		rc.Close()
+		entry.Stored = now()
+		saveEntry(path, entry, nil)
+		return entry.body(body), nil
+	}
+	if resp.StatusCode != http.StatusOK ||
+		strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return rc, nil
+	}
+
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry = &cacheEntry{Response: resp, Stored: now()}
+	if err := saveEntry(path, entry, data); err != nil {
+		return nil, err
+	}
+	return entry.body(data), nil
+}
+
+func (f *CachingFetcher) CookieJar() http.CookieJar {
+	if jf, ok := f.Fetcher.(CookieJarFetcher); ok {
+		return jf.CookieJar()
+	}
+	return nil
+}
+
+func (f *CachingFetcher) Close() {
+	f.Fetcher.Close()
+}
+
+// SetScripts passes the given scripts to the underlying Fetcher, if it is a
+// ScriptEvaluator.
+func (f *CachingFetcher) SetScripts(scripts []string) {
+	setScripts(f.Fetcher, scripts)
+}
+
+// Check calls the underlying Fetcher's Check method, if it has one.
+func (f *CachingFetcher) Check() error {
+	return checkFetcher(f.Fetcher)
+}
+
+// entryPath returns the path of the files in the given directory that store
+// the page with the given key (e.g. its URL), without their extensions.
+func entryPath(dir, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(dir, name[:2], name)
+}
+
+// loadEntry returns the stored entry and body at the given path, or nil if
+// there isn't one (or it can't be read).
+func loadEntry(path string) (*cacheEntry, []byte) {
+	data, err := ioutil.ReadFile(path + ".json")
+	if err != nil {
+		return nil, nil
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || entry.Response == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadFile(path + ".body")
+	if err != nil {
+		return nil, nil
+	}
+	return entry, body
+}
+
+// saveEntry stores the given entry and body at the given path.  If body is
+// nil, then only the entry is updated.  The entry is written last, so that a
+// partially-written page is never used.
+func saveEntry(path string, entry *cacheEntry, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if body != nil {
+		if err := writeFileAtomic(path+".body", body); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".json", data)
+}
+
+// writeFileAtomic writes a file by writing a temporary file and renaming it,
+// so that readers never see it partially written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (e *cacheEntry) body(data []byte) io.ReadCloser {
+	return &responseBody{ReadCloser: ioutil.NopCloser(bytes.NewReader(data)), response: e.Response}
+}
+
+type validatorsKey struct{}
+
+// setValidators makes the given request conditional on the page having
+// changed since it was cached, if it is being revalidated by a
+// CachingFetcher.
+func setValidators(ctx context.Context, req *http.Request) {
+	header, _ := ctx.Value(validatorsKey{}).(http.Header)
+	if etag := header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified := header.Get("Last-Modified"); modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+}
+
+// Static type assertion
+var _ ContextFetcher = &CachingFetcher{}
+var _ CookieJarFetcher = &CachingFetcher{}
+var _ ScriptEvaluator = &CachingFetcher{}
@@ -0,0 +1,97 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingFetcher(t *testing.T) {
+	var requests []string
+	version := "1"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+" "+r.Header.Get("If-None-Match"))
+		switch r.URL.Path {
+		case "/page":
+			etag := `"` + version + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			fmt.Fprint(w, "version "+version)
+		case "/private":
+			w.Header().Set("Cache-Control", "no-store")
+			fmt.Fprint(w, "private")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	hf, err := NewHttpClientFetcher()
+	assert.NoError(t, err)
+	now := time.Unix(1000, 0)
+	dir := t.TempDir()
+	fetcher := &CachingFetcher{Fetcher: hf, Dir: dir, TTL: time.Minute, now: func() time.Time { return now }}
+	assert.NoError(t, fetcher.Prepare())
+
+	get := func(f *CachingFetcher, path string) (int, string) {
+		body, err := f.Fetch("GET", ts.URL+path)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer body.Close()
+		data, _ := ioutil.ReadAll(body)
+		return body.(ResponseMetadata).Response().StatusCode, string(data)
+	}
+
+	// Pages are cached until they expire.
+	_, body := get(fetcher, "/page")
+	assert.Equal(t, "version 1", body)
+	_, body = get(fetcher, "/page")
+	assert.Equal(t, "version 1", body)
+	assert.Equal(t, []string{"/page "}, requests)
+
+	// Then they're revalidated.
+	now = now.Add(time.Minute)
+	status, body := get(fetcher, "/page")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "version 1", body)
+	assert.Equal(t, []string{"/page ", `/page "1"`}, requests)
+
+	now = now.Add(time.Minute)
+	version = "2"
+	_, body = get(fetcher, "/page")
+	assert.Equal(t, "version 2", body)
+	assert.Equal(t, `/page "1"`, requests[2])
+
+	// Errors and uncacheable pages aren't stored.
+	requests = nil
+	for i := 0; i < 2; i++ {
+		status, _ = get(fetcher, "/missing")
+		assert.Equal(t, http.StatusNotFound, status)
+		_, body = get(fetcher, "/private")
+		assert.Equal(t, "private", body)
+	}
+	assert.Len(t, requests, 4)
+
+	// Offline, only cached pages can be fetched, however old.
+	now = now.Add(time.Hour)
+	requests = nil
+	offline := &CachingFetcher{Fetcher: hf, Dir: dir, Offline: true}
+	assert.NoError(t, offline.Prepare())
+	_, body = get(offline, "/page")
+	assert.Equal(t, "version 2", body)
+	_, err = offline.Fetch("GET", ts.URL+"/private")
+	assert.True(t, errors.Is(err, ErrNotCached), err)
+	assert.Empty(t, requests)
+
+	assert.EqualError(t, (&CachingFetcher{Fetcher: hf}).Prepare(), "no cache directory given")
+}
@@ -0,0 +1,170 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Challenge describes a page that was detected as a captcha or other
+// anti-bot challenge during a scrape.
+type Challenge struct {
+	// The URL of the page.
+	URL string
+
+	// The HTML of the page, as fetched.
+	HTML string
+
+	// The number of times this page has already been challenged during this
+	// fetch, starting at 0.
+	Attempt int
+}
+
+// ChallengeConfig enables handling of captchas and other anti-bot challenges
+// during a scrape, so that semi-automated scrapes can survive them.
+//
+// Each fetched page is checked with Detect.  If it's a challenge, then the
+// whole scrape pauses - including any other scrapes run at the same time by
+// the same Scraper - while the Solver or OnChallenge callback deals with it.
+// This can, for example, ask a person to solve the captcha in a real browser
+// and paste in the resulting cookies, or run a JavaScript challenge in a
+// headless browser.  Once it returns, the page is fetched again.
+type ChallengeConfig struct {
+	// Detect reports whether the given page is a challenge.  If this is nil,
+	// then DetectChallenge is used.
+	Detect func(url string, doc *goquery.Selection) bool
+
+	// Solver is called when a challenge is detected.  It can use the
+	// Fetcher's cookie jar to add the cookies that show that the challenge
+	// was passed.  If it returns an error, then the scrape is aborted with
+	// that error.
+	Solver ChallengeSolver
+
+	// OnChallenge is a simpler alternative to Solver, which returns the
+	// cookies that should be used to fetch the page again.  These are added
+	// to the Fetcher's cookie jar, so the Fetcher must implement
+	// CookieJarFetcher.  Exactly one of Solver and OnChallenge must be set.
+	OnChallenge func(c *Challenge) ([]*http.Cookie, error)
+
+	// MaxAttempts is the number of times a single page can be challenged
+	// before the scrape is aborted with ErrChallengeUnsolved.  If this is 0,
+	// then 3 is used.
+	MaxAttempts int
+}
+
+// ErrChallengeUnsolved is returned when a page is still a challenge after
+// ChallengeConfig.MaxAttempts attempts to solve it.
+var ErrChallengeUnsolved = errors.New("challenge was not solved")
+
+func (c *ChallengeConfig) validate() error {
+	if c.Solver == nil && c.OnChallenge == nil {
+		return errors.New("no challenge solver provided")
+	}
+	if c.Solver != nil && c.OnChallenge != nil {
+		return errors.New("only one of a challenge solver and callback can be provided")
+	}
+	if c.MaxAttempts < 0 {
+		return errors.New("challenge attempts must not be negative")
+	}
+	return nil
+}
+
+func (c *ChallengeConfig) detect(url string, doc *goquery.Selection) bool {
+	if c.Detect != nil {
+		return c.Detect(url, doc)
+	}
+	return DetectChallenge(url, doc)
+}
+
+func (c *ChallengeConfig) maxAttempts() int {
+	if c.MaxAttempts == 0 {
+		return 3
+	}
+	return c.MaxAttempts
+}
+
+// Selectors for the widgets used by common captcha and challenge providers.
+const challengeSelector = `.g-recaptcha, .h-captcha, .cf-turnstile, ` +
+	`iframe[src*="recaptcha"], iframe[src*="hcaptcha"], ` +
+	`#challenge-form, #cf-challenge-running, #px-captcha`
+
+// DetectChallenge is the default ChallengeConfig.Detect function.  It reports
+// whether the page contains the widget of a common captcha provider (such as
+// reCAPTCHA, hCaptcha or Cloudflare Turnstile), a Cloudflare or PerimeterX
+// challenge form, or has a title mentioning a captcha.
+func DetectChallenge(url string, doc *goquery.Selection) bool {
+	if doc.Find(challengeSelector).Length() > 0 {
+		return true
+	}
+	title := strings.ToLower(doc.Find("title").First().Text())
+	return strings.Contains(title, "captcha")
+}
+
+// ChallengeSolver is the interface that must be satisfied by things that can
+// solve anti-bot challenges, such as JavaScript challenges or captchas, so
+// that third-party or in-house solvers can be plugged into a scrape.  See
+// ChallengeConfig for more information.
+type ChallengeSolver interface {
+	// Solve is called with a page that was detected as a challenge, and the
+	// cookie jar of the Fetcher.  The jar is nil if the Fetcher doesn't
+	// implement CookieJarFetcher.
+	Solve(c *Challenge, jar http.CookieJar) error
+}
+
+// The ChallengeSolverFunc type is an adapter to allow the use of ordinary
+// functions as a ChallengeSolver.
+type ChallengeSolverFunc func(c *Challenge, jar http.CookieJar) error
+
+func (f ChallengeSolverFunc) Solve(c *Challenge, jar http.CookieJar) error {
+	return f(c, jar)
+}
+
+// CookieJarFetcher is an optional interface that can be implemented by a
+// Fetcher that stores cookies, which allows a ChallengeSolver to read and
+// add cookies - e.g. after a challenge has been solved.
+type CookieJarFetcher interface {
+	Fetcher
+
+	CookieJar() http.CookieJar
+}
+
+// handleChallenge pauses all fetches while the challenge is handled.
+func (s *Scraper) handleChallenge(c *Challenge) error {
+	s.challengeMu.Lock()
+	defer s.challengeMu.Unlock()
+
+	s.logf("challenge detected at %s, pausing", c.URL)
+
+	var jar http.CookieJar
+	if jf, ok := s.config.Fetcher.(CookieJarFetcher); ok {
+		jar = jf.CookieJar()
+	}
+
+	if s.config.Challenge.Solver != nil {
+		return s.config.Challenge.Solver.Solve(c, jar)
+	}
+
+	cookies, err := s.config.Challenge.OnChallenge(c)
+	if err != nil {
+		return err
+	}
+	if len(cookies) == 0 {
+		return nil
+	}
+	if jar == nil {
+		return fmt.Errorf("fetcher %T does not implement CookieJarFetcher", s.config.Fetcher)
+	}
+	u, err := neturl.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	jar.SetCookies(u, cookies)
+	return nil
+}
+
+// Static type assertion
+var _ ChallengeSolver = ChallengeSolverFunc(nil)
@@ -0,0 +1,147 @@
+package scrape_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallenge(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("clearance"); err != nil || c.Value != "ok" {
+			fmt.Fprint(w, `<html><head><title>Are you human?</title></head>
+				<body><div class="g-recaptcha"></div></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><h1>Content</h1></body></html>`)
+	}))
+	defer ts.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+
+	var challenges []*scrape.Challenge
+	config := &scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+		Challenge: &scrape.ChallengeConfig{
+			OnChallenge: func(c *scrape.Challenge) ([]*http.Cookie, error) {
+				challenges = append(challenges, c)
+				return []*http.Cookie{{Name: "clearance", Value: "ok"}}, nil
+			},
+		},
+	}
+	results, err := mustNew(config).Scrape(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Content", results.First()["title"])
+	if assert.Equal(t, 1, len(challenges)) {
+		assert.Equal(t, ts.URL, challenges[0].URL)
+		assert.Contains(t, challenges[0].HTML, "g-recaptcha")
+	}
+
+	// The page is still a challenge after solving it.
+	fetcher, _ = scrape.NewHttpClientFetcher()
+	config.Fetcher = fetcher
+	config.Challenge.MaxAttempts = 2
+	challenges = nil
+	config.Challenge.OnChallenge = func(c *scrape.Challenge) ([]*http.Cookie, error) {
+		challenges = append(challenges, c)
+		return []*http.Cookie{{Name: "clearance", Value: "wrong"}}, nil
+	}
+	_, err = mustNew(config).Scrape(ts.URL)
+	assert.Equal(t, scrape.ErrChallengeUnsolved, err)
+	assert.Equal(t, 2, len(challenges))
+
+	// Errors from the callback abort the scrape.
+	config.Challenge.OnChallenge = func(c *scrape.Challenge) ([]*http.Cookie, error) {
+		return nil, errors.New("gave up")
+	}
+	_, err = mustNew(config).Scrape(ts.URL)
+	assert.EqualError(t, err, "gave up")
+
+	// Cookies can't be given to a fetcher that doesn't support them.
+	config.Fetcher = mapFetcher{"http://example.com": `<div class="h-captcha"></div>`}
+	config.Challenge.OnChallenge = func(c *scrape.Challenge) ([]*http.Cookie, error) {
+		return []*http.Cookie{{Name: "clearance", Value: "ok"}}, nil
+	}
+	_, err = mustNew(config).Scrape("http://example.com")
+	assert.EqualError(t, err, "fetcher scrape_test.mapFetcher does not implement CookieJarFetcher")
+}
+
+func TestDetectChallenge(t *testing.T) {
+	for _, tc := range []struct {
+		html string
+		want bool
+	}{
+		{`<p>normal page</p>`, false},
+		{`<iframe src="https://www.google.com/recaptcha/api2/anchor"></iframe>`, true},
+		{`<form id="challenge-form"></form>`, true},
+		{`<title>Captcha check</title>`, true},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, scrape.DetectChallenge("http://example.com", doc.Selection), tc.html)
+	}
+
+	_, err := scrape.New(&scrape.ScrapeConfig{
+		Pieces:    []scrape.Piece{{Name: "x", Selector: "p", Extractor: extract.Text{}}},
+		Challenge: &scrape.ChallengeConfig{},
+	})
+	assert.Error(t, err)
+}
+
+func TestChallengeSolver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("js_ok"); err != nil {
+			fmt.Fprint(w, `<html><body><form id="challenge-form"><input name="token" value="abc"></form></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><h1>Content</h1></body></html>`)
+	}))
+	defer ts.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+
+	solver := scrape.ChallengeSolverFunc(func(c *scrape.Challenge, jar http.CookieJar) error {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(c.HTML))
+		if err != nil {
+			return err
+		}
+		u, _ := url.Parse(c.URL)
+		token := doc.Find(`input[name="token"]`).AttrOr("value", "")
+		jar.SetCookies(u, []*http.Cookie{{Name: "js_ok", Value: token}})
+		return nil
+	})
+	results, err := mustNew(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+		Challenge: &scrape.ChallengeConfig{Solver: solver},
+	}).Scrape(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Content", results.First()["title"])
+
+	_, err = scrape.New(&scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{{Name: "x", Selector: "p", Extractor: extract.Text{}}},
+		Challenge: &scrape.ChallengeConfig{
+			Solver: solver,
+			OnChallenge: func(c *scrape.Challenge) ([]*http.Cookie, error) {
+				return nil, nil
+			},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package scrape
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Checkpoint records the progress of a scrape, so that it can be continued
+// later with the Resume method.  For more information, please see the
+// documentation on the CheckpointConfig type.
+type Checkpoint struct {
+	// The URL of the next page to be scraped.
+	NextURL string
+
+	// The number of pages that have been scraped so far.
+	PagesDone int
+
+	// The results from all pages that have been scraped so far.
+	Results *ScrapeResults
+
+	// The keys of all new blocks found so far, if deduplication is enabled.
+	// These are recorded in the dedup Store once the resumed scrape finishes.
+	DedupKeys []string
+}
+
+// CheckpointConfig controls checkpointing of a scrape's progress.  When
+// enabled, the Scraper periodically saves a Checkpoint to the given Store, and
+// deletes it when the scrape finishes successfully.  If a scrape is
+// interrupted, the Checkpoint can be loaded with Scraper.LoadCheckpoint and
+// passed to Scraper.Resume to continue where it left off.  A Checkpoint only
+// records the progress of a single scrape, so ScrapeAll can't be given more
+// than one start URL when checkpointing is enabled.
+//
+// Note: since the saved Checkpoint is encoded as JSON, the values in the
+// results of a resumed scrape are decoded as the equivalent JSON types (e.g.
+// numbers become float64).
+type CheckpointConfig struct {
+	// Store is where checkpoints are saved.  Required.
+	Store Store
+
+	// Key identifies this scrape's checkpoint in the Store.  Scrapes that share
+	// a Store should use different keys.  Required.
+	Key string
+
+	// Interval is the number of pages between checkpoints.  If this is 0, then
+	// a checkpoint is saved after every page.
+	Interval int
+}
+
+func (c *CheckpointConfig) validate() error {
+	if c.Store == nil {
+		return errors.New("no store provided for checkpoints")
+	}
+	if len(c.Key) == 0 {
+		return errors.New("no key provided for checkpoints")
+	}
+	return nil
+}
+
+func (c *CheckpointConfig) storeKey() string {
+	return "checkpoint:" + c.Key
+}
+
+// shouldSave returns whether a checkpoint should be saved after the given
+// number of pages.
+func (c *CheckpointConfig) shouldSave(pagesDone int) bool {
+	return c.Interval <= 1 || pagesDone%c.Interval == 0
+}
+
+func (c *CheckpointConfig) save(cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return c.Store.Put(c.storeKey(), data)
+}
+
+// LoadCheckpoint retrieves the most recently saved Checkpoint for this
+// Scraper's configuration.  It returns nil if there is no saved Checkpoint, or
+// an error if checkpointing is not enabled.
+func (s *Scraper) LoadCheckpoint() (*Checkpoint, error) {
+	if s.config.Checkpoint == nil {
+		return nil, errors.New("checkpoints are not enabled")
+	}
+
+	data, err := s.config.Checkpoint.Store.Get(s.config.Checkpoint.storeKey())
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err = json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Resume continues a scrape from the given Checkpoint, with default options.
+// See 'ResumeWithOpts' for more information.
+func (s *Scraper) Resume(cp *Checkpoint) (*ScrapeResults, error) {
+	return s.ResumeWithOpts(cp, s.opts)
+}
+
+// ResumeWithOpts continues a scrape from the given Checkpoint.  The returned
+// results include those from the pages scraped before the Checkpoint was
+// saved.  Note that the MaxPages option includes these pages.
+func (s *Scraper) ResumeWithOpts(cp *Checkpoint, opts ScrapeOptions) (*ScrapeResults, error) {
+	if cp == nil {
+		return nil, errors.New("no checkpoint provided")
+	}
+
+	// Don't modify the caller's checkpoint.
+	start := &Checkpoint{
+		NextURL:   cp.NextURL,
+		PagesDone: cp.PagesDone,
+		Results: &ScrapeResults{
+			URLs:    []string{},
+			Results: [][]map[string]interface{}{},
+		},
+		DedupKeys: append([]string{}, cp.DedupKeys...),
+	}
+	if cp.Results != nil {
+		start.Results.URLs = append(start.Results.URLs, cp.Results.URLs...)
+		start.Results.Results = append(start.Results.Results, cp.Results.Results...)
+		if cp.Results.Usage != nil {
+			start.Results.Usage = map[string]*HostUsage{}
+			mergeUsage(start.Results.Usage, cp.Results.Usage)
+		}
+		for name, failures := range cp.Results.Failures {
+			mergeFailures(start.Results, name, failures, s.config.FailureSamples)
+		}
+		start.Results.Duration = cp.Results.Duration
+		mergeResponses(start.Results, cp.Results.Responses)
+	}
+
+	return s.run(start, opts, true)
+}
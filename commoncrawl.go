@@ -0,0 +1,272 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+)
+
+// The default endpoints of CommonCrawlFetcher.
+const (
+	defaultCommonCrawlIndex = "https://index.commoncrawl.org"
+	defaultCommonCrawlData  = "https://data.commoncrawl.org"
+)
+
+// commonCrawlRecord is a single line of a response from the Common Crawl
+// index.
+type commonCrawlRecord struct {
+	URL      string `json:"url"`
+	Status   string `json:"status"`
+	Filename string `json:"filename"`
+	Offset   string `json:"offset"`
+	Length   string `json:"length"`
+}
+
+// CommonCrawlFetcher is a Fetcher that retrieves pages from the Common Crawl
+// archive instead of from the sites themselves, so that data can be extracted
+// at a large scale without sending any requests to the origin servers.  Each
+// URL is looked up in the index of a single crawl, and the page is read from
+// the WARC record that the index points to.  Only captures with a 200 status
+// code are used.
+//
+// URLs returns the URLs in the crawl that match a pattern, which can be used
+// as the start URLs of ScrapeAll, or as the input of a crawl - e.g.
+//
+//	fetcher := &scrape.CommonCrawlFetcher{Crawl: "CC-MAIN-2024-10"}
+//	urls, err := fetcher.URLs("example.com/products/*", 1000)
+//	...
+//	results, err := scraper.ScrapeAll(urls)
+//
+// A CommonCrawlFetcher is safe to use concurrently.
+type CommonCrawlFetcher struct {
+	// Client is the http.Client used to make requests.  If this is nil, then
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// The ID of the crawl to use, e.g. "CC-MAIN-2024-10".  The list of crawls
+	// is available at https://index.commoncrawl.org/collinfo.json.
+	Crawl string
+
+	// The base URLs of the index server and of the WARC files.  If these are
+	// empty, then "https://index.commoncrawl.org" and
+	// "https://data.commoncrawl.org" are used.
+	IndexEndpoint string
+	DataEndpoint  string
+}
+
+func (f *CommonCrawlFetcher) Prepare() error {
+	if f.Crawl == "" {
+		return errors.New("no Common Crawl crawl given")
+	}
+	return nil
+}
+
+func (f *CommonCrawlFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *CommonCrawlFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	if method != "GET" {
+		return nil, fmt.Errorf("Common Crawl only supports GET requests, not %s", method)
+	}
+
+	params := neturl.Values{}
+	params.Set("url", url)
+	params.Set("limit", "1")
+	records, err := f.query(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s not found in Common Crawl %s", url, f.Crawl)
+	}
+	return f.readRecord(ctx, records[0])
+}
+
+func (f *CommonCrawlFetcher) Close() {
+	return
+}
+
+// URLs returns up to limit distinct URLs in the crawl that match the given
+// pattern, in the order of the index.  A pattern can be a single URL, a
+// prefix ending in "*" (e.g. "example.com/products/*"), or a domain starting
+// with "*." to match all of its subdomains (e.g. "*.example.com").  If limit is
+// 0, then every match is returned.
+func (f *CommonCrawlFetcher) URLs(pattern string, limit int) ([]string, error) {
+	ctx := context.Background()
+
+	// Large results are split into pages, so find out how many there are.
+	params := neturl.Values{}
+	params.Set("url", pattern)
+	params.Set("showNumPages", "true")
+	body, err := f.get(ctx, f.indexURL(params), nil)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Pages int `json:"pages"`
+	}
+	err = json.Unmarshal(body, &info)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response from the Common Crawl index: %s", err)
+	}
+
+	ret := []string{}
+	seen := map[string]bool{}
+	for page := 0; page < info.Pages; page++ {
+		params := neturl.Values{}
+		params.Set("url", pattern)
+		params.Set("page", strconv.Itoa(page))
+		params.Set("fl", "url,status")
+		records, err := f.query(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			if seen[record.URL] {
+				continue
+			}
+			seen[record.URL] = true
+			ret = append(ret, record.URL)
+			if limit > 0 && len(ret) >= limit {
+				return ret, nil
+			}
+		}
+	}
+	return ret, nil
+}
+
+// query runs a query against the crawl's index, and returns the captures
+// with a 200 status code.
+func (f *CommonCrawlFetcher) query(ctx context.Context, params neturl.Values) ([]commonCrawlRecord, error) {
+	params.Set("output", "json")
+	params.Set("filter", "status:200")
+
+	body, err := f.get(ctx, f.indexURL(params), nil)
+	if err == errCommonCrawlNotFound {
+		// The index returns 404 Not Found when nothing matches.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret := []commonCrawlRecord{}
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var record commonCrawlRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("invalid response from the Common Crawl index: %s", err)
+		}
+		if record.Status == "200" {
+			ret = append(ret, record)
+		}
+	}
+	return ret, nil
+}
+
+// readRecord fetches the given capture from its WARC file, and returns the
+// body of the HTTP response it contains.
+func (f *CommonCrawlFetcher) readRecord(ctx context.Context, record commonCrawlRecord) (io.ReadCloser, error) {
+	offset, err := strconv.ParseInt(record.Offset, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset %q in Common Crawl index", record.Offset)
+	}
+	length, err := strconv.ParseInt(record.Length, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid length %q in Common Crawl index", record.Length)
+	}
+
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	data, err := f.get(ctx, f.dataEndpoint()+"/"+record.Filename, header)
+	if err == errCommonCrawlNotFound {
+		return nil, fmt.Errorf("WARC file %s not found", record.Filename)
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Each record is compressed separately, and consists of the WARC headers
+	// followed by the HTTP response.
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC record for %s: %s", record.URL, err)
+	}
+	r := bufio.NewReader(gz)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid WARC record for %s: %s", record.URL, err)
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+	}
+
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WARC record for %s: %s", record.URL, err)
+	}
+	return newResponseBody(resp, record.URL), nil
+}
+
+// errCommonCrawlNotFound is returned by get for a 404 Not Found response.
+var errCommonCrawlNotFound = errors.New("not found")
+
+// get fetches the given URL, failing if the response isn't successful.
+func (f *CommonCrawlFetcher) get(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, vals := range header {
+		req.Header[key] = vals
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errCommonCrawlNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("request to %s failed: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (f *CommonCrawlFetcher) indexURL(params neturl.Values) string {
+	endpoint := defaultCommonCrawlIndex
+	if f.IndexEndpoint != "" {
+		endpoint = strings.TrimSuffix(f.IndexEndpoint, "/")
+	}
+	return endpoint + "/" + f.Crawl + "-index?" + params.Encode()
+}
+
+func (f *CommonCrawlFetcher) dataEndpoint() string {
+	if f.DataEndpoint != "" {
+		return strings.TrimSuffix(f.DataEndpoint, "/")
+	}
+	return defaultCommonCrawlData
+}
+
+// Static type assertion
+var _ ContextFetcher = &CommonCrawlFetcher{}
@@ -0,0 +1,142 @@
+package scrape_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+func warcRecord(url, body string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	payload := "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nLast-Modified: yesterday\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	fmt.Fprintf(gz, "WARC/1.0\r\nWARC-Type: response\r\nWARC-Target-URI: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		url, len(payload), payload)
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestCommonCrawlFetcher(t *testing.T) {
+	pages := []struct {
+		url, status, body string
+	}{
+		{"http://example.com/products/1", "200", `<h1>One</h1>`},
+		{"http://example.com/products/1", "200", `<h1>One again</h1>`},
+		{"http://example.com/products/2", "301", ``},
+		{"http://example.com/products/3", "200", `<h1>Three</h1>`},
+	}
+
+	// Concatenate the records into a single WARC file, and index them.
+	var warc bytes.Buffer
+	var index []map[string]string
+	for _, page := range pages {
+		record := warcRecord(page.url, page.body)
+		index = append(index, map[string]string{
+			"url":      page.url,
+			"status":   page.status,
+			"filename": "crawl-data/segment/warc/00000.warc.gz",
+			"offset":   strconv.Itoa(warc.Len()),
+			"length":   strconv.Itoa(len(record)),
+		})
+		warc.Write(record)
+	}
+
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/CC-MAIN-2024-10-index":
+			q := r.URL.Query()
+			if q.Get("showNumPages") == "true" {
+				fmt.Fprint(w, `{"pages": 2, "pageSize": 5, "blocks": 7}`)
+				return
+			}
+			assert.Equal(t, "json", q.Get("output"))
+			assert.Equal(t, "status:200", q.Get("filter"))
+
+			pattern := q.Get("url")
+			var matches []map[string]string
+			for _, entry := range index {
+				if entry["url"] == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(entry["url"], "http://"+strings.TrimSuffix(pattern, "*"))) {
+					matches = append(matches, entry)
+				}
+			}
+			// Split the matches between two pages.
+			if page := q.Get("page"); page == "0" && len(matches) > 2 {
+				matches = matches[:2]
+			} else if page == "1" && len(matches) > 2 {
+				matches = matches[2:]
+			}
+			if len(matches) == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			for _, entry := range matches {
+				json.NewEncoder(w).Encode(entry)
+			}
+
+		case "/crawl-data/segment/warc/00000.warc.gz":
+			ranges = append(ranges, r.Header.Get("Range"))
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(warc.Bytes()[start : end+1])
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher := &scrape.CommonCrawlFetcher{
+		Crawl:         "CC-MAIN-2024-10",
+		IndexEndpoint: ts.URL,
+		DataEndpoint:  ts.URL + "/",
+	}
+
+	urls, err := fetcher.URLs("example.com/products/*", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com/products/1", "http://example.com/products/3"}, urls)
+
+	urls, err = fetcher.URLs("example.com/products/*", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com/products/1"}, urls)
+
+	urls, err = fetcher.URLs("example.com/other/*", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, urls)
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+			{Name: "modified", Selector: ".", Extractor: extract.Header{Name: "Last-Modified"}},
+		},
+	})
+	results, err := sc.ScrapeAll([]string{"http://example.com/products/1", "http://example.com/products/3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"title": "One", "modified": "yesterday"},
+		{"title": "Three", "modified": "yesterday"},
+	}, results.AllBlocks())
+	assert.Equal(t, "bytes=0-"+strconv.Itoa(len(warcRecord(pages[0].url, pages[0].body))-1), ranges[0])
+
+	_, err = sc.Scrape("http://example.com/products/2")
+	assert.EqualError(t, err, "http://example.com/products/2 not found in Common Crawl CC-MAIN-2024-10")
+
+	_, err = mustNew(&scrape.ScrapeConfig{
+		Fetcher: &scrape.CommonCrawlFetcher{},
+		Pieces:  []scrape.Piece{{Name: "title", Selector: "h1", Extractor: extract.Text{}}},
+	}).Scrape("http://example.com/")
+	assert.EqualError(t, err, "no Common Crawl crawl given")
+}
@@ -0,0 +1,66 @@
+package scrape
+
+import (
+	"errors"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type limitedExtractor struct {
+	sem chan struct{}
+	e   PieceExtractor
+}
+
+// WithConcurrencyLimit returns a PieceExtractor that will allow at most the
+// given number of calls to the underlying extractor to run at once.  The
+// limit is shared between every Piece that uses the returned extractor, so to
+// limit a type of extractor (e.g. one that downloads files), create a single
+// limited extractor and use it in each Piece.
+//
+// This is only useful when ScrapeConfig.ConcurrentPieces is greater than 1,
+// or when multiple scrapes are running at the same time.
+func WithConcurrencyLimit(limit int, e PieceExtractor) PieceExtractor {
+	if limit < 1 {
+		limit = 1
+	}
+	return &limitedExtractor{
+		sem: make(chan struct{}, limit),
+		e:   e,
+	}
+}
+
+func (l *limitedExtractor) Validate() error {
+	if l.e == nil {
+		return errors.New("no extractor provided for concurrency limit")
+	}
+	if v, ok := l.e.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (l *limitedExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+
+	return l.e.Extract(sel)
+}
+
+func (l *limitedExtractor) ExtractWithContext(ctx ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+
+	if ce, ok := l.e.(ContextualExtractor); ok {
+		return ce.ExtractWithContext(ctx, sel)
+	}
+	return l.e.Extract(sel)
+}
+
+func (l *limitedExtractor) Unwrap() []PieceExtractor {
+	return []PieceExtractor{l.e}
+}
+
+// Static type assertion
+var _ ContextualExtractor = &limitedExtractor{}
+var _ WrappingExtractor = &limitedExtractor{}
+var _ Validator = &limitedExtractor{}
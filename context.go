@@ -0,0 +1,44 @@
+package scrape
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ExtractContext describes where a block being extracted came from.  It is
+// passed to extractors that implement ContextualExtractor.
+type ExtractContext struct {
+	// The URL of the page containing the block.
+	URL string
+
+	// The time at which the page was fetched.
+	FetchedAt time.Time
+
+	// The index of the page in the scrape, starting at 0.
+	PageIndex int
+
+	// The index of the block among those returned by the DividePage function
+	// for this page, starting at 0.  Note that this can differ from the
+	// block's index in the results if earlier blocks were removed by
+	// deduplication.
+	BlockIndex int
+
+	// The HTTP headers of the page's response, if the Fetcher provides them
+	// (see ResponseBody).
+	Header http.Header
+}
+
+// The ContextualExtractor interface can optionally be implemented by a
+// PieceExtractor that needs to know where a block came from - e.g. to resolve
+// relative URLs against the page's URL, or to tag its results with their
+// provenance.  If a Piece's extractor implements this interface, then
+// ExtractWithContext is called instead of Extract during a scrape.
+type ContextualExtractor interface {
+	PieceExtractor
+
+	// ExtractWithContext is the same as Extract, but is also given the
+	// context of the block being extracted.
+	ExtractWithContext(ctx ExtractContext, sel *goquery.Selection) (interface{}, error)
+}
@@ -0,0 +1,301 @@
+package scrape
+
+import (
+	"errors"
+	neturl "net/url"
+	"regexp"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// CrawlConfig controls how pages are discovered during a crawl.  For more
+// information, please see the documentation on the Crawl method.
+type CrawlConfig struct {
+	// LinkSelector is the CSS selector used to find links on each page.  If
+	// this is empty, then "a[href]" is used.
+	LinkSelector string
+
+	// LinkAttr is the HTML attribute of each element matched by LinkSelector
+	// that contains the link.  If this is empty, then "href" is used.
+	LinkAttr string
+
+	// Allow contains the patterns that a link must match (at least one of) in
+	// order to be followed.  If this is empty, then all links to the same host
+	// as the start URL are followed.
+	Allow []*regexp.Regexp
+
+	// Deny contains patterns for links that are never followed, even if they
+	// match one of the Allow patterns.
+	Deny []*regexp.Regexp
+
+	// Extract contains the patterns that a page's URL must match (at least one
+	// of) in order for the Pieces to be extracted from it.  Pages that don't
+	// match are only used to discover further links.  If this is empty, then
+	// every page is extracted.
+	Extract []*regexp.Regexp
+
+	// MaxDepth is the maximum number of links that will be followed from the
+	// start URL to reach a page.  Set this value to 0 to indicate that there is
+	// no limit.
+	MaxDepth int
+
+	// MaxURLs is the maximum number of pages that will be fetched during the
+	// crawl.  Set this value to 0 to indicate that there is no limit.
+	MaxURLs int
+
+	// If MaxConsecutiveErrors is greater than 0, then a page that can't be
+	// fetched doesn't end the crawl.  Instead, it is skipped (so no links are
+	// found on it), and the crawl stops once that many pages in a row have
+	// failed.  The results so far are then returned along with an error
+	// wrapping ErrTooManyErrors.  Failed pages still count towards MaxURLs.
+	MaxConsecutiveErrors int
+
+	// Priorities controls the order in which discovered URLs are crawled, so
+	// that important pages are fetched before MaxURLs is reached.  Each URL is
+	// given the priority of the first rule whose pattern it matches, or 0 if
+	// none match.  URLs with a higher priority are crawled first; URLs with the
+	// same priority are crawled in the order they were found.
+	Priorities []PriorityRule
+
+	// Priority, if given, is called to get the priority of each discovered URL
+	// (found at the given depth) instead of using Priorities.
+	Priority func(url string, depth int) int
+
+	// TrapThreshold, if greater than 0, is the maximum number of URLs with the
+	// same URL pattern (see URLPattern) that will be queued.  Once a pattern
+	// exceeds this limit it is considered a crawler trap - such as a calendar,
+	// endless faceted navigation, or session IDs in URLs - and no further URLs
+	// matching it are followed.  A message is logged to the ScrapeConfig's
+	// Logger when this happens.
+	TrapThreshold int
+}
+
+// crawlItem is a single URL in the crawl frontier.
+type crawlItem struct {
+	url   string
+	depth int
+
+	// The URL of the page on which this URL was found.
+	from string
+
+	// The priority of this URL, and the order in which it was found.
+	priority int
+	seq      int
+}
+
+// Crawl starts at the given URL and follows links to discover further pages,
+// instead of following the linear chain of pages given by the Paginator (which
+// is not used).  Pages are visited in breadth-first order (unless the
+// CrawlConfig sets priorities), and each URL is only visited once.  Every page
+// that matches the CrawlConfig's Extract patterns is divided into blocks and
+// extracted as with a regular scrape.
+//
+// The URLs and Results of the returned results only contain the pages that
+// were extracted, while URLPatterns summarizes every URL that was discovered.
+// The Sink, StopCondition and Dedup settings of the ScrapeConfig apply as
+// usual - so with Dedup.StopOnSeen, the crawl stops after the first page that
+// contains a block seen in a previous scrape - but checkpointing is not
+// supported.
+func (s *Scraper) Crawl(url string, c *CrawlConfig) (*ScrapeResults, error) {
+	if len(url) == 0 {
+		return nil, errors.New("no URL provided")
+	}
+	if c == nil {
+		c = &CrawlConfig{}
+	}
+
+	if err := s.begin(); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	started := time.Now()
+	res, err := s.crawl(url, c)
+	if res != nil {
+		res.Duration = time.Since(started)
+	}
+	if serr := s.flushSink(err); serr != nil && err == nil {
+		return nil, serr
+	}
+	return res, err
+}
+
+func (s *Scraper) crawl(start string, c *CrawlConfig) (*ScrapeResults, error) {
+	startURL, err := neturl.Parse(start)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.prepareFetcher(); err != nil {
+		return nil, err
+	}
+
+	res := &ScrapeResults{
+		URLs:    []string{},
+		Results: [][]map[string]interface{}{},
+	}
+	dedup := newDedupState(s.config.Dedup, nil)
+
+	queue := &frontier{}
+	queue.push(crawlItem{url: start})
+	visited := map[string]struct{}{start: {}}
+	discovered := []string{start}
+	traps := newTrapDetector(c.TrapThreshold)
+
+	// The error with which the crawl ended early, if any.
+	var stopErr error
+	stopLoss := &stopLoss{opts: ScrapeOptions{
+		MaxConsecutiveErrors: c.MaxConsecutiveErrors,
+	}}
+
+	var numFetched int
+	for queue.len() > 0 {
+		if c.MaxURLs > 0 && numFetched >= c.MaxURLs {
+			break
+		}
+		if s.isDraining() {
+			return res, ErrDrained
+		}
+
+		item := queue.pop()
+
+		doc, err := s.fetchDocument(item.url, RequestInfo{
+			PageIndex:   numFetched,
+			PreviousURL: item.from,
+		})
+		numFetched++
+		if err != nil {
+			if c.MaxConsecutiveErrors <= 0 {
+				return nil, err
+			}
+			if stopErr = stopLoss.fetchFailed(err); stopErr != nil {
+				s.logf("stopping after %d failed pages", c.MaxConsecutiveErrors)
+				break
+			}
+			continue
+		}
+		stopLoss.errors = 0
+		s.recordUsage(res, item.url, doc)
+
+		// Queue up all new links on this page.
+		if c.MaxDepth == 0 || item.depth < c.MaxDepth {
+			for _, link := range c.links(startURL, item.url, doc.Selection) {
+				if _, seen := visited[link]; seen {
+					continue
+				}
+				visited[link] = struct{}{}
+				discovered = append(discovered, link)
+
+				ok, pattern, tripped := traps.allow(link)
+				if tripped {
+					s.logf("possible crawler trap, not following links matching %s", pattern)
+				}
+				if !ok {
+					continue
+				}
+
+				queue.push(crawlItem{
+					url:      link,
+					depth:    item.depth + 1,
+					from:     item.url,
+					priority: c.priority(link, item.depth+1),
+				})
+			}
+		}
+
+		if !c.shouldExtract(item.url) {
+			continue
+		}
+
+		page, foundSeen, err := s.processPage(item.url, len(res.URLs), doc, dedup, res)
+		if err != nil {
+			return nil, err
+		}
+		res.URLs = append(res.URLs, item.url)
+		res.Results = append(res.Results, page.Blocks)
+
+		if s.config.StopCondition != nil && s.config.StopCondition(page) {
+			break
+		}
+		if foundSeen && s.config.Dedup.StopOnSeen {
+			break
+		}
+	}
+
+	res.URLPatterns = LearnURLPatterns(discovered)
+
+	if err = dedup.commit(); err != nil {
+		return nil, err
+	}
+	if s.config.Usage != nil {
+		if err = s.config.Usage.save(res.Usage); err != nil {
+			return nil, err
+		}
+	}
+	return res, stopErr
+}
+
+// links returns the absolute URLs of all links on the given page that should
+// be followed.
+func (c *CrawlConfig) links(start *neturl.URL, pageURL string, doc *goquery.Selection) []string {
+	base, err := neturl.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	sel, attr := c.LinkSelector, c.LinkAttr
+	if sel == "" {
+		sel = "a[href]"
+	}
+	if attr == "" {
+		attr = "href"
+	}
+
+	ret := []string{}
+	doc.Find(sel).Each(func(i int, s *goquery.Selection) {
+		val, found := s.Attr(attr)
+		if !found {
+			return
+		}
+
+		ref, err := neturl.Parse(val)
+		if err != nil {
+			return
+		}
+		u := base.ResolveReference(ref)
+		u.Fragment = ""
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return
+		}
+
+		if c.shouldFollow(start, u) {
+			ret = append(ret, u.String())
+		}
+	})
+	return ret
+}
+
+func (c *CrawlConfig) shouldFollow(start, u *neturl.URL) bool {
+	link := u.String()
+	if matchesAny(c.Deny, link) {
+		return false
+	}
+	if len(c.Allow) == 0 {
+		return u.Host == start.Host
+	}
+	return matchesAny(c.Allow, link)
+}
+
+func (c *CrawlConfig) shouldExtract(url string) bool {
+	return len(c.Extract) == 0 || matchesAny(c.Extract, url)
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package scrape_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawl(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{
+			"http://example.com/": `
+				<a href="/item/1">one</a>
+				<a href="/item/2#comments">two</a>
+				<a href="/list/2">more</a>
+				<a href="http://other.com/item/9">elsewhere</a>
+				<a href="mailto:foo@example.com">email</a>`,
+			"http://example.com/item/1": `<h1>Item 1</h1><a href="/">home</a>`,
+			"http://example.com/item/2": `<h1>Item 2</h1>`,
+			"http://example.com/list/2": `<a href="/item/3">three</a>`,
+			"http://example.com/item/3": `<h1>Item 3</h1>`,
+		},
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+	})
+
+	results, err := sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		Extract: []*regexp.Regexp{regexp.MustCompile(`/item/`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://example.com/item/1",
+		"http://example.com/item/2",
+		"http://example.com/item/3",
+	}, results.URLs)
+	assert.Equal(t, "Item 3", results.Results[2][0]["title"])
+
+	// Depth and URL limits.
+	results, err = sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		Extract:  []*regexp.Regexp{regexp.MustCompile(`/item/`)},
+		MaxDepth: 1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(results.URLs))
+
+	results, err = sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		Deny:    []*regexp.Regexp{regexp.MustCompile(`/item/2`)},
+		MaxURLs: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://example.com/",
+		"http://example.com/item/1",
+		"http://example.com/list/2",
+	}, results.URLs)
+}
+
+func TestCrawlPriorities(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{
+			"http://example.com/": `
+				<a href="/list/2">more</a>
+				<a href="/item/1">one</a>
+				<a href="/list/3">even more</a>
+				<a href="/item/2">two</a>`,
+			"http://example.com/list/2": `<a href="/item/3">three</a>`,
+			"http://example.com/list/3": `<a href="/item/4">four</a>`,
+			"http://example.com/item/1": `<h1>Item 1</h1>`,
+			"http://example.com/item/2": `<h1>Item 2</h1>`,
+			"http://example.com/item/3": `<h1>Item 3</h1>`,
+			"http://example.com/item/4": `<h1>Item 4</h1>`,
+		},
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+	})
+
+	results, err := sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		Priorities: []scrape.PriorityRule{
+			{Pattern: regexp.MustCompile(`/item/`), Priority: 10},
+		},
+		MaxURLs: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://example.com/",
+		"http://example.com/item/1",
+		"http://example.com/item/2",
+	}, results.URLs)
+
+	// The callback takes precedence over the rules.
+	results, err = sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		Priorities: []scrape.PriorityRule{
+			{Pattern: regexp.MustCompile(`/item/`), Priority: 10},
+		},
+		Priority: func(url string, depth int) int {
+			if url == "http://example.com/list/3" {
+				return 1
+			}
+			return 0
+		},
+		MaxURLs: 4,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://example.com/",
+		"http://example.com/list/3",
+		"http://example.com/list/2",
+		"http://example.com/item/1",
+	}, results.URLs)
+}
+
+func TestCrawlTraps(t *testing.T) {
+	pages := mapFetcher{
+		"http://example.com/": `
+			<a href="/calendar/2024/01">calendar</a>
+			<a href="/item/1">one</a>`,
+		"http://example.com/item/1": `<h1>Item 1</h1><a href="/item/2">two</a>`,
+		"http://example.com/item/2": `<h1>Item 2</h1>`,
+	}
+	// Each calendar page links to the next month, forever.
+	for i := 1; i <= 50; i++ {
+		pages[fmt.Sprintf("http://example.com/calendar/2024/%02d", i)] =
+			fmt.Sprintf(`<a href="/calendar/2024/%02d">next</a>`, i+1)
+	}
+
+	var logs bytes.Buffer
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: pages,
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+		Logger: log.New(&logs, "", 0),
+	})
+
+	results, err := sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		TrapThreshold: 5,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(results.URLs))
+	assert.Contains(t, results.URLs, "http://example.com/item/2")
+	assert.NotContains(t, results.URLs, "http://example.com/calendar/2024/06")
+	assert.Contains(t, logs.String(), "example.com/calendar/{n}/{n}")
+}
+
+func TestCrawlErrors(t *testing.T) {
+	// The first two items can't be fetched.
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{
+			"http://example.com/": `
+				<a href="/item/1">one</a>
+				<a href="/item/2">two</a>
+				<a href="/item/3">three</a>`,
+			"http://example.com/item/3": `<h1>Item 3</h1>`,
+		},
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+	})
+
+	// By default, the first failure ends the crawl.
+	_, err := sc.Crawl("http://example.com/", &scrape.CrawlConfig{})
+	assert.EqualError(t, err, "unknown URL: http://example.com/item/1")
+
+	// Failed pages are skipped...
+	results, err := sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		MaxConsecutiveErrors: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"http://example.com/",
+		"http://example.com/item/3",
+	}, results.URLs)
+
+	// ... until too many fail in a row.
+	results, err = sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		MaxConsecutiveErrors: 2,
+	})
+	assert.True(t, errors.Is(err, scrape.ErrTooManyErrors))
+	assert.Equal(t, []string{"http://example.com/"}, results.URLs)
+}
+
+func TestCrawlStopOnSeen(t *testing.T) {
+	config := &scrape.ScrapeConfig{
+		Fetcher: mapFetcher{
+			"http://example.com/": `
+				<a href="/item/1">one</a>
+				<a href="/item/2">two</a>
+				<a href="/item/3">three</a>`,
+			"http://example.com/item/1": `<h1>Item 1</h1>`,
+			"http://example.com/item/2": `<h1>Item 2</h1>`,
+			"http://example.com/item/3": `<h1>Item 3</h1>`,
+		},
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+		Dedup: &scrape.DedupConfig{Store: store.NewMemory(), Key: "title"},
+	}
+
+	items := []*regexp.Regexp{regexp.MustCompile(`/item/`)}
+
+	results, err := mustNew(config).Crawl("http://example.com/", &scrape.CrawlConfig{
+		Extract: items,
+		MaxURLs: 2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com/item/1"}, results.URLs)
+
+	// The second crawl stops at the first item seen by the first.
+	config.Dedup.StopOnSeen = true
+	results, err = mustNew(config).Crawl("http://example.com/", &scrape.CrawlConfig{
+		Extract: items,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com/item/1"}, results.URLs)
+
+	// Without StopOnSeen, the crawl carries on to the new items.
+	config.Dedup.StopOnSeen = false
+	results, err = mustNew(config).Crawl("http://example.com/", &scrape.CrawlConfig{
+		Extract: items,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(results.URLs))
+}
+
+func TestURLPattern(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"http://example.com/", "example.com/"},
+		{"http://example.com/cal/2024/05?view=month&sid=abc", "example.com/cal/{n}/{n}?sid&view"},
+		{"https://example.com/p/page-12#top", "example.com/p/page-{n}"},
+		{"http://example.com/s/0a1b2c3d4e5f60718293;jsessionid=xyz", "example.com/s/{id}"},
+		{"http://example.com/list?color=red&color=blue", "example.com/list?color"},
+	} {
+		assert.Equal(t, tc.want, scrape.URLPattern(tc.in), tc.in)
+	}
+}
+
+func TestCrawlURLPatterns(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{
+			"http://example.com/": `
+				<a href="/item/1">one</a>
+				<a href="/item/2">two</a>
+				<a href="/about">about</a>`,
+		},
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		},
+	})
+
+	results, err := sc.Crawl("http://example.com/", &scrape.CrawlConfig{
+		MaxURLs: 1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []scrape.URLCluster{
+		{
+			Pattern:  "example.com/item/{n}",
+			Count:    2,
+			Examples: []string{"http://example.com/item/1", "http://example.com/item/2"},
+		},
+		{Pattern: "example.com/", Count: 1, Examples: []string{"http://example.com/"}},
+		{Pattern: "example.com/about", Count: 1, Examples: []string{"http://example.com/about"}},
+	}, results.URLPatterns)
+}
@@ -0,0 +1,333 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The time layouts that are tried, in order, when decoding a string into a
+// time.Time field that doesn't specify a layout.
+var defaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode converts every block in these results (in the same order as
+// AllBlocks) into a Go value, and stores them in the slice pointed to by v.
+// The slice's elements must be structs, or pointers to structs.  See
+// DecodeBlock for how each block is converted.
+func (r *ScrapeResults) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("decode target must be a non-nil pointer to a slice")
+	}
+
+	blocks := r.AllBlocks()
+	slice := rv.Elem()
+	out := reflect.MakeSlice(slice.Type(), len(blocks), len(blocks))
+	for i, block := range blocks {
+		if err := decodeValue(block, out.Index(i), ""); err != nil {
+			return fmt.Errorf("block %d: %s", i, err)
+		}
+	}
+
+	slice.Set(out)
+	return nil
+}
+
+// DecodeBlock converts a single block into the struct pointed to by v.  Each
+// exported field of the struct is filled in from the Piece with the same name;
+// this can be changed with a struct tag - e.g.:
+//
+//	type Story struct {
+//		Title  string    `scrape:"title"`
+//		Points int       `scrape:"score"`
+//		Posted time.Time `scrape:"date,layout=2006-01-02"`
+//		Ignore string    `scrape:"-"`
+//	}
+//
+// Fields without a tag match Pieces case-insensitively.  Fields for which the
+// block has no value are left unchanged.
+//
+// Values are converted to the type of the field where possible: strings are
+// parsed into numbers, booleans and times, numbers are converted between
+// types, a single value can be decoded into a slice (and a one-element list
+// into a single value), and nested maps can be decoded into nested structs.
+func DecodeBlock(block map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("decode target must be a non-nil pointer")
+	}
+	return decodeValue(block, rv.Elem(), "")
+}
+
+// decodeValue stores the given value in 'out', converting it to out's type.
+// The layout is used when decoding times.
+func decodeValue(val interface{}, out reflect.Value, layout string) error {
+	if val == nil {
+		return nil
+	}
+
+	in := reflect.ValueOf(val)
+	t := out.Type()
+
+	// Values that already have the right type are just copied.
+	if in.Type().AssignableTo(t) {
+		out.Set(in)
+		return nil
+	}
+
+	switch {
+	case t == timeType:
+		return decodeTime(val, out, layout)
+
+	case t.Kind() == reflect.Ptr:
+		elem := reflect.New(t.Elem())
+		if err := decodeValue(val, elem.Elem(), layout); err != nil {
+			return err
+		}
+		out.Set(elem)
+		return nil
+
+	case t.Kind() == reflect.Struct:
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot decode %T into %s", val, t)
+		}
+		return decodeStruct(m, out)
+
+	case t.Kind() == reflect.Slice:
+		if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+			// Decode a single value into a one-element slice.
+			elem := reflect.New(t.Elem()).Elem()
+			if err := decodeValue(val, elem, layout); err != nil {
+				return err
+			}
+			out.Set(reflect.Append(reflect.MakeSlice(t, 0, 1), elem))
+			return nil
+		}
+
+		ret := reflect.MakeSlice(t, in.Len(), in.Len())
+		for i := 0; i < in.Len(); i++ {
+			if err := decodeValue(in.Index(i).Interface(), ret.Index(i), layout); err != nil {
+				return fmt.Errorf("index %d: %s", i, err)
+			}
+		}
+		out.Set(ret)
+		return nil
+	}
+
+	// Decode a one-element list into a single value.
+	if in.Kind() == reflect.Slice || in.Kind() == reflect.Array {
+		if in.Len() != 1 {
+			return fmt.Errorf("cannot decode list of %d elements into %s", in.Len(), t)
+		}
+		return decodeValue(in.Index(0).Interface(), out, layout)
+	}
+
+	return decodeScalar(in, out)
+}
+
+// decodeScalar converts between strings, numbers and booleans.
+func decodeScalar(in, out reflect.Value) error {
+	t := out.Type()
+
+	switch t.Kind() {
+	case reflect.String:
+		out.SetString(fmt.Sprint(in.Interface()))
+		return nil
+
+	case reflect.Bool:
+		if in.Kind() == reflect.String {
+			b, err := strconv.ParseBool(strings.TrimSpace(in.String()))
+			if err != nil {
+				return err
+			}
+			out.SetBool(b)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch {
+		case in.Kind() == reflect.String:
+			n, err := strconv.ParseInt(strings.TrimSpace(in.String()), 10, 64)
+			if err != nil {
+				return err
+			}
+			i = n
+		case isInt(in):
+			i = in.Int()
+		case isUint(in):
+			i = int64(in.Uint())
+		case isFloat(in):
+			f := in.Float()
+			if f != math.Trunc(f) {
+				return fmt.Errorf("cannot decode non-integer %v into %s", f, t)
+			}
+			i = int64(f)
+		default:
+			return fmt.Errorf("cannot decode %s into %s", in.Type(), t)
+		}
+		if out.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, t)
+		}
+		out.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch {
+		case in.Kind() == reflect.String:
+			n, err := strconv.ParseUint(strings.TrimSpace(in.String()), 10, 64)
+			if err != nil {
+				return err
+			}
+			u = n
+		case isInt(in) && in.Int() >= 0:
+			u = uint64(in.Int())
+		case isUint(in):
+			u = in.Uint()
+		case isFloat(in) && in.Float() >= 0 && in.Float() == math.Trunc(in.Float()):
+			u = uint64(in.Float())
+		default:
+			return fmt.Errorf("cannot decode %v into %s", in.Interface(), t)
+		}
+		if out.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, t)
+		}
+		out.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch {
+		case in.Kind() == reflect.String:
+			n, err := strconv.ParseFloat(strings.TrimSpace(in.String()), 64)
+			if err != nil {
+				return err
+			}
+			f = n
+		case isInt(in):
+			f = float64(in.Int())
+		case isUint(in):
+			f = float64(in.Uint())
+		case isFloat(in):
+			f = in.Float()
+		default:
+			return fmt.Errorf("cannot decode %s into %s", in.Type(), t)
+		}
+		out.SetFloat(f)
+		return nil
+	}
+
+	if in.Type().ConvertibleTo(t) {
+		out.Set(in.Convert(t))
+		return nil
+	}
+	return fmt.Errorf("cannot decode %s into %s", in.Type(), t)
+}
+
+func decodeTime(val interface{}, out reflect.Value, layout string) error {
+	if t, ok := val.(time.Time); ok {
+		out.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return fmt.Errorf("cannot decode %T into time.Time", val)
+	}
+	s = strings.TrimSpace(s)
+
+	layouts := defaultTimeLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			out.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a time", s)
+}
+
+func decodeStruct(m map[string]interface{}, out reflect.Value) error {
+	t := out.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+
+		name, layout := field.Name, ""
+		tagged := false
+		if tag := field.Tag.Get("scrape"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name, tagged = parts[0], true
+			}
+			for _, opt := range parts[1:] {
+				if strings.HasPrefix(opt, "layout=") {
+					layout = strings.TrimPrefix(opt, "layout=")
+				}
+			}
+		}
+
+		val, found := m[name]
+		if !found && !tagged {
+			for key, v := range m {
+				if strings.EqualFold(key, name) {
+					val, found = v, true
+					break
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+
+		if err := decodeValue(val, out.Field(i), layout); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isFloat(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
@@ -0,0 +1,49 @@
+package scrape
+
+import (
+	"context"
+)
+
+// Drain gracefully shuts down the Scraper.  All running scrapes stop before
+// fetching their next page, and return the results collected so far along
+// with the error ErrDrained.  Before they return, the Sink (if any) is
+// flushed, and, if checkpointing is enabled, a Checkpoint is saved so that the
+// scrape can be continued later with Resume.  Any scrapes started after Drain
+// is called fail immediately with ErrDrained.
+//
+// Drain waits until all running scrapes have stopped, or until the given
+// context is done, in which case it returns the context's error.  This makes
+// it suitable for use with a termination grace period - e.g.:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+//	defer cancel()
+//	scraper.Drain(ctx)
+func (s *Scraper) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.isDraining() {
+		close(s.draining)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scraper) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
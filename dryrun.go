@@ -0,0 +1,123 @@
+package scrape
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// The maximum length of the HTML snippets in a DryRunReport.
+const snippetLength = 200
+
+// DryRunReport describes how the selectors in a ScrapeConfig match a single
+// page.  It is returned by Scraper.DryRun.
+type DryRunReport struct {
+	// The URL of the page.
+	URL string
+
+	// The number of blocks that DividePage found on the page, and a snippet of
+	// the HTML of the first block.
+	Blocks       int
+	BlockSnippet string
+
+	// A report for each Piece (except computed Pieces), in the same order as
+	// in the config.
+	Pieces []PieceReport
+}
+
+// PieceReport describes how a single Piece's selector matches a page.
+type PieceReport struct {
+	// The name and selector of the Piece.
+	Name     string
+	Selector string
+
+	// The total number of elements that the selector matched, across all
+	// blocks.
+	Matches int
+
+	// The number of blocks in which the selector matched at least one element.
+	BlocksMatched int
+
+	// A snippet of the HTML of the first element that was matched.
+	Snippet string
+}
+
+// DryRun fetches a single page and reports how many elements the DividePage
+// function and each Piece's selector match, without running any extractors.
+// This is useful for finding out why a Piece is missing from the results.
+func (s *Scraper) DryRun(url string) (*DryRunReport, error) {
+	if len(url) == 0 {
+		return nil, errors.New("no URL provided")
+	}
+
+	if err := s.prepareFetcher(); err != nil {
+		return nil, err
+	}
+	doc, err := s.fetchDocument(url, RequestInfo{})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := s.config.DividePage(doc.Selection)
+	ret := &DryRunReport{
+		URL:    url,
+		Blocks: len(blocks),
+		Pieces: []PieceReport{},
+	}
+	if len(blocks) > 0 {
+		ret.BlockSnippet = snippet(blocks[0])
+	}
+
+	for _, piece := range s.config.Pieces {
+		if piece.Compute != nil {
+			// Computed pieces don't have a selector.
+			continue
+		}
+
+		report := PieceReport{
+			Name:     piece.Name,
+			Selector: piece.Selector,
+		}
+
+		for _, block := range blocks {
+			sel := block
+			if piece.Selector != "." {
+				sel = sel.Find(piece.Selector)
+			}
+			if sel.Length() == 0 {
+				continue
+			}
+
+			if report.BlocksMatched == 0 {
+				report.Snippet = snippet(sel)
+			}
+			report.Matches += sel.Length()
+			report.BlocksMatched++
+		}
+
+		ret.Pieces = append(ret.Pieces, report)
+	}
+
+	return ret, nil
+}
+
+// snippet returns the (possibly truncated) outer HTML of the first element in
+// the given selection.
+func snippet(sel *goquery.Selection) string {
+	if sel.Length() == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, sel.Nodes[0]); err != nil {
+		return ""
+	}
+
+	ret := []rune(buf.String())
+	if len(ret) > snippetLength {
+		return string(ret[:snippetLength]) + "..."
+	}
+	return string(ret)
+}
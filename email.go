@@ -0,0 +1,139 @@
+package scrape
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// The maximum number of added or removed blocks that are listed in an email.
+const maxEmailedBlocks = 20
+
+// EmailSink is a Sink that emails a report at the end of a scrape, for simple
+// monitoring scrapers that just need to "email me when something changes or
+// breaks".  The email contains the scrape's Report: a Summary, the error that
+// aborted it (if any), any Pieces that stopped matching, and the blocks that
+// were added or removed since the previous scrape.
+//
+// Blocks are compared with those of the previous scrape by the same
+// EmailSink.  To compare scrapes in separate runs of a program - e.g. from a
+// cron job - set StateFile.  The first scrape is never reported as a change.
+//
+// An EmailSink is safe to share between Scrapers, but scrapes that overlap in
+// time are reported together.
+type EmailSink struct {
+	// The address of the SMTP server, as "host:port", and the authentication
+	// to use, if any.
+	Addr string
+	Auth smtp.Auth
+
+	// The sender and recipients of the email.
+	From string
+	To   []string
+
+	// The prefix of the email's subject.  If this is empty, then "[goscrape]"
+	// is used.
+	SubjectPrefix string
+
+	// Which scrapes to send an email for.  OnFailure sends one when a scrape
+	// fails, or finds fewer than MinBlocks blocks.  OnDrift sends one when a
+	// Piece stops matching (see Report.Broken).  OnChange sends one when at
+	// least MinChanges blocks (or 1, if MinChanges is 0) were added or
+	// removed.  Always sends one after every scrape.
+	OnFailure bool
+	OnDrift   bool
+	OnChange  bool
+	Always    bool
+
+	MinBlocks  int
+	MinChanges int
+
+	// If StateFile is non-empty, then the blocks of each scrape are saved to
+	// this file as JSON, and compared with those of the next scrape.
+	StateFile string
+
+	tracker reportTracker
+}
+
+func (s *EmailSink) Write(p *Page) error {
+	s.tracker.write(p)
+	return nil
+}
+
+func (s *EmailSink) Failed(err error) {
+	s.tracker.failed(err)
+}
+
+// Flush compares the scrape with the previous one, and sends an email if
+// needed.
+func (s *EmailSink) Flush() error {
+	report, notify, err := s.tracker.finish(notifyConfig{
+		onFailure:  s.OnFailure,
+		onDrift:    s.OnDrift,
+		onChange:   s.OnChange,
+		always:     s.Always,
+		minBlocks:  s.MinBlocks,
+		minChanges: s.MinChanges,
+		stateFile:  s.StateFile,
+	})
+	if err != nil || !notify {
+		return err
+	}
+
+	var body bytes.Buffer
+	if report.Err != nil {
+		fmt.Fprintf(&body, "error: %s\n\n", report.Err)
+	}
+	body.WriteString(report.Summary.String())
+	if len(report.Broken) > 0 {
+		fmt.Fprintf(&body, "\nbroken pieces: %s\n", strings.Join(report.Broken, ", "))
+	}
+	writeEmailedBlocks(&body, "added", report.Added)
+	writeEmailedBlocks(&body, "removed", report.Removed)
+	return s.send(report.Title, body.String())
+}
+
+func writeEmailedBlocks(buf *bytes.Buffer, what string, blocks []string) {
+	if len(blocks) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "\n%s %d blocks:\n", what, len(blocks))
+	for i, block := range blocks {
+		if i == maxEmailedBlocks {
+			fmt.Fprintf(buf, "  ... and %d more\n", len(blocks)-i)
+			break
+		}
+		fmt.Fprintf(buf, "  %s\n", block)
+	}
+}
+
+func (s *EmailSink) send(subject, body string) error {
+	if s.Addr == "" || s.From == "" || len(s.To) == 0 {
+		return errors.New("EmailSink needs an Addr, From and To")
+	}
+
+	prefix := s.SubjectPrefix
+	if prefix == "" {
+		prefix = "[goscrape]"
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s %s\r\n", prefix, subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+
+	if err := smtp.SendMail(s.Addr, s.Auth, s.From, s.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	return nil
+}
+
+// Static type assertion
+var _ FailureSink = &EmailSink{}
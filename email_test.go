@@ -0,0 +1,182 @@
+package scrape_test
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP is a minimal SMTP server that records the messages it receives.
+type fakeSMTP struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var msg strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				msg.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTP) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := s.messages
+	s.messages = nil
+	return ret
+}
+
+func TestEmailSink(t *testing.T) {
+	server := newFakeSMTP(t)
+	defer server.ln.Close()
+
+	pages := mapFetcher{
+		"http://example.com": `<li>one</li><li>two</li>`,
+	}
+	sink := &scrape.EmailSink{
+		Addr:      server.ln.Addr().String(),
+		From:      "scraper@example.com",
+		To:        []string{"me@example.com"},
+		OnFailure: true,
+		OnChange:  true,
+		MinBlocks: 1,
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+	}
+	newScraper := func(sink scrape.Sink) *scrape.Scraper {
+		return mustNew(&scrape.ScrapeConfig{
+			Fetcher:    pages,
+			DividePage: scrape.DividePageBySelector("li"),
+			Pieces:     []scrape.Piece{{Name: "item", Selector: ".", Extractor: extract.Text{}}},
+			Sink:       sink,
+		})
+	}
+	sc := newScraper(sink)
+
+	// The first scrape has nothing to compare with.
+	_, err := sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, server.take())
+
+	// Nothing changed.
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, server.take())
+
+	// A block was replaced.  The state is kept in the state file, so a new
+	// sink sees the change too.
+	pages["http://example.com"] = `<li>one</li><li>three</li>`
+	sink = &scrape.EmailSink{
+		Addr:          sink.Addr,
+		From:          sink.From,
+		To:            sink.To,
+		SubjectPrefix: "[monitor]",
+		OnFailure:     true,
+		OnChange:      true,
+		MinBlocks:     1,
+		StateFile:     sink.StateFile,
+	}
+	sc = newScraper(scrape.NewQueuedSink(sink, 10, scrape.Block))
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	msgs := server.take()
+	if assert.Len(t, msgs, 1) {
+		assert.Contains(t, msgs[0], "To: me@example.com\r\n")
+		assert.Contains(t, msgs[0], "Subject: [monitor] 1 blocks added, 1 removed\r\n")
+		assert.Contains(t, msgs[0], "blocks:   2\r\n")
+		assert.Contains(t, msgs[0], "added 1 blocks:\r\n  {\"item\":\"three\"}\r\n")
+		assert.Contains(t, msgs[0], "removed 1 blocks:\r\n  {\"item\":\"two\"}\r\n")
+	}
+
+	// A page without any blocks is a failure, and isn't treated as a change.
+	pages["http://example.com"] = `<p>gone</p>`
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	msgs = server.take()
+	if assert.Len(t, msgs, 1) {
+		assert.Contains(t, msgs[0], "Subject: [monitor] scrape found 0 blocks, expected at least 1\r\n")
+		assert.NotContains(t, msgs[0], "removed")
+	}
+
+	// So is an error.
+	_, err = sc.Scrape("http://example.com/missing")
+	assert.Error(t, err)
+	msgs = server.take()
+	if assert.Len(t, msgs, 1) {
+		assert.Contains(t, msgs[0], "Subject: [monitor] scrape failed: unknown URL: http://example.com/missing\r\n")
+	}
+
+	// Recovering isn't a change.
+	pages["http://example.com"] = `<li>one</li><li>three</li>`
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, server.take())
+}
+
+func TestEmailSinkErrors(t *testing.T) {
+	sink := &scrape.EmailSink{Always: true}
+	sink.Failed(errors.New("oops"))
+	assert.EqualError(t, sink.Flush(), "EmailSink needs an Addr, From and To")
+}
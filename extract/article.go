@@ -0,0 +1,316 @@
+package extract
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+	"golang.org/x/net/html"
+)
+
+// ArticleContent is the main content of a page, as returned by the Article
+// extractor.
+type ArticleContent struct {
+	Title  string
+	Byline string `json:",omitempty"`
+
+	// The article's text, with a blank line between paragraphs.
+	Text string
+
+	// The article's HTML, without scripts, styles, navigation, or any
+	// attributes other than links and image sources.
+	HTML string
+}
+
+// Article is a PieceExtractor that finds the main content of a page - e.g. the
+// body of a news story or blog post - without any site-specific selectors, in
+// the style of Readability.  It returns an ArticleContent, or nil if no
+// content is found.
+//
+// The content is found by scoring each element by the paragraphs of text it
+// contains, penalizing links and class names such as "sidebar" or "comment",
+// and picking the highest-scoring element.  The title and byline are taken
+// from the page's metadata where possible, so the selection is usually the
+// whole page (i.e. a Piece with the selector "body").  The page itself isn't
+// modified.
+type Article struct {
+	// The minimum length of the article's text.  If the text found is any
+	// shorter, then Extract returns nil.  If this is 0, then 200 is used.
+	MinLength int
+}
+
+const defaultArticleMinLength = 200
+
+var (
+	articleUnlikely = regexp.MustCompile(`(?i)ad-|ads|advert|banner|breadcrumb|combx|comment|community|cookie|disqus|footer|header|menu|modal|nav|newsletter|pager|popup|promo|related|remark|rss|share|shoutbox|sidebar|social|sponsor|subscribe|tags|tool|widget`)
+	articleLikely   = regexp.MustCompile(`(?i)article|body|column|content|entry|main|page|post|story|text`)
+	articleBylineRe = regexp.MustCompile(`(?i)byline|author|writtenby`)
+)
+
+// Elements that are never part of an article's content.
+const articleJunk = "script, style, noscript, iframe, object, embed, form, button, input, select, textarea, nav, aside, header, footer, svg, canvas"
+
+// Elements that break the article's text into paragraphs.
+var articleBlocks = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"figure": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "hr": true, "li": true, "main": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"td": true, "th": true, "tr": true, "ul": true,
+}
+
+func (e Article) Extract(sel *goquery.Selection) (interface{}, error) {
+	if sel.Length() == 0 {
+		return nil, nil
+	}
+
+	// Find the root of the document, for the title and byline.
+	root := sel.Get(0)
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	doc := goquery.NewDocumentFromNode(root).Selection
+
+	// Work on a copy, so that cleaning up the content doesn't affect other
+	// pieces.
+	content := goquery.NewDocumentFromNode(cloneNode(sel.Get(0))).Selection
+	content.Find(articleJunk).Remove()
+	content.Find("*").Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "body" || goquery.NodeName(s) == "html" {
+			return
+		}
+		names := s.AttrOr("class", "") + " " + s.AttrOr("id", "") + " " + s.AttrOr("role", "")
+		if articleUnlikely.MatchString(names) && !articleLikely.MatchString(names) {
+			s.Remove()
+		}
+	})
+
+	best := bestArticleCandidate(content)
+	if best == nil {
+		return nil, nil
+	}
+	cleanArticle(best)
+
+	minLength := e.MinLength
+	if minLength == 0 {
+		minLength = defaultArticleMinLength
+	}
+	text := articleText(best)
+	if len(text) < minLength {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	for c := best.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return ArticleContent{
+		Title:  articleTitle(doc),
+		Byline: articleByline(doc),
+		Text:   text,
+		HTML:   strings.TrimSpace(buf.String()),
+	}, nil
+}
+
+// bestArticleCandidate scores the parents of every paragraph in the given
+// selection, and returns the one with the highest score.
+func bestArticleCandidate(sel *goquery.Selection) *html.Node {
+	scores := map[*html.Node]float64{}
+	var order []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, found := scores[n]; !found {
+			scores[n] = initialArticleScore(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	sel.Find("p, pre, td").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + float64(len(text)/100)
+		if score > 4 {
+			score = 4
+		}
+		parent := s.Get(0).Parent
+		addScore(parent, score)
+		if parent != nil {
+			addScore(parent.Parent, score/2)
+		}
+	})
+
+	var (
+		best      *html.Node
+		bestScore float64
+	)
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(goquery.NewDocumentFromNode(n).Selection))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+func initialArticleScore(n *html.Node) float64 {
+	var score float64
+	switch n.Data {
+	case "article", "main":
+		score = 10
+	case "div":
+		score = 5
+	case "blockquote", "pre", "td":
+		score = 3
+	case "form", "ol", "ul", "li", "dl", "dd", "dt":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	for _, attr := range n.Attr {
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		if articleUnlikely.MatchString(attr.Val) {
+			score -= 25
+		}
+		if articleLikely.MatchString(attr.Val) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity returns the fraction of the selection's text that is inside
+// links.
+func linkDensity(sel *goquery.Selection) float64 {
+	total := len(strings.TrimSpace(sel.Text()))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	sel.Find("a").Each(func(i int, s *goquery.Selection) {
+		links += len(strings.TrimSpace(s.Text()))
+	})
+	return float64(links) / float64(total)
+}
+
+// cleanArticle removes link lists and presentational attributes from the
+// chosen content.
+func cleanArticle(n *html.Node) {
+	sel := goquery.NewDocumentFromNode(n).Selection
+	sel.Find("div, ul, ol, table, section").Each(func(i int, s *goquery.Selection) {
+		if s.Find("p").Length() == 0 && linkDensity(s) > 0.5 {
+			s.Remove()
+		}
+	})
+	sel.Find("*").Each(func(i int, s *goquery.Selection) {
+		node := s.Get(0)
+		var attrs []html.Attribute
+		for _, attr := range node.Attr {
+			switch attr.Key {
+			case "href", "src", "alt", "title":
+				attrs = append(attrs, attr)
+			}
+		}
+		node.Attr = attrs
+	})
+}
+
+// articleText returns the text of the given node, with a blank line between
+// each block of text.
+func articleText(n *html.Node) string {
+	var (
+		paragraphs []string
+		buf        bytes.Buffer
+	)
+	flush := func() {
+		if p := strings.TrimSpace(collapseWhitespace(buf.String())); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+		buf.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if articleBlocks[n.Data] {
+				flush()
+				defer flush()
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func articleTitle(doc *goquery.Selection) string {
+	for _, sel := range []string{`meta[property="og:title"]`, `meta[name="twitter:title"]`} {
+		if title := strings.TrimSpace(doc.Find(sel).AttrOr("content", "")); title != "" {
+			return title
+		}
+	}
+	if title := strings.TrimSpace(collapseWhitespace(doc.Find("h1").First().Text())); title != "" {
+		return title
+	}
+	return strings.TrimSpace(collapseWhitespace(doc.Find("title").First().Text()))
+}
+
+func articleByline(doc *goquery.Selection) string {
+	if author := strings.TrimSpace(doc.Find(`meta[name="author"]`).AttrOr("content", "")); author != "" {
+		return author
+	}
+
+	var byline string
+	doc.Find(`[rel="author"], [itemprop="author"], [class], [id]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		isAuthor := s.AttrOr("rel", "") == "author" || s.AttrOr("itemprop", "") == "author"
+		if !isAuthor && !articleBylineRe.MatchString(s.AttrOr("class", "")+" "+s.AttrOr("id", "")) {
+			return true
+		}
+		text := strings.TrimSpace(collapseWhitespace(s.Text()))
+		if text == "" || len(text) > 100 {
+			return true
+		}
+		byline = text
+		return false
+	})
+	return byline
+}
+
+// cloneNode returns a deep copy of the given node.
+func cloneNode(n *html.Node) *html.Node {
+	ret := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		ret.AppendChild(cloneNode(c))
+	}
+	return ret
+}
+
+var _ scrape.PieceExtractor = Article{}
@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestArticle(t *testing.T) {
+	const page = `<html><head>
+		<title>Rivers rise again | The Daily Example</title>
+		<meta property="og:title" content="Rivers rise again">
+		<meta name="author" content="Jane Doe">
+		<script>var tracking = true;</script>
+	</head><body>
+		<nav><a href="/">Home</a> <a href="/news">News</a> <a href="/sport">Sport</a></nav>
+		<div class="sidebar"><p>Sign up for our newsletter, it is the best newsletter around.</p></div>
+		<div id="main" class="layout">
+			<div class="story-body" style="color: red">
+				<h2>Flooding</h2>
+				<p>The river rose by two metres overnight, flooding low-lying streets, gardens and shops across the town.</p>
+				<p>Residents were advised to move valuables upstairs, and the council opened a rest centre in the school hall.</p>
+				<div class="share-links"><a href="/share/fb">Facebook</a> <a href="/share/tw">Twitter</a></div>
+				<p>Forecasters expect the water to recede by <a href="/weather">the weekend</a>, although more rain is due.</p>
+			</div>
+			<div class="comments"><p>Great article, thanks, really informative and well written!</p></div>
+		</div>
+		<footer><p>Copyright, The Daily Example, all rights reserved and so on.</p></footer>
+	</body></html>`
+
+	extracttest.Run(t, Article{}, []extracttest.Case{
+		{Name: "story", HTML: page, Selector: "body", Want: ArticleContent{
+			Title:  "Rivers rise again",
+			Byline: "Jane Doe",
+			Text: "Flooding\n\n" +
+				"The river rose by two metres overnight, flooding low-lying streets, gardens and shops across the town.\n\n" +
+				"Residents were advised to move valuables upstairs, and the council opened a rest centre in the school hall.\n\n" +
+				"Forecasters expect the water to recede by the weekend, although more rain is due.",
+			HTML: "<h2>Flooding</h2>\n\t\t\t\t" +
+				"<p>The river rose by two metres overnight, flooding low-lying streets, gardens and shops across the town.</p>\n\t\t\t\t" +
+				"<p>Residents were advised to move valuables upstairs, and the council opened a rest centre in the school hall.</p>\n\t\t\t\t\n\t\t\t\t" +
+				`<p>Forecasters expect the water to recede by <a href="/weather">the weekend</a>, although more rain is due.</p>`,
+		}},
+		{Name: "nothing", HTML: `<p>Too short.</p>`, Selector: "body", Want: nil},
+		{Name: "no match", HTML: page, Selector: "table", Want: nil},
+	})
+
+	const byline = `<html><head><title>A Long Walk</title></head><body>
+		<article>
+			<h1>A long walk</h1>
+			<span class="byline">By <a rel="author" href="/people/sam">Sam Smith</a></span>
+			<p>We set off early in the morning, with sandwiches, a flask of tea and a map.</p>
+			<p>By lunchtime the hills had disappeared into cloud, and the map was no help at all.</p>
+		</article>
+	</body></html>`
+
+	extracttest.Run(t, Article{MinLength: 50}, []extracttest.Case{
+		{Name: "byline", HTML: byline, Selector: "body", Want: ArticleContent{
+			Title:  "A long walk",
+			Byline: "By Sam Smith",
+			Text: "A long walk\n\n" +
+				"By Sam Smith\n\n" +
+				"We set off early in the morning, with sandwiches, a flask of tea and a map.\n\n" +
+				"By lunchtime the hills had disappeared into cloud, and the map was no help at all.",
+			HTML: "<h1>A long walk</h1>\n\t\t\t" +
+				`<span>By <a href="/people/sam">Sam Smith</a></span>` + "\n\t\t\t" +
+				"<p>We set off early in the morning, with sandwiches, a flask of tea and a map.</p>\n\t\t\t" +
+				"<p>By lunchtime the hills had disappeared into cloud, and the map was no help at all.</p>",
+		}},
+	})
+}
@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Attrs is a PieceExtractor that returns the attributes of the first element
+// in the selection as a map[string]string, keyed by attribute name.  This is
+// useful for sites that store structured values in data-* attributes:
+//
+//	<div class="product" data-sku="A12" data-price="9.99" data-in-stock="true">
+//
+// With Data set, this results in {"sku": "A12", "price": "9.99", "in-stock":
+// "true"}.
+type Attrs struct {
+	// If Data is true, then only data-* attributes are returned, and the
+	// "data-" prefix is removed from their names.
+	Data bool
+
+	// If no attributes are found, then return 'nil' from Extract, instead of
+	// the empty map.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// map.
+	OmitIfEmpty bool
+}
+
+func (e Attrs) Extract(sel *goquery.Selection) (interface{}, error) {
+	ret := map[string]string{}
+	if sel.Length() > 0 {
+		for _, attr := range sel.Get(0).Attr {
+			name := attr.Key
+			if e.Data {
+				if !strings.HasPrefix(name, "data-") {
+					continue
+				}
+				name = strings.TrimPrefix(name, "data-")
+			}
+			ret[name] = attr.Val
+		}
+	}
+
+	if len(ret) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return ret, nil
+}
+
+var _ scrape.PieceExtractor = Attrs{}
@@ -0,0 +1,35 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestAttrs(t *testing.T) {
+	const page = `<div class="product" id="p1" data-sku="A12" data-in-stock="true">Widget</div>
+		<div class="product" data-sku="B34"></div>
+		<p>plain</p>`
+
+	extracttest.Run(t, Attrs{}, []extracttest.Case{
+		{Name: "all", HTML: page, Selector: ".product", Want: map[string]string{
+			"class":         "product",
+			"id":            "p1",
+			"data-sku":      "A12",
+			"data-in-stock": "true",
+		}},
+		{Name: "no element", HTML: page, Selector: "span", Want: map[string]string{}},
+	})
+
+	extracttest.Run(t, Attrs{Data: true}, []extracttest.Case{
+		{Name: "data", HTML: page, Selector: ".product", Want: map[string]string{
+			"sku":      "A12",
+			"in-stock": "true",
+		}},
+		{Name: "no data", HTML: page, Selector: "p", Want: map[string]string{}},
+	})
+
+	extracttest.Run(t, Attrs{Data: true, OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: page, Selector: "p", Want: nil},
+	})
+}
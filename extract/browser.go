@@ -0,0 +1,93 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// BrowserEval is a PieceExtractor whose value is the result of evaluating a
+// JavaScript expression in the rendered page, for data that's only reachable
+// through the page's runtime objects - e.g.
+//
+//	extract.BrowserEval{Script: "window.store.getState().cart.items"}
+//
+// The script is evaluated by the Fetcher, which must implement
+// scrape.ScriptEvaluator (such as scrape.PhantomJSFetcher), and the result is
+// returned as with encoding/json: objects are returned as
+// map[string]interface{}, arrays as []interface{}, and numbers as float64.  If
+// the script throws an error, then Extract returns it.
+//
+// Since the result belongs to the whole page rather than to any element, the
+// Piece's selector only needs to match something in the page; "." is
+// recommended.
+type BrowserEval struct {
+	// The JavaScript to evaluate.  The result is the value of the last
+	// expression, which must be serializable as JSON.
+	Script string
+}
+
+func (e BrowserEval) BrowserScript() string {
+	return e.Script
+}
+
+func (e BrowserEval) Validate() error {
+	if e.Script == "" {
+		return errors.New("no script provided")
+	}
+	return nil
+}
+
+func (e BrowserEval) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if sel.Length() == 0 {
+		return nil, nil
+	}
+
+	// Find the root of the document.
+	root := sel.Get(0)
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	var (
+		ret   interface{}
+		found bool
+		err   error
+	)
+	goquery.NewDocumentFromNode(root).Find("script[data-goscrape-eval]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if s.AttrOr("data-goscrape-eval", "") != e.Script {
+			return true
+		}
+		found = true
+
+		var result struct {
+			Value interface{}
+			Error *string
+		}
+		if err = json.Unmarshal([]byte(s.Text()), &result); err != nil {
+			return false
+		}
+		if result.Error != nil {
+			err = fmt.Errorf("error evaluating script: %s", *result.Error)
+			return false
+		}
+		ret = result.Value
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("script was not evaluated; BrowserEval requires a fetcher that implements scrape.ScriptEvaluator")
+	}
+	return ret, nil
+}
+
+var _ scrape.BrowserScripter = BrowserEval{}
+var _ scrape.Validator = BrowserEval{}
@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/andrew-d/goscrape/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// evalFetcher is a ScriptEvaluator that returns a fixed page, with a fixed
+// result for each script.
+type evalFetcher struct {
+	results map[string]string
+	scripts []string
+}
+
+func (f *evalFetcher) Prepare() error { return nil }
+func (f *evalFetcher) Close()         {}
+
+func (f *evalFetcher) SetScripts(scripts []string) { f.scripts = scripts }
+
+func (f *evalFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	page := "<p>page</p>"
+	for _, script := range f.scripts {
+		page += `<script type="application/json" data-goscrape-eval="` + script + `">` + f.results[script] + `</script>`
+	}
+	return ioutil.NopCloser(strings.NewReader(page)), nil
+}
+
+func TestBrowserEval(t *testing.T) {
+	fetcher := &evalFetcher{results: map[string]string{
+		"app.items":  `{"value": [{"id": 1}]}`,
+		"app.broken": `{"error": "TypeError: app.broken is undefined"}`,
+	}}
+	sc, err := scrape.New(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: "p", Extractor: Text{}},
+			{Name: "items", Selector: ".", Extractor: BrowserEval{Script: "app.items"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	results, err := sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.items"}, fetcher.scripts)
+	assert.Equal(t, map[string]interface{}{
+		"text":  "page",
+		"items": []interface{}{map[string]interface{}{"id": float64(1)}},
+	}, results.First())
+
+	extracttest.Run(t, BrowserEval{Script: "app.broken"}, []extracttest.Case{
+		{Name: "error", HTML: `<script type="application/json" data-goscrape-eval="app.broken">{"error": "oops"}</script>`, WantErr: true},
+		{Name: "not evaluated", HTML: `<p>page</p>`, WantErr: true},
+	})
+	extracttest.Run(t, BrowserEval{Script: "1 + 1"}, []extracttest.Case{
+		{Name: "value", HTML: `<script type="application/json" data-goscrape-eval="1 + 1">{"value": 2}</script>`, Want: float64(2)},
+	})
+}
+
+func TestBrowserEvalWrapped(t *testing.T) {
+	fetcher := &evalFetcher{results: map[string]string{
+		"a": `{"value": "A"}`,
+		"b": `{"value": "B"}`,
+		"c": `{"value": "C"}`,
+		"d": `{"value": "D"}`,
+	}}
+	sc, err := scrape.New(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "a", Selector: ".", Extractor: First(BrowserEval{Script: "a"})},
+			{Name: "b", Selector: ".", Extractor: FirstNonNil(Attr{Attr: "data-b", OmitIfEmpty: true}, BrowserEval{Script: "b"})},
+			{Name: "c", Selector: ".", Extractor: Chain(BrowserEval{Script: "c"})},
+			{Name: "d", Selector: ".", Extractor: scrape.WithConcurrencyLimit(1,
+				scrape.WithCache(store.NewMemory(), "d", BrowserEval{Script: "d"}))},
+		},
+	})
+	assert.NoError(t, err)
+
+	results, err := sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, fetcher.scripts)
+	assert.Equal(t, map[string]interface{}{
+		"a": "A", "b": "B", "c": "C", "d": "D",
+	}, results.First())
+}
@@ -0,0 +1,156 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// ValueExtractor is implemented by extractors that can also process the
+// value returned by a previous stage of a Pipeline, rather than a selection.
+type ValueExtractor interface {
+	ExtractValue(v interface{}) (interface{}, error)
+}
+
+// Pipeline is a PieceExtractor that runs a series of extractors, each one
+// processing the output of the one before.  Create one with the Chain
+// function.
+//
+// The first stage is given the Piece's selection.  Each following stage is
+// given the previous stage's result as follows:
+//
+//   - If the result is a *goquery.Selection (e.g. from Find), then it is
+//     passed to the stage's Extract method.
+//   - Otherwise, the stage must implement ValueExtractor, and the result is
+//     passed to its ExtractValue method.
+//
+// If any stage returns nil, then the Pipeline stops and returns nil.  Stages
+// that implement ContextualExtractor are given the block's context during a
+// scrape.
+type Pipeline struct {
+	Stages []scrape.PieceExtractor
+}
+
+// Chain returns a Pipeline that runs the given extractors in order - e.g.
+//
+//	extract.Chain(
+//		extract.Attr{Attr: "href"},
+//		extract.Regex{Regex: regexp.MustCompile(`id=(\d+)`)},
+//	)
+func Chain(extractors ...scrape.PieceExtractor) Pipeline {
+	return Pipeline{Stages: extractors}
+}
+
+func (e Pipeline) Validate() error {
+	if len(e.Stages) == 0 {
+		return errors.New("no stages in pipeline")
+	}
+	for i, stage := range e.Stages {
+		if stage == nil {
+			return fmt.Errorf("stage %d: no extractor provided", i)
+		}
+		if v, ok := stage.(scrape.Validator); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("stage %d: %s", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e Pipeline) Extract(sel *goquery.Selection) (interface{}, error) {
+	return e.run(nil, sel)
+}
+
+func (e Pipeline) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	return e.run(&ctx, sel)
+}
+
+// run runs every stage in turn, passing the given context (if any) to stages
+// that implement ContextualExtractor.
+func (e Pipeline) run(ctx *scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		val interface{} = sel
+		err error
+	)
+	for i, stage := range e.Stages {
+		if s, ok := val.(*goquery.Selection); ok {
+			val, err = extractWithContext(ctx, stage, s)
+		} else if ve, ok := stage.(ValueExtractor); ok {
+			val, err = ve.ExtractValue(val)
+		} else {
+			return nil, fmt.Errorf("stage %d: %T cannot process a %T", i, stage, val)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %s", i, err)
+		}
+		if val == nil {
+			return nil, nil
+		}
+	}
+	return val, nil
+}
+
+func (e Pipeline) Unwrap() []scrape.PieceExtractor {
+	return e.Stages
+}
+
+var _ scrape.ContextualExtractor = Pipeline{}
+var _ scrape.WrappingExtractor = Pipeline{}
+var _ scrape.Validator = Pipeline{}
+
+// extractWithContext runs the given extractor, with the given context if there
+// is one and the extractor implements ContextualExtractor.
+func extractWithContext(ctx *scrape.ExtractContext, e scrape.PieceExtractor, sel *goquery.Selection) (interface{}, error) {
+	if ce, ok := e.(scrape.ContextualExtractor); ok && ctx != nil {
+		return ce.ExtractWithContext(*ctx, sel)
+	}
+	return e.Extract(sel)
+}
+
+// Find is a PieceExtractor that returns the elements matching a selector
+// within the given selection, for use as a stage in a Pipeline.
+type Find struct {
+	// The CSS selector to match.
+	Selector string
+}
+
+func (e Find) Extract(sel *goquery.Selection) (interface{}, error) {
+	return sel.Find(e.Selector), nil
+}
+
+var _ scrape.PieceExtractor = Find{}
+
+// ValueFunc adapts a function into a Pipeline stage.  When used as a regular
+// PieceExtractor, the function is given the *goquery.Selection itself.
+type ValueFunc func(v interface{}) (interface{}, error)
+
+func (f ValueFunc) Extract(sel *goquery.Selection) (interface{}, error) {
+	return f(sel)
+}
+
+func (f ValueFunc) ExtractValue(v interface{}) (interface{}, error) {
+	return f(v)
+}
+
+var _ scrape.PieceExtractor = ValueFunc(nil)
+var _ ValueExtractor = ValueFunc(nil)
+
+// toStrings converts a string or []string result into a list of strings.
+func toStrings(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("expected a string or []string, got %T", v)
+	}
+}
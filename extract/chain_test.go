@@ -0,0 +1,61 @@
+package extract
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	absolute := ValueFunc(func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("not a string: %T", v)
+		}
+		base, _ := url.Parse("http://example.com/a/")
+		u, err := base.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return u.String(), nil
+	})
+
+	extracttest.Run(t, Chain(
+		Find{Selector: "a"},
+		Attr{Attr: "href"},
+		Regex{Regex: regexp.MustCompile(`^(.*)\?`)},
+		absolute,
+	), []extracttest.Case{
+		{Name: "relative", HTML: `<p><a href="b/c?x=1">link</a></p>`, Want: "http://example.com/a/b/c"},
+		{Name: "not a string", HTML: `<a href="x?1">1</a><a href="y?2">2</a>`, WantErr: true},
+	})
+
+	extracttest.Run(t, Chain(Attr{Attr: "href", OmitIfEmpty: true}, Text{}), []extracttest.Case{
+		{Name: "stops on nil", HTML: `<p>none</p>`, Want: nil},
+		{Name: "no value support", HTML: `<a href="x">x</a>`, Selector: "a", WantErr: true},
+	})
+
+	// Contextual stages are given the block's context.
+	ctx := scrape.ExtractContext{
+		URL:    "http://example.com/a/",
+		Header: http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+	}
+	extracttest.Run(t, Chain(Header{Name: "Content-Type"}, Regex{Regex: regexp.MustCompile(`charset=(.+)`)}), []extracttest.Case{
+		{Name: "header", HTML: `<p></p>`, Context: ctx, Want: "utf-8"},
+	})
+	extracttest.Run(t, Chain(Find{Selector: "a"}, Links{Resolve: true}), []extracttest.Case{
+		{Name: "links", HTML: `<p><a href="b">B</a></p>`, Context: ctx, Want: []Link{{URL: "http://example.com/a/b", Text: "B"}}},
+	})
+}
+
+func TestChainValidate(t *testing.T) {
+	assert.EqualError(t, Chain().Validate(), "no stages in pipeline")
+	assert.EqualError(t, Chain(Text{}, Attr{}).Validate(), "stage 1: no attribute provided")
+	assert.NoError(t, Chain(Text{}, Regex{Regex: regexp.MustCompile(`(a)`)}).Validate())
+}
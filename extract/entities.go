@@ -0,0 +1,414 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+	"golang.org/x/net/html"
+)
+
+var (
+	emailRe = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9\-]+(?:\.[a-z0-9\-]+)*\.[a-z]{2,}`)
+
+	// Common ways of hiding an address from scrapers, e.g.
+	// "jane [at] example [dot] com".
+	emailAtRe  = regexp.MustCompile(`(?i)\s*[\[({]\s*at\s*[\])}]\s*`)
+	emailDotRe = regexp.MustCompile(`(?i)\s*[\[({]\s*dot\s*[\])}]\s*`)
+)
+
+// Emails is a PieceExtractor that returns a []string of the email addresses
+// in the selection - both in its text and in any mailto: links within it.
+// Addresses obfuscated in the common "jane [at] example [dot] com" style are
+// recognised too.  Each address is returned once, in the order it was first
+// found, with its domain converted to lower case.
+type Emails struct {
+	// If no addresses are found, then return 'nil' from Extract, instead of
+	// the empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+func (e Emails) Extract(sel *goquery.Selection) (interface{}, error) {
+	results := []string{}
+	seen := map[string]bool{}
+	add := func(addr string) {
+		addr = strings.Trim(addr, ".-")
+		if i := strings.LastIndex(addr, "@"); i >= 0 {
+			addr = addr[:i] + strings.ToLower(addr[i:])
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			results = append(results, addr)
+		}
+	}
+
+	links := sel.Filter("a[href]").AddSelection(sel.Find("a[href]"))
+	links.Each(func(i int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if len(href) < 7 || !strings.EqualFold(href[:7], "mailto:") {
+			return
+		}
+		to := href[7:]
+		if i := strings.IndexByte(to, '?'); i >= 0 {
+			to = to[:i]
+		}
+		if unescaped, err := url.PathUnescape(to); err == nil {
+			to = unescaped
+		}
+		for _, addr := range strings.Split(to, ",") {
+			if addr = strings.TrimSpace(addr); emailRe.MatchString(addr) {
+				add(addr)
+			}
+		}
+	})
+
+	text := sel.Text()
+	text = emailAtRe.ReplaceAllString(text, "@")
+	text = emailDotRe.ReplaceAllString(text, ".")
+	for _, addr := range emailRe.FindAllString(text, -1) {
+		add(addr)
+	}
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return results, nil
+}
+
+var _ scrape.PieceExtractor = Emails{}
+
+// The calling code and trunk prefix of each country that Phones knows about,
+// keyed by ISO 3166-1 alpha-2 code.
+var phoneCountries = map[string]struct {
+	code, trunk string
+}{
+	"AR": {"54", "0"}, "AT": {"43", "0"}, "AU": {"61", "0"}, "BE": {"32", "0"},
+	"BR": {"55", "0"}, "CA": {"1", "1"}, "CH": {"41", "0"}, "CN": {"86", "0"},
+	"CZ": {"420", ""}, "DE": {"49", "0"}, "DK": {"45", ""}, "ES": {"34", ""},
+	"FI": {"358", "0"}, "FR": {"33", "0"}, "GB": {"44", "0"}, "GR": {"30", ""},
+	"HK": {"852", ""}, "IE": {"353", "0"}, "IL": {"972", "0"}, "IN": {"91", "0"},
+	"IT": {"39", ""}, "JP": {"81", "0"}, "KR": {"82", "0"}, "MX": {"52", ""},
+	"NL": {"31", "0"}, "NO": {"47", ""}, "NZ": {"64", "0"}, "PL": {"48", ""},
+	"PT": {"351", ""}, "RU": {"7", "8"}, "SE": {"46", "0"}, "SG": {"65", ""},
+	"TR": {"90", "0"}, "UA": {"380", "0"}, "US": {"1", "1"}, "ZA": {"27", "0"},
+}
+
+var phoneRe = regexp.MustCompile(`(?:\+|\b00)?\(?\d[\d \t\x{a0}().\-/]{5,}\d`)
+
+// Phones is a PieceExtractor that returns a []string of the phone numbers in
+// the selection - both in its text and in any tel: links within it.  The text
+// of tel: links is skipped, since it's usually the same number in a local
+// form.  Each number is returned once, in the order it was first found.
+//
+// Numbers written in international form (with a leading "+" or "00") are
+// normalized to E.164 form, e.g. "+442079460000".  Other numbers are
+// normalized the same way if DefaultCountry is set, and are otherwise returned
+// as just their digits.
+//
+// Sequences of digits that are too short or too long to be phone numbers, or
+// that look like dates or ranges of years, are ignored.
+type Phones struct {
+	// The ISO 3166-1 alpha-2 code (e.g. "US" or "GB") of the country that
+	// numbers without a country code are assumed to be in.
+	DefaultCountry string
+
+	// If no numbers are found, then return 'nil' from Extract, instead of the
+	// empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+func (e Phones) Validate() error {
+	if e.DefaultCountry == "" {
+		return nil
+	}
+	if _, found := phoneCountries[strings.ToUpper(e.DefaultCountry)]; !found {
+		return fmt.Errorf("unknown country %q", e.DefaultCountry)
+	}
+	return nil
+}
+
+var phoneDateRe = regexp.MustCompile(`^\d{1,4}[./\-]\d{1,2}[./\-]\d{1,4}$|^\d{4}\s*[\-/]\s*\d{4}$`)
+
+func (e Phones) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := []string{}
+	seen := map[string]bool{}
+	add := func(raw string) {
+		if phoneDateRe.MatchString(strings.TrimSpace(raw)) {
+			return
+		}
+		if num := e.normalize(raw); num != "" && !seen[num] {
+			seen[num] = true
+			results = append(results, num)
+		}
+	}
+
+	links := sel.Filter("a[href]").AddSelection(sel.Find("a[href]"))
+	links.Each(func(i int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if len(href) > 4 && strings.EqualFold(href[:4], "tel:") {
+			add(href[4:])
+		}
+	})
+	for _, raw := range phoneRe.FindAllString(textOutsideTelLinks(sel), -1) {
+		add(raw)
+	}
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return results, nil
+}
+
+// textOutsideTelLinks returns the text of the selection, except for that
+// inside tel: links.
+func textOutsideTelLinks(sel *goquery.Selection) string {
+	var buf bytes.Buffer
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if n.Data == "a" {
+				for _, attr := range n.Attr {
+					if attr.Key == "href" && len(attr.Val) > 4 && strings.EqualFold(attr.Val[:4], "tel:") {
+						buf.WriteByte(' ')
+						return
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range sel.Nodes {
+		walk(n)
+	}
+	return buf.String()
+}
+
+// normalize returns the given number in E.164 form if possible, or as digits
+// otherwise.  It returns the empty string if the number is invalid.
+func (e Phones) normalize(raw string) string {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+	if international {
+		// A trunk prefix written after the country code, as in
+		// "+44 (0)20 7946 0000", isn't dialled.
+		raw = strings.Replace(raw, "(0)", "", 1)
+	}
+
+	var digits strings.Builder
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	num := digits.String()
+	if !international && strings.HasPrefix(num, "00") {
+		international = true
+		num = num[2:]
+	}
+
+	if international {
+		// E.164 allows at most 15 digits, including the country code.
+		if len(num) < 8 || len(num) > 15 {
+			return ""
+		}
+		return "+" + num
+	}
+
+	if len(num) < 7 || len(num) > 13 {
+		return ""
+	}
+	country, found := phoneCountries[strings.ToUpper(e.DefaultCountry)]
+	if !found {
+		return num
+	}
+	if country.trunk != "" && strings.HasPrefix(num, country.trunk) {
+		num = num[len(country.trunk):]
+	}
+	if len(country.code)+len(num) > 15 {
+		return ""
+	}
+	return "+" + country.code + num
+}
+
+var _ scrape.PieceExtractor = Phones{}
+var _ scrape.Validator = Phones{}
+
+// SocialProfile is a single profile returned by the SocialProfiles
+// extractor.
+type SocialProfile struct {
+	// The name of the network, e.g. "twitter" or "github".
+	Network string
+
+	// The handle or ID of the profile on that network, without any leading
+	// "@".
+	Handle string
+
+	// The link to the profile.
+	URL string
+}
+
+// The paths on each network's site that aren't profiles.
+var socialReserved = map[string]bool{
+	"share": true, "sharer": true, "sharer.php": true, "intent": true,
+	"home": true, "login": true, "signup": true, "search": true,
+	"hashtag": true, "explore": true, "about": true, "help": true,
+	"privacy": true, "legal": true, "tos": true, "settings": true,
+	"p": true, "reel": true, "status": true, "watch": true, "embed": true,
+	"dialog": true, "plugins": true, "groups": true, "events": true,
+}
+
+// socialProfile returns the profile linked to by the given URL, if any.
+func socialProfile(u *url.URL) (SocialProfile, bool) {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	parts := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	var network, handle string
+	switch host {
+	case "twitter.com", "x.com":
+		network = "twitter"
+		if len(parts) > 0 {
+			handle = parts[0]
+		}
+	case "instagram.com":
+		network = "instagram"
+		if len(parts) > 0 {
+			handle = parts[0]
+		}
+	case "facebook.com", "fb.com":
+		network = "facebook"
+		if len(parts) > 0 {
+			handle = parts[0]
+		}
+		if handle == "profile.php" {
+			handle = u.Query().Get("id")
+		}
+	case "linkedin.com":
+		network = "linkedin"
+		if len(parts) > 1 && (parts[0] == "in" || parts[0] == "company" || parts[0] == "school") {
+			handle = parts[0] + "/" + parts[1]
+		}
+	case "github.com":
+		network = "github"
+		if len(parts) > 0 {
+			handle = parts[0]
+		}
+	case "youtube.com":
+		network = "youtube"
+		if len(parts) > 0 && strings.HasPrefix(parts[0], "@") {
+			handle = parts[0]
+		} else if len(parts) > 1 && (parts[0] == "c" || parts[0] == "channel" || parts[0] == "user") {
+			handle = parts[0] + "/" + parts[1]
+		}
+	case "tiktok.com":
+		network = "tiktok"
+		if len(parts) > 0 && strings.HasPrefix(parts[0], "@") {
+			handle = parts[0]
+		}
+	case "pinterest.com":
+		network = "pinterest"
+		if len(parts) > 0 {
+			handle = parts[0]
+		}
+	case "mastodon.social", "threads.net":
+		network = strings.TrimSuffix(strings.TrimSuffix(host, ".social"), ".net")
+		if len(parts) > 0 && strings.HasPrefix(parts[0], "@") {
+			handle = parts[0]
+		}
+	}
+
+	handle = strings.TrimPrefix(handle, "@")
+	if network == "" || handle == "" || socialReserved[strings.ToLower(handle)] {
+		return SocialProfile{}, false
+	}
+	return SocialProfile{Network: network, Handle: handle, URL: u.String()}, true
+}
+
+// SocialProfiles is a PieceExtractor that returns a []SocialProfile for each
+// link in the selection, or inside it, to a profile on a well-known social
+// network: Twitter/X, Instagram, Facebook, LinkedIn, GitHub, YouTube, TikTok,
+// Pinterest, Threads, and mastodon.social.  Share buttons and links to
+// individual posts are ignored.  Each profile is returned once, in the order
+// it was first found.
+type SocialProfiles struct {
+	// If Networks is set, then only profiles on these networks (e.g.
+	// "twitter") are returned.
+	Networks []string
+
+	// If no profiles are found, then return 'nil' from Extract, instead of the
+	// empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+func (e SocialProfiles) Validate() error {
+	for _, network := range e.Networks {
+		if network == "" {
+			return errors.New("empty network name")
+		}
+	}
+	return nil
+}
+
+func (e SocialProfiles) Extract(sel *goquery.Selection) (interface{}, error) {
+	results := []SocialProfile{}
+	seen := map[string]bool{}
+
+	links := sel.Filter("a[href]").AddSelection(sel.Find("a[href]"))
+	links.Each(func(i int, s *goquery.Selection) {
+		u, err := url.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil {
+			return
+		}
+		profile, ok := socialProfile(u)
+		if !ok || !e.allowed(profile.Network) {
+			return
+		}
+
+		key := profile.Network + "/" + strings.ToLower(profile.Handle)
+		if !seen[key] {
+			seen[key] = true
+			results = append(results, profile)
+		}
+	})
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return results, nil
+}
+
+func (e SocialProfiles) allowed(network string) bool {
+	if len(e.Networks) == 0 {
+		return true
+	}
+	for _, n := range e.Networks {
+		if strings.EqualFold(n, network) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ scrape.PieceExtractor = SocialProfiles{}
+var _ scrape.Validator = SocialProfiles{}
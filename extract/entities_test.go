@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestEmails(t *testing.T) {
+	const page = `<div class="contact">
+		<p>Write to <a href="mailto:Sales@Example.COM?subject=Hi">our sales team</a>
+		or to support@example.com.</p>
+		<p>Press: jane.doe [at] example (dot) org, or Sales@example.com again.</p>
+		<p>Look at example.com, or email nobody at all.</p>
+	</div>`
+
+	extracttest.Run(t, Emails{}, []extracttest.Case{
+		{Name: "all", HTML: page, Selector: ".contact", Want: []string{
+			"Sales@example.com",
+			"support@example.com",
+			"jane.doe@example.org",
+		}},
+		{Name: "none", HTML: `<p>no addresses here @ all</p>`, Selector: "p", Want: []string{}},
+	})
+
+	extracttest.Run(t, Emails{OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: `<p>nothing</p>`, Selector: "p", Want: nil},
+	})
+}
+
+func TestPhones(t *testing.T) {
+	const page = `<div class="contact">
+		<p>Call <a href="tel:+1-800-555-0199">1 (800) 555-0199</a> or +44 (0)20 7946 0000.</p>
+		<p>Local: 020 7946 0001</p>
+		<p>From abroad: 0033 1 23 45 67 89</p>
+		<p>Open 2019-2026, since 18/10/2019, order #1234.</p>
+	</div>`
+
+	extracttest.Run(t, Phones{}, []extracttest.Case{
+		{Name: "no country", HTML: page, Selector: ".contact", Want: []string{
+			"+18005550199",
+			"+442079460000",
+			"02079460001",
+			"+33123456789",
+		}},
+	})
+
+	extracttest.Run(t, Phones{DefaultCountry: "gb"}, []extracttest.Case{
+		{Name: "default country", HTML: page, Selector: ".contact", Want: []string{
+			"+18005550199",
+			"+442079460000",
+			"+442079460001",
+			"+33123456789",
+		}},
+	})
+
+	extracttest.Run(t, Phones{DefaultCountry: "US", OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "us", HTML: `<p>(555) 123-4567
+			1-555-123-4567</p>`, Selector: "p", Want: []string{"+15551234567"}},
+		{Name: "omitted", HTML: `<p>call us</p>`, Selector: "p", Want: nil},
+	})
+
+	extracttest.Run(t, Phones{DefaultCountry: "XX"}, []extracttest.Case{
+		{Name: "bad country", HTML: page, Selector: ".contact", WantErr: true},
+	})
+}
+
+func TestSocialProfiles(t *testing.T) {
+	const page = `<footer>
+		<a href="https://twitter.com/example">Twitter</a>
+		<a href="https://x.com/Example/status/123">A post</a>
+		<a href="https://twitter.com/intent/tweet?text=hi">Share</a>
+		<a href="https://www.instagram.com/example.shop/">Instagram</a>
+		<a href="https://www.facebook.com/sharer/sharer.php?u=x">Share</a>
+		<a href="https://www.facebook.com/profile.php?id=1234">Facebook</a>
+		<a href="https://www.linkedin.com/company/example-inc/">LinkedIn</a>
+		<a href="https://github.com/example">GitHub</a>
+		<a href="https://www.youtube.com/@ExampleTV">YouTube</a>
+		<a href="https://www.youtube.com/watch?v=abc">A video</a>
+		<a href="https://example.com/about">About</a>
+	</footer>`
+
+	extracttest.Run(t, SocialProfiles{}, []extracttest.Case{
+		{Name: "all", HTML: page, Selector: "footer", Want: []SocialProfile{
+			{Network: "twitter", Handle: "example", URL: "https://twitter.com/example"},
+			{Network: "instagram", Handle: "example.shop", URL: "https://www.instagram.com/example.shop/"},
+			{Network: "facebook", Handle: "1234", URL: "https://www.facebook.com/profile.php?id=1234"},
+			{Network: "linkedin", Handle: "company/example-inc", URL: "https://www.linkedin.com/company/example-inc/"},
+			{Network: "github", Handle: "example", URL: "https://github.com/example"},
+			{Network: "youtube", Handle: "ExampleTV", URL: "https://www.youtube.com/@ExampleTV"},
+		}},
+		{Name: "none", HTML: `<p><a href="/x">x</a></p>`, Selector: "p", Want: []SocialProfile{}},
+	})
+
+	extracttest.Run(t, SocialProfiles{Networks: []string{"GitHub"}, OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "filtered", HTML: page, Selector: "footer", Want: []SocialProfile{
+			{Network: "github", Handle: "example", URL: "https://github.com/example"},
+		}},
+		{Name: "omitted", HTML: `<p>none</p>`, Selector: "p", Want: nil},
+	})
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
+	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/andrew-d/goscrape"
@@ -21,17 +24,83 @@ func (e Const) Extract(sel *goquery.Selection) (interface{}, error) {
 	return e.Val, nil
 }
 
+func (e Const) JSONSchema() map[string]interface{} {
+	if e.Val == nil {
+		return map[string]interface{}{}
+	}
+	return scrape.TypeSchema(reflect.TypeOf(e.Val))
+}
+
 var _ scrape.PieceExtractor = Const{}
+var _ scrape.SchemaExtractor = Const{}
 
 // Text is a PieceExtractor that returns the combined text contents of
 // the given selection.
-type Text struct{}
+type Text struct {
+	// If TrimSpace is true, then leading and trailing whitespace is removed
+	// from the text of each element.
+	TrimSpace bool
+
+	// If CollapseWhitespace is true, then every run of whitespace (including
+	// newlines and non-breaking spaces) in the text of each element is
+	// replaced by a single space.
+	CollapseWhitespace bool
+
+	// Separator is placed between the text of each element, when the
+	// selection contains more than one.  By default, the texts are joined
+	// without any separator.
+	Separator string
+}
 
 func (e Text) Extract(sel *goquery.Selection) (interface{}, error) {
-	return sel.Text(), nil
+	if !e.TrimSpace && !e.CollapseWhitespace && e.Separator == "" {
+		return sel.Text(), nil
+	}
+
+	var buf bytes.Buffer
+	sel.Each(func(i int, s *goquery.Selection) {
+		if i > 0 {
+			buf.WriteString(e.Separator)
+		}
+		buf.WriteString(e.clean(s.Text()))
+	})
+	return buf.String(), nil
+}
+
+func (e Text) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string"}
+}
+
+func (e Text) clean(s string) string {
+	if e.CollapseWhitespace {
+		s = collapseWhitespace(s)
+	}
+	if e.TrimSpace {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// collapseWhitespace replaces every run of whitespace in s with a single space.
+func collapseWhitespace(s string) string {
+	var buf bytes.Buffer
+	inSpace := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				buf.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		buf.WriteRune(r)
+	}
+	return buf.String()
 }
 
 var _ scrape.PieceExtractor = Text{}
+var _ scrape.SchemaExtractor = Text{}
 
 // MultipleText is a PieceExtractor that extracts the text from each element
 // in the given selection and returns the texts as an array.
@@ -57,6 +126,13 @@ func (e MultipleText) Extract(sel *goquery.Selection) (interface{}, error) {
 	return results, nil
 }
 
+func (e MultipleText) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+}
+
+var _ scrape.PieceExtractor = MultipleText{}
+var _ scrape.SchemaExtractor = MultipleText{}
+
 // Html extracts and returns the HTML from inside each element of the
 // given selection, as a string.
 //
@@ -87,7 +163,12 @@ func (e Html) Extract(sel *goquery.Selection) (interface{}, error) {
 	return ret, nil
 }
 
+func (e Html) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string"}
+}
+
 var _ scrape.PieceExtractor = Html{}
+var _ scrape.SchemaExtractor = Html{}
 
 // OuterHtml extracts and returns the HTML of each element of the
 // given selection, as a string.
@@ -110,11 +191,22 @@ func (e OuterHtml) Extract(sel *goquery.Selection) (interface{}, error) {
 	return output.String(), nil
 }
 
+func (e OuterHtml) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string"}
+}
+
 var _ scrape.PieceExtractor = OuterHtml{}
+var _ scrape.SchemaExtractor = OuterHtml{}
 
 // Regex runs the given regex over the contents of each element in the
 // given selection, and, for each match, extracts the given subexpression.
 // The return type of the extractor is a list of string matches (i.e. []string).
+//
+// If NamedGroups is set, then each match is instead a map from the name of
+// each named subexpression to its text (i.e. the extractor returns a
+// []map[string]string), so that several fields can be extracted at once - e.g.
+// `(?P<day>\d+)/(?P<month>\d+)`.  Named subexpressions that didn't
+// participate in a match are mapped to the empty string.
 type Regex struct {
 	// The regular expression to match.  This regular expression must define
 	// exactly one parenthesized subexpression (sometimes known as a "capturing
@@ -125,6 +217,12 @@ type Regex struct {
 	// the given regex has more than one subexpression, an error will be thrown.
 	Subexpression int
 
+	// If NamedGroups is true, then return a map of the named subexpressions
+	// for each match, as described above, instead of a single subexpression.
+	// The regex must have at least one named subexpression, and the
+	// Subexpression field is ignored.
+	NamedGroups bool
+
 	// When OnlyText is true, only run the given regex over the text contents of
 	// each element in the selection, as opposed to the HTML contents.
 	OnlyText bool
@@ -142,49 +240,107 @@ type Regex struct {
 	OmitIfEmpty bool
 }
 
-func (e Regex) Extract(sel *goquery.Selection) (interface{}, error) {
+// subexp returns the index of the subexpression to extract, or an error if the
+// options are invalid.
+func (e Regex) subexp() (int, error) {
 	if e.Regex == nil {
-		return nil, errors.New("no regex given")
+		return 0, errors.New("no regex given")
 	}
 	if e.Regex.NumSubexp() == 0 {
-		return nil, errors.New("regex has no subexpressions")
+		return 0, errors.New("regex has no subexpressions")
+	}
+	if e.NamedGroups {
+		for _, name := range e.Regex.SubexpNames() {
+			if name != "" {
+				return 0, nil
+			}
+		}
+		return 0, errors.New("regex has no named subexpressions")
 	}
 
-	var subexp int
 	if e.Subexpression == 0 {
 		if e.Regex.NumSubexp() != 1 {
 			e := fmt.Errorf(
 				"regex has more than one subexpression (%d), but which to "+
 					"extract was not specified",
 				e.Regex.NumSubexp())
-			return nil, e
+			return 0, e
 		}
 
-		subexp = 1
-	} else {
-		subexp = e.Subexpression
+		return 1, nil
 	}
 
-	results := []string{}
+	if e.Subexpression < 0 || e.Subexpression > e.Regex.NumSubexp() {
+		return 0, fmt.Errorf("subexpression %d is out of range (regex has %d)",
+			e.Subexpression, e.Regex.NumSubexp())
+	}
+	return e.Subexpression, nil
+}
+
+func (e Regex) Validate() error {
+	_, err := e.subexp()
+	return err
+}
+
+func (e Regex) Extract(sel *goquery.Selection) (interface{}, error) {
+	subexp, err := e.subexp()
+	if err != nil {
+		return nil, err
+	}
+
+	contents := []string{}
 
 	// For each element in the selector...
-	var err error
 	sel.EachWithBreak(func(i int, s *goquery.Selection) bool {
-		var contents string
 		if e.OnlyText {
-			contents = s.Text()
-		} else {
-			contents, err = s.Html()
-			if err != nil {
-				return false
-			}
+			contents = append(contents, s.Text())
+			return true
+		}
+
+		var h string
+		if h, err = s.Html(); err != nil {
+			return false
 		}
+		contents = append(contents, h)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return e.match(subexp, contents), nil
+}
+
+// ExtractValue runs the regex over a string, or each string in a []string,
+// that was returned by a previous stage of a Pipeline.
+func (e Regex) ExtractValue(v interface{}) (interface{}, error) {
+	subexp, err := e.subexp()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := toStrings(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.match(subexp, contents), nil
+}
 
-		ret := e.Regex.FindAllStringSubmatch(contents, -1)
+// match runs the regex over each of the given strings, and returns the
+// results as described on the Regex type.
+func (e Regex) match(subexp int, contents []string) interface{} {
+	if e.NamedGroups {
+		return e.matchNamed(contents)
+	}
+
+	results := []string{}
+
+	for _, c := range contents {
+		ret := e.Regex.FindAllStringSubmatch(c, -1)
 
 		// A return value of nil == no match
 		if ret == nil {
-			return true
+			continue
 		}
 
 		// For each regex match...
@@ -196,24 +352,60 @@ func (e Regex) Extract(sel *goquery.Selection) (interface{}, error) {
 				results = append(results, submatches[subexp])
 			}
 		}
+	}
 
-		return true
-	})
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil
+	}
+	if len(results) == 1 && !e.AlwaysReturnList {
+		return results[0]
+	}
 
-	if err != nil {
-		return nil, err
+	return results
+}
+
+// matchNamed runs the regex over each of the given strings, and returns a map
+// of the named subexpressions for each match.
+func (e Regex) matchNamed(contents []string) interface{} {
+	results := []map[string]string{}
+	names := e.Regex.SubexpNames()
+
+	for _, c := range contents {
+		for _, submatches := range e.Regex.FindAllStringSubmatch(c, -1) {
+			m := map[string]string{}
+			for i, name := range names {
+				if name != "" {
+					m[name] = submatches[i]
+				}
+			}
+			results = append(results, m)
+		}
 	}
+
 	if len(results) == 0 && e.OmitIfEmpty {
-		return nil, nil
+		return nil
 	}
 	if len(results) == 1 && !e.AlwaysReturnList {
-		return results[0], nil
+		return results[0]
 	}
 
-	return results, nil
+	return results
+}
+
+// JSONSchema returns the schema of each match - a string, or an object if
+// NamedGroups is set - or of a list of them.
+func (e Regex) JSONSchema() map[string]interface{} {
+	item := map[string]interface{}{"type": "string"}
+	if e.NamedGroups {
+		item = map[string]interface{}{"type": "object", "additionalProperties": item}
+	}
+	return listSchema(item, e.AlwaysReturnList)
 }
 
 var _ scrape.PieceExtractor = Regex{}
+var _ scrape.Validator = Regex{}
+var _ ValueExtractor = Regex{}
+var _ scrape.SchemaExtractor = Regex{}
 
 // Attr extracts the value of a given HTML attribute from each element
 // in the selection, and returns them as a list.
@@ -235,9 +427,16 @@ type Attr struct {
 	OmitIfEmpty bool
 }
 
-func (e Attr) Extract(sel *goquery.Selection) (interface{}, error) {
+func (e Attr) Validate() error {
 	if len(e.Attr) == 0 {
-		return nil, errors.New("no attribute provided")
+		return errors.New("no attribute provided")
+	}
+	return nil
+}
+
+func (e Attr) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
 	}
 
 	results := []string{}
@@ -258,7 +457,23 @@ func (e Attr) Extract(sel *goquery.Selection) (interface{}, error) {
 	return results, nil
 }
 
+func (e Attr) JSONSchema() map[string]interface{} {
+	return listSchema(map[string]interface{}{"type": "string"}, e.AlwaysReturnList)
+}
+
 var _ scrape.PieceExtractor = Attr{}
+var _ scrape.Validator = Attr{}
+var _ scrape.SchemaExtractor = Attr{}
+
+// listSchema returns the schema of the values of an extractor that returns a
+// list of items, or a single item on its own if alwaysList is false.
+func listSchema(item map[string]interface{}, alwaysList bool) map[string]interface{} {
+	list := map[string]interface{}{"type": "array", "items": item}
+	if alwaysList {
+		return list
+	}
+	return map[string]interface{}{"anyOf": []interface{}{item, list}}
+}
 
 // Count extracts the count of elements that are matched and returns it.
 type Count struct {
@@ -277,3 +492,80 @@ func (e Count) Extract(sel *goquery.Selection) (interface{}, error) {
 
 	return l, nil
 }
+
+func (e Count) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "integer"}
+}
+
+var _ scrape.PieceExtractor = Count{}
+var _ scrape.SchemaExtractor = Count{}
+
+// GroupCount counts the elements that are matched, grouped by the value of an
+// attribute, and returns a map from each value to its count - e.g. the number
+// of posts with each tag.  Elements without the attribute aren't counted.
+type GroupCount struct {
+	// The attribute to group by.  If this is "class", then an element is
+	// counted once for each of its classes.  If this is empty, then elements
+	// are grouped by their text, with whitespace trimmed.
+	Attr string
+
+	// If no elements are counted, then return 'nil' from Extract, instead of
+	// an empty map.  This signals that the result of this Piece should be
+	// omitted entirely from the results.
+	OmitIfEmpty bool
+}
+
+func (e GroupCount) Extract(sel *goquery.Selection) (interface{}, error) {
+	counts := map[string]int{}
+	sel.Each(func(i int, s *goquery.Selection) {
+		if e.Attr == "" {
+			if text := strings.TrimSpace(s.Text()); text != "" {
+				counts[text]++
+			}
+			return
+		}
+
+		val, found := s.Attr(e.Attr)
+		if !found {
+			return
+		}
+		if e.Attr == "class" {
+			for _, class := range strings.Fields(val) {
+				counts[class]++
+			}
+			return
+		}
+		counts[val]++
+	})
+
+	if len(counts) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return counts, nil
+}
+
+func (e GroupCount) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "integer"}}
+}
+
+var _ scrape.PieceExtractor = GroupCount{}
+var _ scrape.SchemaExtractor = GroupCount{}
+
+// Exists returns true if the selector matched any elements, and false
+// otherwise.  This is useful for flags such as "is sponsored" or "sold out".
+type Exists struct {
+	// If Invert is true, then the result is reversed - i.e. Exists returns
+	// true if the selector didn't match any elements.
+	Invert bool
+}
+
+func (e Exists) Extract(sel *goquery.Selection) (interface{}, error) {
+	return (sel.Length() > 0) != e.Invert, nil
+}
+
+func (e Exists) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "boolean"}
+}
+
+var _ scrape.PieceExtractor = Exists{}
+var _ scrape.SchemaExtractor = Exists{}
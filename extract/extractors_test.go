@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape/extract/extracttest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,6 +30,19 @@ func TestText(t *testing.T) {
 	ret, err = Text{}.Extract(sel)
 	assert.NoError(t, err)
 	assert.Equal(t, ret, "FirstSecond")
+
+	sel = selFrom("<li>\n  Red\u00a0 and\n blue </li><li> Green</li>")
+	ret, err = Text{TrimSpace: true}.Extract(sel.Find("li"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Red\u00a0 and\n blueGreen", ret)
+
+	ret, err = Text{CollapseWhitespace: true}.Extract(sel.Find("li"))
+	assert.NoError(t, err)
+	assert.Equal(t, " Red and blue  Green", ret)
+
+	ret, err = Text{TrimSpace: true, CollapseWhitespace: true, Separator: ", "}.Extract(sel.Find("li"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Red and blue, Green", ret)
 }
 
 func TestMultipleText(t *testing.T) {
@@ -84,6 +98,9 @@ func TestRegexInvalid(t *testing.T) {
 
 	_, err = Regex{Regex: regexp.MustCompile(`(a)(b)`)}.Extract(selFrom(`bar`))
 	assert.Error(t, err, "regex has more than one subexpression (2), but which to extract was not specified")
+
+	_, err = Regex{Regex: regexp.MustCompile(`(a)(b)`), Subexpression: 3}.Extract(selFrom(`bar`))
+	assert.Error(t, err, "subexpression 3 is out of range (regex has 2)")
 }
 
 func TestRegex(t *testing.T) {
@@ -122,6 +139,71 @@ func TestRegex(t *testing.T) {
 	assert.Nil(t, ret)
 }
 
+func TestRegexCases(t *testing.T) {
+	extracttest.Run(t, Regex{
+		Regex:         regexp.MustCompile(`(\d+)-(\d+)?`),
+		Subexpression: 2,
+		OnlyText:      true,
+	}, []extracttest.Case{
+		{Name: "match", HTML: `<p>12-34</p>`, Want: "34"},
+		{Name: "optional group", HTML: `<p>12-</p>`, Want: ""},
+		{Name: "multiple", HTML: `<p>1-2</p><p>3-4</p>`, Selector: "p", Want: []string{"2", "4"}},
+		{Name: "no match", HTML: `<p>foo</p>`, Want: []string{}},
+	})
+
+	extracttest.Run(t, Regex{
+		Regex:         regexp.MustCompile(`(a)(b)`),
+		Subexpression: 3,
+	}, []extracttest.Case{
+		{Name: "out of range", HTML: `<p>ab</p>`, WantErr: true},
+	})
+}
+
+func TestRegexNamedGroups(t *testing.T) {
+	extracttest.Run(t, Regex{
+		Regex:       regexp.MustCompile(`(?P<day>\d+)-(?P<month>\d+)(-(?P<year>\d+))?`),
+		NamedGroups: true,
+		OnlyText:    true,
+	}, []extracttest.Case{
+		{Name: "match", HTML: `<p>18-10-2026</p>`, Want: map[string]string{
+			"day": "18", "month": "10", "year": "2026",
+		}},
+		{Name: "optional group", HTML: `<p>18-10</p>`, Want: map[string]string{
+			"day": "18", "month": "10", "year": "",
+		}},
+		{Name: "multiple", HTML: `<p>1-2 and 3-4-5</p>`, Want: []map[string]string{
+			{"day": "1", "month": "2", "year": ""},
+			{"day": "3", "month": "4", "year": "5"},
+		}},
+		{Name: "no match", HTML: `<p>foo</p>`, Want: []map[string]string{}},
+	})
+
+	extracttest.Run(t, Regex{
+		Regex:            regexp.MustCompile(`(?P<key>\w+)=(\w+)`),
+		Subexpression:    5,
+		NamedGroups:      true,
+		AlwaysReturnList: true,
+	}, []extracttest.Case{
+		{Name: "list", HTML: `<p>a=b</p>`, Want: []map[string]string{{"key": "a"}}},
+	})
+
+	extracttest.Run(t, Regex{
+		Regex:       regexp.MustCompile(`(\w+)=(\w+)`),
+		NamedGroups: true,
+		OmitIfEmpty: true,
+	}, []extracttest.Case{
+		{Name: "no names", HTML: `<p>a=b</p>`, WantErr: true},
+	})
+
+	ret, err := Regex{
+		Regex:       regexp.MustCompile(`(?P<n>\d+)`),
+		NamedGroups: true,
+		OmitIfEmpty: true,
+	}.ExtractValue([]string{"no numbers"})
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+}
+
 func TestAttrInvalid(t *testing.T) {
 	var err error
 
@@ -189,3 +271,48 @@ func TestCount(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Nil(t, ret)
 }
+
+func TestGroupCount(t *testing.T) {
+	sel := selFrom(`
+	<a class="tag go" data-kind="lang">go</a>
+	<a class="tag" data-kind="topic"> scraping </a>
+	<a class="tag go" data-kind="lang">go</a>
+	<a>untagged</a>
+	`)
+
+	ret, err := GroupCount{Attr: "data-kind"}.Extract(sel.Find("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"lang": 2, "topic": 1}, ret)
+
+	ret, err = GroupCount{Attr: "class"}.Extract(sel.Find("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"tag": 3, "go": 2}, ret)
+
+	ret, err = GroupCount{}.Extract(sel.Find("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"go": 2, "scraping": 1, "untagged": 1}, ret)
+
+	ret, err = GroupCount{Attr: "href"}.Extract(sel.Find("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{}, ret)
+
+	ret, err = GroupCount{Attr: "href", OmitIfEmpty: true}.Extract(sel.Find("a"))
+	assert.NoError(t, err)
+	assert.Nil(t, ret)
+}
+
+func TestExists(t *testing.T) {
+	sel := selFrom(`<div class="sponsored">Ad</div>`)
+
+	ret, err := Exists{}.Extract(sel.Find(".sponsored"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret)
+
+	ret, err = Exists{}.Extract(sel.Find(".sold-out"))
+	assert.NoError(t, err)
+	assert.Equal(t, false, ret)
+
+	ret, err = Exists{Invert: true}.Extract(sel.Find(".sold-out"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret)
+}
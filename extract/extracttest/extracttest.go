@@ -0,0 +1,93 @@
+// Package extracttest provides helpers for testing PieceExtractors.
+package extracttest
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Case is a single test case for an extractor.
+type Case struct {
+	// A name for this case, used in failure messages.  If empty, then the
+	// case's index is used.
+	Name string
+
+	// The HTML document (or fragment) to parse.
+	HTML string
+
+	// Selector narrows the parsed document down to the selection that is
+	// passed to the extractor.  If this is empty, then the whole document is
+	// passed, which is the same as using "." in a Piece.
+	Selector string
+
+	// Context is passed to extractors that implement ContextualExtractor,
+	// which are run with ExtractWithContext as they would be during a scrape.
+	Context scrape.ExtractContext
+
+	// The expected result from the extractor.  A nil value means that the
+	// extractor is expected to return nil - i.e. that the Piece is omitted from
+	// the results.
+	Want interface{}
+
+	// If WantErr is true, then the extractor is expected to return an error,
+	// and Want is ignored.
+	WantErr bool
+}
+
+// Run runs the given extractor on every case, and reports a test failure for
+// each case whose result doesn't match.  Results are compared with
+// reflect.DeepEqual, so types must match exactly - e.g. a []string result
+// must be compared against a []string, not a []interface{}.
+func Run(t testing.TB, e scrape.PieceExtractor, cases []Case) {
+	t.Helper()
+
+	ce, contextual := e.(scrape.ContextualExtractor)
+	for i, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = "case " + strconv.Itoa(i)
+		}
+
+		sel := Selection(t, c.HTML)
+		if c.Selector != "" {
+			sel = sel.Find(c.Selector)
+		}
+
+		var got interface{}
+		var err error
+		if contextual {
+			got, err = ce.ExtractWithContext(c.Context, sel)
+		} else {
+			got, err = e.Extract(sel)
+		}
+		switch {
+		case c.WantErr && err == nil:
+			t.Errorf("%s: expected an error, got result %#v", name, got)
+		case c.WantErr:
+			// Expected error; nothing else to check.
+		case err != nil:
+			t.Errorf("%s: unexpected error: %s", name, err)
+		case c.Want == nil && got != nil:
+			t.Errorf("%s: expected nil (omitted) result, got %#v", name, got)
+		case !reflect.DeepEqual(c.Want, got):
+			t.Errorf("%s: expected %#v, got %#v", name, c.Want, got)
+		}
+	}
+}
+
+// Selection parses the given HTML and returns the root selection of the
+// resulting document.  It fails the test immediately if parsing fails.
+func Selection(t testing.TB, html string) *goquery.Selection {
+	t.Helper()
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("could not parse HTML: %s", err)
+	}
+	return doc.Selection
+}
@@ -0,0 +1,84 @@
+package extracttest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+type textExtractor struct{}
+
+func (textExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	if sel.Length() == 0 {
+		return nil, nil
+	}
+	if sel.Text() == "error" {
+		return nil, errors.New("bad text")
+	}
+	return sel.Text(), nil
+}
+
+// urlExtractor returns the URL of the page, which is only known with a
+// context.
+type urlExtractor struct{}
+
+func (urlExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	return nil, nil
+}
+
+func (urlExtractor) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	return ctx.URL, nil
+}
+
+// recordingT captures failures instead of reporting them.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRun(t *testing.T) {
+	rt := &recordingT{TB: t}
+	Run(rt, textExtractor{}, []Case{
+		{Name: "ok", HTML: `<p>foo</p>`, Selector: "p", Want: "foo"},
+		{Name: "omitted", HTML: `<p>foo</p>`, Selector: "b"},
+		{Name: "error", HTML: `<p>error</p>`, Selector: "p", WantErr: true},
+	})
+	if len(rt.errors) != 0 {
+		t.Errorf("expected no failures, got %v", rt.errors)
+	}
+
+	rt = &recordingT{TB: t}
+	Run(rt, textExtractor{}, []Case{
+		{HTML: `<p>foo</p>`, Selector: "p", Want: "bar"},
+		{HTML: `<p>foo</p>`, Selector: "p"},
+		{HTML: `<p>foo</p>`, Selector: "p", WantErr: true},
+		{HTML: `<p>error</p>`, Selector: "p", Want: "error"},
+	})
+	expected := []string{
+		`case 0: expected "bar", got "foo"`,
+		`case 1: expected nil (omitted) result, got "foo"`,
+		`case 2: expected an error, got result "foo"`,
+		`case 3: unexpected error: bad text`,
+	}
+	if fmt.Sprint(rt.errors) != fmt.Sprint(expected) {
+		t.Errorf("expected failures %v, got %v", expected, rt.errors)
+	}
+}
+
+func TestRunContextual(t *testing.T) {
+	rt := &recordingT{TB: t}
+	Run(rt, urlExtractor{}, []Case{
+		{HTML: `<p>foo</p>`, Context: scrape.ExtractContext{URL: "http://example.com/"}, Want: "http://example.com/"},
+		{HTML: `<p>foo</p>`, Want: ""},
+	})
+	if len(rt.errors) != 0 {
+		t.Errorf("expected no failures, got %v", rt.errors)
+	}
+}
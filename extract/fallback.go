@@ -0,0 +1,98 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Fallback is a PieceExtractor that tries a list of extractors in order, and
+// returns the result of the first one that succeeds.  Create one with the
+// FirstNonNil function.
+//
+// An extractor succeeds if it returns a non-nil result without an error.  Note
+// that extractors such as Attr return an empty list rather than nil when
+// nothing matches, unless their OmitIfEmpty option is set.
+//
+// If no extractor succeeds, then Fallback returns nil, so that the Piece is
+// omitted from the results - unless every extractor returned an error, in
+// which case the first error is returned.  Extractors that implement
+// ContextualExtractor are given the block's context during a scrape.
+type Fallback struct {
+	Extractors []scrape.PieceExtractor
+}
+
+// FirstNonNil returns a Fallback that tries the given extractors in order -
+// e.g. to handle several markup variants of the same field:
+//
+//	extract.FirstNonNil(
+//		extract.Attr{Attr: "content", OmitIfEmpty: true},
+//		extract.Attr{Attr: "data-value", OmitIfEmpty: true},
+//	)
+func FirstNonNil(extractors ...scrape.PieceExtractor) Fallback {
+	return Fallback{Extractors: extractors}
+}
+
+func (e Fallback) Validate() error {
+	if len(e.Extractors) == 0 {
+		return errors.New("no extractors provided")
+	}
+	for i, ex := range e.Extractors {
+		if ex == nil {
+			return fmt.Errorf("extractor %d: no extractor provided", i)
+		}
+		if v, ok := ex.(scrape.Validator); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("extractor %d: %s", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e Fallback) Extract(sel *goquery.Selection) (interface{}, error) {
+	return e.run(nil, sel)
+}
+
+func (e Fallback) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	return e.run(&ctx, sel)
+}
+
+// run tries each extractor in turn, passing the given context (if any) to
+// those that implement ContextualExtractor.
+func (e Fallback) run(ctx *scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	numErrs := 0
+	for _, ex := range e.Extractors {
+		val, err := extractWithContext(ctx, ex, sel)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			numErrs++
+			continue
+		}
+		if val != nil {
+			return val, nil
+		}
+	}
+
+	if numErrs == len(e.Extractors) {
+		return nil, firstErr
+	}
+	return nil, nil
+}
+
+func (e Fallback) Unwrap() []scrape.PieceExtractor {
+	return e.Extractors
+}
+
+var _ scrape.ContextualExtractor = Fallback{}
+var _ scrape.WrappingExtractor = Fallback{}
+var _ scrape.Validator = Fallback{}
@@ -0,0 +1,47 @@
+package extract
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstNonNil(t *testing.T) {
+	failing := ValueFunc(func(v interface{}) (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+
+	extracttest.Run(t, FirstNonNil(
+		failing,
+		Attr{Attr: "content", OmitIfEmpty: true},
+		Regex{Regex: regexp.MustCompile(`\$(\d+)`), OnlyText: true, OmitIfEmpty: true},
+	), []extracttest.Case{
+		{Name: "first", HTML: `<span content="10">$20</span>`, Selector: "span", Want: "10"},
+		{Name: "second", HTML: `<span>$20</span>`, Selector: "span", Want: "20"},
+		{Name: "none", HTML: `<span>free</span>`, Selector: "span", Want: nil},
+	})
+
+	extracttest.Run(t, FirstNonNil(failing, failing), []extracttest.Case{
+		{Name: "all errors", HTML: `<span>x</span>`, WantErr: true},
+	})
+
+	// Contextual extractors are given the block's context.
+	ctx := scrape.ExtractContext{Header: http.Header{"Last-Modified": {"Wed, 21 Oct 2015 07:28:00 GMT"}}}
+	extracttest.Run(t, FirstNonNil(
+		Header{Name: "Last-Modified"},
+		Attr{Attr: "datetime", OmitIfEmpty: true},
+	), []extracttest.Case{
+		{Name: "header", HTML: `<time datetime="2020-01-01">x</time>`, Selector: "time", Context: ctx, Want: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{Name: "no header", HTML: `<time datetime="2020-01-01">x</time>`, Selector: "time", Want: "2020-01-01"},
+	})
+}
+
+func TestFirstNonNilValidate(t *testing.T) {
+	assert.EqualError(t, FirstNonNil().Validate(), "no extractors provided")
+	assert.EqualError(t, FirstNonNil(Text{}, Attr{}).Validate(), "extractor 1: no attribute provided")
+}
@@ -0,0 +1,115 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+	"golang.org/x/net/html"
+)
+
+// Hash is a PieceExtractor that returns a fingerprint of the selection: the
+// hex-encoded SHA-256 hash of its normalized HTML or text.  Storing it
+// alongside a block's results makes it cheap to tell whether the block has
+// changed since a previous run.
+//
+// The HTML is normalized so that changes in formatting don't change the hash:
+// comments are removed, runs of whitespace are collapsed, and attributes are
+// sorted.  If the selection is empty, then the result is nil.
+//
+// As a stage in a Pipeline, Hash returns the hash of the JSON encoding of the
+// previous stage's result.
+type Hash struct {
+	// If OnlyText is true, then only the text of the selection is hashed, so
+	// that changes to its markup alone don't change the hash.
+	OnlyText bool
+
+	// Ignore is an optional CSS selector for parts of the selection that are
+	// left out of the hash - e.g. timestamps, counters or ads that change on
+	// every visit.
+	Ignore string
+}
+
+func (e Hash) Extract(sel *goquery.Selection) (interface{}, error) {
+	if sel.Length() == 0 {
+		return nil, nil
+	}
+
+	if e.Ignore != "" {
+		// Work on a copy, so that the page itself isn't modified.
+		nodes := make([]*html.Node, len(sel.Nodes))
+		for i, n := range sel.Nodes {
+			nodes[i] = cloneNode(n)
+		}
+		sel = goquery.NewDocumentFromNode(nodes[0]).Selection.AddNodes(nodes[1:]...)
+		sel.Find(e.Ignore).Remove()
+		sel = sel.Not(e.Ignore)
+	}
+
+	h := sha256.New()
+	if e.OnlyText {
+		h.Write([]byte(strings.TrimSpace(collapseWhitespace(sel.Text()))))
+	} else {
+		for _, n := range sel.Nodes {
+			if err := html.Render(h, normalizeNode(n)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ExtractValue returns the hash of the JSON encoding of a value returned by a
+// previous stage of a Pipeline.
+func (e Hash) ExtractValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeNode returns a copy of the given node without comments, with
+// whitespace collapsed, and with its attributes sorted.
+func normalizeNode(n *html.Node) *html.Node {
+	ret := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	if n.Type == html.TextNode {
+		ret.Data = collapseWhitespace(n.Data)
+	}
+	sort.Slice(ret.Attr, func(i, j int) bool {
+		if ret.Attr[i].Namespace != ret.Attr[j].Namespace {
+			return ret.Attr[i].Namespace < ret.Attr[j].Namespace
+		}
+		return ret.Attr[i].Key < ret.Attr[j].Key
+	})
+	for i := range ret.Attr {
+		ret.Attr[i].Val = strings.TrimSpace(collapseWhitespace(ret.Attr[i].Val))
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.CommentNode {
+			continue
+		}
+		child := normalizeNode(c)
+		if child.Type == html.TextNode && strings.TrimSpace(child.Data) == "" {
+			// Whitespace between elements doesn't matter.
+			continue
+		}
+		ret.AppendChild(child)
+	}
+	return ret
+}
+
+var _ scrape.PieceExtractor = Hash{}
+var _ ValueExtractor = Hash{}
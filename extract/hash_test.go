@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	hash := func(e Hash, page, sel string) interface{} {
+		got, err := e.Extract(extracttest.Selection(t, page).Find(sel))
+		assert.NoError(t, err)
+		return got
+	}
+
+	const page = `<div class="item" id="a" data-x="1"><h2>Widget</h2> <p>Only <b>$10</b></p><time>today</time></div>`
+	base := hash(Hash{}, page, ".item")
+	assert.Len(t, base, 64)
+
+	// Formatting changes don't matter.
+	assert.Equal(t, base, hash(Hash{}, `<div data-x="1"  id="a" class="item">
+		<!-- price -->
+		<h2>Widget</h2>
+		<p>Only   <b>$10</b></p><time>today</time>
+	</div>`, ".item"))
+
+	// Content changes do.
+	changed := `<div class="item" id="a" data-x="1"><h2>Widget</h2> <p>Only <b>$12</b></p><time>yesterday</time></div>`
+	assert.NotEqual(t, base, hash(Hash{}, changed, ".item"))
+
+	// Markup changes only matter for HTML.
+	markup := `<div class="item"><h3>Widget</h3> <p>Only $10</p><time>today</time></div>`
+	assert.NotEqual(t, base, hash(Hash{}, markup, ".item"))
+	assert.Equal(t, hash(Hash{OnlyText: true}, page, ".item"), hash(Hash{OnlyText: true}, markup, ".item"))
+
+	// Ignored parts don't matter, and aren't removed from the page.
+	doc := extracttest.Selection(t, page)
+	got, err := Hash{Ignore: "time"}.Extract(doc.Find(".item"))
+	assert.NoError(t, err)
+	assert.Equal(t, hash(Hash{Ignore: "time"}, `<div class="item" id="a" data-x="1"><h2>Widget</h2> <p>Only <b>$10</b></p><time>now</time></div>`, ".item"), got)
+	assert.Equal(t, 1, doc.Find("time").Length())
+	assert.Equal(t, hash(Hash{}, `<p>x</p>`, "p"), hash(Hash{Ignore: "time"}, `<p>x</p><time>y</time>`, "p, time"))
+
+	assert.Nil(t, hash(Hash{}, page, "table"))
+
+	got, err = Hash{}.ExtractValue([]string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "0473ef2dc0d324ab659d3580c1134e9d812035905c4781fdd6d529b0c6860e13", got)
+}
@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"errors"
+	"net/textproto"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Header is a PieceExtractor that returns the value of an HTTP header from the
+// response of the page being scraped, as a string - e.g.
+//
+//	extract.Header{Name: "Last-Modified"}
+//
+// This is useful for tracking how fresh a page is, or for conditional
+// re-scraping with the ETag header.  Since the value belongs to the page, it is
+// the same for every block on that page; the Piece's selector only needs to
+// match something in each block, so "." is recommended.
+//
+// The headers are only known during a scrape with a Fetcher that provides them
+// (see scrape.ResponseBody), such as scrape.HttpClientFetcher.  If the header
+// isn't present, or the headers aren't known, then the Piece is omitted.
+type Header struct {
+	// The name of the header, which is case-insensitive.
+	Name string
+}
+
+func (e Header) Validate() error {
+	if e.Name == "" {
+		return errors.New("no header name provided")
+	}
+	return nil
+}
+
+func (e Header) Extract(sel *goquery.Selection) (interface{}, error) {
+	return nil, e.Validate()
+}
+
+func (e Header) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if _, found := ctx.Header[textproto.CanonicalMIMEHeaderKey(e.Name)]; !found {
+		return nil, nil
+	}
+	return ctx.Header.Get(e.Name), nil
+}
+
+var _ scrape.ContextualExtractor = Header{}
+var _ scrape.Validator = Header{}
@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader(t *testing.T) {
+	sel := extracttest.Selection(t, `<p>text</p>`)
+	ctx := scrape.ExtractContext{Header: http.Header{
+		"Last-Modified": {"Wed, 21 Oct 2015 07:28:00 GMT"},
+		"Etag":          {`"abc"`},
+		"X-Empty":       {""},
+	}}
+
+	got, err := Header{"last-modified"}.ExtractWithContext(ctx, sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", got)
+
+	got, err = Header{"ETag"}.ExtractWithContext(ctx, sel)
+	assert.NoError(t, err)
+	assert.Equal(t, `"abc"`, got)
+
+	got, err = Header{"X-Empty"}.ExtractWithContext(ctx, sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	got, err = Header{"Expires"}.ExtractWithContext(ctx, sel)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = Header{"Expires"}.ExtractWithContext(scrape.ExtractContext{}, sel)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = Header{"Expires"}.Extract(sel)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = Header{}.ExtractWithContext(ctx, sel)
+	assert.Error(t, err)
+}
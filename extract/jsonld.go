@@ -0,0 +1,113 @@
+package extract
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// JSONLD is a PieceExtractor that parses the JSON-LD structured data in
+// <script type="application/ld+json"> elements - either the elements in the
+// selection itself, or those inside it.  Many sites include their cleanest
+// data (e.g. products, articles and events) in this form.
+//
+// Each script may contain a single object, a list of objects, or an object
+// with an "@graph" list; all of these are flattened into one list of objects
+// (each a map[string]interface{}), in document order.  Scripts that don't
+// contain valid JSON are skipped, since broken JSON-LD is common.
+//
+// By default, if only a single object is found, then JSONLD returns the object
+// itself rather than a list.
+type JSONLD struct {
+	// If Type is set, then only objects with this "@type" are returned.  The
+	// comparison ignores any namespace, so "Product" matches "Product",
+	// "schema:Product" and "http://schema.org/Product".  Objects with several
+	// types match if any of them matches.
+	Type string
+
+	// Set AlwaysReturnList to true to always return a list of objects, even if
+	// there is only one.
+	AlwaysReturnList bool
+
+	// If no objects are found, then return 'nil' from Extract, instead of the
+	// empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+const jsonLDSelector = `script[type="application/ld+json"]`
+
+func (e JSONLD) Extract(sel *goquery.Selection) (interface{}, error) {
+	scripts := sel.Filter(jsonLDSelector).AddSelection(sel.Find(jsonLDSelector))
+
+	results := []interface{}{}
+	scripts.Each(func(i int, s *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return
+		}
+
+		for _, obj := range flattenJSONLD(data) {
+			if e.Type == "" || jsonLDHasType(obj, e.Type) {
+				results = append(results, obj)
+			}
+		}
+	})
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	if len(results) == 1 && !e.AlwaysReturnList {
+		return results[0], nil
+	}
+	return results, nil
+}
+
+// flattenJSONLD returns the top-level objects in the given JSON-LD document.
+func flattenJSONLD(data interface{}) []map[string]interface{} {
+	switch data := data.(type) {
+	case []interface{}:
+		ret := []map[string]interface{}{}
+		for _, item := range data {
+			ret = append(ret, flattenJSONLD(item)...)
+		}
+		return ret
+
+	case map[string]interface{}:
+		if graph, found := data["@graph"].([]interface{}); found {
+			return flattenJSONLD(graph)
+		}
+		return []map[string]interface{}{data}
+	}
+	return nil
+}
+
+func jsonLDHasType(obj map[string]interface{}, want string) bool {
+	matches := func(t interface{}) bool {
+		s, ok := t.(string)
+		if !ok {
+			return false
+		}
+		if i := strings.LastIndexAny(s, "/:#"); i >= 0 {
+			s = s[i+1:]
+		}
+		return s == want
+	}
+
+	switch t := obj["@type"].(type) {
+	case []interface{}:
+		for _, item := range t {
+			if matches(item) {
+				return true
+			}
+		}
+		return false
+	default:
+		return matches(t)
+	}
+}
+
+var _ scrape.PieceExtractor = JSONLD{}
@@ -0,0 +1,43 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestJSONLD(t *testing.T) {
+	const page = `<head>
+		<script type="application/ld+json">{"@type": "Organization", "name": "Shop"}</script>
+		<script type="application/ld+json">{"@graph": [
+			{"@type": "http://schema.org/Product", "name": "Widget", "offers": {"price": "9.99"}},
+			{"@type": ["Thing", "schema:Product"], "name": "Gadget"}
+		]}</script>
+		<script type="application/ld+json">{ broken</script>
+		<script>{"@type": "Product", "name": "Not JSON-LD"}</script>
+	</head>`
+
+	extracttest.Run(t, JSONLD{Type: "Product"}, []extracttest.Case{
+		{Name: "filtered", HTML: page, Want: []interface{}{
+			map[string]interface{}{
+				"@type":  "http://schema.org/Product",
+				"name":   "Widget",
+				"offers": map[string]interface{}{"price": "9.99"},
+			},
+			map[string]interface{}{
+				"@type": []interface{}{"Thing", "schema:Product"},
+				"name":  "Gadget",
+			},
+		}},
+		{Name: "none", HTML: `<p>no data</p>`, Want: []interface{}{}},
+	})
+
+	extracttest.Run(t, JSONLD{Type: "Organization"}, []extracttest.Case{
+		{Name: "single", HTML: page, Want: map[string]interface{}{"@type": "Organization", "name": "Shop"}},
+		{Name: "script selected", HTML: page, Selector: "script", Want: map[string]interface{}{"@type": "Organization", "name": "Shop"}},
+	})
+
+	extracttest.Run(t, JSONLD{Type: "Event", OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: page, Want: nil},
+	})
+}
@@ -0,0 +1,99 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// KeyValue is a PieceExtractor that converts a list of labels and values into
+// a map[string]string, such as a definition list:
+//
+//	<dl>
+//	  <dt>Weight:</dt> <dd>1.2 kg</dd>
+//	  <dt>Colours</dt> <dd>Red</dd> <dd>Blue</dd>
+//	</dl>
+//
+// which results in {"Weight": "1.2 kg", "Colours": "Red, Blue"}.
+//
+// Elements that match the Label and Value selectors are processed in document
+// order: each value is assigned to the closest label before it, and values
+// before the first label are ignored.  Labels are trimmed of whitespace and
+// trailing colons.  If a label appears more than once, then all of its values
+// are combined.
+type KeyValue struct {
+	// The selectors for the label and value elements.  If both are empty,
+	// then "dt" and "dd" are used.  For example, use "th" and "td" for a table
+	// with a label and value in each row.
+	Label string
+	Value string
+
+	// The string used to join multiple values for the same label.  If this is
+	// empty, then ", " is used.
+	Separator string
+
+	// If no labels with values are found, then return 'nil' from Extract,
+	// instead of the empty map.  This signals that the result of this Piece
+	// should be omitted entirely from the results, as opposed to including the
+	// empty map.
+	OmitIfEmpty bool
+}
+
+func (e KeyValue) selectors() (string, string) {
+	if e.Label == "" && e.Value == "" {
+		return "dt", "dd"
+	}
+	return e.Label, e.Value
+}
+
+func (e KeyValue) Validate() error {
+	label, value := e.selectors()
+	if label == "" || value == "" {
+		return errors.New("both label and value selectors must be provided")
+	}
+	return nil
+}
+
+func (e KeyValue) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	label, value := e.selectors()
+
+	sep := e.Separator
+	if sep == "" {
+		sep = ", "
+	}
+
+	ret := map[string]string{}
+	key := ""
+	haveKey := false
+	sel.Find(label + ", " + value).Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+
+		if s.Is(label) {
+			key = strings.TrimSpace(strings.TrimRight(text, ":"))
+			haveKey = true
+			return
+		}
+		if !haveKey {
+			return
+		}
+
+		if existing, found := ret[key]; found {
+			ret[key] = existing + sep + text
+		} else {
+			ret[key] = text
+		}
+	})
+
+	if len(ret) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return ret, nil
+}
+
+var _ scrape.PieceExtractor = KeyValue{}
+var _ scrape.Validator = KeyValue{}
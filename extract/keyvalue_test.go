@@ -0,0 +1,31 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyValue(t *testing.T) {
+	extracttest.Run(t, KeyValue{}, []extracttest.Case{
+		{Name: "definition list", HTML: `<dl>
+			<dd>orphan</dd>
+			<dt>Weight:</dt> <dd> 1.2 kg </dd>
+			<dt>Colours</dt> <dd>Red</dd> <dd>Blue</dd>
+			<dt>Weight</dt> <dd>1.3 kg</dd>
+		</dl>`, Want: map[string]string{
+			"Weight":  "1.2 kg, 1.3 kg",
+			"Colours": "Red, Blue",
+		}},
+		{Name: "empty", HTML: `<p>nothing</p>`, Want: map[string]string{}},
+	})
+
+	extracttest.Run(t, KeyValue{Label: "th", Value: "td", Separator: "|", OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "table", HTML: `<table><tr><th>Size</th><td>M</td><td>L</td></tr><tr><th>SKU</th><td>123</td></tr></table>`,
+			Want: map[string]string{"Size": "M|L", "SKU": "123"}},
+		{Name: "omitted", HTML: `<p>nothing</p>`, Want: nil},
+	})
+
+	assert.Error(t, KeyValue{Label: "th"}.Validate())
+}
@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Link is a single link returned by the Links extractor.
+type Link struct {
+	URL   string
+	Text  string
+	Title string `json:",omitempty"`
+	Rel   string `json:",omitempty"`
+}
+
+// Links is a PieceExtractor that returns a []Link for each link (an <a> or
+// <area> element with an href attribute) in the selection, or inside it.  This
+// keeps each link's URL and text together, rather than needing separate Text
+// and Attr pieces that may not line up.
+//
+// The Text of each link has its surrounding whitespace trimmed.
+type Links struct {
+	// If Resolve is true, then each URL is resolved against the URL of the page
+	// being scraped, so that relative links become absolute.  Links that can't
+	// be parsed are left as they are.  Note that this only works during a
+	// scrape, where the page's URL is known; calling Extract directly returns
+	// the links unresolved.
+	Resolve bool
+
+	// If Unique is true, then only the first link to each URL is returned.
+	Unique bool
+
+	// If no links are found, then return 'nil' from Extract, instead of the
+	// empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+const linksSelector = "a[href], area[href]"
+
+func (e Links) Extract(sel *goquery.Selection) (interface{}, error) {
+	return e.extract(nil, sel)
+}
+
+func (e Links) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	var base *url.URL
+	if e.Resolve {
+		base, _ = url.Parse(ctx.URL)
+	}
+	return e.extract(base, sel)
+}
+
+func (e Links) extract(base *url.URL, sel *goquery.Selection) (interface{}, error) {
+	links := sel.Filter(linksSelector).AddSelection(sel.Find(linksSelector))
+
+	results := []Link{}
+	seen := map[string]struct{}{}
+	links.Each(func(i int, s *goquery.Selection) {
+		href := s.AttrOr("href", "")
+		if base != nil {
+			if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+				href = base.ResolveReference(ref).String()
+			}
+		}
+
+		if e.Unique {
+			if _, found := seen[href]; found {
+				return
+			}
+			seen[href] = struct{}{}
+		}
+
+		results = append(results, Link{
+			URL:   href,
+			Text:  strings.TrimSpace(s.Text()),
+			Title: s.AttrOr("title", ""),
+			Rel:   s.AttrOr("rel", ""),
+		})
+	})
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return results, nil
+}
+
+var _ scrape.ContextualExtractor = Links{}
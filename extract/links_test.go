@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	const page = `<nav>
+		<a href="/a" title="First"> One </a>
+		<a name="anchor">no href</a>
+		<a href="b.html" rel="next">Two</a>
+		<a href="/a">One again</a>
+	</nav>`
+
+	extracttest.Run(t, Links{}, []extracttest.Case{
+		{Name: "all", HTML: page, Want: []Link{
+			{URL: "/a", Text: "One", Title: "First"},
+			{URL: "b.html", Text: "Two", Rel: "next"},
+			{URL: "/a", Text: "One again"},
+		}},
+		{Name: "selected", HTML: page, Selector: "a[rel]", Want: []Link{
+			{URL: "b.html", Text: "Two", Rel: "next"},
+		}},
+		{Name: "none", HTML: `<p>text</p>`, Want: []Link{}},
+	})
+
+	extracttest.Run(t, Links{Unique: true}, []extracttest.Case{
+		{Name: "unique", HTML: page, Want: []Link{
+			{URL: "/a", Text: "One", Title: "First"},
+			{URL: "b.html", Text: "Two", Rel: "next"},
+		}},
+	})
+
+	extracttest.Run(t, Links{OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: `<p>text</p>`, Want: nil},
+	})
+
+	sel := extracttest.Selection(t, page)
+	ctx := scrape.ExtractContext{URL: "http://example.com/dir/page"}
+	got, err := Links{Resolve: true, Unique: true}.ExtractWithContext(ctx, sel)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{
+		{URL: "http://example.com/a", Text: "One", Title: "First"},
+		{URL: "http://example.com/dir/b.html", Text: "Two", Rel: "next"},
+	}, got)
+}
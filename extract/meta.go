@@ -0,0 +1,77 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Meta is a PieceExtractor that collects the <meta> tags in the selection -
+// typically the document's <head> - into a map[string]string.  Each tag is
+// keyed by its property attribute (as used by OpenGraph, e.g. "og:title") or
+// its name attribute (as used by Twitter cards and standard tags, e.g.
+// "twitter:card" or "description"), converted to lower case, and its value is
+// the content attribute.  This replaces a separate Attr piece for each of a
+// page's title, description, image and so on.
+//
+// If a key appears more than once (e.g. several "og:image" tags), then only
+// the first value is used.
+type Meta struct {
+	// If Properties is set, then only these keys are returned.  An entry that
+	// ends in "*" matches every key with that prefix, so "og:*" returns all
+	// OpenGraph properties.  Comparisons ignore case.
+	Properties []string
+
+	// If no meta tags are found, then return 'nil' from Extract, instead of
+	// the empty map.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// map.
+	OmitIfEmpty bool
+}
+
+const metaSelector = "meta[content]"
+
+func (e Meta) Extract(sel *goquery.Selection) (interface{}, error) {
+	tags := sel.Filter(metaSelector).AddSelection(sel.Find(metaSelector))
+
+	ret := map[string]string{}
+	tags.Each(func(i int, s *goquery.Selection) {
+		key := s.AttrOr("property", "")
+		if key == "" {
+			key = s.AttrOr("name", "")
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" || !e.allowed(key) {
+			return
+		}
+
+		if _, found := ret[key]; !found {
+			ret[key] = s.AttrOr("content", "")
+		}
+	})
+
+	if len(ret) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	return ret, nil
+}
+
+func (e Meta) allowed(key string) bool {
+	if len(e.Properties) == 0 {
+		return true
+	}
+	for _, prop := range e.Properties {
+		prop = strings.ToLower(prop)
+		if strings.HasSuffix(prop, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(prop, "*")) {
+				return true
+			}
+		} else if key == prop {
+			return true
+		}
+	}
+	return false
+}
+
+var _ scrape.PieceExtractor = Meta{}
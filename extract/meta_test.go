@@ -0,0 +1,41 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestMeta(t *testing.T) {
+	const page = `<head>
+		<meta charset="utf-8">
+		<meta property="og:title" content="Widget">
+		<meta property="og:image" content="/a.png">
+		<meta property="og:image" content="/b.png">
+		<meta name="twitter:card" content="summary">
+		<meta name="Description" content="A fine widget.">
+		<meta http-equiv="refresh" content="30">
+	</head><body><p>text</p></body>`
+
+	extracttest.Run(t, Meta{}, []extracttest.Case{
+		{Name: "all", HTML: page, Selector: "head", Want: map[string]string{
+			"og:title":     "Widget",
+			"og:image":     "/a.png",
+			"twitter:card": "summary",
+			"description":  "A fine widget.",
+		}},
+		{Name: "none", HTML: page, Selector: "body", Want: map[string]string{}},
+	})
+
+	extracttest.Run(t, Meta{Properties: []string{"OG:*", "description"}}, []extracttest.Case{
+		{Name: "allowlist", HTML: page, Selector: "head", Want: map[string]string{
+			"og:title":    "Widget",
+			"og:image":    "/a.png",
+			"description": "A fine widget.",
+		}},
+	})
+
+	extracttest.Run(t, Meta{OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: page, Selector: "body", Want: nil},
+	})
+}
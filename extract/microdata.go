@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+	"golang.org/x/net/html"
+)
+
+// Microdata is a PieceExtractor that reads the structured data embedded in
+// HTML attributes, using either Microdata (itemscope, itemtype and itemprop)
+// or RDFa (typeof and property).  It complements JSONLD for sites that mark up
+// their data inline rather than in a separate script.
+//
+// Each top-level item in the selection - an element with an itemscope or
+// typeof attribute that is not itself the value of a property - is
+// returned as a map[string]interface{} in the same shape as JSON-LD: the
+// item's type is stored under "@type", its itemid or resource under "@id", and
+// every property under its name.  Properties that are items themselves are
+// returned as nested maps, and properties that appear more than once are
+// returned as a list of values.
+//
+// The value of a property is taken from the element's content attribute if it
+// has one, then from the attribute that holds its value for that kind of
+// element (e.g. href for <a> and <link>, src for <img>, datetime for <time>),
+// and otherwise from its text.
+//
+// By default, if only a single item is found, then Microdata returns the item
+// itself rather than a list.
+type Microdata struct {
+	// If Type is set, then only items with this type are returned.  As with
+	// JSONLD, the comparison ignores any namespace, so "Product" matches
+	// "https://schema.org/Product".
+	Type string
+
+	// Set AlwaysReturnList to true to always return a list of items, even if
+	// there is only one.
+	AlwaysReturnList bool
+
+	// If no items are found, then return 'nil' from Extract, instead of the
+	// empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+const microdataSelector = `[itemscope]:not([itemprop]), [typeof]:not([property])`
+
+func (e Microdata) Extract(sel *goquery.Selection) (interface{}, error) {
+	items := sel.Filter(microdataSelector).AddSelection(sel.Find(microdataSelector))
+
+	results := []interface{}{}
+	items.Each(func(i int, s *goquery.Selection) {
+		item := microdataItem(s.Get(0))
+		if e.Type == "" || jsonLDHasType(item, e.Type) {
+			results = append(results, item)
+		}
+	})
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil, nil
+	}
+	if len(results) == 1 && !e.AlwaysReturnList {
+		return results[0], nil
+	}
+	return results, nil
+}
+
+// microdataItem returns the properties of the item rooted at the given node.
+func microdataItem(n *html.Node) map[string]interface{} {
+	item := map[string]interface{}{}
+
+	types := strings.Fields(attrOr(n, "itemtype", attrOr(n, "typeof", "")))
+	switch len(types) {
+	case 0:
+	case 1:
+		item["@type"] = types[0]
+	default:
+		list := []interface{}{}
+		for _, t := range types {
+			list = append(list, t)
+		}
+		item["@type"] = list
+	}
+	if id := attrOr(n, "itemid", attrOr(n, "resource", "")); id != "" {
+		item["@id"] = id
+	}
+
+	microdataProps(n, item)
+	return item
+}
+
+// microdataProps adds the properties found in the children of the given node
+// to the item.
+func microdataProps(n *html.Node, item map[string]interface{}) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+
+		names := strings.Fields(attrOr(c, "itemprop", attrOr(c, "property", "")))
+		isItem := hasAttr(c, "itemscope") || hasAttr(c, "typeof")
+
+		if len(names) > 0 {
+			var value interface{}
+			if isItem {
+				value = microdataItem(c)
+			} else {
+				value = microdataValue(c)
+			}
+			for _, name := range names {
+				addMicrodataProp(item, name, value)
+			}
+		}
+
+		// The properties of a nested item belong to it, not to this one.
+		if !isItem {
+			microdataProps(c, item)
+		}
+	}
+}
+
+func addMicrodataProp(item map[string]interface{}, name string, value interface{}) {
+	switch existing := item[name].(type) {
+	case nil:
+		item[name] = value
+	case []interface{}:
+		item[name] = append(existing, value)
+	default:
+		item[name] = []interface{}{existing, value}
+	}
+}
+
+// microdataValue returns the value of a property element that isn't an item.
+func microdataValue(n *html.Node) string {
+	if v, ok := attr(n, "content"); ok {
+		return v
+	}
+
+	var valueAttr string
+	switch n.Data {
+	case "a", "area", "link":
+		valueAttr = "href"
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		valueAttr = "src"
+	case "object":
+		valueAttr = "data"
+	case "data", "meter":
+		valueAttr = "value"
+	case "time":
+		valueAttr = "datetime"
+	}
+	if valueAttr != "" {
+		if v, ok := attr(n, valueAttr); ok {
+			return v
+		}
+	}
+	if v, ok := attr(n, "resource"); ok {
+		return v
+	}
+
+	return strings.TrimSpace(goquery.NewDocumentFromNode(n).Text())
+}
+
+func attr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrOr(n *html.Node, name, def string) string {
+	if v, ok := attr(n, name); ok {
+		return v
+	}
+	return def
+}
+
+func hasAttr(n *html.Node, name string) bool {
+	_, ok := attr(n, name)
+	return ok
+}
+
+var _ scrape.PieceExtractor = Microdata{}
@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestMicrodata(t *testing.T) {
+	const page = `<div itemscope itemtype="https://schema.org/Product" itemid="#widget">
+		<h1 itemprop="name">  Widget </h1>
+		<img itemprop="image" src="/widget.png">
+		<a itemprop="url" href="/p/widget">link</a>
+		<span itemprop="color">red</span>, <span itemprop="color">blue</span>
+		<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+			<meta itemprop="priceCurrency" content="USD">
+			<span itemprop="price">9.99</span>
+			<time itemprop="validFrom" datetime="2024-01-01">New year</time>
+		</div>
+		<div itemscope itemtype="https://schema.org/Review">
+			<span itemprop="author">Jo</span>
+		</div>
+	</div>
+	<div vocab="https://schema.org/" typeof="Person">
+		<span property="name">Alex</span>
+		<a property="sameAs" href="https://example.com/alex">home</a>
+	</div>`
+
+	product := map[string]interface{}{
+		"@type": "https://schema.org/Product",
+		"@id":   "#widget",
+		"name":  "Widget",
+		"image": "/widget.png",
+		"url":   "/p/widget",
+		"color": []interface{}{"red", "blue"},
+		"offers": map[string]interface{}{
+			"@type":         "https://schema.org/Offer",
+			"priceCurrency": "USD",
+			"price":         "9.99",
+			"validFrom":     "2024-01-01",
+		},
+	}
+
+	extracttest.Run(t, Microdata{Type: "Product"}, []extracttest.Case{
+		{Name: "microdata", HTML: page, Want: product},
+		{Name: "none", HTML: `<p>no data</p>`, Want: []interface{}{}},
+	})
+
+	extracttest.Run(t, Microdata{Type: "Person"}, []extracttest.Case{
+		{Name: "rdfa", HTML: page, Want: map[string]interface{}{
+			"@type":  "Person",
+			"name":   "Alex",
+			"sameAs": "https://example.com/alex",
+		}},
+	})
+
+	extracttest.Run(t, Microdata{AlwaysReturnList: true}, []extracttest.Case{
+		{Name: "nested item without itemprop", HTML: page, Selector: "div[itemid]", Want: []interface{}{
+			product,
+			map[string]interface{}{"@type": "https://schema.org/Review", "author": "Jo"},
+		}},
+	})
+
+	extracttest.Run(t, Microdata{Type: "Event", OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: page, Want: nil},
+	})
+}
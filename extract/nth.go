@@ -0,0 +1,70 @@
+package extract
+
+import (
+	"errors"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Nth is a PieceExtractor that narrows the selection down to a single element
+// before passing it to another extractor.  This selects e.g. "the second
+// matching element" when CSS can't express it cleanly.
+//
+// If the selection doesn't contain an element at the index, then the
+// extractor is given an empty selection.
+type Nth struct {
+	// The index of the element, starting at 0.  Negative indexes count back
+	// from the end of the selection, so -1 is the last element.
+	Index int
+
+	// The extractor that is given the narrowed selection.
+	Extractor scrape.PieceExtractor
+}
+
+// First returns an Nth that passes the first element of the selection to the
+// given extractor.
+func First(e scrape.PieceExtractor) Nth {
+	return Nth{Index: 0, Extractor: e}
+}
+
+// Last returns an Nth that passes the last element of the selection to the
+// given extractor.
+func Last(e scrape.PieceExtractor) Nth {
+	return Nth{Index: -1, Extractor: e}
+}
+
+func (e Nth) Validate() error {
+	if e.Extractor == nil {
+		return errors.New("no extractor provided")
+	}
+	if v, ok := e.Extractor.(scrape.Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (e Nth) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e.Extractor.Extract(sel.Eq(e.Index))
+}
+
+func (e Nth) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if ce, ok := e.Extractor.(scrape.ContextualExtractor); ok {
+		return ce.ExtractWithContext(ctx, sel.Eq(e.Index))
+	}
+	return e.Extractor.Extract(sel.Eq(e.Index))
+}
+
+func (e Nth) Unwrap() []scrape.PieceExtractor {
+	return []scrape.PieceExtractor{e.Extractor}
+}
+
+var _ scrape.ContextualExtractor = Nth{}
+var _ scrape.WrappingExtractor = Nth{}
+var _ scrape.Validator = Nth{}
@@ -0,0 +1,36 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNth(t *testing.T) {
+	const page = `<ul><li>One</li><li>Two</li><li>Three</li></ul>`
+
+	extracttest.Run(t, Nth{Index: 1, Extractor: Text{}}, []extracttest.Case{
+		{Name: "second", HTML: page, Selector: "li", Want: "Two"},
+		{Name: "out of range", HTML: `<li>One</li>`, Selector: "li", Want: ""},
+	})
+	extracttest.Run(t, Nth{Index: -2, Extractor: Text{}}, []extracttest.Case{
+		{Name: "negative", HTML: page, Selector: "li", Want: "Two"},
+	})
+	extracttest.Run(t, First(Text{}), []extracttest.Case{
+		{Name: "first", HTML: page, Selector: "li", Want: "One"},
+	})
+	extracttest.Run(t, Last(MultipleText{OmitIfEmpty: true}), []extracttest.Case{
+		{Name: "last", HTML: page, Selector: "li", Want: []string{"Three"}},
+		{Name: "empty", HTML: page, Selector: "p", Want: nil},
+	})
+
+	sel := extracttest.Selection(t, `<a href="/a">A</a><a href="/b">B</a>`).Find("a")
+	got, err := Last(Links{Resolve: true}).ExtractWithContext(scrape.ExtractContext{URL: "http://example.com/"}, sel)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{{URL: "http://example.com/b", Text: "B"}}, got)
+
+	assert.EqualError(t, Nth{}.Validate(), "no extractor provided")
+	assert.EqualError(t, First(Attr{}).Validate(), "no attribute provided")
+}
@@ -0,0 +1,135 @@
+package extract
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Matches a number with optional grouping separators, decimal part and
+// magnitude suffix.  Groups separated by spaces must have three digits.
+var numberRe = regexp.MustCompile(
+	`([-−]?)(\d+(?:(?:[.,'’]\d+)|(?:[ \x{00a0}\x{202f}]\d{3}))*)(?:\s?(k|K|m|M|bn|b|B)\b)?`)
+
+var numberSuffixes = map[string]float64{
+	"k": 1e3, "K": 1e3,
+	"m": 1e6, "M": 1e6,
+	"b": 1e9, "B": 1e9, "bn": 1e9,
+}
+
+// Number is a PieceExtractor that parses the first number in the text of the
+// given selection, ignoring any surrounding text such as currency symbols or
+// units.  It handles the following:
+//
+//   - Thousands separators: "1,234,567", "1.234.567", "1 234 567" and
+//     "1'234'567" all become 1234567.
+//   - Decimal commas: "1.234,5" becomes 1234.5.
+//   - Magnitude suffixes: "1.2k" becomes 1200, "3M" becomes 3000000, and
+//     "2bn" becomes 2000000000.
+//   - Negative numbers, with either a hyphen or a minus sign.
+//
+// When a number contains a single separator, it's ambiguous whether it is a
+// thousands separator or a decimal point.  By default, a comma followed by
+// exactly three digits is treated as a thousands separator ("1,234" is 1234),
+// and any other single separator as a decimal point ("1.234" and "1,5" are
+// 1.234 and 1.5).  Set DecimalComma to treat commas as decimal points and
+// periods as thousands separators instead.
+//
+// The result is an int64 if the number has no fractional part, and a float64
+// otherwise.  If no number is found, then Number returns nil, so that the
+// Piece is omitted from the results.
+type Number struct {
+	// If DecimalComma is true, then commas are always decimal points, and
+	// periods are always thousands separators.
+	DecimalComma bool
+
+	// If Float is true, then the result is always a float64.
+	Float bool
+}
+
+func (e Number) Extract(sel *goquery.Selection) (interface{}, error) {
+	val, ok, err := e.parse(sel.Text())
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if !e.Float && val == math.Trunc(val) && math.Abs(val) < 1<<63 {
+		return int64(val), nil
+	}
+	return val, nil
+}
+
+// parse returns the first number in the given string, and whether there was
+// one.
+func (e Number) parse(s string) (float64, bool, error) {
+	idx := numberRe.FindStringSubmatchIndex(s)
+	if idx == nil {
+		return 0, false, nil
+	}
+	m := []string{s[idx[0]:idx[1]], s[idx[2]:idx[3]], s[idx[4]:idx[5]], ""}
+	if idx[6] >= 0 {
+		// Since \b only considers ASCII letters, check that the suffix isn't
+		// the start of a longer (non-ASCII) word.
+		next, _ := utf8.DecodeRuneInString(s[idx[7]:])
+		if !unicode.IsLetter(next) {
+			m[3] = s[idx[6]:idx[7]]
+		}
+	}
+
+	digits := strings.NewReplacer("'", "", "’", "", " ", "", " ", "", " ", "").Replace(m[2])
+	digits = e.normalizeSeparators(digits)
+
+	val, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, false, errors.New("could not parse number: " + m[0])
+	}
+
+	if mult, found := numberSuffixes[m[3]]; found {
+		val *= mult
+	}
+	if m[1] != "" {
+		val = -val
+	}
+	return val, true, nil
+}
+
+// normalizeSeparators converts a string of digits, commas and periods into a
+// form that can be parsed by strconv.ParseFloat.
+func (e Number) normalizeSeparators(s string) string {
+	if e.DecimalComma {
+		s = strings.Replace(s, ".", "", -1)
+		return strings.Replace(s, ",", ".", -1)
+	}
+
+	lastComma := strings.LastIndex(s, ",")
+	lastPeriod := strings.LastIndex(s, ".")
+
+	switch {
+	case lastComma >= 0 && lastPeriod >= 0:
+		// Whichever comes last is the decimal point.
+		if lastComma > lastPeriod {
+			s = strings.Replace(s, ".", "", -1)
+			return strings.Replace(s, ",", ".", -1)
+		}
+		return strings.Replace(s, ",", "", -1)
+
+	case lastComma >= 0:
+		if strings.Count(s, ",") > 1 || len(s)-lastComma-1 == 3 {
+			return strings.Replace(s, ",", "", -1)
+		}
+		return strings.Replace(s, ",", ".", -1)
+
+	case lastPeriod >= 0 && strings.Count(s, ".") > 1:
+		return strings.Replace(s, ".", "", -1)
+	}
+	return s
+}
+
+var _ scrape.PieceExtractor = Number{}
@@ -0,0 +1,32 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestNumber(t *testing.T) {
+	extracttest.Run(t, Number{}, []extracttest.Case{
+		{Name: "integer", HTML: `<span>42 points</span>`, Want: int64(42)},
+		{Name: "thousands", HTML: `<span>$1,234,567</span>`, Want: int64(1234567)},
+		{Name: "single comma group", HTML: `<span>1,234</span>`, Want: int64(1234)},
+		{Name: "decimal", HTML: `<span>£12.50</span>`, Want: 12.5},
+		{Name: "mixed", HTML: `<span>1.234,56 €</span>`, Want: 1234.56},
+		{Name: "short decimal comma", HTML: `<span>1,5 kg</span>`, Want: 1.5},
+		{Name: "spaces", HTML: "<span>1 234 567 Kč</span>", Want: int64(1234567)},
+		{Name: "apostrophes", HTML: `<span>CHF 1'234.50</span>`, Want: 1234.5},
+		{Name: "suffix", HTML: `<span>1.2k views</span>`, Want: int64(1200)},
+		{Name: "suffix with space", HTML: `<span>3 M</span>`, Want: int64(3000000)},
+		{Name: "billions", HTML: `<span>2.5bn</span>`, Want: int64(2500000000)},
+		{Name: "not a suffix", HTML: `<span>5 Mbps</span>`, Want: int64(5)},
+		{Name: "negative", HTML: `<span>−3.5</span>`, Want: -3.5},
+		{Name: "none", HTML: `<span>free</span>`, Want: nil},
+	})
+
+	extracttest.Run(t, Number{DecimalComma: true, Float: true}, []extracttest.Case{
+		{Name: "european thousands", HTML: `<span>1.234</span>`, Want: 1234.0},
+		{Name: "european decimal", HTML: `<span>1.234,5</span>`, Want: 1234.5},
+		{Name: "comma group", HTML: `<span>1,234</span>`, Want: 1.234},
+	})
+}
@@ -0,0 +1,100 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Money is the result of the Price extractor.
+type Money struct {
+	// The amount of money, in units of the currency (e.g. dollars, not
+	// cents).
+	Amount float64
+
+	// The ISO 4217 code of the currency (e.g. "USD"), or the empty string if
+	// the currency could not be determined.
+	Currency string
+}
+
+// Currency symbols, in the order they're checked.  Symbols that are prefixes
+// of others come later.
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"US$", "USD"}, {"C$", "CAD"}, {"CA$", "CAD"}, {"A$", "AUD"}, {"AU$", "AUD"},
+	{"NZ$", "NZD"}, {"HK$", "HKD"}, {"S$", "SGD"}, {"R$", "BRL"}, {"MX$", "MXN"},
+	{"€", "EUR"}, {"£", "GBP"}, {"¥", "JPY"}, {"₹", "INR"}, {"₩", "KRW"},
+	{"₽", "RUB"}, {"₺", "TRY"}, {"₪", "ILS"}, {"₫", "VND"}, {"฿", "THB"},
+	{"₱", "PHP"}, {"₴", "UAH"}, {"zł", "PLN"}, {"Kč", "CZK"}, {"Fr.", "CHF"},
+}
+
+var isoCodeRe = regexp.MustCompile(`\b[A-Z]{3}\b`)
+
+// ISO 4217 codes that are recognized in the text of a price.
+var currencyCodes = map[string]struct{}{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		AED ARS AUD BDT BGN BRL CAD CHF CLP CNY COP CZK DKK EGP EUR GBP HKD HUF
+		IDR ILS INR ISK JPY KES KRW MAD MXN MYR NGN NOK NZD PEN PHP PKR PLN QAR
+		RON RUB SAR SEK SGD THB TRY TWD UAH USD VND ZAR`) {
+		currencyCodes[code] = struct{}{}
+	}
+}
+
+// Price is a PieceExtractor that parses a monetary value from the text of the
+// given selection, and returns it as a Money value.  The amount is parsed in
+// the same way as by the Number extractor, and the currency is detected from
+// either an ISO 4217 code (e.g. "EUR 12,50") or a currency symbol (e.g.
+// "€12,50").
+//
+// Since the "$" symbol is used by many currencies, it is only recognized in
+// its unambiguous forms (e.g. "US$" or "C$"), and otherwise DefaultCurrency
+// is used.
+//
+// If no number is found, then Price returns nil, so that the Piece is omitted
+// from the results.
+type Price struct {
+	// If DecimalComma is true, then commas are always decimal points, and
+	// periods are always thousands separators.  See the Number extractor for
+	// more information.
+	DecimalComma bool
+
+	// The currency code to use if no currency is detected.
+	DefaultCurrency string
+}
+
+func (e Price) Extract(sel *goquery.Selection) (interface{}, error) {
+	text := sel.Text()
+
+	amount, ok, err := Number{DecimalComma: e.DecimalComma}.parse(text)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	currency := detectCurrency(text)
+	if currency == "" {
+		currency = e.DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func detectCurrency(text string) string {
+	for _, code := range isoCodeRe.FindAllString(text, -1) {
+		if _, found := currencyCodes[code]; found {
+			return code
+		}
+	}
+	for _, c := range currencySymbols {
+		if strings.Contains(text, c.symbol) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+var _ scrape.PieceExtractor = Price{}
@@ -0,0 +1,25 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestPrice(t *testing.T) {
+	extracttest.Run(t, Price{DefaultCurrency: "USD"}, []extracttest.Case{
+		{Name: "dollars", HTML: `<span>$1,299.99</span>`, Want: Money{1299.99, "USD"}},
+		{Name: "euro symbol", HTML: `<span>12,50 €</span>`, Want: Money{12.5, "EUR"}},
+		{Name: "pounds", HTML: `<span>Now £8</span>`, Want: Money{8, "GBP"}},
+		{Name: "iso code", HTML: `<span>CHF 1'234.50</span>`, Want: Money{1234.5, "CHF"}},
+		{Name: "code after", HTML: `<span>99 SEK</span>`, Want: Money{99, "SEK"}},
+		{Name: "prefixed dollar", HTML: `<span>C$15</span>`, Want: Money{15, "CAD"}},
+		{Name: "unknown code", HTML: `<span>ABC 5</span>`, Want: Money{5, "USD"}},
+		{Name: "no amount", HTML: `<span>Sold out</span>`, Want: nil},
+	})
+
+	extracttest.Run(t, Price{DecimalComma: true}, []extracttest.Case{
+		{Name: "european", HTML: `<span>1.299,00 zł</span>`, Want: Money{1299, "PLN"}},
+		{Name: "no currency", HTML: `<span>3,5</span>`, Want: Money{3.5, ""}},
+	})
+}
@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// QueryParam is a PieceExtractor that parses a URL in an attribute of each
+// element in the selection, and returns the value of one of its query
+// parameters - e.g. the video ID from the src of an embedded YouTube player
+// ("https://www.youtube.com/watch?v=ID"), or the item ID from a link.  The
+// return type of the extractor is a list of values (i.e. []string).
+//
+// Elements without the attribute, whose attribute isn't a valid URL, or whose
+// URL doesn't have the parameter, are skipped.  It can also be used as a stage
+// in a Pipeline, where it parses a URL, or each URL in a []string, returned by
+// the previous stage.
+type QueryParam struct {
+	// The HTML attribute containing the URL.  If this is empty, then "href"
+	// is used, falling back to "src" for elements without an href.
+	Attr string
+
+	// The name of the query parameter to return.
+	Param string
+
+	// By default, if there is only a single value extracted, QueryParam will
+	// return the value itself (as opposed to an array containing the single
+	// value).  Set AlwaysReturnList to true to disable this behaviour,
+	// ensuring that the Extract function always returns an array.
+	AlwaysReturnList bool
+
+	// If no values are found, then return 'nil' from Extract, instead of the
+	// empty list.  This signals that the result of this Piece should be
+	// omitted entirely from the results, as opposed to including the empty
+	// list.
+	OmitIfEmpty bool
+}
+
+func (e QueryParam) Validate() error {
+	if e.Param == "" {
+		return errors.New("no query parameter provided")
+	}
+	return nil
+}
+
+func (e QueryParam) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	sel.Each(func(i int, s *goquery.Selection) {
+		var (
+			val   string
+			found bool
+		)
+		if e.Attr != "" {
+			val, found = s.Attr(e.Attr)
+		} else if val, found = s.Attr("href"); !found {
+			val, found = s.Attr("src")
+		}
+		if found {
+			urls = append(urls, val)
+		}
+	})
+	return e.params(urls), nil
+}
+
+// ExtractValue parses a URL, or each URL in a []string, that was returned by
+// a previous stage of a Pipeline.
+func (e QueryParam) ExtractValue(v interface{}) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	urls, err := toStrings(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.params(urls), nil
+}
+
+// params returns the value of the parameter from each of the given URLs, as
+// described on the QueryParam type.
+func (e QueryParam) params(urls []string) interface{} {
+	results := []string{}
+	for _, raw := range urls {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			continue
+		}
+		if vals, found := u.Query()[e.Param]; found && len(vals) > 0 {
+			results = append(results, vals[0])
+		}
+	}
+
+	if len(results) == 0 && e.OmitIfEmpty {
+		return nil
+	}
+	if len(results) == 1 && !e.AlwaysReturnList {
+		return results[0]
+	}
+	return results
+}
+
+var _ scrape.PieceExtractor = QueryParam{}
+var _ scrape.Validator = QueryParam{}
+var _ ValueExtractor = QueryParam{}
@@ -0,0 +1,45 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryParam(t *testing.T) {
+	const page = `<div>
+		<iframe src="https://www.youtube.com/watch?v=dQw4w9WgXcQ&amp;t=42"></iframe>
+		<a href="/item?id=17&amp;ref=home">Item</a>
+		<a href="/item?id=18" data-track="/t?v=abc">Other</a>
+		<a href="/about">About</a>
+		<a>No link</a>
+	</div>`
+
+	extracttest.Run(t, QueryParam{Param: "v"}, []extracttest.Case{
+		{Name: "src", HTML: page, Selector: "iframe", Want: "dQw4w9WgXcQ"},
+		{Name: "missing", HTML: page, Selector: "a", Want: []string{}},
+	})
+
+	extracttest.Run(t, QueryParam{Param: "id"}, []extracttest.Case{
+		{Name: "href", HTML: page, Selector: "a", Want: []string{"17", "18"}},
+		{Name: "single", HTML: page, Selector: "a:first-of-type", Want: "17"},
+	})
+
+	extracttest.Run(t, QueryParam{Attr: "data-track", Param: "v", AlwaysReturnList: true}, []extracttest.Case{
+		{Name: "attr", HTML: page, Selector: "a", Want: []string{"abc"}},
+	})
+
+	extracttest.Run(t, QueryParam{Param: "id", OmitIfEmpty: true}, []extracttest.Case{
+		{Name: "omitted", HTML: page, Selector: "iframe", Want: nil},
+	})
+
+	extracttest.Run(t, QueryParam{}, []extracttest.Case{
+		{Name: "no param", HTML: page, Selector: "a", WantErr: true},
+	})
+
+	got, err := Chain(Attr{Attr: "data-url"}, QueryParam{Param: "q"}).
+		Extract(extracttest.Selection(t, `<span data-url="https://example.com/search?q=go+scrape">x</span>`).Find("span"))
+	assert.NoError(t, err)
+	assert.Equal(t, "go scrape", got)
+}
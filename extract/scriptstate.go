@@ -0,0 +1,140 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// ScriptState is a PieceExtractor that finds JSON assigned to a JavaScript
+// variable inside a <script> element - either the elements in the selection
+// itself, or those inside it - such as:
+//
+//	<script>window.__INITIAL_STATE__ = {"items": [{"name": "Widget"}]};</script>
+//
+// Single-page applications often embed all of a page's data this way.  Unlike
+// a Regex, the JSON value is parsed in full, so nested braces and braces
+// inside strings are handled correctly.  The value may also be wrapped in
+// JSON.parse("..."), as long as the string uses double quotes.
+//
+// The parsed value is returned as with encoding/json: objects are returned as
+// map[string]interface{}, arrays as []interface{}, and numbers as float64.  If
+// the variable (or the Path within it) isn't found, then Extract returns nil,
+// which omits the result of this Piece.
+type ScriptState struct {
+	// The name of the variable, e.g. "window.__INITIAL_STATE__" or
+	// "__NEXT_DATA__".  A name also matches when it is preceded by an object,
+	// so "__INITIAL_STATE__" matches "window.__INITIAL_STATE__ = ...".
+	Variable string
+
+	// If Path is set, then only the value at this path within the parsed JSON
+	// is returned.  The path is a list of object keys and array indexes,
+	// separated by periods, e.g. "props.pageProps.items.0.name".
+	Path string
+}
+
+func (e ScriptState) Validate() error {
+	if e.Variable == "" {
+		return errors.New("no variable name provided")
+	}
+	return nil
+}
+
+func (e ScriptState) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	scripts := sel.Filter("script").AddSelection(sel.Find("script"))
+
+	var (
+		ret   interface{}
+		found bool
+	)
+	scripts.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		ret, found = findAssignment(s.Text(), e.Variable)
+		return !found
+	})
+	if !found {
+		return nil, nil
+	}
+
+	if e.Path != "" {
+		ret = lookupPath(ret, e.Path)
+	}
+	return ret, nil
+}
+
+// findAssignment finds the first assignment to the given variable in the given
+// script, and parses the JSON value that is assigned.
+func findAssignment(script, name string) (interface{}, bool) {
+	for offset := 0; ; {
+		idx := strings.Index(script[offset:], name)
+		if idx < 0 {
+			return nil, false
+		}
+		start := offset + idx
+		offset = start + len(name)
+
+		// Make sure that we've matched the whole name.
+		if start > 0 && isIdentChar(script[start-1]) {
+			continue
+		}
+		if offset < len(script) && isIdentChar(script[offset]) {
+			continue
+		}
+
+		rest := strings.TrimLeft(script[offset:], " \t\r\n")
+		if !strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, "==") {
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\r\n")
+
+		if strings.HasPrefix(rest, "JSON.parse(") {
+			var encoded string
+			dec := json.NewDecoder(strings.NewReader(rest[len("JSON.parse("):]))
+			if err := dec.Decode(&encoded); err != nil {
+				continue
+			}
+			rest = encoded
+		}
+
+		var val interface{}
+		if err := json.NewDecoder(strings.NewReader(rest)).Decode(&val); err != nil {
+			continue
+		}
+		return val, true
+	}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// lookupPath returns the value at the given path within a parsed JSON value,
+// or nil if there is none.
+func lookupPath(val interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			val = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			val = v[i]
+		default:
+			return nil
+		}
+	}
+	return val
+}
+
+var _ scrape.PieceExtractor = ScriptState{}
+var _ scrape.Validator = ScriptState{}
@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestScriptState(t *testing.T) {
+	const page = `<head>
+		<script>var __INITIAL_STATE__count = 3;</script>
+		<script>
+			if (window.__INITIAL_STATE__ == null) {}
+			window.__INITIAL_STATE__ = {"items": [{"name": "Widget {1}"}, {"name": "Gadget"}], "total": 2};
+			window.other = {};
+		</script>
+		<script>window.__DATA__ = JSON.parse("{\"id\": \"a\\\"b\"}");</script>
+	</head>`
+
+	extracttest.Run(t, ScriptState{Variable: "__INITIAL_STATE__"}, []extracttest.Case{
+		{Name: "whole value", HTML: page, Want: map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "Widget {1}"},
+				map[string]interface{}{"name": "Gadget"},
+			},
+			"total": float64(2),
+		}},
+		{Name: "missing", HTML: `<script>var x = 1;</script>`, Want: nil},
+	})
+
+	extracttest.Run(t, ScriptState{Variable: "window.__INITIAL_STATE__", Path: "items.1.name"}, []extracttest.Case{
+		{Name: "path", HTML: page, Want: "Gadget"},
+	})
+
+	extracttest.Run(t, ScriptState{Variable: "__INITIAL_STATE__", Path: "items.5.name"}, []extracttest.Case{
+		{Name: "missing path", HTML: page, Want: nil},
+	})
+
+	extracttest.Run(t, ScriptState{Variable: "__DATA__", Path: "id"}, []extracttest.Case{
+		{Name: "JSON.parse", HTML: page, Want: `a"b`},
+	})
+
+	extracttest.Run(t, ScriptState{}, []extracttest.Case{
+		{Name: "no variable", HTML: page, WantErr: true},
+	})
+}
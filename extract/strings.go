@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Split is a PieceExtractor that splits the text of the selection on a
+// separator and returns one of the parts, with its surrounding whitespace
+// trimmed - e.g. the "Widget" in "Widget | Example Store".  It can also be
+// used as a stage in a Pipeline, where it splits a string, or each string in
+// a []string, returned by the previous stage.
+//
+// If there is no part with the given index, then the result is nil.
+type Split struct {
+	// The separator to split on.  If this is empty, then the text is split
+	// around each run of whitespace.
+	Sep string
+
+	// The index of the part to return, starting at 0.  Negative indexes count
+	// back from the end, so -1 is the last part.
+	Index int
+}
+
+func (e Split) Extract(sel *goquery.Selection) (interface{}, error) {
+	return e.split(sel.Text()), nil
+}
+
+// ExtractValue splits a string, or each string in a []string, that was
+// returned by a previous stage of a Pipeline.  Strings without the requested
+// part are left out of a []string.
+func (e Split) ExtractValue(v interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		return e.split(s), nil
+	}
+
+	strs, err := toStrings(v)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, s := range strs {
+		if part := e.split(s); part != nil {
+			ret = append(ret, part.(string))
+		}
+	}
+	return ret, nil
+}
+
+func (e Split) split(s string) interface{} {
+	var parts []string
+	if e.Sep == "" {
+		parts = strings.Fields(s)
+	} else {
+		parts = strings.Split(s, e.Sep)
+	}
+
+	i := e.Index
+	if i < 0 {
+		i += len(parts)
+	}
+	if i < 0 || i >= len(parts) {
+		return nil
+	}
+	return strings.TrimSpace(parts[i])
+}
+
+var _ scrape.PieceExtractor = Split{}
+var _ ValueExtractor = Split{}
+
+// Replace is a PieceExtractor that returns the text of the selection with
+// every occurrence of a string or regular expression replaced - e.g. to remove
+// a "Price:" label, or to reformat a date.  It can also be used as a stage in
+// a Pipeline, where it replaces within a string, or each string in a []string,
+// returned by the previous stage.
+//
+// Exactly one of Old and Regex must be set.
+type Replace struct {
+	// The string to replace.
+	Old string
+
+	// The regular expression to replace.  Within New, $1 or ${name} is
+	// replaced by the text of the corresponding subexpression, as with
+	// regexp.Regexp.ReplaceAllString.
+	Regex *regexp.Regexp
+
+	// The replacement, which may be empty to remove the matches.
+	New string
+}
+
+func (e Replace) Validate() error {
+	if e.Old == "" && e.Regex == nil {
+		return errors.New("no string or regex to replace given")
+	}
+	if e.Old != "" && e.Regex != nil {
+		return errors.New("only one of Old and Regex can be given")
+	}
+	return nil
+}
+
+func (e Replace) Extract(sel *goquery.Selection) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e.replace(sel.Text()), nil
+}
+
+// ExtractValue replaces within a string, or each string in a []string, that
+// was returned by a previous stage of a Pipeline.
+func (e Replace) ExtractValue(v interface{}) (interface{}, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if s, ok := v.(string); ok {
+		return e.replace(s), nil
+	}
+
+	strs, err := toStrings(v)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, len(strs))
+	for i, s := range strs {
+		ret[i] = e.replace(s)
+	}
+	return ret, nil
+}
+
+func (e Replace) replace(s string) string {
+	if e.Regex != nil {
+		return e.Regex.ReplaceAllString(s, e.New)
+	}
+	return strings.Replace(s, e.Old, e.New, -1)
+}
+
+var _ scrape.PieceExtractor = Replace{}
+var _ scrape.Validator = Replace{}
+var _ ValueExtractor = Replace{}
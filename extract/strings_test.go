@@ -0,0 +1,65 @@
+package extract
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	const page = `<h1>Widget | Example Store | Home</h1>`
+
+	extracttest.Run(t, Split{Sep: "|"}, []extracttest.Case{
+		{Name: "first", HTML: page, Selector: "h1", Want: "Widget"},
+		{Name: "no separator", HTML: `<h1>Widget</h1>`, Selector: "h1", Want: "Widget"},
+	})
+	extracttest.Run(t, Split{Sep: "|", Index: -1}, []extracttest.Case{
+		{Name: "last", HTML: page, Selector: "h1", Want: "Home"},
+	})
+	extracttest.Run(t, Split{Sep: "|", Index: 3}, []extracttest.Case{
+		{Name: "out of range", HTML: page, Selector: "h1", Want: nil},
+	})
+	extracttest.Run(t, Split{Index: 1}, []extracttest.Case{
+		{Name: "whitespace", HTML: `<p>  Posted
+			by   admin</p>`, Selector: "p", Want: "by"},
+	})
+
+	got, err := Chain(Attr{Attr: "href", AlwaysReturnList: true}, Split{Sep: "/", Index: -1}).
+		Extract(extracttest.Selection(t, `<a href="/items/1">a</a><a href="/items/2">b</a><a href="">c</a>`).Find("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", ""}, got)
+
+	got, err = Split{Sep: ",", Index: 2}.ExtractValue([]string{"a,b,c", "d,e"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, got)
+
+	_, err = Split{}.ExtractValue(42)
+	assert.Error(t, err)
+}
+
+func TestReplace(t *testing.T) {
+	extracttest.Run(t, Replace{Old: "Price:"}, []extracttest.Case{
+		{Name: "remove", HTML: `<p>Price: $10</p>`, Selector: "p", Want: " $10"},
+	})
+	extracttest.Run(t, Replace{Regex: regexp.MustCompile(`(\d+)/(\d+)/(\d+)`), New: "$3-$2-$1"}, []extracttest.Case{
+		{Name: "regex", HTML: `<time>18/10/2026 and 1/2/2003</time>`, Selector: "time", Want: "2026-10-18 and 2003-2-1"},
+		{Name: "no match", HTML: `<time>today</time>`, Selector: "time", Want: "today"},
+	})
+	extracttest.Run(t, Replace{}, []extracttest.Case{
+		{Name: "nothing to replace", HTML: `<p>text</p>`, Selector: "p", WantErr: true},
+	})
+	extracttest.Run(t, Replace{Old: "a", Regex: regexp.MustCompile("b")}, []extracttest.Case{
+		{Name: "both", HTML: `<p>text</p>`, Selector: "p", WantErr: true},
+	})
+
+	got, err := Chain(Attr{Attr: "title"}, Replace{Old: "_", New: " "}).
+		Extract(extracttest.Selection(t, `<p title="big_red_widget">text</p>`).Find("p"))
+	assert.NoError(t, err)
+	assert.Equal(t, "big red widget", got)
+
+	got, err = Replace{Old: "-", New: " "}.ExtractValue([]string{"a-b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a b", "c"}, got)
+}
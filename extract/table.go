@@ -0,0 +1,185 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Table is a PieceExtractor that converts an HTML table into a list of rows.
+// If the selection contains <table> elements, then the first one is used;
+// otherwise, the first table inside the selection is used.  If there is no
+// table, then Table returns nil.
+//
+// By default, the result is a []map[string]string with one map per row after
+// the header row, keyed by the text of the header cells.  Columns with an empty
+// header are keyed by their (1-based) position - e.g. "3" - and repeated
+// headers have their position appended - e.g. "Total 4".  Set Rows to
+// return every row as a [][]string instead.
+//
+// Cells that span several rows or columns are repeated in each row and column
+// they cover, so that every row lines up with the header.
+type Table struct {
+	// The (1-based) index of the header row.  Rows before it are ignored.  If
+	// this is 0, then the first row in the table's <thead> is used, or the
+	// first row of the table if there is no <thead>.
+	HeaderRow int
+
+	// If Rows is true, then every row is returned as a list of cell texts -
+	// i.e. a [][]string - and HeaderRow is ignored.
+	Rows bool
+
+	// If NoSpans is true, then cells that span several rows or columns only
+	// appear once, in the first row and column they cover.
+	NoSpans bool
+}
+
+func (e Table) Extract(sel *goquery.Selection) (interface{}, error) {
+	table := sel.Filter("table").First()
+	if table.Length() == 0 {
+		table = sel.Find("table").First()
+	}
+	if table.Length() == 0 {
+		return nil, nil
+	}
+
+	rows, headerIdx := tableRows(table)
+	grid := e.grid(rows)
+	if e.Rows {
+		return grid, nil
+	}
+
+	if e.HeaderRow > 0 {
+		headerIdx = e.HeaderRow - 1
+	}
+	if headerIdx >= len(grid) {
+		return []map[string]string{}, nil
+	}
+
+	keys := tableKeys(grid[headerIdx])
+	ret := []map[string]string{}
+	for _, row := range grid[headerIdx+1:] {
+		m := map[string]string{}
+		for i, cell := range row {
+			if i < len(keys) {
+				m[keys[i]] = cell
+			} else {
+				m[strconv.Itoa(i+1)] = cell
+			}
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+// tableRows returns the rows of the given table (but not of any tables nested
+// inside it), and the index of the first row in its <thead>, or 0 if there is
+// none.
+func tableRows(table *goquery.Selection) ([]*goquery.Selection, int) {
+	rows := []*goquery.Selection{}
+	headerIdx := -1
+
+	table.Children().Each(func(i int, child *goquery.Selection) {
+		switch goquery.NodeName(child) {
+		case "tr":
+			rows = append(rows, child)
+		case "thead", "tbody", "tfoot":
+			if goquery.NodeName(child) == "thead" && headerIdx < 0 {
+				headerIdx = len(rows)
+			}
+			child.ChildrenFiltered("tr").Each(func(i int, tr *goquery.Selection) {
+				rows = append(rows, tr)
+			})
+		}
+	})
+
+	if headerIdx < 0 || headerIdx >= len(rows) {
+		headerIdx = 0
+	}
+	return rows, headerIdx
+}
+
+// grid returns the text of every cell in the given rows, expanding cells that
+// span several rows or columns unless NoSpans is set.
+func (e Table) grid(rows []*goquery.Selection) [][]string {
+	type carry struct {
+		remaining int
+		text      string
+	}
+
+	// Cells from previous rows that span into later ones, by column.
+	carries := map[int]*carry{}
+
+	grid := [][]string{}
+	for _, tr := range rows {
+		row := []string{}
+		col := 0
+
+		fillCarries := func() {
+			for c, ok := carries[col]; ok && c.remaining > 0; c, ok = carries[col] {
+				row = append(row, c.text)
+				if c.remaining--; c.remaining == 0 {
+					delete(carries, col)
+				}
+				col++
+			}
+		}
+
+		tr.ChildrenFiltered("td, th").Each(func(i int, cell *goquery.Selection) {
+			fillCarries()
+
+			text := strings.TrimSpace(cell.Text())
+			colspan, rowspan := 1, 1
+			if !e.NoSpans {
+				colspan = spanAttr(cell, "colspan")
+				rowspan = spanAttr(cell, "rowspan")
+			}
+
+			for j := 0; j < colspan; j++ {
+				row = append(row, text)
+				if rowspan > 1 {
+					carries[col] = &carry{remaining: rowspan - 1, text: text}
+				}
+				col++
+			}
+		})
+		fillCarries()
+
+		grid = append(grid, row)
+	}
+	return grid
+}
+
+func spanAttr(cell *goquery.Selection, attr string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(cell.AttrOr(attr, "1")))
+	if err != nil || n < 1 {
+		return 1
+	}
+	// Guard against absurd values, as browsers do.
+	if n > 1000 {
+		n = 1000
+	}
+	return n
+}
+
+// tableKeys returns the map key to use for each column, given the texts of
+// the header cells.
+func tableKeys(header []string) []string {
+	keys := make([]string, len(header))
+	seen := map[string]struct{}{}
+	for i, text := range header {
+		key := text
+		if key == "" {
+			key = strconv.Itoa(i + 1)
+		} else if _, dup := seen[key]; dup {
+			key = key + " " + strconv.Itoa(i+1)
+		}
+		seen[key] = struct{}{}
+		keys[i] = key
+	}
+	return keys
+}
+
+var _ scrape.PieceExtractor = Table{}
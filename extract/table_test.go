@@ -0,0 +1,52 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape/extract/extracttest"
+)
+
+func TestTable(t *testing.T) {
+	const simple = `<table>
+		<thead><tr><th>Name</th><th>Score</th><th></th></tr></thead>
+		<tbody>
+			<tr><td>Alice</td><td> 10 </td><td>x</td></tr>
+			<tr><td>Bob</td><td>7</td></tr>
+		</tbody>
+	</table>`
+
+	const spans = `<div><table>
+		<caption>Ignored</caption>
+		<tr><th>Group</th><th colspan="2">Range</th></tr>
+		<tr><td rowspan="2">A</td><td>1</td><td>2</td></tr>
+		<tr><td>3</td><td>4</td></tr>
+		<tr><td>B</td><td>5<table><tr><td>nested</td></tr></table></td><td>6</td></tr>
+	</table></div>`
+
+	extracttest.Run(t, Table{}, []extracttest.Case{
+		{Name: "simple", HTML: simple, Want: []map[string]string{
+			{"Name": "Alice", "Score": "10", "3": "x"},
+			{"Name": "Bob", "Score": "7"},
+		}},
+		{Name: "spans", HTML: spans, Selector: "div", Want: []map[string]string{
+			{"Group": "A", "Range": "1", "Range 3": "2"},
+			{"Group": "A", "Range": "3", "Range 3": "4"},
+			{"Group": "B", "Range": "5nested", "Range 3": "6"},
+		}},
+		{Name: "no table", HTML: `<p>none</p>`, Want: nil},
+	})
+
+	extracttest.Run(t, Table{Rows: true, NoSpans: true}, []extracttest.Case{
+		{Name: "rows", HTML: spans, Selector: "table", Want: [][]string{
+			{"Group", "Range"},
+			{"A", "1", "2"},
+			{"3", "4"},
+			{"B", "5nested", "6"},
+		}},
+	})
+
+	extracttest.Run(t, Table{HeaderRow: 2}, []extracttest.Case{
+		{Name: "header row", HTML: `<table><tr><td>title</td></tr><tr><th>a</th><th>b</th></tr><tr><td>1</td><td>2</td></tr></table>`,
+			Want: []map[string]string{{"a": "1", "b": "2"}}},
+	})
+}
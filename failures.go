@@ -0,0 +1,95 @@
+package scrape
+
+import (
+	"reflect"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PieceFailures describes the blocks in which a single Piece was empty.
+type PieceFailures struct {
+	// The total number of blocks in which the Piece was empty.
+	Count int
+
+	// A sample of the blocks in which the Piece was empty - up to
+	// ScrapeConfig.FailureSamples of them, in the order they were scraped.
+	Samples []FailureSample
+}
+
+// FailureSample identifies a single block in which a Piece was empty.
+type FailureSample struct {
+	// The URL of the page containing the block.
+	URL string
+
+	// The index of the block in the page's results.
+	Block int
+
+	// A snippet of the HTML of the block.
+	Snippet string
+}
+
+// recordFailures adds every empty Piece in the given block to the failure
+// report in the given results.
+func recordFailures(res *ScrapeResults, pieces []Piece, maxSamples int,
+	url string, index int, block *goquery.Selection, blockResults map[string]interface{}) {
+
+	for _, piece := range pieces {
+		if !isEmpty(blockResults[piece.Name]) {
+			continue
+		}
+
+		if res.Failures == nil {
+			res.Failures = map[string]*PieceFailures{}
+		}
+		failures, found := res.Failures[piece.Name]
+		if !found {
+			failures = &PieceFailures{Samples: []FailureSample{}}
+			res.Failures[piece.Name] = failures
+		}
+
+		failures.Count++
+		if len(failures.Samples) < maxSamples {
+			failures.Samples = append(failures.Samples, FailureSample{
+				URL:     url,
+				Block:   index,
+				Snippet: snippet(block),
+			})
+		}
+	}
+}
+
+// mergeFailures adds the given failures for a Piece to the failure report in
+// the given results.
+func mergeFailures(res *ScrapeResults, name string, failures *PieceFailures, maxSamples int) {
+	if res.Failures == nil {
+		res.Failures = map[string]*PieceFailures{}
+	}
+	existing, found := res.Failures[name]
+	if !found {
+		existing = &PieceFailures{Samples: []FailureSample{}}
+		res.Failures[name] = existing
+	}
+
+	existing.Count += failures.Count
+	for _, sample := range failures.Samples {
+		if len(existing.Samples) >= maxSamples {
+			break
+		}
+		existing.Samples = append(existing.Samples, sample)
+	}
+}
+
+// isEmpty returns whether the given extractor result is nil, or an empty
+// string, slice or map.
+func isEmpty(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package scrape
+
+import (
+	"container/heap"
+	"regexp"
+)
+
+// PriorityRule assigns a priority to URLs that match a pattern during a crawl.
+// See CrawlConfig.Priorities for more information.
+type PriorityRule struct {
+	Pattern  *regexp.Regexp
+	Priority int
+}
+
+// priority returns the priority of the given URL, found at the given depth.
+func (c *CrawlConfig) priority(url string, depth int) int {
+	if c.Priority != nil {
+		return c.Priority(url, depth)
+	}
+	for _, rule := range c.Priorities {
+		if rule.Pattern.MatchString(url) {
+			return rule.Priority
+		}
+	}
+	return 0
+}
+
+// frontier holds the URLs waiting to be crawled, ordered by priority, and then
+// by the order in which they were found.
+type frontier struct {
+	items []crawlItem
+	seq   int
+}
+
+func (f *frontier) push(item crawlItem) {
+	item.seq = f.seq
+	f.seq++
+	heap.Push((*frontierHeap)(f), item)
+}
+
+func (f *frontier) pop() crawlItem {
+	return heap.Pop((*frontierHeap)(f)).(crawlItem)
+}
+
+func (f *frontier) len() int {
+	return len(f.items)
+}
+
+// frontierHeap implements heap.Interface for a frontier.
+type frontierHeap frontier
+
+func (h *frontierHeap) Len() int { return len(h.items) }
+
+func (h *frontierHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+func (h *frontierHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *frontierHeap) Push(x interface{}) { h.items = append(h.items, x.(crawlItem)) }
+
+func (h *frontierHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
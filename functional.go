@@ -0,0 +1,175 @@
+package scrape
+
+import (
+	"log"
+)
+
+// An Option configures a Scraper that is created by NewScraper.
+type Option func(*optionSet)
+
+type optionSet struct {
+	config ScrapeConfig
+	opts   ScrapeOptions
+}
+
+// NewScraper creates a new Scraper from the given options - e.g.:
+//
+//	scraper, err := scrape.NewScraper(
+//		scrape.WithPieces(
+//			scrape.Piece{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+//		),
+//		scrape.WithPaginator(paginate.BySelector("a.next", "href")),
+//		scrape.WithLimits(scrape.ScrapeOptions{MaxPages: 10}),
+//	)
+//
+// This is an alternative to building a ScrapeConfig by hand and passing it to
+// New, which is better suited to loading a configuration declaratively; the
+// resulting configuration is validated in the same way.  Options are applied
+// in order, so later options override earlier ones.
+func NewScraper(options ...Option) (*Scraper, error) {
+	set := &optionSet{opts: DefaultOptions}
+	for _, option := range options {
+		option(set)
+	}
+
+	ret, err := New(&set.config)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.opts = set.opts
+	return ret, nil
+}
+
+// WithFetcher sets the Fetcher used to fetch documents.
+func WithFetcher(f Fetcher) Option {
+	return func(s *optionSet) { s.config.Fetcher = f }
+}
+
+// WithMiddleware adds the given FetcherMiddlewares to the Middleware that
+// wraps the Fetcher.
+func WithMiddleware(middleware ...FetcherMiddleware) Option {
+	return func(s *optionSet) { s.config.Middleware = append(s.config.Middleware, middleware...) }
+}
+
+// WithPaginator sets the Paginator used to find the next page.
+func WithPaginator(p Paginator) Option {
+	return func(s *optionSet) { s.config.Paginator = p }
+}
+
+// WithDividePage sets the function used to split each page into blocks.
+func WithDividePage(f DividePageFunc) Option {
+	return func(s *optionSet) { s.config.DividePage = f }
+}
+
+// WithPieces adds the given Pieces to the list of data to extract from each
+// block.
+func WithPieces(pieces ...Piece) Option {
+	return func(s *optionSet) { s.config.Pieces = append(s.config.Pieces, pieces...) }
+}
+
+// WithStopCondition sets the function used to end a scrape early.
+func WithStopCondition(f StopConditionFunc) Option {
+	return func(s *optionSet) { s.config.StopCondition = f }
+}
+
+// WithDedup enables deduplication of blocks across scrapes.
+func WithDedup(d *DedupConfig) Option {
+	return func(s *optionSet) { s.config.Dedup = d }
+}
+
+// WithConcurrentPieces sets the maximum number of Pieces that are extracted
+// from a block at the same time.
+func WithConcurrentPieces(n int) Option {
+	return func(s *optionSet) { s.config.ConcurrentPieces = n }
+}
+
+// WithSink sets the Sink that receives each page as it is scraped.
+func WithSink(sink Sink) Option {
+	return func(s *optionSet) { s.config.Sink = sink }
+}
+
+// WithCheckpoint enables checkpointing of the scrape's progress.
+func WithCheckpoint(c *CheckpointConfig) Option {
+	return func(s *optionSet) { s.config.Checkpoint = c }
+}
+
+// WithLogger sets the Logger used to log the progress of each scrape.
+func WithLogger(l *log.Logger) Option {
+	return func(s *optionSet) { s.config.Logger = l }
+}
+
+// WithFailureSamples sets the maximum number of blocks that are recorded for
+// each empty Piece.
+func WithFailureSamples(n int) Option {
+	return func(s *optionSet) { s.config.FailureSamples = n }
+}
+
+// WithRewrite adds functions that modify the raw HTML of each page before it's
+// parsed.
+func WithRewrite(funcs ...RewriteFunc) Option {
+	return func(s *optionSet) { s.config.Rewrite = append(s.config.Rewrite, funcs...) }
+}
+
+// WithPromoteNoscript replaces every <noscript> element with its contents
+// before extraction.
+func WithPromoteNoscript() Option {
+	return func(s *optionSet) { s.config.PromoteNoscript = true }
+}
+
+// WithUsage enables per-host accounting of the resources used to fetch pages.
+func WithUsage(c *UsageConfig) Option {
+	return func(s *optionSet) { s.config.Usage = c }
+}
+
+// WithChallenge enables handling of captchas and other anti-bot challenges.
+func WithChallenge(c *ChallengeConfig) Option {
+	return func(s *optionSet) { s.config.Challenge = c }
+}
+
+// WithStatusPolicy sets what happens to pages with a non-2xx status code.
+func WithStatusPolicy(p *StatusPolicy) Option {
+	return func(s *optionSet) { s.config.StatusPolicy = p }
+}
+
+// WithStrict enables strict mode, in which every Piece's value is checked
+// against its declared type.
+func WithStrict() Option {
+	return func(s *optionSet) { s.config.Strict = true }
+}
+
+// WithBlockID adds an ID to every block, using the given generator.  The ID is
+// stored under DefaultBlockIDName.
+func WithBlockID(g BlockIDGenerator) Option {
+	return func(s *optionSet) { s.config.BlockID = &BlockIDConfig{Generator: g} }
+}
+
+// WithLimits sets all of the options that limit a scrape, replacing any that
+// were set by earlier options.  These are used as the default options for
+// Scrape and ScrapeAll.
+func WithLimits(opts ScrapeOptions) Option {
+	return func(s *optionSet) { s.opts = opts }
+}
+
+// WithMaxPages sets the maximum number of pages that Scrape will fetch.
+func WithMaxPages(n int) Option {
+	return func(s *optionSet) { s.opts.MaxPages = n }
+}
+
+// WithParallelism sets the number of start URLs that ScrapeAll will scrape at
+// the same time.
+func WithParallelism(n int) Option {
+	return func(s *optionSet) { s.opts.Parallelism = n }
+}
+
+// WithMaxConsecutiveEmptyPages stops a scrape once n pages in a row have had
+// no blocks.  See ScrapeOptions.MaxConsecutiveEmptyPages.
+func WithMaxConsecutiveEmptyPages(n int) Option {
+	return func(s *optionSet) { s.opts.MaxConsecutiveEmptyPages = n }
+}
+
+// WithMaxConsecutiveErrors skips pages that can't be fetched, until n pages in
+// a row have failed.  See ScrapeOptions.MaxConsecutiveErrors.
+func WithMaxConsecutiveErrors(n int) Option {
+	return func(s *optionSet) { s.opts.MaxConsecutiveErrors = n }
+}
@@ -0,0 +1,28 @@
+//go:build go1.18
+// +build go1.18
+
+package scrape
+
+// ScrapeAs scrapes the given URL with the Scraper's default options, and
+// decodes each block of the results into a value of type T, which must be a
+// struct or a pointer to a struct.  See DecodeBlock for how blocks are
+// decoded.
+//
+// This function requires Go 1.18 or later.
+func ScrapeAs[T any](s *Scraper, url string) ([]T, error) {
+	return ScrapeAsWithOpts[T](s, url, s.opts)
+}
+
+// ScrapeAsWithOpts is like ScrapeAs, but uses the given options.
+func ScrapeAsWithOpts[T any](s *Scraper, url string, opts ScrapeOptions) ([]T, error) {
+	res, err := s.ScrapeWithOpts(url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []T{}
+	if err = res.Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,35 @@
+//go:build go1.18
+// +build go1.18
+
+package scrape_test
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrapeAs(t *testing.T) {
+	type item struct {
+		Name  string `scrape:"name"`
+		Count int    `scrape:"count"`
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<ul><li><b>one</b> <i>1</i></li><li><b>two</b> <i>2</i></li></ul>`},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "name", Selector: "b", Extractor: extract.Text{}},
+			{Name: "count", Selector: "i", Extractor: extract.Text{}},
+		},
+	})
+
+	items, err := scrape.ScrapeAs[item](sc, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{"one", 1}, {"two", 2}}, items)
+
+	_, err = scrape.ScrapeAs[int](sc, "a")
+	assert.Error(t, err)
+}
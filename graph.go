@@ -0,0 +1,175 @@
+package scrape
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GraphFormat is the output format of ScrapeConfig.WriteGraph.
+type GraphFormat int
+
+const (
+	// DOT is the Graphviz DOT language.
+	DOT GraphFormat = iota
+
+	// Mermaid is the Mermaid flowchart syntax.
+	Mermaid
+)
+
+// GraphOptions contains additional information that is included when
+// rendering a ScrapeConfig as a graph.
+type GraphOptions struct {
+	// The URLs that the scrape starts from.
+	StartURLs []string
+
+	// If the config is used for a crawl, then the CrawlConfig in use.
+	Crawl *CrawlConfig
+}
+
+type graphNode struct {
+	id    string
+	label []string
+}
+
+type graphEdge struct {
+	from, to string
+	label    string
+}
+
+// WriteGraph renders this configuration as a graph in the given format,
+// showing the start URLs, how pages are fetched and paginated (or crawled),
+// the Pieces extracted from each block, and where results are sent.  This is
+// intended to help review complex configurations.
+func (c *ScrapeConfig) WriteGraph(w io.Writer, format GraphFormat, opts GraphOptions) error {
+	nodes, edges := c.graph(opts)
+
+	switch format {
+	case DOT:
+		return writeDOT(w, nodes, edges)
+	case Mermaid:
+		return writeMermaid(w, nodes, edges)
+	default:
+		return fmt.Errorf("unknown graph format: %d", format)
+	}
+}
+
+func (c *ScrapeConfig) graph(opts GraphOptions) ([]graphNode, []graphEdge) {
+	nodes := []graphNode{}
+	edges := []graphEdge{}
+
+	fetcher := "HttpClientFetcher (default)"
+	if c.Fetcher != nil {
+		fetcher = typeName(c.Fetcher)
+	}
+	nodes = append(nodes, graphNode{"fetch", []string{"Fetch", fetcher}})
+
+	for i, url := range opts.StartURLs {
+		id := fmt.Sprintf("start%d", i)
+		nodes = append(nodes, graphNode{id, []string{"Start", url}})
+		edges = append(edges, graphEdge{id, "fetch", ""})
+	}
+
+	nodes = append(nodes, graphNode{"page", []string{"Page"}})
+	edges = append(edges, graphEdge{"fetch", "page", ""})
+
+	// How further pages are found.
+	if opts.Crawl != nil {
+		label := []string{"Crawl"}
+		if opts.Crawl.LinkSelector != "" {
+			label = append(label, "links: "+opts.Crawl.LinkSelector)
+		}
+		for _, re := range opts.Crawl.Allow {
+			label = append(label, "allow: "+re.String())
+		}
+		for _, re := range opts.Crawl.Deny {
+			label = append(label, "deny: "+re.String())
+		}
+		nodes = append(nodes, graphNode{"follow", label})
+		edges = append(edges, graphEdge{"page", "follow", "links"})
+		edges = append(edges, graphEdge{"follow", "fetch", "follow"})
+	} else if c.Paginator != nil {
+		nodes = append(nodes, graphNode{"paginate", []string{"Paginate", typeName(c.Paginator)}})
+		edges = append(edges, graphEdge{"page", "paginate", ""})
+		edges = append(edges, graphEdge{"paginate", "fetch", "next page"})
+	}
+
+	// What is extracted from each page.
+	divide := "body (default)"
+	if c.DividePage != nil {
+		divide = "custom"
+	}
+	nodes = append(nodes, graphNode{"divide", []string{"DividePage", divide}})
+	edges = append(edges, graphEdge{"page", "divide", ""})
+
+	for i, piece := range c.Pieces {
+		id := fmt.Sprintf("piece%d", i)
+		label := []string{piece.Name, piece.Selector}
+		if piece.Compute != nil {
+			label = []string{piece.Name, "computed"}
+		} else if piece.Extractor != nil {
+			label = append(label, typeName(piece.Extractor))
+		}
+		nodes = append(nodes, graphNode{id, label})
+		edges = append(edges, graphEdge{"divide", id, "block"})
+	}
+
+	// Where results go.
+	if c.Dedup != nil {
+		nodes = append(nodes, graphNode{"dedup", []string{"Dedup", "key: " + c.Dedup.Key}})
+		edges = append(edges, graphEdge{"divide", "dedup", ""})
+	}
+	if c.Sink != nil {
+		nodes = append(nodes, graphNode{"sink", []string{"Sink", typeName(c.Sink)}})
+		edges = append(edges, graphEdge{"page", "sink", "results"})
+	}
+
+	return nodes, edges
+}
+
+func typeName(v interface{}) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+}
+
+func writeDOT(w io.Writer, nodes []graphNode, edges []graphEdge) error {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	lines := []string{"digraph scrape {", "\tnode [shape=box];"}
+	for _, n := range nodes {
+		lines = append(lines, fmt.Sprintf("\t%s [label=\"%s\"];",
+			n.id, escape.Replace(strings.Join(n.label, "\n"))))
+	}
+	for _, e := range edges {
+		if e.label == "" {
+			lines = append(lines, fmt.Sprintf("\t%s -> %s;", e.from, e.to))
+		} else {
+			lines = append(lines, fmt.Sprintf("\t%s -> %s [label=\"%s\"];",
+				e.from, e.to, escape.Replace(e.label)))
+		}
+	}
+	lines = append(lines, "}")
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func writeMermaid(w io.Writer, nodes []graphNode, edges []graphEdge) error {
+	escape := strings.NewReplacer(`"`, "#quot;", "\n", "<br/>")
+
+	lines := []string{"flowchart TD"}
+	for _, n := range nodes {
+		lines = append(lines, fmt.Sprintf("\t%s[\"%s\"]",
+			n.id, escape.Replace(strings.Join(n.label, "\n"))))
+	}
+	for _, e := range edges {
+		if e.label == "" {
+			lines = append(lines, fmt.Sprintf("\t%s --> %s", e.from, e.to))
+		} else {
+			lines = append(lines, fmt.Sprintf("\t%s -->|\"%s\"| %s",
+				e.from, escape.Replace(e.label), e.to))
+		}
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
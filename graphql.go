@@ -0,0 +1,145 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// GraphQLFetcher is a Fetcher for GraphQL endpoints that use cursor-based
+// pagination, in the style of Relay connections.  Each request POSTs the same
+// Query, with the cursor for the current page in the CursorVariable variable,
+// and the response is converted into an HTML document in the same way as by
+// APIFetcher.
+//
+// The cursor is carried in the fragment of each page's URL: the start URL is
+// the GraphQL endpoint (which requests the first page, with a null cursor),
+// and the Paginator returned by the Paginator method adds the cursor of the
+// next page to the fragment.  For example:
+//
+//	fetcher := &GraphQLFetcher{
+//		APIFetcher: APIFetcher{Token: token},
+//		Query: `query($after: String) {
+//			repository(owner: "golang", name: "go") {
+//				issues(first: 100, after: $after) {
+//					nodes { number title }
+//					pageInfo { endCursor hasNextPage }
+//				}
+//			}
+//		}`,
+//	}
+//	config := &ScrapeConfig{
+//		Fetcher:    fetcher,
+//		Paginator:  fetcher.Paginator(),
+//		DividePage: DividePageBySelector(".nodes > li"),
+//		...
+//	}
+//	scraper.Scrape("https://api.github.com/graphql")
+//
+// If the response contains any GraphQL errors, then the first one is returned
+// as an error.
+type GraphQLFetcher struct {
+	APIFetcher
+
+	// The GraphQL query that is sent with each request.
+	Query string
+
+	// Variables contains any other variables that are sent with the query.
+	Variables map[string]interface{}
+
+	// The name of the variable (without the leading "$") that the cursor for
+	// each page is stored in.  If this is empty, then "after" is used.
+	CursorVariable string
+}
+
+func (f *GraphQLFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *GraphQLFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]interface{}, len(f.Variables)+1)
+	for name, val := range f.Variables {
+		variables[name] = val
+	}
+	cursorVar := f.CursorVariable
+	if cursorVar == "" {
+		cursorVar = "after"
+	}
+	if u.Fragment != "" {
+		variables[cursorVar] = u.Fragment
+	} else {
+		variables[cursorVar] = nil
+	}
+	u.Fragment = ""
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     f.Query,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// GraphQL requests are always POSTed, regardless of the given method.
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	doc, err := f.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := goquery.NewDocumentFromNode(doc).Find("body > .errors > li")
+	if errs.Length() > 0 {
+		msg := strings.TrimSpace(errs.First().Find(".message").First().Text())
+		return nil, fmt.Errorf("GraphQL error from %s: %s", u, msg)
+	}
+
+	return renderDocument(doc)
+}
+
+// Paginator returns a Paginator that follows the "pageInfo" object in each
+// response: if its "hasNextPage" field is true, then the next page is the
+// current URL with the value of "endCursor" in its fragment.  If the response
+// contains more than one "pageInfo" object, then the first one is used.
+func (f *GraphQLFetcher) Paginator() Paginator {
+	return graphQLPaginator{}
+}
+
+type graphQLPaginator struct{}
+
+func (graphQLPaginator) NextPage(url string, doc *goquery.Selection) (string, error) {
+	info := doc.Find(".pageInfo").First()
+	if strings.TrimSpace(info.Find(".hasNextPage").First().Text()) != "true" {
+		return "", nil
+	}
+	cursor := strings.TrimSpace(info.Find(".endCursor").First().Text())
+	if cursor == "" {
+		return "", nil
+	}
+
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+	u.Fragment = cursor
+	return u.String(), nil
+}
+
+// Static type assertion
+var _ ContextFetcher = &GraphQLFetcher{}
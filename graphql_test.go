@@ -0,0 +1,73 @@
+package scrape_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLFetcher(t *testing.T) {
+	var cursors []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if r.Method != "POST" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Variables["owner"] != "golang" {
+			fmt.Fprint(w, `{"data": null, "errors": [{"message": "bad owner"}]}`)
+			return
+		}
+
+		cursor := req.Variables["cursor"]
+		cursors = append(cursors, cursor)
+		if cursor == nil {
+			fmt.Fprint(w, `{"data": {"issues": {
+				"nodes": [{"title": "one"}, {"title": "two"}],
+				"pageInfo": {"endCursor": "Y3Vyc29yOjI=", "hasNextPage": true}
+			}}}`)
+		} else {
+			fmt.Fprint(w, `{"data": {"issues": {
+				"nodes": [{"title": "three"}],
+				"pageInfo": {"endCursor": "Y3Vyc29yOjM=", "hasNextPage": false}
+			}}}`)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher := &scrape.GraphQLFetcher{
+		Query:          `query($owner: String!, $cursor: String) { ... }`,
+		Variables:      map[string]interface{}{"owner": "golang"},
+		CursorVariable: "cursor",
+	}
+	config := &scrape.ScrapeConfig{
+		Fetcher:    fetcher,
+		Paginator:  fetcher.Paginator(),
+		DividePage: scrape.DividePageBySelector(".nodes > li"),
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: ".title", Extractor: extract.Text{}},
+		},
+	}
+	results, err := mustNew(config).Scrape(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ts.URL, ts.URL + "#Y3Vyc29yOjI="}, results.URLs)
+	assert.Equal(t, []interface{}{nil, "Y3Vyc29yOjI="}, cursors)
+	assert.Equal(t, []map[string]interface{}{
+		{"title": "one"},
+		{"title": "two"},
+		{"title": "three"},
+	}, results.AllBlocks())
+
+	fetcher.Variables["owner"] = "other"
+	_, err = mustNew(config).Scrape(ts.URL)
+	assert.EqualError(t, err, "GraphQL error from "+ts.URL+": bad owner")
+}
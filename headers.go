@@ -0,0 +1,157 @@
+package scrape
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultUserAgents is the pool of User-Agent strings used by a
+// RotatingHeaderFetcher when none are given: recent versions of common
+// desktop browsers.
+var DefaultUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+}
+
+// DefaultBrowserHeaders are the headers that a RotatingHeaderFetcher sends
+// with each request when none are given, which are those that browsers send
+// when navigating to a page.  Accept-Encoding isn't included, since setting
+// it stops the http package from decompressing responses.
+var DefaultBrowserHeaders = http.Header{
+	"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+	"Accept-Language":           {"en-US,en;q=0.9"},
+	"Upgrade-Insecure-Requests": {"1"},
+}
+
+// RotatingHeaderFetcher is a Fetcher that sets the User-Agent and other
+// headers of each request made by an HttpClientFetcher, so that requests look
+// as if they were made by a variety of browsers.  User-Agents are used in
+// turn from a pool.
+//
+// The headers are set before the fetcher's AutoReferer and PrepareRequest
+// are applied, so PrepareRequest can still change them.  Note that the http
+// package always sends headers in the same order, whatever their order here.
+type RotatingHeaderFetcher struct {
+	// The fetcher to make requests with.  If this is nil, then a new
+	// HttpClientFetcher is used.
+	Fetcher *HttpClientFetcher
+
+	// The User-Agents to rotate through.  If this is empty, then
+	// DefaultUserAgents is used.
+	UserAgents []string
+
+	// Headers are set on every request.  If this is nil, then
+	// DefaultBrowserHeaders is used; set it to an empty http.Header to only
+	// set the User-Agent.
+	Headers http.Header
+
+	// If PerHost is true, then every request to the same host uses the same
+	// User-Agent, as a single browser would.  Otherwise, each request uses the
+	// next one.
+	PerHost bool
+
+	mu    sync.Mutex
+	next  int
+	hosts map[string]string
+
+	// The Fetcher that requests are made with, if not Fetcher itself, when
+	// this is used as middleware.
+	wrapped Fetcher
+
+	// The fetcher that the headers are set by.
+	hooked *HttpClientFetcher
+}
+
+func (f *RotatingHeaderFetcher) Prepare() error {
+	if f.Fetcher == nil {
+		hf, err := NewHttpClientFetcher()
+		if err != nil {
+			return err
+		}
+		f.Fetcher = hf
+	}
+	if f.hooked != f.Fetcher {
+		f.Fetcher.requestHooks = append(f.Fetcher.requestHooks, f.setHeaders)
+		f.hooked = f.Fetcher
+	}
+	return f.fetcher().Prepare()
+}
+
+// fetcher returns the Fetcher that requests are made with.
+func (f *RotatingHeaderFetcher) fetcher() Fetcher {
+	if f.wrapped != nil {
+		return f.wrapped
+	}
+	return f.Fetcher
+}
+
+func (f *RotatingHeaderFetcher) setHeaders(req *http.Request) {
+	headers := f.Headers
+	if headers == nil {
+		headers = DefaultBrowserHeaders
+	}
+	for key, values := range headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	req.Header.Set("User-Agent", f.userAgent(req.URL.Host))
+}
+
+// userAgent returns the User-Agent to use for the next request to the given
+// host.
+func (f *RotatingHeaderFetcher) userAgent(host string) string {
+	agents := f.UserAgents
+	if len(agents) == 0 {
+		agents = DefaultUserAgents
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.PerHost {
+		if ua, found := f.hosts[host]; found {
+			return ua
+		}
+	}
+	ua := agents[f.next%len(agents)]
+	f.next++
+	if f.PerHost {
+		if f.hosts == nil {
+			f.hosts = map[string]string{}
+		}
+		f.hosts[host] = ua
+	}
+	return ua
+}
+
+func (f *RotatingHeaderFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.fetcher().Fetch(method, url)
+}
+
+func (f *RotatingHeaderFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	return fetchFunc(f.fetcher())(ctx, method, url)
+}
+
+func (f *RotatingHeaderFetcher) httpClientFetcher() *HttpClientFetcher {
+	return f.Fetcher
+}
+
+// CookieJar returns the cookie jar of the underlying HttpClientFetcher.
+func (f *RotatingHeaderFetcher) CookieJar() http.CookieJar {
+	return f.Fetcher.CookieJar()
+}
+
+func (f *RotatingHeaderFetcher) Close() {
+	f.fetcher().Close()
+}
+
+// Static type assertion
+var _ ContextFetcher = &RotatingHeaderFetcher{}
+var _ CookieJarFetcher = &RotatingHeaderFetcher{}
@@ -0,0 +1,56 @@
+package scrape_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingHeaderFetcher(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.UserAgent(), r.Header.Get("Accept-Language"), r.Header.Get("X-Test"))
+	}))
+	defer ts.Close()
+
+	fetch := func(f scrape.Fetcher, url string) string {
+		body, err := f.Fetch("GET", url)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer body.Close()
+		data := make([]byte, 1024)
+		n, _ := body.Read(data)
+		return string(data[:n])
+	}
+
+	// User-Agents are used in turn, with the default headers.
+	fetcher := &scrape.RotatingHeaderFetcher{UserAgents: []string{"one", "two"}}
+	assert.NoError(t, fetcher.Prepare())
+	assert.Equal(t, "one|en-US,en;q=0.9|", fetch(fetcher, ts.URL))
+	assert.Equal(t, "two|en-US,en;q=0.9|", fetch(fetcher, ts.URL))
+	assert.Equal(t, "one|en-US,en;q=0.9|", fetch(fetcher, ts.URL))
+
+	// PrepareRequest can override the headers.
+	hf, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	hf.PrepareRequest = func(req *http.Request) error {
+		req.Header.Set("Accept-Language", "de")
+		return nil
+	}
+	fetcher = &scrape.RotatingHeaderFetcher{
+		Fetcher: hf,
+		Headers: http.Header{"X-Test": {"yes"}},
+		PerHost: true,
+	}
+	assert.NoError(t, fetcher.Prepare())
+	assert.Equal(t, scrape.DefaultUserAgents[0]+"|de|yes", fetch(fetcher, ts.URL))
+
+	// The same host keeps its User-Agent.
+	assert.Equal(t, scrape.DefaultUserAgents[0]+"|de|yes", fetch(fetcher, ts.URL))
+	other := "http://localhost:" + ts.URL[len("http://127.0.0.1:"):]
+	assert.Equal(t, scrape.DefaultUserAgents[1]+"|de|yes", fetch(fetcher, other))
+}
@@ -0,0 +1,24 @@
+package scrape
+
+// Check reports whether this Scraper is ready to start new scrapes.  It fails
+// if the Scraper is being drained, or if the Fetcher or Sink implement a
+// "Check() error" method that returns an error.  This allows a Scraper to be
+// used with the "health" subpackage.
+func (s *Scraper) Check() error {
+	if s.isDraining() {
+		return ErrDrained
+	}
+
+	if err := checkFetcher(s.config.Fetcher); err != nil {
+		return err
+	}
+	type checker interface {
+		Check() error
+	}
+	if c, ok := s.config.Sink.(checker); ok {
+		if err := c.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Checker is the interface that must be satisfied by things that can report
+// their health.  A *scrape.Scraper is a Checker, as are some Sinks.
+type Checker interface {
+	// Check returns nil if the component is healthy, or an error describing
+	// the problem otherwise.
+	Check() error
+}
+
+// CheckerFunc is an adapter that allows an ordinary function to be used as a
+// Checker.
+type CheckerFunc func() error
+
+func (f CheckerFunc) Check() error {
+	return f()
+}
+
+// Handler is an http.Handler that serves liveness ("/healthz") and readiness
+// ("/readyz") endpoints, suitable for use by orchestration platforms such as
+// Kubernetes.  Each endpoint runs all of its registered checks, and responds
+// with a 200 status code if they all pass, or a 503 status code otherwise.
+// The response body is a JSON object mapping each check's name to "ok" or to
+// its error message.
+//
+// Handler is safe for concurrent use.
+type Handler struct {
+	mu        sync.RWMutex
+	liveness  map[string]Checker
+	readiness map[string]Checker
+}
+
+// NewHandler creates a new Handler with no checks.  With no checks, both
+// endpoints always report success.
+func NewHandler() *Handler {
+	return &Handler{
+		liveness:  map[string]Checker{},
+		readiness: map[string]Checker{},
+	}
+}
+
+// AddLiveness registers a check that is run for the "/healthz" endpoint.
+// Liveness checks should only fail when the process needs to be restarted.
+func (h *Handler) AddLiveness(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness[name] = c
+}
+
+// AddReadiness registers a check that is run for the "/readyz" endpoint.
+// Readiness checks should fail when the process is temporarily unable to
+// accept work - e.g. because a Sink's database is unreachable, or because the
+// Scraper is being drained.  Liveness checks are also run for this endpoint.
+func (h *Handler) AddReadiness(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness[name] = c
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	checks := map[string]Checker{}
+	switch r.URL.Path {
+	case "/healthz":
+		for name, c := range h.liveness {
+			checks[name] = c
+		}
+	case "/readyz":
+		for name, c := range h.liveness {
+			checks[name] = c
+		}
+		for name, c := range h.readiness {
+			checks[name] = c
+		}
+	default:
+		h.mu.RUnlock()
+		http.NotFound(w, r)
+		return
+	}
+	h.mu.RUnlock()
+
+	status, results := runChecks(checks)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
+
+func runChecks(checks map[string]Checker) (int, map[string]string) {
+	names := []string{}
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	status := http.StatusOK
+	results := map[string]string{}
+	for _, name := range names {
+		if err := checks[name].Check(); err != nil {
+			results[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			results[name] = "ok"
+		}
+	}
+	return status, results
+}
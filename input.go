@@ -0,0 +1,200 @@
+package scrape
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// How many bytes at the start of the input are examined to decide how it
+// should be parsed.
+const sniffLength = 1024
+
+type inputKind int
+
+const (
+	inputDocument inputKind = iota
+	inputFragment
+	inputXML
+)
+
+// ExtractFromReader extracts the results from content that has already been
+// retrieved, rather than fetching a page.  The content is divided into blocks
+// and extracted in the same way as a page during a scrape; the given URL is
+// only used as the URL of the returned Page and passed to any RewriteFuncs.
+// The Paginator, Sink and Dedup settings of the ScrapeConfig are not used.
+//
+// Since content from APIs or message queues often isn't a full HTML document,
+// the input is parsed as follows:
+//
+//   - Input starting with an XML declaration ("<?xml") is parsed as XML, so
+//     that elements such as <link> that have special meaning in HTML are kept
+//     intact.  Element and attribute names are lowercased, and namespace
+//     prefixes are dropped.
+//   - Input that contains an <html>, <head> or <body> tag, or starts with a
+//     doctype, is parsed as a regular HTML document.
+//   - Anything else is parsed as an HTML fragment, without the fixups that
+//     the document parser applies (e.g. table rows without an enclosing table
+//     are kept).
+//
+// In all cases, the parsed content is placed inside a <body> element, so the
+// default DividePage function works as usual.
+func (s *Scraper) ExtractFromReader(url string, r io.Reader) (*Page, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.rewrite(url, string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *goquery.Document
+	switch detectInput(body) {
+	case inputXML:
+		doc, err = parseXML(body)
+	case inputFragment:
+		doc, err = parseFragment(body)
+	default:
+		doc, err = goquery.NewDocumentFromReader(strings.NewReader(body))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = s.finishDocument(doc); err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		URL:    url,
+		Blocks: []map[string]interface{}{},
+	}
+	fetchedAt := time.Now()
+	for i, block := range s.config.DividePage(doc.Selection) {
+		blockResults, err := s.extractBlock(block, ExtractContext{
+			URL:        url,
+			FetchedAt:  fetchedAt,
+			BlockIndex: i,
+		})
+		if err != nil {
+			return nil, err
+		}
+		page.Blocks = append(page.Blocks, blockResults)
+	}
+	return page, nil
+}
+
+// detectInput decides how the given content should be parsed.
+func detectInput(body string) inputKind {
+	head := strings.TrimLeft(strings.TrimPrefix(body, "\ufeff"), " \t\r\n")
+	if len(head) > sniffLength {
+		head = head[:sniffLength]
+	}
+	head = asciiLower(head)
+
+	switch {
+	case strings.HasPrefix(head, "<?xml"):
+		return inputXML
+	case strings.HasPrefix(head, "<!doctype"),
+		strings.Contains(head, "<html"),
+		strings.Contains(head, "<head"),
+		strings.Contains(head, "<body"):
+		return inputDocument
+	}
+	return inputFragment
+}
+
+// emptyDocument returns a new document containing only empty <head> and <body>
+// elements, along with the <body> element.
+func emptyDocument() (*goquery.Document, *html.Node, error) {
+	root, err := html.Parse(strings.NewReader(""))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := goquery.NewDocumentFromNode(root)
+	return doc, doc.Find("body").Get(0), nil
+}
+
+// parseFragment parses the given HTML fragment into the <body> of a new
+// document.
+func parseFragment(body string) (*goquery.Document, error) {
+	doc, parent, err := emptyDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parsing in the context of a <template> element allows any element to
+	// appear at the top level, including table rows and cells.
+	context := &html.Node{Type: html.ElementNode, Data: "template", DataAtom: atom.Template}
+	nodes, err := html.ParseFragment(strings.NewReader(body), context)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		parent.AppendChild(n)
+	}
+	return doc, nil
+}
+
+// parseXML parses the given XML into the <body> of a new document.
+func parseXML(body string) (*goquery.Document, error) {
+	doc, parent, err := emptyDocument()
+	if err != nil {
+		return nil, err
+	}
+	root := parent
+
+	dec := xml.NewDecoder(strings.NewReader(body))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	// The declared encoding is ignored, since the content has already been
+	// read as a string.
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &html.Node{
+				Type: html.ElementNode,
+				Data: strings.ToLower(tok.Name.Local),
+			}
+			for _, attr := range tok.Attr {
+				n.Attr = append(n.Attr, html.Attribute{
+					Key: strings.ToLower(attr.Name.Local),
+					Val: attr.Value,
+				})
+			}
+			parent.AppendChild(n)
+			parent = n
+
+		case xml.EndElement:
+			// Unbalanced end elements must not close the <body> itself.
+			if parent != root {
+				parent = parent.Parent
+			}
+
+		case xml.CharData:
+			parent.AppendChild(&html.Node{Type: html.TextNode, Data: string(tok)})
+
+		case xml.Comment:
+			parent.AppendChild(&html.Node{Type: html.CommentNode, Data: string(tok)})
+		}
+	}
+	return doc, nil
+}
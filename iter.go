@@ -0,0 +1,85 @@
+//go:build go1.23
+// +build go1.23
+
+package scrape
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// Blocks returns an iterator over the results from every block on all pages,
+// in the same order as AllBlocks.
+//
+// This method requires Go 1.23 or later.
+func (r *ScrapeResults) Blocks() iter.Seq[map[string]interface{}] {
+	return func(yield func(map[string]interface{}) bool) {
+		for _, page := range r.Results {
+			for _, block := range page {
+				if !yield(block) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Pages returns an iterator that scrapes the given URL lazily, with the
+// Scraper's default options: each page is only fetched once the previous one
+// has been consumed, and breaking out of the loop stops the scrape.  For
+// example:
+//
+//	for page, err := range scraper.Pages(ctx, url) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// If an error occurs, including the context being cancelled, then it is
+// yielded along with an empty Page, and the iteration ends.  Errors that occur
+// after the caller has stopped iterating (e.g. from the Sink) are logged to
+// the Logger instead.  The StopCondition, Sink, Dedup and Usage settings of
+// the ScrapeConfig apply as usual, but checkpointing is not supported.
+//
+// This method requires Go 1.23 or later.
+func (s *Scraper) Pages(ctx context.Context, url string) iter.Seq2[Page, error] {
+	return func(yield func(Page, error) bool) {
+		if len(url) == 0 {
+			yield(Page{}, errors.New("no URL provided"))
+			return
+		}
+		if err := s.begin(); err != nil {
+			yield(Page{}, err)
+			return
+		}
+		defer s.end()
+
+		// Whether the caller has stopped iterating, after which yield must
+		// not be called again.
+		var stopped bool
+
+		// Failures are collected in the results, but not reported.
+		start := &Checkpoint{NextURL: url, Results: &ScrapeResults{}}
+		err := s.prepareFetcher()
+		if err == nil {
+			_, err = s.scrape(ctx, start, s.opts, func(page *Page) bool {
+				stopped = !yield(*page, nil)
+				return !stopped
+			})
+		}
+
+		// Ensure the sink has handled everything we've sent, even on failure.
+		if serr := s.flushSink(err); serr != nil && err == nil {
+			err = serr
+		}
+		if err != nil {
+			if stopped {
+				s.logf("scraping %s: %s", url, err)
+			} else {
+				yield(Page{}, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+//go:build go1.23
+// +build go1.23
+
+package scrape_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterators(t *testing.T) {
+	fetcher := mapFetcher{
+		"a":   `<b>1</b><b>2</b>`,
+		"a-2": `<b>3</b>`,
+		"a-3": `<b>4</b>`,
+	}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    fetcher,
+		Paginator:  mapPaginator{"a": "a-2", "a-2": "a-3"},
+		DividePage: scrape.DividePageBySelector("b"),
+		Pieces: []scrape.Piece{
+			{Name: "n", Selector: ".", Extractor: extract.Text{}},
+		},
+	})
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+
+	got := []interface{}{}
+	for block := range results.Blocks() {
+		got = append(got, block["n"])
+		if len(got) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []interface{}{"1", "2", "3"}, got)
+
+	urls := []string{}
+	for page, err := range sc.Pages(context.Background(), "a") {
+		assert.NoError(t, err)
+		urls = append(urls, page.URL)
+		if page.URL == "a-2" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a", "a-2"}, urls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lastErr error
+	for page, err := range sc.Pages(ctx, "a") {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		assert.Equal(t, "a", page.URL)
+		cancel()
+	}
+	assert.Equal(t, context.Canceled, lastErr)
+}
+
+// failingSink is a Sink whose Flush always fails.
+type failingSink struct{}
+
+func (failingSink) Write(p *scrape.Page) error { return nil }
+func (failingSink) Flush() error               { return errors.New("flush failed") }
+
+func TestPagesAfterBreak(t *testing.T) {
+	st := store.NewMemory()
+	config := &scrape.ScrapeConfig{
+		Fetcher:   mapFetcher{"a": `<b>1</b>`, "a-2": `<b>2</b>`},
+		Paginator: mapPaginator{"a": "a-2"},
+		Pieces: []scrape.Piece{
+			{Name: "n", Selector: "b", Extractor: extract.Text{}},
+		},
+		Sink:  failingSink{},
+		Usage: &scrape.UsageConfig{Store: st, Key: "pages"},
+	}
+	sc := mustNew(config)
+
+	// An error after the loop has ended isn't yielded.
+	assert.NotPanics(t, func() {
+		for range sc.Pages(context.Background(), "a") {
+			break
+		}
+	})
+
+	// An error while iterating is.
+	var lastErr error
+	for _, err := range sc.Pages(context.Background(), "a") {
+		lastErr = err
+	}
+	assert.EqualError(t, lastErr, "flush failed")
+
+	// Usage is recorded as for Scrape.
+	usage, err := scrape.LoadUsage(st, "pages")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, usage["a"].Requests+usage["a-2"].Requests)
+}
@@ -0,0 +1,145 @@
+package scrape
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+// PieceLimits bounds the size of a Piece's values, so that a selector that
+// accidentally matches far more than intended (e.g. the whole body of the
+// page) doesn't produce enormous results.
+type PieceLimits struct {
+	// MaxLength is the maximum length, in characters, of a string value, or of
+	// each string in a list or map value.  If this is 0, then the length of
+	// strings isn't limited.
+	MaxLength int
+
+	// MaxItems is the maximum number of items in a list value, or of entries
+	// in a map value.  If this is 0, then the number isn't limited.
+	MaxItems int
+
+	// If Truncate is true, then values that are over a limit are cut down to
+	// it - lists keep their first items, and maps the entries with the
+	// lowest keys.  Otherwise, such values fail with a *LimitError, which
+	// aborts the scrape.
+	Truncate bool
+}
+
+// LimitError is returned when a Piece's value is over one of its
+// PieceLimits.
+type LimitError struct {
+	// The name of the Piece.
+	Piece string
+
+	// What was over the limit - "characters" or "items" - and by how much.
+	What  string
+	Size  int
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("piece %q has %d %s, over the limit of %d", e.Piece, e.Size, e.What, e.Limit)
+}
+
+func (l *PieceLimits) validate(index int) error {
+	if l.MaxLength < 0 || l.MaxItems < 0 {
+		return fmt.Errorf("piece %d has negative limits", index)
+	}
+	return nil
+}
+
+// apply checks the given value of the named Piece against the limits, and
+// returns it, truncated if necessary.  The value itself is never modified.
+func (l *PieceLimits) apply(piece string, val interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := l.limitString(piece, rv.String())
+		if err != nil {
+			return nil, err
+		}
+		if s == rv.String() {
+			return val, nil
+		}
+		return reflect.ValueOf(s).Convert(rv.Type()).Interface(), nil
+
+	case reflect.Slice:
+		n := rv.Len()
+		if l.MaxItems > 0 && n > l.MaxItems {
+			if !l.Truncate {
+				return nil, &LimitError{piece, "items", n, l.MaxItems}
+			}
+			n = l.MaxItems
+		}
+		rv = rv.Slice(0, n)
+		if l.MaxLength == 0 || rv.Type().Elem().Kind() != reflect.String {
+			return rv.Interface(), nil
+		}
+
+		ret := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), n, n)
+		for i := 0; i < n; i++ {
+			s, err := l.limitString(piece, rv.Index(i).String())
+			if err != nil {
+				return nil, err
+			}
+			ret.Index(i).SetString(s)
+		}
+		return ret.Interface(), nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return val, nil
+		}
+		n := rv.Len()
+		if l.MaxItems > 0 && n > l.MaxItems {
+			if !l.Truncate {
+				return nil, &LimitError{piece, "items", n, l.MaxItems}
+			}
+			n = l.MaxItems
+		}
+		limitValues := l.MaxLength > 0 && rv.Type().Elem().Kind() == reflect.String
+		if n == rv.Len() && !limitValues {
+			return val, nil
+		}
+
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		ret := reflect.MakeMapWithSize(rv.Type(), n)
+		for _, key := range keys[:n] {
+			v := rv.MapIndex(key)
+			if limitValues {
+				s, err := l.limitString(piece, v.String())
+				if err != nil {
+					return nil, err
+				}
+				v = reflect.ValueOf(s).Convert(v.Type())
+			}
+			ret.SetMapIndex(key, v)
+		}
+		return ret.Interface(), nil
+	}
+	return val, nil
+}
+
+func (l *PieceLimits) limitString(piece, s string) (string, error) {
+	if l.MaxLength == 0 || len(s) <= l.MaxLength {
+		return s, nil
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= l.MaxLength {
+		return s, nil
+	}
+	if !l.Truncate {
+		return "", &LimitError{piece, "characters", n, l.MaxLength}
+	}
+
+	// Find the byte offset of the first character that's over the limit.
+	i := 0
+	for count := 0; count < l.MaxLength; count++ {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return s[:i], nil
+}
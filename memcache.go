@@ -0,0 +1,220 @@
+package scrape
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The default MemoryCacheFetcher.MaxBytes.
+const defaultMemoryCacheBytes = 64 << 20
+
+// MemoryCacheFetcher is a Fetcher that wraps another Fetcher, and keeps the
+// pages that it fetches in memory, so that a page that is fetched several
+// times - e.g. a detail page that is linked from several list pages - is
+// only downloaded once.  The least recently used pages are removed once the
+// cache is full.  To keep pages between scrapes, use CachingFetcher.
+//
+// Only successful (200 OK) responses to GET requests are cached, as for
+// CachingFetcher.
+//
+// A MemoryCacheFetcher is safe to use concurrently if the underlying Fetcher
+// is.
+type MemoryCacheFetcher struct {
+	// The Fetcher to make requests with.
+	Fetcher Fetcher
+
+	// MaxBytes is the maximum total size of the bodies of the cached pages.
+	// If this is 0, then 64 MiB is used.  Pages larger than this are never
+	// cached.
+	MaxBytes int64
+
+	// TTL is how long pages are cached for.  If this is 0, then they are
+	// cached until they are removed to make room for others.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   MemoryCacheStats
+
+	// Used to get the current time, so that tests can override it.
+	now func() time.Time
+}
+
+// MemoryCacheStats describes the use of a MemoryCacheFetcher.
+type MemoryCacheStats struct {
+	// The number of GET requests that were served from the cache, and that
+	// weren't.
+	Hits   int
+	Misses int
+
+	// The number of pages that were removed to make room for others, or
+	// because they expired.
+	Evictions int
+
+	// The number of pages in the cache, and the total size of their bodies.
+	Entries int
+	Bytes   int64
+}
+
+type memoryCacheEntry struct {
+	url      string
+	response *Response
+	body     []byte
+	stored   time.Time
+}
+
+func (f *MemoryCacheFetcher) Prepare() error {
+	if f.Fetcher == nil {
+		return errors.New("no fetcher to cache")
+	}
+	if f.MaxBytes < 0 || f.TTL < 0 {
+		return errors.New("memory cache limits must not be negative")
+	}
+	return f.Fetcher.Prepare()
+}
+
+func (f *MemoryCacheFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *MemoryCacheFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	if method != "GET" {
+		return fetchFunc(f.Fetcher)(ctx, method, url)
+	}
+	if entry := f.get(url); entry != nil {
+		return (&cacheEntry{Response: entry.response}).body(entry.body), nil
+	}
+
+	rc, err := fetchFunc(f.Fetcher)(ctx, method, url)
+	if err != nil {
+		return nil, err
+	}
+	resp := &Response{StatusCode: http.StatusOK, URL: url}
+	if rm, ok := rc.(ResponseMetadata); ok {
+		resp = rm.Response()
+	}
+	if resp.StatusCode != http.StatusOK ||
+		strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return rc, nil
+	}
+
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	f.put(&memoryCacheEntry{url: url, response: resp, body: data})
+	return (&cacheEntry{Response: resp}).body(data), nil
+}
+
+func (f *MemoryCacheFetcher) CookieJar() http.CookieJar {
+	if jf, ok := f.Fetcher.(CookieJarFetcher); ok {
+		return jf.CookieJar()
+	}
+	return nil
+}
+
+func (f *MemoryCacheFetcher) Close() {
+	f.Fetcher.Close()
+}
+
+// SetScripts passes the given scripts to the underlying Fetcher, if it is a
+// ScriptEvaluator.
+func (f *MemoryCacheFetcher) SetScripts(scripts []string) {
+	setScripts(f.Fetcher, scripts)
+}
+
+// Check calls the underlying Fetcher's Check method, if it has one.
+func (f *MemoryCacheFetcher) Check() error {
+	return checkFetcher(f.Fetcher)
+}
+
+// Stats returns the current statistics of the cache.
+func (f *MemoryCacheFetcher) Stats() MemoryCacheStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+func (f *MemoryCacheFetcher) timeNow() time.Time {
+	if f.now != nil {
+		return f.now()
+	}
+	return time.Now()
+}
+
+// get returns the cached page for the given URL, or nil if there isn't one.
+func (f *MemoryCacheFetcher) get(url string) *memoryCacheEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	elem, found := f.entries[url]
+	if found {
+		entry := elem.Value.(*memoryCacheEntry)
+		if f.TTL == 0 || f.timeNow().Sub(entry.stored) < f.TTL {
+			f.lru.MoveToFront(elem)
+			f.stats.Hits++
+			return entry
+		}
+		f.remove(elem)
+		f.stats.Evictions++
+	}
+	f.stats.Misses++
+	return nil
+}
+
+// put adds the given page to the cache, removing the least recently used
+// pages to make room for it.
+func (f *MemoryCacheFetcher) put(entry *memoryCacheEntry) {
+	maxBytes := f.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = defaultMemoryCacheBytes
+	}
+	size := int64(len(entry.body))
+	if size > maxBytes {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.entries == nil {
+		f.entries = map[string]*list.Element{}
+		f.lru = list.New()
+	}
+	if elem, found := f.entries[entry.url]; found {
+		// Fetched concurrently; replace the old copy.
+		f.remove(elem)
+	}
+	for f.stats.Bytes+size > maxBytes {
+		f.remove(f.lru.Back())
+		f.stats.Evictions++
+	}
+
+	entry.stored = f.timeNow()
+	f.entries[entry.url] = f.lru.PushFront(entry)
+	f.stats.Entries++
+	f.stats.Bytes += size
+}
+
+// remove removes the given element from the cache.  Must be called with the
+// lock held.
+func (f *MemoryCacheFetcher) remove(elem *list.Element) {
+	entry := f.lru.Remove(elem).(*memoryCacheEntry)
+	delete(f.entries, entry.url)
+	f.stats.Entries--
+	f.stats.Bytes -= int64(len(entry.body))
+}
+
+// Static type assertion
+var _ ContextFetcher = &MemoryCacheFetcher{}
+var _ CookieJarFetcher = &MemoryCacheFetcher{}
+var _ ScriptEvaluator = &MemoryCacheFetcher{}
@@ -0,0 +1,83 @@
+package scrape
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingFetcher returns each URL as its body, and counts the requests for
+// each.
+type countingFetcher map[string]int
+
+func (f countingFetcher) Prepare() error { return nil }
+func (f countingFetcher) Close()         {}
+
+func (f countingFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	f[url]++
+	if url == "error" {
+		return nil, fmt.Errorf("failed")
+	}
+	return ioutil.NopCloser(strings.NewReader(url)), nil
+}
+
+func TestMemoryCacheFetcher(t *testing.T) {
+	inner := countingFetcher{}
+	now := time.Unix(1000, 0)
+	fetcher := &MemoryCacheFetcher{
+		Fetcher:  inner,
+		MaxBytes: 10,
+		TTL:      time.Minute,
+		now:      func() time.Time { return now },
+	}
+	assert.NoError(t, fetcher.Prepare())
+
+	get := func(url string) {
+		body, err := fetcher.Fetch("GET", url)
+		if assert.NoError(t, err) {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			assert.Equal(t, url, string(data))
+		}
+	}
+
+	get("aaaa")
+	get("bbbb")
+	get("aaaa")
+	assert.Equal(t, countingFetcher{"aaaa": 1, "bbbb": 1}, inner)
+	assert.Equal(t, MemoryCacheStats{Hits: 1, Misses: 2, Entries: 2, Bytes: 8}, fetcher.Stats())
+
+	// The least recently used page is removed to make room.
+	get("cccc")
+	get("aaaa")
+	get("bbbb")
+	assert.Equal(t, countingFetcher{"aaaa": 1, "bbbb": 2, "cccc": 1}, inner)
+	assert.Equal(t, 2, fetcher.Stats().Evictions)
+
+	// Pages expire.
+	now = now.Add(time.Minute)
+	get("bbbb")
+	assert.Equal(t, 3, inner["bbbb"])
+
+	// Errors, large pages and other methods aren't cached.
+	for i := 0; i < 2; i++ {
+		_, err := fetcher.Fetch("GET", "error")
+		assert.Error(t, err)
+		get("much too large")
+		body, err := fetcher.Fetch("POST", "aaaa")
+		assert.NoError(t, err)
+		body.Close()
+	}
+	assert.Equal(t, 2, inner["error"])
+	assert.Equal(t, 2, inner["much too large"])
+	assert.Equal(t, 3, inner["aaaa"])
+
+	stats := fetcher.Stats()
+	assert.True(t, stats.Bytes <= 10, stats.Bytes)
+	assert.Equal(t, stats.Entries, len(fetcher.entries))
+}
@@ -0,0 +1,158 @@
+package scrape
+
+import (
+	"fmt"
+	"math"
+)
+
+// MergeStrategy controls how MergeResults combines blocks with the same key.
+type MergeStrategy int
+
+const (
+	// MergeUnion keeps every Piece from both blocks.  If both blocks contain
+	// the same Piece, then the value from the earlier block is kept.
+	MergeUnion MergeStrategy = iota
+
+	// MergePreferNewest replaces the earlier block with the later one.
+	MergePreferNewest
+
+	// MergePreferNonEmpty keeps every Piece from both blocks.  If both blocks
+	// contain the same Piece, then the value from the later block is used,
+	// unless it is empty (nil, or an empty string, list or map).
+	MergePreferNonEmpty
+)
+
+// MergeResults combines the results of several scrapes - e.g. from workers
+// that each scraped part of a site, or from a re-run that filled in gaps.  The
+// results in b are treated as newer than those in a.
+//
+// Pages with the same URL are combined into one, and blocks with the same
+// value for the key Piece are combined into one using the given strategy,
+// wherever they appear.  The combined block stays in the position of the
+// first one.  Blocks without a value for the key Piece are never combined.
+// If keyPiece is empty, then only pages are combined.
+//
+// The Failures, Usage, URLPatterns and Duration of both results are added
+// together, and their Responses are combined.  Neither input is modified.
+func MergeResults(a, b *ScrapeResults, keyPiece string, strategy MergeStrategy) *ScrapeResults {
+	ret := &ScrapeResults{
+		URLs:    []string{},
+		Results: [][]map[string]interface{}{},
+	}
+	if a.StartURLs != nil || b.StartURLs != nil {
+		ret.StartURLs = []string{}
+	}
+
+	type location struct {
+		page, block int
+	}
+	pages := map[string]int{}
+	blocks := map[string]location{}
+
+	add := func(res *ScrapeResults) {
+		for i, url := range res.URLs {
+			p, found := pages[url]
+			if !found {
+				p = len(ret.URLs)
+				pages[url] = p
+				ret.URLs = append(ret.URLs, url)
+				ret.Results = append(ret.Results, []map[string]interface{}{})
+				if ret.StartURLs != nil {
+					var start string
+					if i < len(res.StartURLs) {
+						start = res.StartURLs[i]
+					}
+					ret.StartURLs = append(ret.StartURLs, start)
+				}
+			}
+
+			if i >= len(res.Results) {
+				continue
+			}
+			for _, block := range res.Results[i] {
+				key := ""
+				if val, found := block[keyPiece]; found && keyPiece != "" {
+					key = fmt.Sprint(val)
+				}
+
+				if loc, found := blocks[key]; found && key != "" {
+					existing := ret.Results[loc.page][loc.block]
+					ret.Results[loc.page][loc.block] = mergeBlocks(existing, block, strategy)
+					continue
+				}
+
+				if key != "" {
+					blocks[key] = location{p, len(ret.Results[p])}
+				}
+				ret.Results[p] = append(ret.Results[p], copyBlock(block))
+			}
+		}
+
+		for name, failures := range res.Failures {
+			mergeFailures(ret, name, failures, math.MaxInt32)
+		}
+		if res.Usage != nil {
+			if ret.Usage == nil {
+				ret.Usage = map[string]*HostUsage{}
+			}
+			mergeUsage(ret.Usage, res.Usage)
+		}
+		mergeResponses(ret, res.Responses)
+		ret.Duration += res.Duration
+		if res.URLPatterns != nil {
+			ret.URLPatterns = mergeClusters(ret.URLPatterns, res.URLPatterns)
+		}
+	}
+
+	add(a)
+	add(b)
+	return ret
+}
+
+// mergeResponses adds the given responses to the results, replacing any for
+// the same URLs.
+func mergeResponses(res *ScrapeResults, responses map[string]*Response) {
+	if responses == nil {
+		return
+	}
+	if res.Responses == nil {
+		res.Responses = map[string]*Response{}
+	}
+	for url, resp := range responses {
+		res.Responses[url] = resp
+	}
+}
+
+// mergeBlocks combines an earlier and a later block with the same key.
+func mergeBlocks(earlier, later map[string]interface{}, strategy MergeStrategy) map[string]interface{} {
+	switch strategy {
+	case MergePreferNewest:
+		return copyBlock(later)
+
+	case MergePreferNonEmpty:
+		ret := copyBlock(earlier)
+		for name, val := range later {
+			if _, found := ret[name]; !found || !isEmpty(val) {
+				ret[name] = val
+			}
+		}
+		return ret
+
+	default:
+		ret := copyBlock(earlier)
+		for name, val := range later {
+			if _, found := ret[name]; !found {
+				ret[name] = val
+			}
+		}
+		return ret
+	}
+}
+
+func copyBlock(block map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(block))
+	for name, val := range block {
+		ret[name] = val
+	}
+	return ret
+}
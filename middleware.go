@@ -0,0 +1,273 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// FetcherMiddleware wraps a Fetcher in another that adds some behaviour -
+// e.g. retrying, throttling or logging requests - so that such behaviour can
+// be combined with any Fetcher, rather than being built into each one.  Use
+// ChainFetcher or ScrapeConfig.Middleware to apply them.
+type FetcherMiddleware func(Fetcher) Fetcher
+
+// ChainFetcher wraps the given Fetcher in each of the given middlewares.  The
+// first middleware is the outermost, so it sees each request first - e.g.
+//
+//	fetcher := scrape.ChainFetcher(hf,
+//		scrape.LoggingMiddleware(logger),
+//		scrape.RetryMiddleware(&scrape.RetryPolicy{}),
+//		scrape.ThrottleMiddleware(time.Second),
+//	)
+//
+// logs each request once, however many times it is retried, and throttles
+// every attempt.
+func ChainFetcher(f Fetcher, middleware ...FetcherMiddleware) Fetcher {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		f = middleware[i](f)
+	}
+	return f
+}
+
+// FetchFunc is the signature of ContextFetcher.FetchContext.
+type FetchFunc func(ctx context.Context, method, url string) (io.ReadCloser, error)
+
+// fetchFunc returns the function that fetches URLs with the given Fetcher.
+func fetchFunc(f Fetcher) FetchFunc {
+	if cf, ok := f.(ContextFetcher); ok {
+		return cf.FetchContext
+	}
+	return func(ctx context.Context, method, url string) (io.ReadCloser, error) {
+		return f.Fetch(method, url)
+	}
+}
+
+// setScripts passes the given scripts to f, if it is a ScriptEvaluator.
+// Fetchers that wrap another use this to implement ScriptEvaluator, so that
+// wrapping a browser-based Fetcher doesn't stop it from evaluating scripts.
+func setScripts(f Fetcher, scripts []string) {
+	if se, ok := f.(ScriptEvaluator); ok {
+		se.SetScripts(scripts)
+	}
+}
+
+// checkFetcher returns the result of f's "Check() error" method, if it has
+// one (see Scraper.Check).
+func checkFetcher(f Fetcher) error {
+	if c, ok := f.(interface {
+		Check() error
+	}); ok {
+		return c.Check()
+	}
+	return nil
+}
+
+// MiddlewareFunc returns a FetcherMiddleware that fetches URLs with the
+// function returned by wrap, which is given the function that fetches them
+// with the wrapped Fetcher.  Prepare, Close, CookieJar, SetScripts and Check
+// are passed on to the wrapped Fetcher.  For example, this logs each URL that is fetched:
+//
+//	scrape.MiddlewareFunc(func(next scrape.FetchFunc) scrape.FetchFunc {
+//		return func(ctx context.Context, method, url string) (io.ReadCloser, error) {
+//			log.Printf("fetching %s", url)
+//			return next(ctx, method, url)
+//		}
+//	})
+func MiddlewareFunc(wrap func(next FetchFunc) FetchFunc) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		return &middlewareFetcher{next: f, fetch: wrap(fetchFunc(f))}
+	}
+}
+
+type middlewareFetcher struct {
+	next  Fetcher
+	fetch FetchFunc
+
+	// Called before the wrapped Fetcher is prepared, if given.
+	prepare func() error
+}
+
+func (f *middlewareFetcher) Prepare() error {
+	if f.prepare != nil {
+		if err := f.prepare(); err != nil {
+			return err
+		}
+	}
+	return f.next.Prepare()
+}
+
+func (f *middlewareFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.fetch(context.Background(), method, url)
+}
+
+func (f *middlewareFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	return f.fetch(ctx, method, url)
+}
+
+// CookieJar returns the cookie jar of the wrapped Fetcher, or nil if it isn't
+// a CookieJarFetcher.
+func (f *middlewareFetcher) CookieJar() http.CookieJar {
+	if jf, ok := f.next.(CookieJarFetcher); ok {
+		return jf.CookieJar()
+	}
+	return nil
+}
+
+func (f *middlewareFetcher) SetScripts(scripts []string) {
+	setScripts(f.next, scripts)
+}
+
+func (f *middlewareFetcher) Check() error {
+	return checkFetcher(f.next)
+}
+
+func (f *middlewareFetcher) httpClientFetcher() *HttpClientFetcher {
+	return httpClientFetcher(f.next)
+}
+
+func (f *middlewareFetcher) Close() {
+	f.next.Close()
+}
+
+// RetryMiddleware returns a FetcherMiddleware that retries requests according
+// to the given policy.  Requests that fail with an error are retried, and so
+// are those whose status code the policy retries, if the wrapped Fetcher
+// reports it with ResponseMetadata (as HttpClientFetcher does).  Unlike
+// HttpClientFetcher.Retry, which uses the same policy, this retries the
+// whole fetch, so it works with any Fetcher.
+func RetryMiddleware(p *RetryPolicy) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		next := fetchFunc(f)
+		return &middlewareFetcher{
+			next: f,
+			fetch: func(ctx context.Context, method, url string) (io.ReadCloser, error) {
+				return p.fetch(ctx, next, method, url)
+			},
+			prepare: p.validate,
+		}
+	}
+}
+
+// ThrottleMiddleware returns a FetcherMiddleware that enforces the given
+// minimum delay between requests to the same host, using a ThrottledFetcher.
+func ThrottleMiddleware(delay time.Duration) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		return &ThrottledFetcher{Fetcher: f, Delay: delay}
+	}
+}
+
+// CacheMiddleware returns a FetcherMiddleware that stores pages in the given
+// directory, and uses them until they are older than the given TTL, using a
+// CachingFetcher.
+func CacheMiddleware(dir string, ttl time.Duration) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		return &CachingFetcher{Fetcher: f, Dir: dir, TTL: ttl}
+	}
+}
+
+// RecordMiddleware returns a FetcherMiddleware that writes every response to
+// the given directory, using a RecordingFetcher.
+func RecordMiddleware(dir string) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		return &RecordingFetcher{Fetcher: f, Dir: dir}
+	}
+}
+
+// MemoryCacheMiddleware returns a FetcherMiddleware that keeps up to
+// maxBytes of pages in memory for the given TTL, using a MemoryCacheFetcher.
+func MemoryCacheMiddleware(maxBytes int64, ttl time.Duration) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		return &MemoryCacheFetcher{Fetcher: f, MaxBytes: maxBytes, TTL: ttl}
+	}
+}
+
+// LoggingMiddleware returns a FetcherMiddleware that logs each request to the
+// given Logger, along with its status code (if the wrapped Fetcher reports it
+// with ResponseMetadata), or its error, and how long it took.
+func LoggingMiddleware(l *log.Logger) FetcherMiddleware {
+	return MiddlewareFunc(func(next FetchFunc) FetchFunc {
+		return func(ctx context.Context, method, url string) (io.ReadCloser, error) {
+			start := time.Now()
+			body, err := next(ctx, method, url)
+			took := time.Since(start).Round(time.Millisecond)
+
+			switch b := body.(type) {
+			case nil:
+				l.Printf("%s %s failed after %s: %s", method, url, took, err)
+			case ResponseMetadata:
+				l.Printf("%s %s: %d in %s", method, url, b.Response().StatusCode, took)
+			default:
+				l.Printf("%s %s: done in %s", method, url, took)
+			}
+			return body, err
+		}
+	})
+}
+
+// clientFetcher is implemented by Fetchers that make their requests with an
+// HttpClientFetcher, so that middleware such as ProxyMiddleware can configure
+// it.
+type clientFetcher interface {
+	httpClientFetcher() *HttpClientFetcher
+}
+
+// httpClientFetcher returns the HttpClientFetcher that f makes its requests
+// with, or nil if there isn't one.
+func httpClientFetcher(f Fetcher) *HttpClientFetcher {
+	if cf, ok := f.(clientFetcher); ok {
+		return cf.httpClientFetcher()
+	}
+	return nil
+}
+
+// ProxyMiddleware returns a FetcherMiddleware that sends requests through the
+// given proxies in turn, using a RotatingProxyFetcher.  It configures the
+// transport of the HttpClientFetcher that the wrapped Fetcher makes its
+// requests with, so it can wrap an HttpClientFetcher, or one that is already
+// wrapped by HeaderMiddleware or by middleware made with MiddlewareFunc (such
+// as LoggingMiddleware and RetryMiddleware).  Otherwise, the returned Fetcher
+// fails to prepare.
+func ProxyMiddleware(proxies []string, config ProxyPoolConfig) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		hf := httpClientFetcher(f)
+		if hf == nil {
+			return errorFetcher{f, fmt.Errorf("proxies can only be used with an HttpClientFetcher, not %T", f)}
+		}
+		return &RotatingProxyFetcher{Fetcher: hf, Proxies: proxies, Config: config, wrapped: f}
+	}
+}
+
+// HeaderMiddleware returns a FetcherMiddleware that rotates the User-Agent
+// of each request and sets the given headers, using a RotatingHeaderFetcher;
+// see that type for the defaults.  Like ProxyMiddleware, it configures the
+// HttpClientFetcher that the wrapped Fetcher makes its requests with, so the
+// two can be used together.
+func HeaderMiddleware(userAgents []string, headers http.Header) FetcherMiddleware {
+	return func(f Fetcher) Fetcher {
+		hf := httpClientFetcher(f)
+		if hf == nil {
+			return errorFetcher{f, fmt.Errorf("headers can only be set by an HttpClientFetcher, not %T", f)}
+		}
+		return &RotatingHeaderFetcher{Fetcher: hf, UserAgents: userAgents, Headers: headers, wrapped: f}
+	}
+}
+
+// errorFetcher is a Fetcher that fails to prepare, for middleware that
+// can't wrap the Fetcher that it was given.
+type errorFetcher struct {
+	Fetcher
+	err error
+}
+
+func (f errorFetcher) Prepare() error {
+	return f.err
+}
+
+// Static type assertion
+var _ ContextFetcher = &middlewareFetcher{}
+var _ CookieJarFetcher = &middlewareFetcher{}
+var _ ScriptEvaluator = &middlewareFetcher{}
@@ -0,0 +1,169 @@
+package scrape_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainFetcher(t *testing.T) {
+	var calls []string
+	trace := func(name string) scrape.FetcherMiddleware {
+		return scrape.MiddlewareFunc(func(next scrape.FetchFunc) scrape.FetchFunc {
+			return func(ctx context.Context, method, url string) (io.ReadCloser, error) {
+				calls = append(calls, name+" "+url)
+				return next(ctx, method, url)
+			}
+		})
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": "<b>one</b>"},
+		Middleware: []scrape.FetcherMiddleware{trace("outer"), trace("inner")},
+		Pieces:     []scrape.Piece{{Name: "b", Selector: "b", Extractor: extract.Text{}}},
+	})
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "one", results.First()["b"])
+	assert.Equal(t, []string{"outer a", "inner a"}, calls)
+
+	// Errors from the wrapped Fetcher are passed through.
+	_, err = scrape.ChainFetcher(mapFetcher{}, trace("only")).Fetch("GET", "b")
+	assert.EqualError(t, err, "unknown URL: b")
+}
+
+// scriptingFetcher is a mapFetcher that records the scripts it's given, and
+// fails its health check with the given error.
+type scriptingFetcher struct {
+	mapFetcher
+	scripts []string
+	err     error
+}
+
+func (f *scriptingFetcher) SetScripts(scripts []string) { f.scripts = scripts }
+func (f *scriptingFetcher) Check() error                { return f.err }
+
+func TestMiddlewareForwarding(t *testing.T) {
+	inner := &scriptingFetcher{mapFetcher: mapFetcher{
+		"a": `<b>one</b><script type="application/json" data-goscrape-eval="document.title">{"value": "One"}</script>`,
+	}}
+	fetcher := scrape.ChainFetcher(inner,
+		scrape.LoggingMiddleware(log.New(ioutil.Discard, "", 0)),
+		scrape.ThrottleMiddleware(time.Millisecond),
+		scrape.CacheMiddleware(t.TempDir(), time.Minute),
+		scrape.MemoryCacheMiddleware(0, 0),
+		scrape.RecordMiddleware(t.TempDir()),
+	)
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "b", Selector: "b", Extractor: extract.Text{}},
+			{Name: "title", Selector: ".", Extractor: extract.BrowserEval{Script: "document.title"}},
+		},
+	})
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "One", results.First()["title"])
+	assert.Equal(t, []string{"document.title"}, inner.scripts)
+
+	assert.NoError(t, sc.Check())
+	inner.err = fmt.Errorf("unhealthy")
+	assert.EqualError(t, sc.Check(), "unhealthy")
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	hf, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	var logs bytes.Buffer
+	fetcher := scrape.ChainFetcher(hf,
+		scrape.LoggingMiddleware(log.New(&logs, "", 0)),
+		scrape.RetryMiddleware(&scrape.RetryPolicy{InitialDelay: time.Millisecond}),
+	)
+	assert.NoError(t, fetcher.Prepare())
+
+	body, err := fetcher.Fetch("GET", ts.URL)
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadAll(body)
+		body.Close()
+		assert.Equal(t, "ok", string(data))
+	}
+	assert.Equal(t, 3, attempts)
+
+	// The request is only logged once.
+	assert.True(t, strings.HasPrefix(logs.String(), "GET "+ts.URL+": 200 in "), logs.String())
+	assert.Equal(t, 1, strings.Count(logs.String(), "\n"))
+
+	// Errors are logged, and the policy is validated.
+	logs.Reset()
+	fetcher = scrape.ChainFetcher(mapFetcher{}, scrape.LoggingMiddleware(log.New(&logs, "", 0)))
+	_, err = fetcher.Fetch("GET", "b")
+	assert.Error(t, err)
+	assert.True(t, strings.HasSuffix(logs.String(), ": unknown URL: b\n"), logs.String())
+
+	fetcher = scrape.ChainFetcher(mapFetcher{}, scrape.RetryMiddleware(&scrape.RetryPolicy{MaxAttempts: -1}))
+	assert.EqualError(t, fetcher.Prepare(), "retry attempts must not be negative")
+}
+
+func TestProxyMiddleware(t *testing.T) {
+	fetcher := scrape.ChainFetcher(mapFetcher{}, scrape.ProxyMiddleware([]string{"http://proxy:8080"}, scrape.ProxyPoolConfig{}))
+	assert.EqualError(t, fetcher.Prepare(), "proxies can only be used with an HttpClientFetcher, not scrape_test.mapFetcher")
+
+	var requests []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String()+" "+r.Header.Get("User-Agent"))
+		fmt.Fprint(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	// Proxies and headers can be combined, in either order, with other
+	// middleware in between.
+	for _, order := range [][]string{{"proxy", "header"}, {"header", "proxy"}} {
+		requests = nil
+		middleware := []scrape.FetcherMiddleware{}
+		for _, name := range order {
+			if name == "proxy" {
+				middleware = append(middleware, scrape.ProxyMiddleware([]string{proxy.URL}, scrape.ProxyPoolConfig{}))
+			} else {
+				middleware = append(middleware, scrape.HeaderMiddleware([]string{"test-agent"}, http.Header{}))
+			}
+			middleware = append(middleware, scrape.LoggingMiddleware(log.New(ioutil.Discard, "", 0)))
+		}
+
+		hf, err := scrape.NewHttpClientFetcher()
+		assert.NoError(t, err)
+		fetcher := scrape.ChainFetcher(hf, middleware...)
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, fetcher.Prepare())
+		}
+		body, err := fetcher.Fetch("GET", "http://example.invalid/page")
+		if assert.NoError(t, err, order) {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			assert.Equal(t, "proxied", string(data))
+		}
+		assert.Equal(t, []string{"http://example.invalid/page test-agent"}, requests, order)
+	}
+}
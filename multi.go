@@ -0,0 +1,131 @@
+package scrape
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ScrapeAll scrapes each of the given start URLs with default options.  See
+// 'ScrapeAllWithOpts' for more information.
+func (s *Scraper) ScrapeAll(urls []string) (*ScrapeResults, error) {
+	return s.ScrapeAllWithOpts(urls, s.opts)
+}
+
+// ScrapeAllWithOpts runs a separate scrape for each of the given start URLs,
+// and combines the results in the order that the URLs were given.  The
+// StartURLs field of the returned results records which start URL each page
+// came from.  Note that the MaxPages option applies to each scrape
+// separately.
+//
+// If opts.Parallelism is greater than 1, then that many scrapes are run at the
+// same time.  Please read the documentation of ScrapeWithOpts about running
+// multiple scrapes in parallel before enabling this.
+//
+// If opts.ShardCount is set, then only the start URLs in this worker's shard
+// are scraped.
+//
+// If any scrape fails, then no further scrapes are started, and the first
+// error (in the order of the given URLs) is returned along with the combined
+// results of the scrapes that returned any - including partial results, such
+// as those returned with ErrDrained.
+//
+// The Fetcher is prepared once, before any of the scrapes are started, so
+// that parallel scrapes don't prepare it at the same time.
+//
+// Checkpoints can only be used to scrape a single start URL (after sharding),
+// since a Checkpoint only records the progress of one scrape, and parallel
+// scrapes would save their progress under the same key.
+func (s *Scraper) ScrapeAllWithOpts(urls []string, opts ScrapeOptions) (*ScrapeResults, error) {
+	if err := validateShard(opts.ShardIndex, opts.ShardCount); err != nil {
+		return nil, err
+	}
+	if s.config.Checkpoint != nil && opts.Parallelism > 1 {
+		return nil, errors.New("checkpoints can't be used with parallel scrapes")
+	}
+	if opts.ShardCount > 0 {
+		urls = ShardURLs(urls, opts.ShardIndex, opts.ShardCount)
+	}
+	if s.config.Checkpoint != nil && len(urls) > 1 {
+		return nil, errors.New("checkpoints can't be used with more than one start URL")
+	}
+
+	started := time.Now()
+	if err := s.prepareFetcher(); err != nil {
+		return nil, err
+	}
+
+	all := make([]*ScrapeResults, len(urls))
+	errs := make([]error, len(urls))
+
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	sem := make(chan struct{}, parallelism)
+	for i, url := range urls {
+		sem <- struct{}{}
+
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			all[i], errs[i] = s.scrapeURL(url, opts, false)
+			if errs[i] != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i, url)
+	}
+	wg.Wait()
+
+	ret := &ScrapeResults{
+		URLs:      []string{},
+		Results:   [][]map[string]interface{}{},
+		StartURLs: []string{},
+	}
+	var firstErr error
+	for i, res := range all {
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+		if res == nil {
+			continue
+		}
+
+		ret.URLs = append(ret.URLs, res.URLs...)
+		ret.Results = append(ret.Results, res.Results...)
+		for range res.URLs {
+			ret.StartURLs = append(ret.StartURLs, urls[i])
+		}
+		for name, failures := range res.Failures {
+			mergeFailures(ret, name, failures, s.config.FailureSamples)
+		}
+		if res.Usage != nil {
+			if ret.Usage == nil {
+				ret.Usage = map[string]*HostUsage{}
+			}
+			mergeUsage(ret.Usage, res.Usage)
+		}
+		mergeResponses(ret, res.Responses)
+	}
+	ret.Duration = time.Since(started)
+
+	return ret, firstErr
+}
@@ -0,0 +1,41 @@
+package scrape
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// promoteNoscript replaces every <noscript> element in the document with its
+// contents, parsed as HTML.  Since the HTML parser treats the contents of a
+// <noscript> element as plain text, they would otherwise be invisible to
+// selectors.
+func promoteNoscript(doc *goquery.Document) error {
+	var err error
+	doc.Find("noscript").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		node := s.Get(0)
+
+		// If the contents were already parsed as elements (e.g. because this
+		// <noscript> was itself inside a <noscript>), just unwrap them.
+		if node.FirstChild == nil || node.FirstChild.Type != html.TextNode || node.FirstChild.NextSibling != nil {
+			s.ReplaceWithSelection(s.Contents())
+			return true
+		}
+
+		context := node.Parent
+		if context == nil || context.Type != html.ElementNode {
+			context = &html.Node{Type: html.ElementNode, Data: "body"}
+		}
+
+		var nodes []*html.Node
+		nodes, err = html.ParseFragment(strings.NewReader(node.FirstChild.Data), context)
+		if err != nil {
+			return false
+		}
+
+		s.ReplaceWithNodes(nodes...)
+		return true
+	})
+	return err
+}
@@ -0,0 +1,260 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report describes the outcome of a scrape, as sent by the notification
+// Sinks EmailSink and WebhookSink.
+type Report struct {
+	// A one-line description of the outcome - e.g. "scrape failed: ...", or
+	// "3 blocks added, 1 removed".
+	Title string
+
+	// A Summary of the pages that were scraped.
+	Summary *Summary
+
+	// The error that aborted the scrape, if any.
+	Err error
+
+	// Whether the scrape failed - i.e. it was aborted, or found fewer blocks
+	// than expected.
+	Failed bool
+
+	// The Pieces that had a value in the previous scrape, but not in any
+	// block of this one, which usually means that the site's layout has
+	// changed and their selectors no longer match.
+	Broken []string
+
+	// The blocks that were added and removed since the previous scrape,
+	// encoded as JSON.  These are only set if the scrape neither failed nor
+	// had broken Pieces.
+	Added   []string
+	Removed []string
+}
+
+// Changes returns the number of blocks that were added or removed.
+func (r *Report) Changes() int {
+	return len(r.Added) + len(r.Removed)
+}
+
+// notifyConfig holds the settings that are shared by the notification Sinks.
+type notifyConfig struct {
+	onFailure, onDrift, onChange, always bool
+
+	minBlocks, minChanges int
+	stateFile             string
+}
+
+// reportTracker collects the pages of a scrape, and compares them with the
+// previous scrape once it finishes.
+type reportTracker struct {
+	mu      sync.Mutex
+	started time.Time
+	pages   []*Page
+	err     error
+
+	// The blocks of the previous successful scrape, encoded as JSON, in
+	// order.  This is nil before the first scrape.
+	previous []string
+	loaded   bool
+}
+
+func (t *reportTracker) write(p *Page) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.started.IsZero() {
+		t.started = time.Now()
+	}
+	t.pages = append(t.pages, p)
+}
+
+func (t *reportTracker) failed(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.err = err
+}
+
+// finish builds the report of the scrape that has just finished, and returns
+// it along with whether a notification should be sent for it.
+func (t *reportTracker) finish(c notifyConfig) (*Report, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pages, scrapeErr, started := t.pages, t.err, t.started
+	t.pages, t.err, t.started = nil, nil, time.Time{}
+
+	if !t.loaded {
+		if err := t.loadState(c.stateFile); err != nil {
+			return nil, false, err
+		}
+		t.loaded = true
+	}
+
+	res := &ScrapeResults{URLs: []string{}, Results: [][]map[string]interface{}{}}
+	if !started.IsZero() {
+		res.Duration = time.Since(started)
+	}
+	current := []string{}
+	seen := map[string]bool{}
+	for _, page := range pages {
+		res.URLs = append(res.URLs, page.URL)
+		res.Results = append(res.Results, page.Blocks)
+		for _, block := range page.Blocks {
+			key, err := json.Marshal(block)
+			if err != nil {
+				return nil, false, err
+			}
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				current = append(current, string(key))
+			}
+		}
+	}
+
+	report := &Report{
+		Summary: res.Summary(),
+		Err:     scrapeErr,
+		Broken:  brokenPieces(t.previous, res.Results),
+	}
+	report.Failed = scrapeErr != nil || report.Summary.Blocks < c.minBlocks
+
+	// Only compare scrapes that worked, so that a broken scrape isn't
+	// reported as every block being removed or changed.
+	if t.previous != nil && !report.Failed && len(report.Broken) == 0 {
+		old := map[string]bool{}
+		for _, key := range t.previous {
+			old[key] = true
+			if !seen[key] {
+				report.Removed = append(report.Removed, key)
+			}
+		}
+		for _, key := range current {
+			if !old[key] {
+				report.Added = append(report.Added, key)
+			}
+		}
+	}
+
+	minChanges := c.minChanges
+	if minChanges < 1 {
+		minChanges = 1
+	}
+	changed := report.Changes() >= minChanges
+
+	switch {
+	case scrapeErr != nil:
+		report.Title = "scrape failed: " + strings.Replace(scrapeErr.Error(), "\n", " ", -1)
+	case report.Failed:
+		report.Title = fmt.Sprintf("scrape found %d blocks, expected at least %d",
+			report.Summary.Blocks, c.minBlocks)
+	case len(report.Broken) > 0:
+		report.Title = "pieces stopped matching: " + strings.Join(report.Broken, ", ")
+	case changed:
+		report.Title = fmt.Sprintf("%d blocks added, %d removed", len(report.Added), len(report.Removed))
+	default:
+		report.Title = "scrape finished"
+	}
+
+	if !report.Failed && len(report.Broken) == 0 {
+		t.previous = current
+		if err := t.saveState(c.stateFile); err != nil {
+			return nil, false, err
+		}
+	}
+
+	notify := c.always ||
+		(report.Failed && c.onFailure) ||
+		(len(report.Broken) > 0 && c.onDrift) ||
+		(changed && c.onChange)
+	return report, notify, nil
+}
+
+// brokenPieces returns the names of the Pieces that had a non-empty value in
+// any of the previous blocks, but not in any of the current ones.
+func brokenPieces(previous []string, current [][]map[string]interface{}) []string {
+	filled := func(block map[string]interface{}, names map[string]bool) {
+		for name, val := range block {
+			if !isEmpty(val) {
+				names[name] = true
+			}
+		}
+	}
+
+	now := map[string]bool{}
+	blocks := 0
+	for _, page := range current {
+		for _, block := range page {
+			filled(block, now)
+			blocks++
+		}
+	}
+	if blocks == 0 {
+		return nil
+	}
+
+	before := map[string]bool{}
+	for _, key := range previous {
+		var block map[string]interface{}
+		if err := json.Unmarshal([]byte(key), &block); err == nil {
+			filled(block, before)
+		}
+	}
+
+	var ret []string
+	for name := range before {
+		if !now[name] {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// loadState reads the blocks of the previous scrape from the given file, if
+// it exists.
+func (t *reportTracker) loadState(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var blocks []json.RawMessage
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("invalid state file %s: %s", path, err)
+	}
+	t.previous = []string{}
+	for _, block := range blocks {
+		t.previous = append(t.previous, string(block))
+	}
+	return nil
+}
+
+func (t *reportTracker) saveState(path string) error {
+	if path == "" {
+		return nil
+	}
+	raw := make([]json.RawMessage, len(t.previous))
+	for i, block := range t.previous {
+		raw[i] = json.RawMessage(block)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -8,6 +8,34 @@ type ScrapeOptions struct {
 	// returns no further URLs.  Set this value to 0 to indicate an unlimited
 	// number of pages can be scraped.
 	MaxPages int
+
+	// The number of start URLs that ScrapeAll will scrape at the same time.  Set
+	// this value to 0 or 1 to scrape each start URL one after the other.
+	Parallelism int
+
+	// ShardCount and ShardIndex split the start URLs given to ScrapeAll
+	// between several workers (e.g. separate machines running the same
+	// config): each worker sets the same ShardCount and a different ShardIndex,
+	// from 0 to ShardCount-1, and only scrapes the start URLs that belong to
+	// its shard.  See ShardFor for how URLs are assigned.  Set ShardCount to 0
+	// to scrape every start URL.
+	ShardCount int
+	ShardIndex int
+
+	// If MaxConsecutiveEmptyPages is greater than 0, then the scrape stops
+	// once that many pages in a row have had no blocks - e.g. when an offset
+	// paginator has walked past the end of the real content.  The results so
+	// far are returned along with ErrTooManyEmptyPages.
+	MaxConsecutiveEmptyPages int
+
+	// If MaxConsecutiveErrors is greater than 0, then a page that can't be
+	// fetched doesn't end the scrape.  Instead, it is left out of the results,
+	// the Paginator is given an empty document for it (so only paginators
+	// that work from the URL alone, such as paginate.ByQueryParam, can carry
+	// on), and the scrape stops once that many pages in a row have failed.
+	// The results so far are then returned along with an error wrapping
+	// ErrTooManyErrors.  Failed pages still count towards MaxPages.
+	MaxConsecutiveErrors int
 }
 
 // The default options during a scrape.
@@ -0,0 +1,215 @@
+package output
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// WriteAvroSchema writes the Avro schema of the schema's record type to w, as
+// JSON.  Every field is a union of "null" and its type, with a default of
+// null.
+func (s *RecordSchema) WriteAvroSchema(w io.Writer) error {
+	data, err := json.Marshal(avroSchema(s.typ, map[string]bool{}))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func avroSchema(t *fieldType, defined map[string]bool) interface{} {
+	switch t.kind {
+	case kindList:
+		return map[string]interface{}{"type": "array", "items": avroSchema(t.elem, defined)}
+	case kindMap:
+		return map[string]interface{}{"type": "map", "values": avroSchema(t.elem, defined)}
+	case kindRecord:
+		// Named types may only be defined once, and are referred to by name
+		// after that.
+		if defined[t.name] {
+			return t.name
+		}
+		defined[t.name] = true
+
+		fields := []interface{}{}
+		for _, f := range t.fields {
+			fields = append(fields, map[string]interface{}{
+				"name":    f.name,
+				"type":    []interface{}{"null", avroSchema(f.typ, defined)},
+				"default": nil,
+			})
+		}
+		return map[string]interface{}{"type": "record", "name": t.name, "fields": fields}
+	case kindInt:
+		return "long"
+	case kindFloat:
+		return "double"
+	case kindBool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// EncodeAvro encodes a block as a value of the schema's record type, in
+// Avro's binary encoding.  It returns an error if a value doesn't match the
+// schema.
+func (s *RecordSchema) EncodeAvro(block map[string]interface{}) ([]byte, error) {
+	m, err := normalize(block)
+	if err != nil {
+		return nil, err
+	}
+	return appendAvroRecord(nil, s.typ, m, "")
+}
+
+func appendAvroRecord(b []byte, t *fieldType, m map[string]interface{}, path string) ([]byte, error) {
+	var err error
+	for _, f := range t.fields {
+		// The index of the branch of the ["null", type] union.
+		v := m[f.key]
+		if v == nil {
+			b = appendAvroLong(b, 0)
+			continue
+		}
+		b = appendAvroLong(b, 1)
+		if b, err = appendAvroValue(b, f.typ, v, path+f.key); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendAvroValue(b []byte, t *fieldType, v interface{}, path string) ([]byte, error) {
+	switch t.kind {
+	case kindInt:
+		i, err := t.int(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendAvroLong(b, i), nil
+
+	case kindFloat:
+		f, err := t.float(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendFixed64(b, math.Float64bits(f)), nil
+
+	case kindBool:
+		val, err := t.bool(path, v)
+		if err != nil {
+			return nil, err
+		}
+		if val {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+
+	case kindList:
+		var err error
+		items := listItems(v)
+		if len(items) > 0 {
+			b = appendAvroLong(b, int64(len(items)))
+			for i, item := range items {
+				if b, err = appendAvroValue(b, t.elem, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return appendAvroLong(b, 0), nil
+
+	case kindMap:
+		m, err := t.object(path, v)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) > 0 {
+			b = appendAvroLong(b, int64(len(m)))
+			for _, key := range sortedKeys(m) {
+				b = appendAvroString(b, key)
+				if b, err = appendAvroValue(b, t.elem, m[key], path+"."+key); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return appendAvroLong(b, 0), nil
+
+	case kindRecord:
+		m, err := t.object(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendAvroRecord(b, t, m, path+".")
+
+	default:
+		s, err := t.str(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendAvroString(b, s), nil
+	}
+}
+
+// appendAvroLong appends a zig-zag encoded variable-length integer.
+func appendAvroLong(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	b = appendAvroLong(b, int64(len(s)))
+	return append(b, s...)
+}
+
+// WriteAvro writes every block of the given results to w as an Avro object
+// container file, which includes the schema so that it can be read without
+// any other information.  The blocks aren't compressed.
+func WriteAvro(w io.Writer, res *scrape.ScrapeResults, s *RecordSchema) error {
+	schema, err := json.Marshal(avroSchema(s.typ, map[string]bool{}))
+	if err != nil {
+		return err
+	}
+	var sync [16]byte
+	if _, err := rand.Read(sync[:]); err != nil {
+		return err
+	}
+
+	header := []byte("Obj\x01")
+	header = appendAvroLong(header, 2)
+	header = appendAvroString(header, "avro.schema")
+	header = appendAvroString(header, string(schema))
+	header = appendAvroString(header, "avro.codec")
+	header = appendAvroString(header, "null")
+	header = appendAvroLong(header, 0)
+	header = append(header, sync[:]...)
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+
+	blocks := res.AllBlocks()
+	if len(blocks) > 0 {
+		var data []byte
+		for _, block := range blocks {
+			m, err := normalize(block)
+			if err != nil {
+				return err
+			}
+			if data, err = appendAvroRecord(data, s.typ, m, ""); err != nil {
+				return err
+			}
+		}
+
+		bw.Write(appendAvroLong(nil, int64(len(blocks))))
+		bw.Write(appendAvroLong(nil, int64(len(data))))
+		bw.Write(data)
+		bw.Write(sync[:])
+	}
+	return bw.Flush()
+}
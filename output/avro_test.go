@@ -0,0 +1,116 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAvroSchema(t *testing.T) {
+	s, err := NewRecordSchema(recordConfig, "Product")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, s.WriteAvroSchema(&buf))
+
+	var schema struct {
+		Type   string
+		Name   string
+		Fields []struct {
+			Name    string
+			Type    []interface{}
+			Default interface{}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &schema))
+	assert.Equal(t, "record", schema.Type)
+	assert.Equal(t, "Product", schema.Name)
+	if assert.Len(t, schema.Fields, 8) {
+		assert.Equal(t, "title", schema.Fields[0].Name)
+		assert.Equal(t, []interface{}{"null", "string"}, schema.Fields[0].Type)
+		assert.Nil(t, schema.Fields[0].Default)
+		assert.Equal(t, []interface{}{"null", map[string]interface{}{"type": "array", "items": "string"}}, schema.Fields[1].Type)
+		assert.Equal(t, []interface{}{"null", "long"}, schema.Fields[2].Type)
+		assert.Equal(t, []interface{}{"null", map[string]interface{}{"type": "map", "values": "long"}}, schema.Fields[5].Type)
+	}
+}
+
+func TestEncodeAvro(t *testing.T) {
+	s, err := NewRecordSchema(recordConfig, "")
+	assert.NoError(t, err)
+
+	data, err := s.EncodeAvro(recordBlock)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		2, 6, 'H', 'a', 't',
+		2, 2, 4, '/', 'a', 0,
+		2, 6,
+		2, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f,
+		2, 1,
+		2, 2, 2, 'x', 4, 0,
+		2, 2, 4, 'B', 'o', 2, 0, 0, 0, 0, 0, 0, 0x12, 0x40,
+		2, 10, '[', '1', ',', '2', ']',
+	}, data)
+
+	// Missing Pieces are null.
+	data, err = s.EncodeAvro(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 8), data)
+
+	_, err = s.EncodeAvro(map[string]interface{}{"seller": map[string]interface{}{"rating": "good"}})
+	assert.EqualError(t, err, "seller.rating: expected number, got string")
+}
+
+func TestWriteAvro(t *testing.T) {
+	s, err := NewRecordSchema(recordConfig, "")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	res := &scrape.ScrapeResults{Results: [][]map[string]interface{}{{recordBlock}, {{"title": "Cap"}}}}
+	assert.NoError(t, WriteAvro(&buf, res, s))
+
+	r := bytes.NewReader(buf.Bytes())
+	magic := make([]byte, 4)
+	r.Read(magic)
+	assert.Equal(t, "Obj\x01", string(magic))
+
+	readLong := func() int64 {
+		n, err := binary.ReadVarint(r)
+		assert.NoError(t, err)
+		return n
+	}
+	readString := func() string {
+		b := make([]byte, readLong())
+		r.Read(b)
+		return string(b)
+	}
+
+	meta := map[string]string{}
+	for n := readLong(); n > 0; n-- {
+		key := readString()
+		meta[key] = readString()
+	}
+	assert.Equal(t, int64(0), readLong())
+	assert.Equal(t, "null", meta["avro.codec"])
+	var schema bytes.Buffer
+	s.WriteAvroSchema(&schema)
+	assert.Equal(t, schema.String(), meta["avro.schema"])
+
+	sync := make([]byte, 16)
+	r.Read(sync)
+
+	assert.Equal(t, int64(2), readLong())
+	first, _ := s.EncodeAvro(recordBlock)
+	second, _ := s.EncodeAvro(map[string]interface{}{"title": "Cap"})
+	data := make([]byte, readLong())
+	r.Read(data)
+	assert.Equal(t, append(first, second...), data)
+
+	rest := make([]byte, 17)
+	n, _ := r.Read(rest)
+	assert.Equal(t, sync, rest[:n])
+}
@@ -0,0 +1,228 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	neturl "net/url"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// FeedConfig controls how WriteRSS and WriteAtom turn blocks into the items of
+// a feed.  Each field that names a Piece defaults to the lowercase name of the
+// field - e.g. the title of each item is taken from the Piece named "title".
+type FeedConfig struct {
+	// The title, link and description of the feed itself.  The link is
+	// usually the page that was scraped.
+	FeedTitle       string
+	FeedLink        string
+	FeedDescription string
+
+	// The names of the Pieces that hold each item's details.  Blocks without
+	// a title or a link are skipped.  Relative links are resolved against the
+	// URL of the page that the block came from.
+	Title       string
+	Link        string
+	Date        string
+	Description string
+
+	// The name of the Piece that holds a unique ID for each item.  If this is
+	// empty, or a block doesn't have it, then the item's link is used, or
+	// else a hash of its title.
+	ID string
+
+	// Layouts and TimeZone control how dates are parsed, as for ICSConfig.
+	// Items without a date that can be parsed have none in RSS feeds, and
+	// the time of writing in Atom feeds, which require one.
+	Layouts  []string
+	TimeZone *time.Location
+}
+
+// feedItem is a single item of a feed, before it's encoded.
+type feedItem struct {
+	title, link, description, id string
+	date                         time.Time
+}
+
+// items returns the items of the feed, from every block of the given results.
+func (c *FeedConfig) items(res *scrape.ScrapeResults) []feedItem {
+	var ret []feedItem
+	for i, page := range res.Results {
+		var base *neturl.URL
+		if i < len(res.URLs) {
+			base, _ = neturl.Parse(res.URLs[i])
+		}
+
+		for _, block := range page {
+			item := feedItem{
+				title:       text(block[pieceName(c.Title, "title")]),
+				link:        text(block[pieceName(c.Link, "link")]),
+				description: text(block[pieceName(c.Description, "description")]),
+			}
+			if item.title == "" && item.link == "" {
+				continue
+			}
+			if item.link != "" && base != nil {
+				if u, err := base.Parse(item.link); err == nil {
+					item.link = u.String()
+				}
+			}
+			if date, _, ok := parseTime(block[pieceName(c.Date, "date")], c.Layouts, c.TimeZone); ok {
+				item.date = date
+			}
+
+			if c.ID != "" {
+				item.id = text(block[c.ID])
+			}
+			if item.id == "" {
+				item.id = item.link
+			}
+			if item.id == "" {
+				sum := sha1.Sum([]byte(item.title))
+				item.id = "urn:sha1:" + hex.EncodeToString(sum[:])
+			}
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title,omitempty"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	GUID        *rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// WriteRSS writes the blocks of the given results to w as an RSS 2.0 feed, so
+// that a feed can be made for a site that doesn't offer one.  If c is nil,
+// then the default Piece names are used.
+func WriteRSS(w io.Writer, res *scrape.ScrapeResults, c *FeedConfig) error {
+	if c == nil {
+		c = &FeedConfig{}
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       c.FeedTitle,
+			Link:        c.FeedLink,
+			Description: c.FeedDescription,
+		},
+	}
+	for _, item := range c.items(res) {
+		ri := rssItem{
+			Title:       item.title,
+			Link:        item.link,
+			Description: item.description,
+			GUID:        &rssGUID{item.id, item.id == item.link},
+		}
+		if !item.date.IsZero() {
+			ri.PubDate = item.date.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, ri)
+	}
+	return writeXML(w, feed)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    *atomLink   `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Link    *atomLink `xml:"link"`
+	Updated string    `xml:"updated"`
+	Summary string    `xml:"summary,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// WriteAtom writes the blocks of the given results to w as an Atom feed.  The
+// feed's ID is its link, and it is updated at the time of its latest item.
+// If c is nil, then the default Piece names are used.
+func WriteAtom(w io.Writer, res *scrape.ScrapeResults, c *FeedConfig) error {
+	if c == nil {
+		c = &FeedConfig{}
+	}
+	now := time.Now()
+
+	feed := atomFeed{
+		Title: c.FeedTitle,
+		ID:    c.FeedLink,
+	}
+	if c.FeedLink != "" {
+		feed.Link = &atomLink{c.FeedLink}
+	}
+
+	var updated time.Time
+	for _, item := range c.items(res) {
+		date := item.date
+		if date.IsZero() {
+			date = now
+		}
+		if date.After(updated) {
+			updated = date
+		}
+
+		entry := atomEntry{
+			Title:   item.title,
+			ID:      item.id,
+			Updated: date.Format(time.RFC3339),
+			Summary: item.description,
+		}
+		if item.link != "" {
+			entry.Link = &atomLink{item.link}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = now
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	return writeXML(w, feed)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
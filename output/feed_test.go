@@ -0,0 +1,111 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+var feedResults = &scrape.ScrapeResults{
+	URLs: []string{"http://example.com/blog/"},
+	Results: [][]map[string]interface{}{{
+		{
+			"title":   "First post",
+			"link":    "posts/1",
+			"date":    "2024-03-01",
+			"summary": "Hello & welcome",
+		},
+		{
+			"title": "Second post",
+			"link":  "http://example.com/blog/posts/2",
+			"date":  time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC),
+		},
+		{"title": "No link"},
+		{"summary": "Neither title nor link"},
+	}},
+}
+
+var feedConfig = &FeedConfig{
+	FeedTitle:       "Example blog",
+	FeedLink:        "http://example.com/blog/",
+	FeedDescription: "Posts from the example blog",
+	Description:     "summary",
+}
+
+func TestWriteRSS(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteRSS(&buf, feedResults, feedConfig))
+	assert.Equal(t, xml.Header+`<rss version="2.0">
+  <channel>
+    <title>Example blog</title>
+    <link>http://example.com/blog/</link>
+    <description>Posts from the example blog</description>
+    <item>
+      <title>First post</title>
+      <link>http://example.com/blog/posts/1</link>
+      <description>Hello &amp; welcome</description>
+      <pubDate>Fri, 01 Mar 2024 00:00:00 +0000</pubDate>
+      <guid isPermaLink="true">http://example.com/blog/posts/1</guid>
+    </item>
+    <item>
+      <title>Second post</title>
+      <link>http://example.com/blog/posts/2</link>
+      <pubDate>Sat, 02 Mar 2024 10:30:00 +0000</pubDate>
+      <guid isPermaLink="true">http://example.com/blog/posts/2</guid>
+    </item>
+    <item>
+      <title>No link</title>
+      <guid isPermaLink="false">urn:sha1:fb997f45c8135520e60b6b34678c161ebb9f7daa</guid>
+    </item>
+  </channel>
+</rss>
+`, buf.String())
+}
+
+func TestWriteAtom(t *testing.T) {
+	var buf bytes.Buffer
+	res := &scrape.ScrapeResults{
+		URLs:    feedResults.URLs,
+		Results: [][]map[string]interface{}{feedResults.Results[0][:2]},
+	}
+	assert.NoError(t, WriteAtom(&buf, res, feedConfig))
+	assert.Equal(t, xml.Header+`<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example blog</title>
+  <id>http://example.com/blog/</id>
+  <link href="http://example.com/blog/"></link>
+  <updated>2024-03-02T10:30:00Z</updated>
+  <entry>
+    <title>First post</title>
+    <id>http://example.com/blog/posts/1</id>
+    <link href="http://example.com/blog/posts/1"></link>
+    <updated>2024-03-01T00:00:00Z</updated>
+    <summary>Hello &amp; welcome</summary>
+  </entry>
+  <entry>
+    <title>Second post</title>
+    <id>http://example.com/blog/posts/2</id>
+    <link href="http://example.com/blog/posts/2"></link>
+    <updated>2024-03-02T10:30:00Z</updated>
+  </entry>
+</feed>
+`, buf.String())
+
+	// Items without dates are updated now.
+	buf.Reset()
+	before := time.Now().Add(-time.Second)
+	assert.NoError(t, WriteAtom(&buf, &scrape.ScrapeResults{
+		Results: [][]map[string]interface{}{{{"title": "Undated", "id": "item-1"}}},
+	}, &FeedConfig{ID: "id"}))
+	var feed atomFeed
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &feed))
+	if assert.Len(t, feed.Entries, 1) {
+		assert.Equal(t, "item-1", feed.Entries[0].ID)
+		updated, err := time.Parse(time.RFC3339, feed.Entries[0].Updated)
+		assert.NoError(t, err)
+		assert.True(t, updated.After(before))
+	}
+}
@@ -0,0 +1,160 @@
+package output
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// ICSConfig controls how WriteICS turns blocks into calendar events.  Each
+// field that names a Piece defaults to the lowercase name of the field - e.g.
+// the title of each event is taken from the Piece named "title".
+type ICSConfig struct {
+	// The name of the calendar, which is shown by most calendar apps.
+	Name string
+
+	// The names of the Pieces that hold each event's details.  Title and Start
+	// are required - blocks without a title, or without a start time that can
+	// be parsed, are skipped.  The rest are optional.
+	Title       string
+	Start       string
+	End         string
+	Location    string
+	Description string
+	URL         string
+
+	// The name of the Piece that holds a unique ID for each event, so that
+	// calendar apps can recognize it when the feed is updated.  If this is
+	// empty, or a block doesn't have it, then the event's URL is used, or
+	// else a hash of its title and start time.
+	UID string
+
+	// Layouts are the formats (as for time.Parse) in which start and end times
+	// are tried, after RFC 3339 and the defaults "2006-01-02T15:04:05",
+	// "2006-01-02 15:04:05", "2006-01-02 15:04" and "2006-01-02".  Times that
+	// are given as a date only become all-day events.  Values that are
+	// already time.Time are used as they are.
+	Layouts []string
+
+	// TimeZone is the time zone of times that don't include one.  If this is
+	// nil, then UTC is used.
+	TimeZone *time.Location
+
+	// Duration is the length of timed events that don't have an end time.  If
+	// this is 0, then such events have no end.
+	Duration time.Duration
+}
+
+const icsDateLayout = "20060102"
+const icsTimeLayout = "20060102T150405Z"
+
+// WriteICS writes the blocks of the given results to w as an iCalendar (.ics)
+// feed, with one event per block - e.g. to subscribe to the events scraped
+// from a listings site in a calendar app.  If c is nil, then the default
+// Piece names are used.
+func WriteICS(w io.Writer, res *scrape.ScrapeResults, c *ICSConfig) error {
+	if c == nil {
+		c = &ICSConfig{}
+	}
+	now := time.Now().UTC().Format(icsTimeLayout)
+
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeICSLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//goscrape//EN")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeICS(c.Name))
+	}
+
+	for _, page := range res.Results {
+		for _, block := range page {
+			title := text(block[pieceName(c.Title, "title")])
+			start, allDay, ok := parseTime(block[pieceName(c.Start, "start")], c.Layouts, c.TimeZone)
+			if title == "" || !ok {
+				continue
+			}
+
+			line("BEGIN", "VEVENT")
+			line("UID", escapeICS(c.uid(block, title, start)))
+			line("DTSTAMP", now)
+			if allDay {
+				line("DTSTART;VALUE=DATE", start.Format(icsDateLayout))
+			} else {
+				line("DTSTART", start.UTC().Format(icsTimeLayout))
+			}
+			if end, endAllDay, ok := parseTime(block[pieceName(c.End, "end")], c.Layouts, c.TimeZone); ok {
+				if endAllDay {
+					// The end date of an all-day event is exclusive.
+					line("DTEND;VALUE=DATE", end.AddDate(0, 0, 1).Format(icsDateLayout))
+				} else {
+					line("DTEND", end.UTC().Format(icsTimeLayout))
+				}
+			} else if !allDay && c.Duration > 0 {
+				line("DTEND", start.Add(c.Duration).UTC().Format(icsTimeLayout))
+			}
+			line("SUMMARY", escapeICS(title))
+			if loc := text(block[pieceName(c.Location, "location")]); loc != "" {
+				line("LOCATION", escapeICS(loc))
+			}
+			if desc := text(block[pieceName(c.Description, "description")]); desc != "" {
+				line("DESCRIPTION", escapeICS(desc))
+			}
+			if url := text(block[pieceName(c.URL, "url")]); url != "" {
+				line("URL", url)
+			}
+			line("END", "VEVENT")
+		}
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+func (c *ICSConfig) uid(block map[string]interface{}, title string, start time.Time) string {
+	if c.UID != "" {
+		if uid := text(block[c.UID]); uid != "" {
+			return uid
+		}
+	}
+	if url := text(block[pieceName(c.URL, "url")]); url != "" {
+		return url
+	}
+	sum := sha1.Sum([]byte(title + "\x00" + start.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:]) + "@goscrape"
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICS(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// writeICSLine writes a content line, folded so that no line is longer than
+// 75 bytes.
+func writeICSLine(w *bufio.Writer, s string) {
+	const maxLine = 75
+	limit := maxLine
+	for len(s) > limit {
+		// Don't split a UTF-8 sequence.
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLine - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteICS(t *testing.T) {
+	res := &scrape.ScrapeResults{
+		URLs: []string{"http://example.com/events"},
+		Results: [][]map[string]interface{}{{
+			{
+				"title":    "Gophers, Unite; Part 1",
+				"start":    "2024-06-01 18:30",
+				"location": "Town Hall",
+				"url":      "http://example.com/events/1",
+			},
+			{
+				"title": "Festival",
+				"start": "2024-07-04",
+				"end":   "2024-07-06",
+				"desc":  strings.Repeat("Lots of music\nand food. ", 5),
+			},
+			{
+				"title": "Launch",
+				"start": time.Date(2024, 8, 1, 9, 0, 0, 0, time.UTC),
+				"id":    "launch-2024",
+			},
+			{"title": "No date"},
+			{"title": "Bad date", "start": "sometime soon"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteICS(&buf, res, &ICSConfig{
+		Name:        "Events",
+		Description: "desc",
+		UID:         "id",
+		TimeZone:    time.FixedZone("CEST", 2*60*60),
+		Duration:    2 * time.Hour,
+	}))
+
+	out := regexp.MustCompile(`DTSTAMP:\d{8}T\d{6}Z`).ReplaceAllString(buf.String(), "DTSTAMP:now")
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//goscrape//EN",
+		"X-WR-CALNAME:Events",
+		"BEGIN:VEVENT",
+		"UID:http://example.com/events/1",
+		"DTSTAMP:now",
+		"DTSTART:20240601T163000Z",
+		"DTEND:20240601T183000Z",
+		`SUMMARY:Gophers\, Unite\; Part 1`,
+		"LOCATION:Town Hall",
+		"URL:http://example.com/events/1",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:" + icsHashUID("Festival", time.Date(2024, 7, 4, 0, 0, 0, 0, time.FixedZone("CEST", 2*60*60))),
+		"DTSTAMP:now",
+		"DTSTART;VALUE=DATE:20240704",
+		"DTEND;VALUE=DATE:20240707",
+		"SUMMARY:Festival",
+		`DESCRIPTION:Lots of music\nand food. Lots of music\nand food. Lots of music`,
+		` \nand food. Lots of music\nand food. Lots of music\nand food. `,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:launch-2024",
+		"DTSTAMP:now",
+		"DTSTART:20240801T090000Z",
+		"DTEND:20240801T110000Z",
+		"SUMMARY:Launch",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), out)
+}
+
+func icsHashUID(title string, start time.Time) string {
+	return (&ICSConfig{}).uid(map[string]interface{}{}, title, start)
+}
+
+func TestWriteICSEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteICS(&buf, &scrape.ScrapeResults{}, nil))
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//goscrape//EN\r\nEND:VCALENDAR\r\n", buf.String())
+}
@@ -0,0 +1,224 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// WriteProto writes a Protocol Buffers (proto3) definition of the schema's
+// record type to w, in the given package (which may be empty).  Fields are
+// numbered in the order of the Pieces, so the numbers only stay compatible
+// while Pieces are added to the end of the configuration.
+func (s *RecordSchema) WriteProto(w io.Writer, pkg string) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("syntax = \"proto3\";\n\n")
+	if pkg != "" {
+		fmt.Fprintf(bw, "package %s;\n\n", pkg)
+	}
+	writeProtoMessage(bw, s.typ, "")
+	return bw.Flush()
+}
+
+func writeProtoMessage(w *bufio.Writer, t *fieldType, indent string) {
+	fmt.Fprintf(w, "%smessage %s {\n", indent, t.name)
+
+	// Nested records are declared within the message that uses them.
+	declared := map[string]bool{}
+	for _, f := range t.fields {
+		rec := f.typ
+		if rec.kind == kindList || rec.kind == kindMap {
+			rec = rec.elem
+		}
+		if rec.kind == kindRecord && !declared[rec.name] {
+			writeProtoMessage(w, rec, indent+"  ")
+			declared[rec.name] = true
+		}
+	}
+
+	for i, f := range t.fields {
+		fmt.Fprintf(w, "%s  %s %s = %d;\n", indent, protoType(f.typ), f.name, i+1)
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+func protoType(t *fieldType) string {
+	switch t.kind {
+	case kindList:
+		return "repeated " + strings.TrimPrefix(protoType(t.elem), "optional ")
+	case kindMap:
+		return "map<string, " + strings.TrimPrefix(protoType(t.elem), "optional ") + ">"
+	case kindRecord:
+		return t.name
+	case kindInt:
+		return "optional int64"
+	case kindFloat:
+		return "optional double"
+	case kindBool:
+		return "optional bool"
+	default:
+		return "optional string"
+	}
+}
+
+// Protocol Buffers wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// EncodeProto encodes a block as a Protocol Buffers message of the schema's
+// record type.  It returns an error if a value doesn't match the schema.
+func (s *RecordSchema) EncodeProto(block map[string]interface{}) ([]byte, error) {
+	m, err := normalize(block)
+	if err != nil {
+		return nil, err
+	}
+	return appendProtoMessage(nil, s.typ, m, "")
+}
+
+func appendProtoMessage(b []byte, t *fieldType, m map[string]interface{}, path string) ([]byte, error) {
+	var err error
+	for i, f := range t.fields {
+		v := m[f.key]
+		if v == nil {
+			continue
+		}
+		if b, err = appendProtoField(b, i+1, f.typ, v, path+f.key); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendProtoField(b []byte, num int, t *fieldType, v interface{}, path string) ([]byte, error) {
+	switch t.kind {
+	case kindInt:
+		i, err := t.int(path, v)
+		if err != nil {
+			return nil, err
+		}
+		b = appendTag(b, num, wireVarint)
+		return appendVarint(b, uint64(i)), nil
+
+	case kindFloat:
+		f, err := t.float(path, v)
+		if err != nil {
+			return nil, err
+		}
+		b = appendTag(b, num, wireFixed64)
+		return appendFixed64(b, math.Float64bits(f)), nil
+
+	case kindBool:
+		val, err := t.bool(path, v)
+		if err != nil {
+			return nil, err
+		}
+		b = appendTag(b, num, wireVarint)
+		if val {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+
+	case kindList:
+		items := listItems(v)
+		switch t.elem.kind {
+		case kindInt, kindFloat, kindBool:
+			// Repeated numbers are packed into a single field.
+			var packed []byte
+			for i, item := range items {
+				field, err := appendProtoField(nil, 1, t.elem, item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				// Drop the tag, which is a single byte for field 1.
+				packed = append(packed, field[1:]...)
+			}
+			return appendBytes(b, num, packed), nil
+		}
+
+		var err error
+		for i, item := range items {
+			if b, err = appendProtoField(b, num, t.elem, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	case kindMap:
+		m, err := t.object(path, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(m) {
+			entry := appendBytes(nil, 1, []byte(key))
+			if entry, err = appendProtoField(entry, 2, t.elem, m[key], path+"."+key); err != nil {
+				return nil, err
+			}
+			b = appendBytes(b, num, entry)
+		}
+		return b, nil
+
+	case kindRecord:
+		m, err := t.object(path, v)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := appendProtoMessage(nil, t, m, path+".")
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, num, msg), nil
+
+	default:
+		s, err := t.str(path, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, num, []byte(s)), nil
+	}
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendTag(b []byte, num, wire int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wire))
+}
+
+func appendBytes(b []byte, num int, data []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// WriteProtoDelimited writes every block of the given results to w as a
+// stream of Protocol Buffers messages of the schema's record type, each
+// preceded by its length as a varint - the format read by, e.g., Java's
+// parseDelimitedFrom.
+func WriteProtoDelimited(w io.Writer, res *scrape.ScrapeResults, s *RecordSchema) error {
+	bw := bufio.NewWriter(w)
+	for _, block := range res.AllBlocks() {
+		msg, err := s.EncodeProto(block)
+		if err != nil {
+			return err
+		}
+		bw.Write(appendVarint(nil, uint64(len(msg))))
+		bw.Write(msg)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,71 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteProto(t *testing.T) {
+	s, err := NewRecordSchema(recordConfig, "Product")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, s.WriteProto(&buf, "shop"))
+	assert.Equal(t, `syntax = "proto3";
+
+package shop;
+
+message Product {
+  message Seller {
+    optional string name = 1;
+    optional double rating = 2;
+  }
+  optional string title = 1;
+  repeated string tags = 2;
+  optional int64 count = 3;
+  optional double price = 4;
+  optional bool in_stock = 5;
+  map<string, int64> counts = 6;
+  Seller seller = 7;
+  optional string raw = 8;
+}
+`, buf.String())
+}
+
+func TestEncodeProto(t *testing.T) {
+	s, err := NewRecordSchema(recordConfig, "")
+	assert.NoError(t, err)
+
+	msg, err := s.EncodeProto(recordBlock)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x0a, 3, 'H', 'a', 't',
+		0x12, 2, '/', 'a',
+		0x18, 3,
+		0x21, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f,
+		0x28, 1,
+		0x32, 5, 0x0a, 1, 'x', 0x10, 2,
+		0x3a, 13, 0x0a, 2, 'B', 'o', 0x11, 0, 0, 0, 0, 0, 0, 0x12, 0x40,
+		0x42, 5, '[', '1', ',', '2', ']',
+	}, msg)
+
+	// Repeated numbers are packed.
+	s, err = NewRecordSchema(&scrape.ScrapeConfig{Pieces: []scrape.Piece{{
+		Name: "n", Schema: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+	}}}, "")
+	assert.NoError(t, err)
+	msg, err = s.EncodeProto(map[string]interface{}{"n": []int{1, 300}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 3, 1, 0xac, 0x02}, msg)
+
+	_, err = s.EncodeProto(map[string]interface{}{"n": []interface{}{1, "two"}})
+	assert.EqualError(t, err, "n[1]: expected integer, got string")
+
+	var buf bytes.Buffer
+	res := &scrape.ScrapeResults{Results: [][]map[string]interface{}{{{"n": 1}}, {{}}}}
+	assert.NoError(t, WriteProtoDelimited(&buf, res, s))
+	assert.Equal(t, []byte{3, 0x0a, 1, 1, 0}, buf.Bytes())
+}
@@ -0,0 +1,313 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/andrew-d/goscrape"
+)
+
+type kind int
+
+const (
+	// Values that can't be described more precisely are encoded as JSON
+	// strings.
+	kindJSON kind = iota
+	kindString
+	kindInt
+	kindFloat
+	kindBool
+	kindList
+	kindMap
+	kindRecord
+)
+
+var kindNames = map[kind]string{
+	kindJSON:   "JSON",
+	kindString: "string",
+	kindInt:    "integer",
+	kindFloat:  "number",
+	kindBool:   "boolean",
+	kindList:   "list",
+	kindMap:    "map",
+	kindRecord: "object",
+}
+
+type fieldType struct {
+	kind kind
+
+	// The type of the items of a list, or the values of a map.
+	elem *fieldType
+
+	// The name and fields of a record.
+	name   string
+	fields []field
+}
+
+type field struct {
+	// The name of the field in the schema, and the key of its value.
+	name, key string
+	typ       *fieldType
+}
+
+// RecordSchema describes the blocks of a scrape as a record type, for encoding
+// them in binary formats such as Protocol Buffers and Avro.  It is derived
+// from the JSON Schema of each Piece (see scrape.ScrapeConfig.BlockSchema):
+//
+//   - Strings, integers, numbers and booleans have the corresponding type.
+//   - Arrays are lists, and objects with known properties are nested
+//     records.  Pieces that return either a single value or a list of them
+//     (e.g. extract.Attr) are always lists.
+//   - Objects whose properties are all of the same type are maps with string
+//     keys.
+//   - Anything else - including lists of lists, and maps of lists - is
+//     encoded as a string containing its JSON.
+//
+// Every field is optional, since Pieces are omitted from blocks when their
+// values are nil.  Piece names are changed to valid identifiers (e.g.
+// "price-usd" becomes "price_usd").  If the configuration has a BlockID, then
+// the block's ID is the last field.
+type RecordSchema struct {
+	typ *fieldType
+}
+
+// NewRecordSchema returns the RecordSchema of the blocks of a scrape with the
+// given configuration.  The record type is given the name name, or "Block" if
+// that is empty.
+func NewRecordSchema(c *scrape.ScrapeConfig, name string) (*RecordSchema, error) {
+	if name == "" {
+		name = "Block"
+	}
+
+	props := c.BlockSchema()["properties"].(map[string]interface{})
+	typ := &fieldType{kind: kindRecord, name: identifier(name)}
+	seen := map[string]bool{}
+	for _, piece := range c.Pieces {
+		f := field{
+			name: identifier(piece.Name),
+			key:  piece.Name,
+			typ:  typeFromSchema(piece.Name, props[piece.Name]),
+		}
+		if seen[f.name] {
+			return nil, fmt.Errorf("pieces have the same field name %q", f.name)
+		}
+		seen[f.name] = true
+		typ.fields = append(typ.fields, f)
+	}
+	if c.BlockID != nil {
+		name := c.BlockID.Name
+		if name == "" {
+			name = scrape.DefaultBlockIDName
+		}
+		f := field{name: identifier(name), key: name, typ: &fieldType{kind: kindString}}
+		if seen[f.name] {
+			return nil, fmt.Errorf("pieces have the same field name %q", f.name)
+		}
+		typ.fields = append(typ.fields, f)
+	}
+	return &RecordSchema{typ}, nil
+}
+
+// typeFromSchema returns the type of values with the given JSON Schema.  The
+// name is used to name records.
+func typeFromSchema(name string, v interface{}) *fieldType {
+	s, _ := v.(map[string]interface{})
+
+	// A single item or a list of them, as returned by listSchema in the
+	// extract package.
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && len(anyOf) == 2 {
+		list, _ := anyOf[1].(map[string]interface{})
+		if list["type"] == "array" && reflect.DeepEqual(list["items"], anyOf[0]) {
+			return listOf(typeFromSchema(name, anyOf[0]))
+		}
+	}
+
+	switch s["type"] {
+	case "string":
+		return &fieldType{kind: kindString}
+	case "integer":
+		return &fieldType{kind: kindInt}
+	case "number":
+		return &fieldType{kind: kindFloat}
+	case "boolean":
+		return &fieldType{kind: kindBool}
+	case "array":
+		return listOf(typeFromSchema(name, s["items"]))
+	case "object":
+		if props, ok := s["properties"].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(props))
+			for key := range props {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			ret := &fieldType{kind: kindRecord, name: typeName(name)}
+			for _, key := range keys {
+				ret.fields = append(ret.fields, field{
+					name: identifier(key),
+					key:  key,
+					typ:  typeFromSchema(key, props[key]),
+				})
+			}
+			return ret
+		}
+		if values, ok := s["additionalProperties"].(map[string]interface{}); ok {
+			elem := typeFromSchema(name, values)
+			if elem.kind != kindList && elem.kind != kindMap {
+				return &fieldType{kind: kindMap, elem: elem}
+			}
+		}
+	}
+	return &fieldType{kind: kindJSON}
+}
+
+func listOf(elem *fieldType) *fieldType {
+	if elem.kind == kindList || elem.kind == kindMap {
+		return &fieldType{kind: kindJSON}
+	}
+	return &fieldType{kind: kindList, elem: elem}
+}
+
+// identifier returns s with every character that isn't valid in an
+// identifier replaced by an underscore.
+func identifier(s string) string {
+	ret := []rune(s)
+	for i, r := range ret {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			ret[i] = '_'
+		}
+	}
+	if len(ret) == 0 || unicode.IsDigit(ret[0]) {
+		ret = append([]rune{'_'}, ret...)
+	}
+	return string(ret)
+}
+
+// typeName returns the name of a record type for a field with the given name
+// - e.g. "line_items" becomes "LineItems".
+func typeName(s string) string {
+	var buf strings.Builder
+	for _, part := range strings.Split(identifier(s), "_") {
+		if part != "" {
+			buf.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	if buf.Len() == 0 {
+		return "Record"
+	}
+	return buf.String()
+}
+
+// normalize converts a block into the values that encoding/json would decode
+// it into (but with numbers as json.Number), so that values of any Go type
+// can be encoded.
+func normalize(block map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(block)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var ret map[string]interface{}
+	if err := dec.Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// typeError is returned when a value doesn't match the schema.
+func typeError(path string, t *fieldType, v interface{}) error {
+	return fmt.Errorf("%s: expected %s, got %s", path, kindNames[t.kind], jsonType(v))
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// str returns a string or JSON value as a string.
+func (t *fieldType) str(path string, v interface{}) (string, error) {
+	if t.kind == kindJSON {
+		return jsonString(v)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", typeError(path, t, v)
+}
+
+func (t *fieldType) int(path string, v interface{}) (int64, error) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+	}
+	return 0, typeError(path, t, v)
+}
+
+func (t *fieldType) float(path string, v interface{}) (float64, error) {
+	if n, ok := v.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			return f, nil
+		}
+	}
+	return 0, typeError(path, t, v)
+}
+
+func (t *fieldType) bool(path string, v interface{}) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, typeError(path, t, v)
+}
+
+func (t *fieldType) object(path string, v interface{}) (map[string]interface{}, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, nil
+	}
+	return nil, typeError(path, t, v)
+}
+
+// listItems returns the items of a list value.  A single value is treated as
+// a list containing only that value.
+func listItems(v interface{}) []interface{} {
+	if items, ok := v.([]interface{}); ok {
+		return items
+	}
+	return []interface{}{v}
+}
+
+// sortedKeys returns the keys of a map value in order, so that encodings are
+// deterministic.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonString(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+var recordConfig = &scrape.ScrapeConfig{
+	Pieces: []scrape.Piece{
+		{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+		{Name: "tags", Selector: "a", Extractor: extract.Attr{Attr: "href"}},
+		{Name: "count", Selector: "li", Extractor: extract.Count{}},
+		{Name: "price", Selector: ".price", Extractor: extract.Text{},
+			Transform: func(v interface{}) (interface{}, error) { return v, nil },
+			Schema:    map[string]interface{}{"type": "number"}},
+		{Name: "in-stock", Selector: ".stock", Extractor: extract.Exists{}},
+		{Name: "counts", Selector: "li", Extractor: extract.GroupCount{Attr: "class"}},
+		{Name: "seller", Compute: func(map[string]interface{}) (interface{}, error) { return nil, nil },
+			Schema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":   map[string]interface{}{"type": "string"},
+					"rating": map[string]interface{}{"type": "number"},
+				},
+			}},
+		{Name: "raw", Selector: "p", Extractor: extract.Const{}},
+	},
+}
+
+var recordBlock = map[string]interface{}{
+	"title":    "Hat",
+	"tags":     "/a",
+	"count":    3,
+	"price":    1.5,
+	"in-stock": true,
+	"counts":   map[string]int{"x": 2},
+	"seller":   map[string]interface{}{"name": "Bo", "rating": 4.5},
+	"raw":      []int{1, 2},
+}
+
+func TestNewRecordSchema(t *testing.T) {
+	s, err := NewRecordSchema(recordConfig, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Block", s.typ.name)
+	if assert.Len(t, s.typ.fields, 8) {
+		assert.Equal(t, "in_stock", s.typ.fields[4].name)
+		assert.Equal(t, kindList, s.typ.fields[1].typ.kind)
+		assert.Equal(t, kindMap, s.typ.fields[5].typ.kind)
+		assert.Equal(t, "Seller", s.typ.fields[6].typ.name)
+		assert.Equal(t, kindJSON, s.typ.fields[7].typ.kind)
+	}
+
+	_, err = NewRecordSchema(&scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{{Name: "a-b"}, {Name: "a b"}},
+	}, "")
+	assert.EqualError(t, err, `pieces have the same field name "a_b"`)
+}
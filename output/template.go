@@ -0,0 +1,112 @@
+package output
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// Executor is the interface satisfied by both *text/template.Template and
+// *html/template.Template.
+type Executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// TemplateData is the data that a template is executed with.
+type TemplateData struct {
+	// The pages that were scraped, in order.
+	Pages []*scrape.Page
+
+	// The blocks from all pages, in order.
+	Blocks []map[string]interface{}
+
+	// A Summary of the scrape.
+	Summary *scrape.Summary
+
+	// When the template was executed.
+	Time time.Time
+}
+
+// RenderTemplate executes the given template with the results of a scrape,
+// and writes the output to w - e.g. to produce an HTML digest or a Markdown
+// report.  See TemplateData for the data that the template can use.
+func RenderTemplate(w io.Writer, tmpl Executor, res *scrape.ScrapeResults) error {
+	pages := make([]*scrape.Page, len(res.URLs))
+	for i, url := range res.URLs {
+		pages[i] = &scrape.Page{URL: url, Index: i}
+		if i < len(res.Results) {
+			pages[i].Blocks = res.Results[i]
+		}
+	}
+	return tmpl.Execute(w, newTemplateData(pages, res.Summary()))
+}
+
+func newTemplateData(pages []*scrape.Page, summary *scrape.Summary) *TemplateData {
+	ret := &TemplateData{
+		Pages:   pages,
+		Blocks:  []map[string]interface{}{},
+		Summary: summary,
+		Time:    time.Now(),
+	}
+	for _, page := range pages {
+		ret.Blocks = append(ret.Blocks, page.Blocks...)
+	}
+	return ret
+}
+
+// TemplateSink is a Sink that renders the pages of each scrape with a template
+// when the scrape finishes.  Create one with Template.
+type TemplateSink struct {
+	w    io.Writer
+	tmpl Executor
+
+	mu    sync.Mutex
+	pages []*scrape.Page
+}
+
+// Template returns a Sink that executes the given template with the pages of
+// each scrape once it finishes, and writes the output to w, so that a report
+// is produced without an extra program - e.g.
+//
+//	tmpl := template.Must(template.New("").Parse(
+//		"{{range .Blocks}}- [{{.title}}]({{.url}})\n{{end}}"))
+//	config.Sink = output.Template(os.Stdout, tmpl)
+//
+// Combined with ScrapeConfig.Dedup, this reports only the new items of each
+// scrape.  The template is executed with a *TemplateData, whose Summary only
+// counts the pages and blocks.  Nothing is written for a scrape without any
+// pages.
+func Template(w io.Writer, tmpl Executor) *TemplateSink {
+	return &TemplateSink{w: w, tmpl: tmpl}
+}
+
+func (s *TemplateSink) Write(p *scrape.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages = append(s.pages, p)
+	return nil
+}
+
+// Flush renders the pages that were written since the last call to Flush.
+func (s *TemplateSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pages := s.pages
+	s.pages = nil
+	if len(pages) == 0 {
+		return nil
+	}
+
+	res := &scrape.ScrapeResults{}
+	for _, page := range pages {
+		res.URLs = append(res.URLs, page.URL)
+		res.Results = append(res.Results, page.Blocks)
+	}
+	return s.tmpl.Execute(s.w, newTemplateData(pages, res.Summary()))
+}
+
+var _ scrape.Sink = &TemplateSink{}
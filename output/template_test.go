@@ -0,0 +1,59 @@
+package output
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"testing"
+	"text/template"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	res := &scrape.ScrapeResults{
+		URLs: []string{"http://example.com/1", "http://example.com/2"},
+		Results: [][]map[string]interface{}{
+			{{"title": "One", "url": "/one"}, {"title": "Two", "url": "/two"}},
+			{{"title": "<Three>", "url": "/three"}},
+		},
+	}
+
+	tmpl := template.Must(template.New("").Parse(
+		"# {{.Summary.Blocks}} items\n" +
+			"{{range .Pages}}## {{.URL}}\n{{range .Blocks}}- [{{.title}}]({{.url}})\n{{end}}{{end}}"))
+	var buf bytes.Buffer
+	assert.NoError(t, RenderTemplate(&buf, tmpl, res))
+	assert.Equal(t, "# 3 items\n"+
+		"## http://example.com/1\n- [One](/one)\n- [Two](/two)\n"+
+		"## http://example.com/2\n- [<Three>](/three)\n", buf.String())
+
+	// HTML templates escape values.
+	htmlTmpl := htmltemplate.Must(htmltemplate.New("").Parse(
+		`<ul>{{range .Blocks}}<li>{{.title}}</li>{{end}}</ul>`))
+	buf.Reset()
+	assert.NoError(t, RenderTemplate(&buf, htmlTmpl, res))
+	assert.Equal(t, `<ul><li>One</li><li>Two</li><li>&lt;Three&gt;</li></ul>`, buf.String())
+
+	// Template errors are returned.
+	tmpl = template.Must(template.New("").Parse(`{{.Missing}}`))
+	assert.Error(t, RenderTemplate(&buf, tmpl, res))
+}
+
+func TestTemplateSink(t *testing.T) {
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("").Parse(
+		"{{len .Pages}} pages:{{range .Blocks}} {{.n}}{{end}}\n"))
+	sink := Template(&buf, tmpl)
+
+	assert.NoError(t, sink.Write(&scrape.Page{URL: "a", Blocks: []map[string]interface{}{{"n": 1}, {"n": 2}}}))
+	assert.NoError(t, sink.Write(&scrape.Page{URL: "b", Index: 1, Blocks: []map[string]interface{}{{"n": 3}}}))
+	assert.NoError(t, sink.Flush())
+	assert.Equal(t, "2 pages: 1 2 3\n", buf.String())
+
+	// Each scrape is rendered separately, and empty scrapes aren't rendered.
+	assert.NoError(t, sink.Flush())
+	assert.NoError(t, sink.Write(&scrape.Page{URL: "c", Blocks: []map[string]interface{}{}}))
+	assert.NoError(t, sink.Flush())
+	assert.Equal(t, "2 pages: 1 2 3\n1 pages:\n", buf.String())
+}
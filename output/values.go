@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The layouts in which times are parsed by default.
+var defaultLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// pieceName returns the given name of a Piece, or the default if it's empty.
+func pieceName(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// text converts a value from a block to text.
+func text(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseTime parses a time from a block, trying RFC 3339 and the default
+// layouts before the given ones.  Times without a time zone are in loc, or UTC
+// if it's nil.  It also returns whether the time is a date only.
+func parseTime(val interface{}, layouts []string, loc *time.Location) (time.Time, bool, bool) {
+	if t, ok := val.(time.Time); ok {
+		return t, false, true
+	}
+	s := strings.TrimSpace(text(val))
+	if s == "" {
+		return time.Time{}, false, false
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range append(append([]string{}, defaultLayouts...), layouts...) {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		return t, isDateLayout(layout), true
+	}
+	return time.Time{}, false, false
+}
+
+// isDateLayout returns whether the given layout has no hours or minutes.
+func isDateLayout(layout string) bool {
+	for _, elem := range []string{"15", "3", "04"} {
+		if strings.Contains(layout, elem) {
+			return false
+		}
+	}
+	return true
+}
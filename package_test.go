@@ -2,13 +2,28 @@ package scrape_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/andrew-d/goscrape"
 	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/paginate"
+	"github.com/andrew-d/goscrape/store"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -64,6 +79,1403 @@ func TestPageLimits(t *testing.T) {
 	}, results.URLs)
 }
 
+func TestStopCondition(t *testing.T) {
+	var seen []int
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: newDummyFetcher([][]byte{
+			[]byte("one"),
+			[]byte("two"),
+			[]byte("three"),
+			[]byte("four"),
+		}),
+
+		Paginator: &dummyPaginator{},
+
+		Pieces: []scrape.Piece{
+			{Name: "dummy", Selector: ".", Extractor: extract.Const{Val: "asdf"}},
+		},
+
+		StopCondition: func(p *scrape.Page) bool {
+			seen = append(seen, p.Index)
+			return p.URL == "url-1"
+		},
+	})
+
+	results, err := sc.Scrape("initial")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"initial", "url-1"}, results.URLs)
+	assert.Equal(t, 2, len(results.Results))
+	assert.Equal(t, []int{0, 1}, seen)
+}
+
+func TestDedup(t *testing.T) {
+	st := store.NewMemory()
+	pages := [][]byte{
+		[]byte(`<ul><li>a</li><li>b</li></ul>`),
+		[]byte(`<ul><li>c</li><li>b</li></ul>`),
+		[]byte(`<ul><li>d</li></ul>`),
+	}
+	config := &scrape.ScrapeConfig{
+		Fetcher:    newDummyFetcher(pages),
+		Paginator:  &dummyPaginator{},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "id", Selector: ".", Extractor: extract.Text{}},
+		},
+		Dedup: &scrape.DedupConfig{Store: st, Key: "id"},
+	}
+
+	// Duplicates within a single scrape are removed.
+	results, err := mustNew(config).ScrapeWithOpts("initial", scrape.ScrapeOptions{MaxPages: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": "a"}, {"id": "b"}, {"id": "c"},
+	}, results.AllBlocks())
+
+	// A second scrape skips everything already seen, and stops paginating.
+	config.Fetcher = newDummyFetcher(pages)
+	config.Dedup.StopOnSeen = true
+	results, err = mustNew(config).Scrape("initial")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"initial"}, results.URLs)
+	assert.Equal(t, []map[string]interface{}{}, results.AllBlocks())
+
+	_, err = scrape.New(&scrape.ScrapeConfig{
+		Pieces: config.Pieces,
+		Dedup:  &scrape.DedupConfig{Store: st, Key: "bad"},
+	})
+	assert.Error(t, err)
+}
+
+func TestConcurrentPieces(t *testing.T) {
+	slow := &slowExtractor{}
+	limited := scrape.WithConcurrencyLimit(2, slow)
+
+	pieces := []scrape.Piece{}
+	for i := 0; i < 6; i++ {
+		pieces = append(pieces, scrape.Piece{
+			Name: fmt.Sprintf("p%d", i), Selector: ".", Extractor: limited,
+		})
+	}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:          newDummyFetcher([][]byte{[]byte("one")}),
+		Pieces:           pieces,
+		ConcurrentPieces: 4,
+	})
+
+	results, err := sc.Scrape("initial")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(results.First()))
+	assert.Equal(t, "slow", results.First()["p5"])
+	assert.Equal(t, 2, slow.max)
+
+	// The limited extractor is validated.
+	_, err = scrape.New(&scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{
+			{Name: "bad", Selector: ".", Extractor: scrape.WithConcurrencyLimit(2, extract.Attr{})},
+		},
+	})
+	assert.EqualError(t, err, "invalid extractor for piece 0: no attribute provided")
+}
+
+type slowExtractor struct {
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (e *slowExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	e.mu.Lock()
+	e.inFlight++
+	if e.inFlight > e.max {
+		e.max = e.inFlight
+	}
+	e.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	e.mu.Lock()
+	e.inFlight--
+	e.mu.Unlock()
+	return "slow", nil
+}
+
+func TestCheckpointResume(t *testing.T) {
+	st := store.NewMemory()
+	fetcher := newDummyFetcher([][]byte{
+		[]byte("one"),
+		[]byte("two"),
+	})
+	config := &scrape.ScrapeConfig{
+		Fetcher:   fetcher,
+		Paginator: &dummyPaginator{},
+		Pieces: []scrape.Piece{
+			{Name: "dummy", Selector: ".", Extractor: extract.Text{}},
+			{Name: "missing", Selector: "b", Extractor: extract.Text{}},
+		},
+		Checkpoint:     &scrape.CheckpointConfig{Store: st, Key: "test"},
+		FailureSamples: 5,
+	}
+	sc := mustNew(config)
+
+	// The fetcher runs out of data on the third page.
+	_, err := sc.Scrape("initial")
+	assert.Error(t, err)
+
+	cp, err := sc.LoadCheckpoint()
+	assert.NoError(t, err)
+	if assert.NotNil(t, cp) {
+		assert.Equal(t, "url-2", cp.NextURL)
+		assert.Equal(t, 2, cp.PagesDone)
+		assert.True(t, cp.Results.Duration > 0)
+	}
+
+	// The time taken before the checkpoint is included in the duration.
+	cp.Results.Duration = time.Hour
+	fetcher.data = append(fetcher.data, []byte("three"))
+	results, err := sc.ResumeWithOpts(cp, scrape.ScrapeOptions{MaxPages: 3})
+	assert.NoError(t, err)
+	assert.True(t, results.Duration > time.Hour, results.Duration)
+
+	// So are the failures from before the checkpoint.
+	if failures := results.Failures["missing"]; assert.NotNil(t, failures) {
+		assert.Equal(t, 3, failures.Count)
+		urls := []string{}
+		for _, sample := range failures.Samples {
+			urls = append(urls, sample.URL)
+		}
+		assert.Equal(t, []string{"initial", "url-1", "url-2"}, urls)
+	}
+	assert.Equal(t, []string{"initial", "url-1", "url-2"}, results.URLs)
+	assert.Equal(t, "three", results.Results[2][0]["dummy"])
+
+	// The checkpoint is removed once the scrape succeeds.
+	cp, err = sc.LoadCheckpoint()
+	assert.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestDrain(t *testing.T) {
+	st := store.NewMemory()
+	fetcher := &gatedFetcher{
+		dummyFetcher: newDummyFetcher([][]byte{[]byte("one"), []byte("two")}),
+		fetching:     make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:   fetcher,
+		Paginator: &dummyPaginator{},
+		Pieces: []scrape.Piece{
+			{Name: "dummy", Selector: ".", Extractor: extract.Text{}},
+		},
+		Checkpoint: &scrape.CheckpointConfig{Store: st, Key: "test"},
+	})
+
+	type result struct {
+		res *scrape.ScrapeResults
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		res, err := sc.Scrape("initial")
+		done <- result{res, err}
+	}()
+
+	// Start draining while the first page is being fetched.
+	<-fetcher.fetching
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, sc.Drain(ctx))
+	close(fetcher.release)
+
+	r := <-done
+	assert.Equal(t, scrape.ErrDrained, r.err)
+	assert.Equal(t, []string{"initial"}, r.res.URLs)
+	assert.NoError(t, sc.Drain(context.Background()))
+
+	cp, err := sc.LoadCheckpoint()
+	assert.NoError(t, err)
+	if assert.NotNil(t, cp) {
+		assert.Equal(t, "url-1", cp.NextURL)
+	}
+
+	_, err = sc.Scrape("initial")
+	assert.Equal(t, scrape.ErrDrained, err)
+}
+
+func TestScrapeAll(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{
+			"a":   `<p>a</p>`,
+			"a-2": `<p>a2</p>`,
+			"b":   `<p>b</p>`,
+			"c":   `<p>c</p>`,
+		},
+		Paginator: mapPaginator{"a": "a-2"},
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: "p", Extractor: extract.Text{}},
+		},
+	})
+
+	for _, parallelism := range []int{0, 3} {
+		results, err := sc.ScrapeAllWithOpts(
+			[]string{"a", "b", "c"},
+			scrape.ScrapeOptions{Parallelism: parallelism},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "a-2", "b", "c"}, results.URLs)
+		assert.Equal(t, []string{"a", "a", "b", "c"}, results.StartURLs)
+		assert.Equal(t, "a2", results.Results[1][0]["text"])
+	}
+
+	// The results of the scrapes before a failure are still returned.
+	results, err := sc.ScrapeAll([]string{"a", "missing", "c"})
+	assert.EqualError(t, err, "unknown URL: missing")
+	if assert.NotNil(t, results) {
+		assert.Equal(t, []string{"a", "a-2"}, results.URLs)
+	}
+
+	// Parallel scrapes can't share a checkpoint.
+	sc = mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{},
+		Pieces:     []scrape.Piece{{Name: "text", Selector: "p", Extractor: extract.Text{}}},
+		Checkpoint: &scrape.CheckpointConfig{Store: store.NewMemory(), Key: "all"},
+	})
+	_, err = sc.ScrapeAllWithOpts([]string{"a"}, scrape.ScrapeOptions{Parallelism: 2})
+	assert.EqualError(t, err, "checkpoints can't be used with parallel scrapes")
+
+	// Nor can sequential scrapes, since the checkpoint doesn't record the
+	// remaining start URLs.
+	_, err = sc.ScrapeAll([]string{"a", "b"})
+	assert.EqualError(t, err, "checkpoints can't be used with more than one start URL")
+}
+
+func TestScrapeAllParallelHttp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<p>%s</p>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	// The default fetcher is only prepared once, rather than by every scrape
+	// at the same time, which the race detector would catch.
+	sc := mustNew(&scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{
+			{Name: "path", Selector: "p", Extractor: extract.Text{}},
+		},
+	})
+
+	urls := []string{}
+	for i := 0; i < 8; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", srv.URL, i))
+	}
+	results, err := sc.ScrapeAllWithOpts(urls, scrape.ScrapeOptions{Parallelism: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, urls, results.URLs)
+	assert.Equal(t, "/7", results.Results[7][0]["path"])
+}
+
+func TestShardURLs(t *testing.T) {
+	urls := []string{}
+	for i := 0; i < 100; i++ {
+		urls = append(urls, fmt.Sprintf("http://example.com/%d", i))
+	}
+
+	// Every URL is in exactly one shard, and all shards are used.
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		shard := scrape.ShardURLs(urls, i, 4)
+		assert.NotEmpty(t, shard)
+		for _, url := range shard {
+			seen[url]++
+		}
+	}
+	assert.Len(t, seen, len(urls))
+	for _, count := range seen {
+		assert.Equal(t, 1, count)
+	}
+
+	// Adding a shard only moves URLs to the new shard.
+	for _, url := range urls {
+		if after := scrape.ShardFor(url, 5); after != 4 {
+			assert.Equal(t, scrape.ShardFor(url, 4), after)
+		}
+	}
+}
+
+func TestScrapeAllSharded(t *testing.T) {
+	fetcher := mapFetcher{}
+	urls := []string{}
+	for i := 0; i < 10; i++ {
+		url := fmt.Sprintf("u%d", i)
+		fetcher[url] = "<p>" + url + "</p>"
+		urls = append(urls, url)
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: "p", Extractor: extract.Text{}},
+		},
+	})
+
+	all := []string{}
+	for i := 0; i < 3; i++ {
+		results, err := sc.ScrapeAllWithOpts(urls, scrape.ScrapeOptions{ShardCount: 3, ShardIndex: i})
+		assert.NoError(t, err)
+		assert.Equal(t, scrape.ShardURLs(urls, i, 3), results.URLs)
+		all = append(all, results.URLs...)
+	}
+	assert.ElementsMatch(t, urls, all)
+
+	_, err := sc.ScrapeAllWithOpts(urls, scrape.ScrapeOptions{ShardCount: 3, ShardIndex: 3})
+	assert.EqualError(t, err, "invalid shard 3 of 3")
+}
+
+func TestReloadable(t *testing.T) {
+	fetcher := mapFetcher{"a": `<p>text</p><b>bold</b>`}
+	r, err := scrape.NewReloadable(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "value", Selector: "p", Extractor: extract.Text{}},
+		},
+	})
+	assert.NoError(t, err)
+
+	results, err := r.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "text", results.First()["value"])
+
+	// An invalid config is rejected, and the old one is kept.
+	assert.Error(t, r.Reload(&scrape.ScrapeConfig{Fetcher: fetcher}))
+
+	err = r.Reload(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "value", Selector: "b", Extractor: extract.Text{}},
+		},
+	})
+	assert.NoError(t, err)
+
+	results, err = r.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "bold", results.First()["value"])
+}
+
+func TestReloadableWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "selector")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("p"), 0600))
+
+	// The config's selector is read from a file in the directory.
+	var logs lockedBuffer
+	fetcher := mapFetcher{"a": `<p>text</p><b>bold</b>`}
+	load := func(dir string) (*scrape.ScrapeConfig, error) {
+		sel, err := ioutil.ReadFile(filepath.Join(dir, "selector"))
+		if err != nil {
+			return nil, err
+		}
+		return &scrape.ScrapeConfig{
+			Fetcher: fetcher,
+			Pieces: []scrape.Piece{
+				{Name: "value", Selector: string(sel), Extractor: extract.Text{}},
+			},
+			Logger: log.New(&logs, "", 0),
+		}, nil
+	}
+	c, err := load(dir)
+	assert.NoError(t, err)
+	r, err := scrape.NewReloadable(c)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.WatchDir(ctx, dir, time.Millisecond, load) }()
+
+	value := func() interface{} {
+		results, err := r.Scrape("a")
+		assert.NoError(t, err)
+		return results.First()["value"]
+	}
+	// Since the watch may not have started yet, the file is written again
+	// until the change is seen.
+	changeUntil := func(sel string, cond func() bool) {
+		for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+			if time.Since(start) > 5*time.Second {
+				t.Fatal("timed out")
+			}
+			assert.NoError(t, ioutil.WriteFile(path, []byte(sel), 0600))
+		}
+	}
+
+	// Changing the file reloads the config.
+	changeUntil("b", func() bool { return value() == "bold" })
+
+	// An invalid config is logged, and the old one is kept.
+	changeUntil("div[", func() bool { return strings.Contains(logs.String(), "reloading config") })
+	assert.Equal(t, "bold", value())
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	assert.Error(t, r.WatchDir(context.Background(), filepath.Join(dir, "missing"), time.Millisecond, load))
+	assert.Error(t, r.WatchDir(context.Background(), dir, 0, load))
+}
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestValidate(t *testing.T) {
+	config := &scrape.ScrapeConfig{}
+	assert.Equal(t, scrape.ErrNoPieces, config.Validate())
+
+	config = &scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{
+			{Name: "ok", Selector: "p", Extractor: extract.Text{}},
+			{Name: "ok", Selector: "div[", Extractor: extract.Text{}},
+			{Name: "", Selector: "", Extractor: extract.Regex{Regex: regexp.MustCompile("foo")}},
+			{Name: "attr", Selector: ".", Extractor: extract.Attr{}},
+			{Name: "noextractor", Selector: "."},
+		},
+	}
+	err := config.Validate()
+	if assert.IsType(t, &scrape.ValidationError{}, err) {
+		assert.Equal(t, 7, len(err.(*scrape.ValidationError).Problems))
+	}
+
+	_, err = scrape.New(config)
+	assert.Error(t, err)
+}
+
+func TestDryRun(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<ul><li><b>one</b></li><li><b>two</b><b>three</b></li><li></li></ul>`},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "bold", Selector: "b", Extractor: extract.Text{}},
+			{Name: "missing", Selector: "i", Extractor: extract.Text{}},
+		},
+	})
+
+	report, err := sc.DryRun("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Blocks)
+	assert.Equal(t, "<li><b>one</b></li>", report.BlockSnippet)
+	assert.Equal(t, scrape.PieceReport{
+		Name: "bold", Selector: "b", Matches: 3, BlocksMatched: 2, Snippet: "<b>one</b>",
+	}, report.Pieces[0])
+	assert.Equal(t, 0, report.Pieces[1].Matches)
+	assert.Equal(t, "", report.Pieces[1].Snippet)
+}
+
+func TestWriteGraph(t *testing.T) {
+	config := &scrape.ScrapeConfig{
+		Paginator:  &dummyPaginator{},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: `a[title="x"]`, Extractor: extract.Text{}},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := config.WriteGraph(&buf, scrape.DOT, scrape.GraphOptions{
+		StartURLs: []string{"http://example.com"},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `start0 [label="Start\nhttp://example.com"];`)
+	assert.Contains(t, buf.String(), `piece0 [label="title\na[title=\"x\"]\nextract.Text"];`)
+	assert.Contains(t, buf.String(), `paginate -> fetch [label="next page"];`)
+
+	buf.Reset()
+	err = config.WriteGraph(&buf, scrape.Mermaid, scrape.GraphOptions{
+		Crawl: &scrape.CrawlConfig{},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "flowchart TD\n")
+	assert.Contains(t, buf.String(), `piece0["title<br/>a[title=#quot;x#quot;]<br/>extract.Text"]`)
+	assert.Contains(t, buf.String(), `follow -->|"follow"| fetch`)
+}
+
+func TestPieceLimits(t *testing.T) {
+	fetcher := mapFetcher{"a": `<p>abcdéfgh</p><a>1</a><a>2</a><a>3</a><i class="x y z">ok</i>`}
+	pieces := func(truncate bool) []scrape.Piece {
+		return []scrape.Piece{
+			{Name: "text", Selector: "p", Extractor: extract.Text{},
+				Limits: &scrape.PieceLimits{MaxLength: 5, Truncate: truncate}},
+			{Name: "items", Selector: "a", Extractor: extract.MultipleText{},
+				Limits: &scrape.PieceLimits{MaxItems: 2, Truncate: truncate}},
+			{Name: "classes", Selector: "i", Extractor: extract.GroupCount{Attr: "class"},
+				Limits: &scrape.PieceLimits{MaxItems: 2, Truncate: true}},
+			{Name: "short", Selector: "i", Extractor: extract.Text{},
+				Limits: &scrape.PieceLimits{MaxLength: 5}},
+		}
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{Fetcher: fetcher, Pieces: pieces(true)})
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"text":    "abcdé",
+		"items":   []string{"1", "2"},
+		"classes": map[string]int{"x": 1, "y": 1},
+		"short":   "ok",
+	}, results.First())
+
+	sc = mustNew(&scrape.ScrapeConfig{Fetcher: fetcher, Pieces: pieces(false)})
+	_, err = sc.Scrape("a")
+	assert.EqualError(t, err, `piece "text" has 8 characters, over the limit of 5`)
+	if le, ok := err.(*scrape.LimitError); assert.True(t, ok) {
+		assert.Equal(t, "characters", le.What)
+	}
+
+	_, err = scrape.New(&scrape.ScrapeConfig{Pieces: []scrape.Piece{
+		{Name: "a", Selector: "a", Extractor: extract.Text{}, Limits: &scrape.PieceLimits{MaxItems: -1}},
+	}})
+	assert.EqualError(t, err, "piece 0 has negative limits")
+}
+
+func TestStrictMode(t *testing.T) {
+	toInt := func(v interface{}) (interface{}, error) { return len(v.(string)), nil }
+	config := &scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<b>one</b><a href="/1">1</a><a href="/2">2</a>`},
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: "b", Extractor: extract.Text{}},
+			{Name: "links", Selector: "a", Extractor: extract.Attr{Attr: "href"}},
+			{Name: "length", Selector: "b", Extractor: extract.Text{}, Transform: toInt,
+				Schema: map[string]interface{}{"type": "integer"}},
+			{Name: "any", Selector: "b", Extractor: extract.Text{}, Transform: toInt},
+		},
+		Strict: true,
+	}
+	results, err := mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, results.First()["length"])
+
+	// A value of the wrong type aborts the scrape.
+	config.Pieces[2].Schema = map[string]interface{}{"type": "string"}
+	_, err = mustNew(config).Scrape("a")
+	assert.EqualError(t, err, `piece "length": expected string, got int`)
+
+	config.Pieces[2].Schema = nil
+	config.Pieces[1].Transform = func(v interface{}) (interface{}, error) { return []interface{}{"/1", 2}, nil }
+	config.Pieces[1].Schema = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	_, err = mustNew(config).Scrape("a")
+	if te, ok := err.(*scrape.PieceTypeError); assert.True(t, ok, err) {
+		assert.Equal(t, "links", te.Piece)
+		assert.Equal(t, "[1]", te.Path)
+		assert.Equal(t, "string", te.Expected)
+		assert.Equal(t, "int", te.Got)
+	}
+
+	// Outside strict mode, values aren't checked.
+	config.Strict = false
+	_, err = mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+}
+
+func TestBlockID(t *testing.T) {
+	st := store.NewMemory()
+	pages := [][]byte{
+		[]byte(`<ul><li>a</li><li>b</li></ul>`),
+		[]byte(`<ul><li>a</li><li>c</li></ul>`),
+	}
+	config := &scrape.ScrapeConfig{
+		Fetcher:    newDummyFetcher(pages),
+		Paginator:  &dummyPaginator{},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: ".", Extractor: extract.Text{}},
+		},
+		BlockID: &scrape.BlockIDConfig{Generator: &scrape.SequenceBlockID{Prefix: "item-"}},
+	}
+	results, err := mustNew(config).ScrapeWithOpts("initial", scrape.ScrapeOptions{MaxPages: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"text": "a", "_id": "item-1"},
+		{"text": "b", "_id": "item-2"},
+		{"text": "a", "_id": "item-3"},
+		{"text": "c", "_id": "item-4"},
+	}, results.AllBlocks())
+
+	// Hashed IDs are the same for the same content, so can be used to
+	// deduplicate blocks.
+	config.Fetcher = newDummyFetcher(pages)
+	config.BlockID = &scrape.BlockIDConfig{Name: "key", Generator: scrape.HashBlockID("text")}
+	config.Dedup = &scrape.DedupConfig{Store: st, Key: "key"}
+	results, err = mustNew(config).ScrapeWithOpts("initial", scrape.ScrapeOptions{MaxPages: 2})
+	assert.NoError(t, err)
+	blocks := results.AllBlocks()
+	if assert.Equal(t, 3, len(blocks)) {
+		assert.Equal(t, "c", blocks[2]["text"])
+		assert.Equal(t, 32, len(blocks[0]["key"].(string)))
+		assert.NotEqual(t, blocks[0]["key"], blocks[1]["key"])
+	}
+
+	id, err := scrape.UUIDBlockID().BlockID(scrape.ExtractContext{}, nil)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+
+	// The ID can't replace a Piece.
+	_, err = scrape.New(&scrape.ScrapeConfig{
+		Pieces:  config.Pieces,
+		BlockID: &scrape.BlockIDConfig{Name: "text", Generator: scrape.UUIDBlockID()},
+	})
+	assert.EqualError(t, err, `block ID name "text" is the name of a piece`)
+}
+
+// watchSink counts the scrapes that it is told about, and calls stop after
+// the given number.
+type watchSink struct {
+	pages, failures, flushes int
+	stopAfter                int
+	stop                     func()
+}
+
+func (s *watchSink) Write(p *scrape.Page) error { s.pages++; return nil }
+func (s *watchSink) Failed(err error)           { s.failures++ }
+
+func (s *watchSink) Flush() error {
+	if s.flushes++; s.flushes == s.stopAfter {
+		s.stop()
+	}
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &watchSink{stopAfter: 3, stop: cancel}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<b>one</b>`},
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: "b", Extractor: extract.Text{}},
+		},
+		Sink: sink,
+	})
+	err := sc.Watch(ctx, "a", time.Millisecond)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 3, sink.pages)
+	assert.Equal(t, 0, sink.failures)
+
+	// Failed scrapes don't stop the watch.
+	ctx, cancel = context.WithCancel(context.Background())
+	*sink = watchSink{stopAfter: 2, stop: cancel}
+	err = sc.Watch(ctx, "missing", time.Millisecond)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 2, sink.failures)
+
+	// Draining the Scraper does.
+	assert.NoError(t, sc.Drain(context.Background()))
+	assert.Equal(t, scrape.ErrDrained, sc.Watch(context.Background(), "a", time.Millisecond))
+
+	assert.Error(t, sc.Watch(context.Background(), "a", 0))
+}
+
+func TestJSONSchema(t *testing.T) {
+	config := &scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{
+			{Name: "title", Selector: "h1", Extractor: extract.Text{}},
+			{Name: "tags", Selector: "a", Extractor: extract.Attr{Attr: "href", AlwaysReturnList: true}},
+			{Name: "comments", Selector: "li", Extractor: extract.Count{}},
+			{Name: "score", Selector: ".score", Extractor: extract.Text{},
+				Transform: func(v interface{}) (interface{}, error) { return len(v.(string)), nil }},
+			{Name: "price", Selector: ".price", Extractor: extract.Text{},
+				Transform: func(v interface{}) (interface{}, error) { return v, nil },
+				Schema:    map[string]interface{}{"type": "number"}},
+		},
+	}
+
+	schema, err := json.Marshal(config.BlockSchema())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"title": {"type": "string"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"comments": {"type": "integer"},
+			"score": {},
+			"price": {"type": "number"}
+		}
+	}`, string(schema))
+
+	full := config.JSONSchema()
+	assert.Equal(t, scrape.JSONSchemaVersion, full["$schema"])
+	assert.Equal(t, []string{"URLs", "Results"}, full["required"])
+	results := full["properties"].(map[string]interface{})["Results"].(map[string]interface{})
+	assert.Equal(t, config.BlockSchema(), results["items"].(map[string]interface{})["items"])
+}
+
+func TestTypeSchema(t *testing.T) {
+	type item struct {
+		Name    string    `json:"name"`
+		Tags    []string  `json:"tags,omitempty"`
+		Price   *float64  `json:"price"`
+		Seen    time.Time `json:"seen"`
+		Hidden  string    `json:"-"`
+		Counts  map[string]int
+		private int
+	}
+
+	schema, err := json.Marshal(scrape.TypeSchema(reflect.TypeOf([]item{})))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "array",
+		"items": {
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"price": {"type": "number"},
+				"seen": {"type": "string", "format": "date-time"},
+				"Counts": {"type": "object", "additionalProperties": {"type": "integer"}}
+			}
+		}
+	}`, string(schema))
+}
+
+func TestNewScraper(t *testing.T) {
+	var logs bytes.Buffer
+	sc, err := scrape.NewScraper(
+		scrape.WithFetcher(newDummyFetcher([][]byte{
+			[]byte("one"),
+			[]byte("two"),
+			[]byte("three"),
+		})),
+		scrape.WithPaginator(&dummyPaginator{}),
+		scrape.WithPieces(
+			scrape.Piece{Name: "dummy", Selector: ".", Extractor: extract.Text{}},
+		),
+		scrape.WithMaxPages(2),
+		scrape.WithLogger(log.New(&logs, "", 0)),
+	)
+	assert.NoError(t, err)
+
+	results, err := sc.Scrape("initial")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"initial", "url-1"}, results.URLs)
+	assert.Equal(t, "fetching initial\nfetching url-1\n", logs.String())
+
+	_, err = scrape.NewScraper(scrape.WithMaxPages(2))
+	assert.Equal(t, scrape.ErrNoPieces, err)
+}
+
+func TestNewScraperLimits(t *testing.T) {
+	sc, err := scrape.NewScraper(
+		scrape.WithFetcher(mapFetcher{"a": "1", "a-2": "2", "a-3": "3"}),
+		scrape.WithPaginator(mapPaginator{"a": "a-2", "a-2": "a-3"}),
+		scrape.WithPieces(
+			scrape.Piece{Name: "dummy", Selector: ".", Extractor: extract.Text{}},
+		),
+		scrape.WithMaxPages(1),
+		scrape.WithLimits(scrape.ScrapeOptions{MaxPages: 2}),
+	)
+	assert.NoError(t, err)
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "a-2"}, results.URLs)
+}
+
+func TestFailureSamples(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<ul><li><b>1</b></li><li>2</li><li>3</li><li>4</li></ul>`},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "bold", Selector: "b", Extractor: extract.Text{}},
+			{Name: "text", Selector: ".", Extractor: extract.Text{}},
+		},
+		FailureSamples: 2,
+	})
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*scrape.PieceFailures{
+		"bold": {
+			Count: 3,
+			Samples: []scrape.FailureSample{
+				{URL: "a", Block: 1, Snippet: "<li>2</li>"},
+				{URL: "a", Block: 2, Snippet: "<li>3</li>"},
+			},
+		},
+	}, results.Failures)
+}
+
+func TestBuilder(t *testing.T) {
+	b := scrape.Builder().
+		Fetcher(mapFetcher{"a": `<ul><li><b>1</b><i>x</i></li><li><b>2</b></li></ul>`, "a-2": ``}).
+		Divide("li").
+		Piece("bold", "b", extract.Text{}).
+		Piece("italic", "i", extract.Text{}).
+		Paginate(mapPaginator{"a": "a-2"})
+
+	config, err := b.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(config.Pieces))
+
+	sc, err := b.New()
+	assert.NoError(t, err)
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "a-2"}, results.URLs)
+	assert.Equal(t, map[string]interface{}{"bold": "1", "italic": "x"}, results.First())
+
+	_, err = b.Piece("bold", "p", extract.Text{}).Build()
+	assert.Error(t, err)
+}
+
+func TestRewrite(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<div>&lt;b&gt;hidden&lt;/b&gt;</div><noscript><i>real</i></noscript>`},
+		Pieces: []scrape.Piece{
+			{Name: "bold", Selector: "b", Extractor: extract.Text{}},
+			{Name: "italic", Selector: "i", Extractor: extract.Text{}},
+		},
+		Rewrite: []scrape.RewriteFunc{
+			scrape.RewriteReplace("&lt;", "<"),
+			scrape.RewriteReplace("&gt;", ">"),
+			scrape.RewriteRegex(regexp.MustCompile(`</?noscript>`), ""),
+		},
+	})
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"bold": "hidden", "italic": "real"}, results.First())
+}
+
+func TestExtractFromReader(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		DividePage: scrape.DividePageBySelector("tr, item"),
+		Pieces: []scrape.Piece{
+			{Name: "cell", Selector: "td", Extractor: extract.Text{}},
+			{Name: "link", Selector: "link", Extractor: extract.Text{}},
+		},
+	})
+
+	page, err := sc.ExtractFromReader("a", strings.NewReader(`<tr><td>one</td></tr><tr><td>two</td></tr>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "a", page.URL)
+	assert.Equal(t, []map[string]interface{}{
+		{"cell": "one", "link": ""},
+		{"cell": "two", "link": ""},
+	}, page.Blocks)
+
+	page, err = sc.ExtractFromReader("b", strings.NewReader(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss><channel><Item><link>http://example.com/1</link></Item><item><link><![CDATA[http://example.com/2]]></link></item></channel></rss>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"cell": "", "link": "http://example.com/1"},
+		{"cell": "", "link": "http://example.com/2"},
+	}, page.Blocks)
+
+	// An XML <body> element doesn't change where later elements go.
+	page, err = sc.ExtractFromReader("b", strings.NewReader(`<?xml version="1.0"?>
+<feed><item><body>one</body><link>http://example.com/1</link></item><item><link>http://example.com/2</link></item></feed>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"cell": "", "link": "http://example.com/1"},
+		{"cell": "", "link": "http://example.com/2"},
+	}, page.Blocks)
+
+	page, err = sc.ExtractFromReader("c", strings.NewReader(`<!DOCTYPE html><html><body><table><tr><td>doc</td></tr></table></body></html>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"cell": "doc", "link": ""}}, page.Blocks)
+}
+
+func TestPrepareRequestContext(t *testing.T) {
+	var mu sync.Mutex
+	referers := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		referers = append(referers, r.Header.Get("Referer")+"|"+r.Header.Get("X-Job"))
+		mu.Unlock()
+
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a class="next" href="/2">next</a>`)
+		} else {
+			fmt.Fprint(w, `last`)
+		}
+	}))
+	defer srv.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	fetcher.PrepareRequest = func(req *http.Request) error {
+		info, ok := scrape.RequestInfoFromContext(req.Context())
+		if !ok {
+			return errors.New("no request info")
+		}
+		req.Header.Set("Referer", info.PreviousURL)
+		req.Header.Set("X-Job", fmt.Sprintf("%s-%d", info.Tags["job"], info.PageIndex))
+		return nil
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:   fetcher,
+		Paginator: paginate.BySelector("a.next", "href"),
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: ".", Extractor: extract.Text{}},
+		},
+		Tags: map[string]string{"job": "test"},
+	})
+
+	_, err = sc.Scrape(srv.URL + "/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"|test-0", srv.URL + "/|test-1"}, referers)
+}
+
+func TestAutoReferer(t *testing.T) {
+	var mu sync.Mutex
+	referers := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		referers[r.URL.Path] = r.Header.Get("Referer")
+		mu.Unlock()
+
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/detail">detail</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	fetcher.AutoReferer = true
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: fetcher,
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: ".", Extractor: extract.Text{}},
+		},
+	})
+
+	_, err = sc.Crawl(srv.URL+"/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"/": "", "/detail": srv.URL + "/"}, referers)
+}
+
+func TestWithCache(t *testing.T) {
+	calls := 0
+	expensive := scrape.ExtractorFunc(func(sel *goquery.Selection) (interface{}, error) {
+		calls++
+		return []string{strings.ToUpper(sel.Text())}, nil
+	})
+
+	st := store.NewMemory()
+	config := &scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<b>one</b><b>two</b><b>one</b>`},
+		DividePage: scrape.DividePageBySelector("b"),
+		Pieces: []scrape.Piece{
+			{Name: "upper", Selector: ".", Extractor: scrape.WithCache(st, "upper-v1", expensive)},
+		},
+	}
+
+	results, err := mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []string{"ONE"}, results.First()["upper"])
+	assert.Equal(t, []interface{}{"ONE"}, results.AllBlocks()[2]["upper"])
+
+	_, err = mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	config.Pieces[0].Extractor = scrape.WithCache(nil, "upper-v1", expensive)
+	assert.EqualError(t, config.Validate(), "invalid extractor for piece 0: no store provided for cache")
+}
+
+func TestUsage(t *testing.T) {
+	st := store.NewMemory()
+	config := &scrape.ScrapeConfig{
+		Fetcher:   mapFetcher{"http://a.com/1": "12345", "http://a.com/2": "123", "http://b.com/1": "1"},
+		Paginator: mapPaginator{"http://a.com/1": "http://a.com/2", "http://a.com/2": "http://b.com/1"},
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: ".", Extractor: extract.Text{}},
+		},
+		Usage: &scrape.UsageConfig{Store: st, Key: "job"},
+	}
+
+	for i := 0; i < 2; i++ {
+		results, err := mustNew(config).Scrape("http://a.com/1")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, results.Usage["a.com"].Requests)
+		assert.Equal(t, int64(8), results.Usage["a.com"].Bytes)
+		assert.Equal(t, 1, results.Usage["b.com"].Requests)
+	}
+
+	totals, err := scrape.LoadUsage(st, "job")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, totals["a.com"].Requests)
+	assert.Equal(t, int64(16), totals["a.com"].Bytes)
+	assert.Equal(t, int64(2), totals["b.com"].Bytes)
+
+	config.Usage.Key = ""
+	assert.EqualError(t, config.Validate(), "no key provided for usage totals")
+}
+
+type provenanceExtractor struct{}
+
+func (e provenanceExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	return "no context", nil
+}
+
+func (e provenanceExtractor) ExtractWithContext(ctx scrape.ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	if ctx.FetchedAt.IsZero() {
+		return nil, errors.New("no fetch time")
+	}
+	return fmt.Sprintf("%s#%d.%d", ctx.URL, ctx.PageIndex, ctx.BlockIndex), nil
+}
+
+func TestContextualExtractor(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<b>1</b><b>2</b>`, "a-2": `<b>3</b>`},
+		Paginator:  mapPaginator{"a": "a-2"},
+		DividePage: scrape.DividePageBySelector("b"),
+		Pieces: []scrape.Piece{
+			{Name: "from", Selector: ".", Extractor: provenanceExtractor{}},
+			{Name: "limited", Selector: ".", Extractor: scrape.WithConcurrencyLimit(1, provenanceExtractor{})},
+		},
+	})
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"from": "a#0.0", "limited": "a#0.0"},
+		{"from": "a#0.1", "limited": "a#0.1"},
+		{"from": "a-2#1.0", "limited": "a-2#1.0"},
+	}, results.AllBlocks())
+}
+
+func TestExtractorFunc(t *testing.T) {
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<b>shout</b>`},
+		Pieces: []scrape.Piece{
+			{Name: "bold", Selector: "b", Extractor: scrape.ExtractorFunc(func(sel *goquery.Selection) (interface{}, error) {
+				return strings.ToUpper(sel.Text()), nil
+			})},
+		},
+	})
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"bold": "SHOUT"}, results.First())
+}
+
+func TestComputedPieces(t *testing.T) {
+	config := &scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<li><a href="/x">x</a><i>5</i><u>2</u></li><li><i>1</i></li>`},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "link", Selector: "a", Extractor: extract.Attr{Attr: "href", OmitIfEmpty: true}},
+			{Name: "url", Compute: func(block map[string]interface{}) (interface{}, error) {
+				if link, ok := block["link"].(string); ok {
+					return "http://example.com" + link, nil
+				}
+				return nil, nil
+			}},
+			{Name: "up", Selector: "i", Extractor: extract.Text{}},
+			{Name: "down", Selector: "u", Extractor: extract.Text{}},
+			{Name: "score", Compute: func(block map[string]interface{}) (interface{}, error) {
+				return fmt.Sprintf("%s-%s", block["up"], block["down"]), nil
+			}, Transform: func(v interface{}) (interface{}, error) {
+				return strings.TrimSuffix(v.(string), "-"), nil
+			}},
+		},
+	}
+
+	results, err := mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"link": "/x", "url": "http://example.com/x", "up": "5", "down": "2", "score": "5-2"},
+		{"up": "1", "down": "", "score": "1"},
+	}, results.AllBlocks())
+
+	config.Pieces[1].Selector = "a"
+	assert.EqualError(t, config.Validate(), "computed piece 1 must not have a selector or extractor")
+}
+
+func TestPieceTransform(t *testing.T) {
+	config := &scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<b> HELLO </b><i>skip</i>`},
+		Pieces: []scrape.Piece{
+			{Name: "bold", Selector: "b", Extractor: extract.Text{}, Transform: func(v interface{}) (interface{}, error) {
+				return strings.ToLower(strings.TrimSpace(v.(string))), nil
+			}},
+			{Name: "italic", Selector: "i", Extractor: extract.Text{}, Transform: func(v interface{}) (interface{}, error) {
+				return nil, nil
+			}},
+			{Name: "missing", Selector: "u", Extractor: extract.Attr{Attr: "x", AlwaysReturnList: true}, Transform: func(v interface{}) (interface{}, error) {
+				return len(v.([]string)), nil
+			}},
+		},
+	}
+
+	results, err := mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"bold": "hello", "missing": 0}, results.First())
+
+	config.Pieces[0].Transform = func(v interface{}) (interface{}, error) {
+		return nil, errors.New("bad value")
+	}
+	_, err = mustNew(config).Scrape("a")
+	assert.EqualError(t, err, "bad value")
+}
+
+func TestRewriteStrip(t *testing.T) {
+	body := `<head><STYLE type="text/css">b { color: red }</STYLE><script>var s = "<b>";</script></head>` +
+		`<body><!-- <script>x</script> --><b>bold</b><scripts>kept</scripts><script src="a.js"></script></body>`
+
+	out, err := scrape.RewriteStrip(scrape.StripAll)("a", body)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head></head><body><b>bold</b><scripts>kept</scripts></body>`, out)
+
+	out, err = scrape.RewriteStrip(scrape.StripScripts)("a", body)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><STYLE type="text/css">b { color: red }</STYLE></head>`+
+		`<body><!-- <script>x</script> --><b>bold</b><scripts>kept</scripts></body>`, out)
+
+	out, err = scrape.RewriteStrip(scrape.StripComments)("a", "a<!-- unterminated")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", out)
+}
+
+func TestPromoteNoscript(t *testing.T) {
+	config := &scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<div class="pic"><img data-src="lazy.jpg"><noscript><img src="real.jpg"></noscript></div>`},
+		Pieces: []scrape.Piece{
+			{Name: "src", Selector: "img[src]", Extractor: extract.Attr{Attr: "src"}},
+		},
+	}
+
+	results, err := mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, results.First()["src"])
+
+	config.PromoteNoscript = true
+	results, err = mustNew(config).Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "real.jpg", results.First()["src"])
+}
+
+func TestResponseHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		fmt.Fprint(w, `<ul><li>one</li><li>two</li></ul>`)
+	}))
+	defer ts.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	results, err := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    fetcher,
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "item", Selector: ".", Extractor: extract.Text{}},
+			{Name: "modified", Selector: ".", Extractor: extract.Header{Name: "Last-Modified"}},
+			{Name: "etag", Selector: ".", Extractor: extract.Header{Name: "ETag"}},
+		},
+	}).Scrape(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"item": "one", "modified": "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{"item": "two", "modified": "Wed, 21 Oct 2015 07:28:00 GMT"},
+	}, results.Results[0])
+}
+
+func TestResponseMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<p>moved</p><a href="/gone">next</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	results, err := mustNew(&scrape.ScrapeConfig{
+		Fetcher:   fetcher,
+		Paginator: paginate.BySelector("a", "href"),
+		Pieces: []scrape.Piece{
+			{Name: "text", Selector: "p", Extractor: extract.Text{}},
+		},
+	}).Scrape(ts.URL + "/old")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ts.URL + "/old", ts.URL + "/gone"}, results.URLs)
+
+	moved := results.Responses[ts.URL+"/old"]
+	if assert.NotNil(t, moved) {
+		assert.Equal(t, http.StatusOK, moved.StatusCode)
+		assert.Equal(t, ts.URL+"/new", moved.URL)
+		assert.Equal(t, "text/html; charset=utf-8", moved.ContentType)
+	}
+	gone := results.Responses[ts.URL+"/gone"]
+	if assert.NotNil(t, gone) {
+		assert.Equal(t, http.StatusNotFound, gone.StatusCode)
+		assert.Equal(t, ts.URL+"/gone", gone.URL)
+	}
+
+	// Fetchers without metadata don't record anything.
+	results, err = mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<p>text</p>`},
+		Pieces:  []scrape.Piece{{Name: "text", Selector: "p", Extractor: extract.Text{}}},
+	}).Scrape("a")
+	assert.NoError(t, err)
+	assert.Nil(t, results.Responses)
+}
+
+func TestStatusPolicy(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		page := r.URL.Query().Get("page")
+		requests[page]++
+		n := requests[page]
+		mu.Unlock()
+
+		switch {
+		case page == "2" && n <= 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case page == "3":
+			w.WriteHeader(http.StatusNotFound)
+		case page == "5":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(w, `<b>%s</b>`, page)
+	}))
+	defer ts.Close()
+
+	fetcher, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	policy := &scrape.StatusPolicy{
+		Default:    scrape.StatusAbort,
+		Codes:      map[int]scrape.StatusAction{404: scrape.StatusSkip, 503: scrape.StatusRetry},
+		RetryDelay: time.Millisecond,
+	}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:      fetcher,
+		Paginator:    paginate.ByQueryParam("page"),
+		DividePage:   scrape.DividePageBySelector("b"),
+		Pieces:       []scrape.Piece{{Name: "n", Selector: ".", Extractor: extract.Text{}}},
+		StatusPolicy: policy,
+	})
+
+	// Page 2 succeeds on the third try, and page 3 is skipped.
+	res, err := sc.ScrapeWithOpts(ts.URL+"/?page=1", scrape.ScrapeOptions{MaxPages: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]map[string]interface{}{
+		{{"n": "1"}}, {{"n": "2"}}, {}, {{"n": "4"}},
+	}, res.Results)
+	mu.Lock()
+	assert.Equal(t, 3, requests["2"])
+	requests["2"] = -10
+	mu.Unlock()
+	assert.Equal(t, http.StatusNotFound, res.Responses[ts.URL+"/?page=3"].StatusCode)
+
+	// Other errors abort the scrape.
+	_, err = sc.Scrape(ts.URL + "/?page=5")
+	assert.EqualError(t, err, ts.URL+"/?page=5 returned status 500")
+	assert.IsType(t, &scrape.StatusError{}, err)
+
+	// Retries run out.
+	policy.MaxRetries = 2
+	_, err = sc.Scrape(ts.URL + "/?page=2")
+	assert.EqualError(t, err, ts.URL+"/?page=2 returned status 503")
+	mu.Lock()
+	assert.Equal(t, -7, requests["2"])
+	mu.Unlock()
+
+	_, err = scrape.New(&scrape.ScrapeConfig{
+		Pieces:       []scrape.Piece{{Name: "n", Selector: ".", Extractor: extract.Text{}}},
+		StatusPolicy: &scrape.StatusPolicy{MaxRetries: -1},
+	})
+	assert.EqualError(t, err, "status retries must not be negative")
+}
+
+func TestStopLoss(t *testing.T) {
+	fetcher := mapFetcher{}
+	for i := 1; i <= 20; i++ {
+		if i <= 3 || i == 6 {
+			fetcher[fmt.Sprintf("http://example.com/?page=%d", i)] = fmt.Sprintf(`<b>%d</b>`, i)
+		} else if i <= 10 {
+			fetcher[fmt.Sprintf("http://example.com/?page=%d", i)] = `<p>No results</p>`
+		}
+	}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    fetcher,
+		Paginator:  paginate.ByQueryParam("page"),
+		DividePage: scrape.DividePageBySelector("b"),
+		Pieces: []scrape.Piece{
+			{Name: "n", Selector: ".", Extractor: extract.Text{}},
+		},
+	})
+	urls := func(res *scrape.ScrapeResults) []int {
+		var pages []int
+		for _, u := range res.URLs {
+			var n int
+			fmt.Sscanf(u, "http://example.com/?page=%d", &n)
+			pages = append(pages, n)
+		}
+		return pages
+	}
+
+	// Pages 4 and 5 are empty, but page 6 isn't.
+	res, err := sc.ScrapeWithOpts("http://example.com/?page=1", scrape.ScrapeOptions{MaxConsecutiveEmptyPages: 3})
+	assert.Equal(t, scrape.ErrTooManyEmptyPages, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, urls(res))
+
+	// Pages past 10 fail.
+	res, err = sc.ScrapeWithOpts("http://example.com/?page=9", scrape.ScrapeOptions{MaxConsecutiveErrors: 3})
+	assert.True(t, errors.Is(err, scrape.ErrTooManyErrors))
+	assert.Contains(t, err.Error(), "unknown URL: http://example.com/?page=13")
+	assert.Equal(t, []int{9, 10}, urls(res))
+
+	// Without the option, the first failure ends the scrape.
+	_, err = sc.ScrapeWithOpts("http://example.com/?page=9", scrape.ScrapeOptions{})
+	assert.EqualError(t, err, "unknown URL: http://example.com/?page=11")
+
+	// Failed pages count towards MaxPages.
+	res, err = sc.ScrapeWithOpts("http://example.com/?page=10", scrape.ScrapeOptions{MaxConsecutiveErrors: 5, MaxPages: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10}, urls(res))
+
+	sc, err = scrape.NewScraper(
+		scrape.WithFetcher(fetcher),
+		scrape.WithPaginator(paginate.ByQueryParam("page")),
+		scrape.WithDividePage(scrape.DividePageBySelector("b")),
+		scrape.WithPieces(scrape.Piece{Name: "n", Selector: ".", Extractor: extract.Text{}}),
+		scrape.WithMaxConsecutiveEmptyPages(2),
+	)
+	assert.NoError(t, err)
+	var pages []string
+	for page, err := range sc.Pages(context.Background(), "http://example.com/?page=6") {
+		if err != nil {
+			assert.Equal(t, scrape.ErrTooManyEmptyPages, err)
+			break
+		}
+		pages = append(pages, page.URL)
+	}
+	assert.Equal(t, []string{"http://example.com/?page=6", "http://example.com/?page=7", "http://example.com/?page=8"}, pages)
+}
+
 func mustNew(c *scrape.ScrapeConfig) *scrape.Scraper {
 	scraper, err := scrape.New(c)
 	if err != nil {
@@ -89,6 +1501,9 @@ func (d *dummyFetcher) Prepare() error {
 }
 
 func (d *dummyFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	if d.idx >= len(d.data) {
+		return nil, errors.New("no more data")
+	}
 	r := dummyReadCloser{bytes.NewReader(d.data[d.idx])}
 	d.idx++
 	return r, nil
@@ -98,6 +1513,45 @@ func (d *dummyFetcher) Close() {
 	return
 }
 
+// gatedFetcher signals when each fetch starts, and then waits to be released.
+type gatedFetcher struct {
+	*dummyFetcher
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (g *gatedFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	g.fetching <- struct{}{}
+	<-g.release
+	return g.dummyFetcher.Fetch(method, url)
+}
+
+// mapFetcher returns the document for each URL from a map.
+type mapFetcher map[string]string
+
+func (m mapFetcher) Prepare() error {
+	return nil
+}
+
+func (m mapFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	doc, found := m[url]
+	if !found {
+		return nil, fmt.Errorf("unknown URL: %s", url)
+	}
+	return dummyReadCloser{strings.NewReader(doc)}, nil
+}
+
+func (m mapFetcher) Close() {
+	return
+}
+
+// mapPaginator returns the next page for each URL from a map.
+type mapPaginator map[string]string
+
+func (m mapPaginator) NextPage(url string, document *goquery.Selection) (string, error) {
+	return m[url], nil
+}
+
 type dummyPaginator struct {
 	idx int
 }
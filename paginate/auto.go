@@ -0,0 +1,204 @@
+package paginate
+
+import (
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// Confidence scores for each of the strategies that Auto can pick.
+const (
+	relNextConfidence    = 0.95
+	nextTextConfidence   = 0.8
+	pageLinksConfidence  = 0.7
+	queryParamConfidence = 0.4
+)
+
+// Names of query parameters that look like they hold a page number.
+var pageParams = []string{"page", "p", "pg", "paged", "pagenum", "pageno", "page_no", "page_num"}
+
+type autoStrategy struct {
+	name       string
+	confidence float64
+	paginator  scrape.Paginator
+}
+
+// AutoPaginator is a Paginator that looks for common pagination signals on the
+// first page of a scrape and picks a pagination strategy from them.  Create one
+// with Auto.
+type AutoPaginator struct {
+	// Logger, if non-nil, is used to log the chosen strategy and how
+	// confident the paginator is in it, between 0 and 1.
+	Logger *log.Logger
+
+	mu       sync.Mutex
+	strategy *autoStrategy
+	next     string
+}
+
+// Auto returns a Paginator that needs no configuration.  On the first page, it
+// looks for the following signals, in order of confidence:
+//
+//   - a <link> or <a> element with rel="next"
+//   - a link whose text is one of NextWords (see ByNextText)
+//   - numbered page links, following the one after the current page
+//   - a query parameter that looks like a page number (e.g. "page=2"), which
+//     is incremented
+//
+// The strategy with the highest confidence is used for the rest of the
+// scrape.  A page other than the one last returned starts a new scrape, and a
+// strategy is picked again.  Since the last of these never ends on its own,
+// you probably want to set MaxPages too.
+func Auto() *AutoPaginator {
+	return &AutoPaginator{}
+}
+
+func (p *AutoPaginator) NextPage(uri string, doc *goquery.Selection) (string, error) {
+	p.mu.Lock()
+	strategy := p.strategy
+	if strategy == nil || uri != p.next {
+		strategy = detectStrategy(uri, doc)
+		p.strategy = strategy
+		if strategy == nil {
+			p.mu.Unlock()
+			p.logf("paginate: no pagination found on %s", uri)
+			return "", nil
+		}
+		p.logf("paginate: using %s pagination for %s (confidence %.2f)",
+			strategy.name, uri, strategy.confidence)
+	}
+	p.mu.Unlock()
+
+	next, err := strategy.paginator.NextPage(uri, doc)
+	if next == uri {
+		next = ""
+	}
+
+	p.mu.Lock()
+	p.next = next
+	p.mu.Unlock()
+	return next, err
+}
+
+func (p *AutoPaginator) logf(format string, args ...interface{}) {
+	if p.Logger != nil {
+		p.Logger.Printf(format, args...)
+	}
+}
+
+// detectStrategy returns the strategy with the highest confidence for the
+// given page, or nil if the page has no pagination signals.
+func detectStrategy(uri string, doc *goquery.Selection) *autoStrategy {
+	var candidates []*autoStrategy
+	add := func(name string, confidence float64, pg scrape.Paginator) {
+		next, err := pg.NextPage(uri, doc)
+		if err == nil && next != "" && next != uri {
+			candidates = append(candidates, &autoStrategy{name, confidence, pg})
+		}
+	}
+
+	if doc != nil {
+		add("rel=next", relNextConfidence, BySelector(`link[rel~="next"][href], a[rel~="next"][href]`, "href"))
+		add("next link text", nextTextConfidence, ByNextText())
+		add("numbered page links", pageLinksConfidence, pageLinksPaginator{})
+	}
+	if u, err := url.Parse(uri); err == nil {
+		vals := u.Query()
+		for _, name := range pageParams {
+			if _, err := strconv.ParseUint(vals.Get(name), 10, 64); err == nil {
+				add("query parameter "+strconv.Quote(name), queryParamConfidence, ByQueryParam(name))
+				break
+			}
+		}
+	}
+
+	var best *autoStrategy
+	for _, c := range candidates {
+		if best == nil || c.confidence > best.confidence {
+			best = c
+		}
+	}
+	return best
+}
+
+// pageLinksPaginator follows the numbered page link after the current page.
+// The current page is the number marked with aria-current or a class like
+// "current" or "active", or otherwise the number missing from the links (a
+// page doesn't link to itself).
+type pageLinksPaginator struct{}
+
+func (pageLinksPaginator) NextPage(uri string, doc *goquery.Selection) (string, error) {
+	links := map[int]string{}
+	current := 0
+	doc.Find("a, span, li, em, strong, b").Each(func(i int, s *goquery.Selection) {
+		n, ok := pageNumber(s.Text())
+		if !ok {
+			return
+		}
+		if href, found := s.Attr("href"); found && goquery.NodeName(s) == "a" {
+			if _, seen := links[n]; !seen {
+				links[n] = href
+			}
+		}
+		if current == 0 && isCurrentPage(s) {
+			current = n
+		}
+	})
+	if len(links) < 2 && current == 0 {
+		// A single number on its own is unlikely to be pagination.
+		return "", nil
+	}
+
+	if current == 0 {
+		// Take the first number that isn't a link.
+		for n := 1; n <= len(links)+1; n++ {
+			if _, found := links[n]; !found {
+				current = n
+				break
+			}
+		}
+	}
+
+	href, found := links[current+1]
+	if !found {
+		return "", nil
+	}
+	return RelUrl(uri, href)
+}
+
+// pageNumber parses the text of a numbered page link.
+func pageNumber(text string) (int, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) > 6 {
+		return 0, false
+	}
+	for _, r := range text {
+		if !unicode.IsDigit(r) {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+func isCurrentPage(s *goquery.Selection) bool {
+	if current, found := s.Attr("aria-current"); found && current != "false" {
+		return true
+	}
+	for _, class := range strings.Fields(s.AttrOr("class", "")) {
+		switch strings.ToLower(class) {
+		case "current", "active", "selected", "is-current", "is-active":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package paginate
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuto(t *testing.T) {
+	testCases := []struct {
+		name string
+		uri  string
+		html string
+		want string
+	}{
+		{
+			name: "rel next",
+			uri:  "http://example.com/list",
+			html: `<head><link rel="next" href="/list/2"></head><a href="/other">next</a>`,
+			want: "http://example.com/list/2",
+		},
+		{
+			name: "next text",
+			uri:  "http://example.com/list",
+			html: `<a href="/about">About</a><a href="?page=2"> Siguiente </a>`,
+			want: "http://example.com/list?page=2",
+		},
+		{
+			name: "aria label",
+			uri:  "http://example.com/list",
+			html: `<a href="/list/2" aria-label="Next page"><svg></svg></a>`,
+			want: "http://example.com/list/2",
+		},
+		{
+			name: "page links with current",
+			uri:  "http://example.com/list/3",
+			html: `<ul><li><a href="/list/1">1</a></li><li><a href="/list/2">2</a></li>` +
+				`<li class="active"><a href="/list/3">3</a></li><li><a href="/list/4">4</a></li></ul>`,
+			want: "http://example.com/list/4",
+		},
+		{
+			name: "page links without current",
+			uri:  "http://example.com/list",
+			html: `<span>1</span> <a href="/list/2">2</a> <a href="/list/3">3</a>`,
+			want: "http://example.com/list/2",
+		},
+		{
+			name: "query parameter",
+			uri:  "http://example.com/list?page=4&q=x",
+			html: `<p>Results</p>`,
+			want: "http://example.com/list?page=5&q=x",
+		},
+		{
+			name: "nothing",
+			uri:  "http://example.com/list",
+			html: `<a href="/about">About</a> <a href="/">1</a>`,
+			want: "",
+		},
+		{
+			name: "link to self",
+			uri:  "http://example.com/list",
+			html: `<a href="/list" rel="next">next</a>`,
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		pg, err := Auto().NextPage(tc.uri, selFrom(tc.html))
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.want, pg, tc.name)
+	}
+}
+
+func TestAutoKeepsStrategy(t *testing.T) {
+	var logs bytes.Buffer
+	p := Auto()
+	p.Logger = log.New(&logs, "", 0)
+
+	pg, err := p.NextPage("http://example.com/?page=1", selFrom(`<a href="/?page=2" rel="next">more</a>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/?page=2", pg)
+
+	// The second page has no rel=next link, so pagination stops rather than
+	// falling back to the query parameter.
+	pg, err = p.NextPage("http://example.com/?page=2", selFrom(`<p>the end</p>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "", pg)
+
+	// A new scrape picks a strategy again.
+	pg, err = p.NextPage("http://example.com/?page=7", selFrom(`<p>the end</p>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/?page=8", pg)
+
+	assert.Equal(t,
+		"paginate: using rel=next pagination for http://example.com/?page=1 (confidence 0.95)\n"+
+			"paginate: using query parameter \"page\" pagination for http://example.com/?page=7 (confidence 0.40)\n",
+		logs.String())
+
+	logs.Reset()
+	pg, err = p.NextPage("http://example.com/", selFrom(`<p>the end</p>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "", pg)
+	assert.Equal(t, "paginate: no pagination found on http://example.com/\n", logs.String())
+}
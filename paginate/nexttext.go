@@ -0,0 +1,101 @@
+package paginate
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// NextWords is the default list of link texts used by ByNextText.  It holds
+// the word for "next" in a number of languages, along with the arrows that
+// are commonly used instead.
+var NextWords = []string{
+	// Arrows
+	"»", "›", ">", ">>", "→",
+
+	// English
+	"next", "next page",
+
+	// Other languages
+	"siguiente", "página siguiente", "suivant", "suivante", "page suivante",
+	"weiter", "nächste", "nächste seite", "avanti", "successivo", "successiva",
+	"próxima", "próximo", "seguinte", "volgende", "następna", "další",
+	"nästa", "neste", "næste", "seuraava", "sonraki", "далее", "следующая",
+	"наступна", "次へ", "次のページ", "次", "下一页", "下一頁", "다음",
+	"التالي", "הבא", "अगला", "ถัดไป", "tiếp", "berikutnya",
+}
+
+type byNextTextPaginator struct {
+	words []string
+}
+
+// ByNextText returns a Paginator that follows the first link whose text (or
+// title, or aria-label) is one of the given words, for sites whose "next" links
+// have no rel attribute or stable class to select them by.  Words are compared
+// case-insensitively after collapsing whitespace, and arrows around the text
+// are ignored, so "next" matches "Next »" and "‹ Next ›".
+//
+// If no words are given, then NextWords is used.  To recognise other words as
+// well as the default ones, use:
+//
+//	paginate.ByNextText(append(paginate.NextWords, "more results")...)
+func ByNextText(words ...string) scrape.Paginator {
+	if len(words) == 0 {
+		words = NextWords
+	}
+
+	p := &byNextTextPaginator{words: make([]string, len(words))}
+	for i, word := range words {
+		p.words[i] = normalizeLinkText(word)
+	}
+	return p
+}
+
+func (p *byNextTextPaginator) NextPage(uri string, doc *goquery.Selection) (string, error) {
+	var href string
+	doc.Find("a[href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		for _, label := range []string{s.Text(), s.AttrOr("title", ""), s.AttrOr("aria-label", "")} {
+			if p.matches(label) {
+				href, _ = s.Attr("href")
+				return false
+			}
+		}
+		return true
+	})
+	if href == "" {
+		return "", nil
+	}
+	return RelUrl(uri, href)
+}
+
+func (p *byNextTextPaginator) matches(label string) bool {
+	label = normalizeLinkText(label)
+	if label == "" {
+		return false
+	}
+	trimmed := strings.TrimFunc(label, isArrow)
+	trimmed = strings.TrimSpace(trimmed)
+
+	for _, word := range p.words {
+		if label == word || (trimmed != "" && trimmed == word) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeLinkText lowercases the given text and collapses any whitespace in
+// it.
+func normalizeLinkText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func isArrow(r rune) bool {
+	switch r {
+	case '»', '«', '›', '‹', '>', '<', '→', '←', '⟩', '⟨', '▶', '▸', '►':
+		return true
+	}
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,34 @@
+package paginate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByNextText(t *testing.T) {
+	testCases := []struct {
+		html  string
+		words []string
+		want  string
+	}{
+		{`<a href="/a">Home</a><a href="/b">Next</a>`, nil, "http://example.com/b"},
+		{`<a href="/a">Next   Page »</a>`, nil, "http://example.com/a"},
+		{`<a href="/a">‹ Anterior</a><a href="/b">Siguiente ›</a>`, nil, "http://example.com/b"},
+		{`<a href="/a">次へ</a>`, nil, "http://example.com/a"},
+		{`<a href="/a">WEITER</a>`, nil, "http://example.com/a"},
+		{`<a href="/a">»</a>`, nil, "http://example.com/a"},
+		{`<a href="/a" title="Next page"><img src="arrow.png"></a>`, nil, "http://example.com/a"},
+		{`<a href="/a">Next steps for beginners</a>`, nil, ""},
+		{`<a href="/a">More results</a>`, nil, ""},
+		{`<a href="/a">More results</a>`, append(NextWords, "more results"), "http://example.com/a"},
+		{`<a href="/a">Next</a><a href="/b">Plus</a>`, []string{"plus"}, "http://example.com/b"},
+		{`<a>Next</a>`, nil, ""},
+	}
+
+	for _, tc := range testCases {
+		pg, err := ByNextText(tc.words...).NextPage("http://example.com/list", selFrom(tc.html))
+		assert.NoError(t, err, tc.html)
+		assert.Equal(t, tc.want, pg, tc.html)
+	}
+}
@@ -3,6 +3,7 @@ package paginate
 import (
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/andrew-d/goscrape"
@@ -91,3 +92,33 @@ func (p *byQueryParamPaginator) NextPage(u string, _ *goquery.Selection) (string
 	uri.RawQuery = query
 	return uri.String(), nil
 }
+
+type byCursorPaginator struct {
+	sel   string
+	param string
+}
+
+// ByCursor returns a Paginator for APIs that return an opaque cursor for the
+// next page in each response.  The cursor is the text of the first element
+// matching the given CSS selector (e.g. ".next_cursor" with an APIFetcher),
+// and the next page is the current URL with the given query parameter set to
+// the cursor.  Pagination stops when the element is missing or empty.
+func ByCursor(sel, param string) scrape.Paginator {
+	return &byCursorPaginator{sel: sel, param: param}
+}
+
+func (p *byCursorPaginator) NextPage(u string, doc *goquery.Selection) (string, error) {
+	cursor := strings.TrimSpace(doc.Find(p.sel).First().Text())
+	if cursor == "" {
+		return "", nil
+	}
+
+	uri, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	vals := uri.Query()
+	vals.Set(p.param, cursor)
+	uri.RawQuery = vals.Encode()
+	return uri.String(), nil
+}
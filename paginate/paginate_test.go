@@ -55,3 +55,15 @@ func TestByQueryParam(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, pg, "")
 }
+
+func TestByCursor(t *testing.T) {
+	sel := selFrom(`<span class="next_cursor">abc=</span>`)
+	pg, err := ByCursor(".next_cursor", "cursor").NextPage("http://api.example.com/items?limit=10&cursor=xyz", sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://api.example.com/items?cursor=abc%3D&limit=10", pg)
+
+	sel = selFrom(`<span class="next_cursor"></span>`)
+	pg, err = ByCursor(".next_cursor", "cursor").NextPage("http://api.example.com/items", sel)
+	assert.NoError(t, err)
+	assert.Equal(t, "", pg)
+}
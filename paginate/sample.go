@@ -0,0 +1,132 @@
+package paginate
+
+import (
+	"errors"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+)
+
+// pageParam returns the value of the given query parameter of a URL as a page
+// number, treating a missing parameter as page 1.
+func pageParam(uri *url.URL, param string) (uint64, bool) {
+	val := uri.Query().Get(param)
+	if val == "" {
+		return 1, true
+	}
+	n, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// setPageParam returns the given URL with the query parameter set to page n.
+func setPageParam(uri *url.URL, param string, n uint64) string {
+	vals := uri.Query()
+	vals.Set(param, strconv.FormatUint(n, 10))
+	uri.RawQuery = vals.Encode()
+	return uri.String()
+}
+
+type exponentialPaginator struct {
+	param string
+	sel   string
+}
+
+// Exponential returns a Paginator that samples a long listing instead of
+// visiting every page, for monitoring listings that are too large to scrape in
+// full.  It doubles the page number in the given query parameter each time,
+// so that pages 1, 2, 4, 8, 16, and so on are scraped.  A missing parameter is
+// treated as page 1.
+//
+// Since the last page isn't known, pagination stops when a page has no
+// elements matching the given CSS selector - typically the selector of the
+// listing's items.  If the selector is empty, then this will paginate
+// infinitely, and you should set MaxPages.
+func Exponential(param, sel string) scrape.Paginator {
+	return &exponentialPaginator{param: param, sel: sel}
+}
+
+func (p *exponentialPaginator) NextPage(u string, doc *goquery.Selection) (string, error) {
+	if p.sel != "" && doc.Find(p.sel).Length() == 0 {
+		return "", nil
+	}
+
+	uri, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	n, ok := pageParam(uri, p.param)
+	if !ok {
+		return "", nil
+	}
+	if n == 0 {
+		n = 1
+	}
+	return setPageParam(uri, p.param, n*2), nil
+}
+
+type sampledPaginator struct {
+	param string
+	pages []uint64
+}
+
+// RandomSample returns a Paginator that scrapes a random sample of n of the
+// pages from 1 to lastPage, in order, by setting the page number in the given
+// query parameter.  The scrape should start on page 1 (or without the
+// parameter), which is always part of the sample.  The same seed always gives
+// the same sample, so use e.g. time.Now().UnixNano() for a different sample on
+// each run.
+func RandomSample(param string, lastPage, n int, seed int64) (scrape.Paginator, error) {
+	if lastPage < 1 {
+		return nil, errors.New("last page must be at least 1")
+	}
+	if n < 1 {
+		return nil, errors.New("sample size must be at least 1")
+	}
+	if n > lastPage {
+		n = lastPage
+	}
+
+	// Page 1 is always in the sample, so choose the rest from pages 2 onwards,
+	// using Floyd's algorithm so that huge listings don't need a permutation
+	// of every page.
+	rng := rand.New(rand.NewSource(seed))
+	chosen := map[int]bool{}
+	for j := lastPage - n; j < lastPage-1; j++ {
+		if i := rng.Intn(j + 1); !chosen[i] {
+			chosen[i] = true
+		} else {
+			chosen[j] = true
+		}
+	}
+	pages := []uint64{1}
+	for i := range chosen {
+		pages = append(pages, uint64(i+2))
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+
+	return &sampledPaginator{param: param, pages: pages}, nil
+}
+
+func (p *sampledPaginator) NextPage(u string, _ *goquery.Selection) (string, error) {
+	uri, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	n, ok := pageParam(uri, p.param)
+	if !ok {
+		return "", nil
+	}
+
+	i := sort.Search(len(p.pages), func(i int) bool { return p.pages[i] > n })
+	if i == len(p.pages) {
+		return "", nil
+	}
+	return setPageParam(uri, p.param, p.pages[i]), nil
+}
@@ -0,0 +1,86 @@
+package paginate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponential(t *testing.T) {
+	items := selFrom(`<ul><li class="item">a</li></ul>`)
+	empty := selFrom(`<p>No results</p>`)
+
+	p := Exponential("page", ".item")
+	var visited []string
+	for url := "http://example.com/list?q=x"; url != ""; {
+		visited = append(visited, url)
+		doc := items
+		if len(visited) > 5 {
+			doc = empty
+		}
+
+		var err error
+		url, err = p.NextPage(url, doc)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{
+		"http://example.com/list?q=x",
+		"http://example.com/list?page=2&q=x",
+		"http://example.com/list?page=4&q=x",
+		"http://example.com/list?page=8&q=x",
+		"http://example.com/list?page=16&q=x",
+		"http://example.com/list?page=32&q=x",
+	}, visited)
+
+	pg, err := Exponential("p", "").NextPage("http://example.com/?p=0", empty)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/?p=2", pg)
+
+	pg, err = Exponential("p", "").NextPage("http://example.com/?p=last", empty)
+	assert.NoError(t, err)
+	assert.Equal(t, "", pg)
+}
+
+func TestRandomSample(t *testing.T) {
+	sample := func(lastPage, n int, seed int64) []string {
+		p, err := RandomSample("page", lastPage, n, seed)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		var pages []string
+		for url := "http://example.com/"; url != ""; {
+			pages = append(pages, url)
+			url, err = p.NextPage(url, nil)
+			assert.NoError(t, err)
+		}
+		return pages
+	}
+
+	pages := sample(1000, 5, 42)
+	assert.Len(t, pages, 5)
+	assert.Equal(t, "http://example.com/", pages[0])
+	assert.Equal(t, pages, sample(1000, 5, 42))
+	assert.NotEqual(t, pages, sample(1000, 5, 43))
+
+	var prev int
+	for _, url := range pages[1:] {
+		var n int
+		_, err := fmt.Sscanf(url, "http://example.com/?page=%d", &n)
+		assert.NoError(t, err)
+		assert.True(t, n > prev && n >= 2 && n <= 1000, url)
+		prev = n
+	}
+
+	assert.Equal(t, []string{
+		"http://example.com/",
+		"http://example.com/?page=2",
+		"http://example.com/?page=3",
+	}, sample(3, 10, 1))
+
+	_, err := RandomSample("page", 0, 1, 1)
+	assert.Error(t, err)
+	_, err = RandomSample("page", 10, 0, 1)
+	assert.Error(t, err)
+}
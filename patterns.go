@@ -0,0 +1,149 @@
+package scrape
+
+import (
+	"sort"
+	"strings"
+)
+
+// URLCluster is a group of URLs that share the same general shape, as reported
+// by LearnURLPatterns.
+type URLCluster struct {
+	// The pattern shared by the URLs, such as "example.com/product/{slug}" or
+	// "example.com/category/{slug}?page".
+	Pattern string
+
+	// The number of URLs with this pattern.
+	Count int
+
+	// Up to maxClusterExamples of the URLs with this pattern, in the order
+	// they were given.
+	Examples []string
+}
+
+const (
+	// The number of distinct values that a path segment must have (with the
+	// rest of the pattern the same) before it's treated as a variable.  Any
+	// query string stays attached to the last segment.
+	slugThreshold = 5
+
+	maxClusterExamples = 3
+)
+
+// LearnURLPatterns groups the given URLs into clusters with the same general
+// shape, which can help when writing the Allow, Deny, Extract and Priorities
+// rules of a CrawlConfig.  Each URL is first reduced with URLPattern, and then
+// any path segment that takes many different values among otherwise-identical
+// patterns is replaced by "{slug}".
+//
+// The clusters are returned from largest to smallest.
+func LearnURLPatterns(urls []string) []URLCluster {
+	type entry struct {
+		url      string
+		segments []string
+	}
+	entries := make([]entry, len(urls))
+	maxSegments := 0
+	for i, url := range urls {
+		entries[i] = entry{url, strings.Split(URLPattern(url), "/")}
+		if n := len(entries[i].segments); n > maxSegments {
+			maxSegments = n
+		}
+	}
+
+	// Working from the end of the path (which is most likely to vary), look
+	// for segments with many distinct values among otherwise-identical
+	// patterns.  The first "segment" is the host, which is never replaced.
+	for pos := maxSegments - 1; pos > 0; pos-- {
+		values := map[string]map[string]struct{}{}
+		key := func(e entry) string {
+			rest := append([]string{}, e.segments...)
+			rest[pos] = ""
+			return strings.Join(rest, "\x00")
+		}
+
+		for _, e := range entries {
+			if pos >= len(e.segments) {
+				continue
+			}
+			k := key(e)
+			if values[k] == nil {
+				values[k] = map[string]struct{}{}
+			}
+			values[k][e.segments[pos]] = struct{}{}
+		}
+
+		for _, e := range entries {
+			if pos >= len(e.segments) {
+				continue
+			}
+			if len(values[key(e)]) >= slugThreshold {
+				e.segments[pos] = slugSegment(e.segments[pos])
+			}
+		}
+	}
+
+	clusters := map[string]*URLCluster{}
+	order := []string{}
+	for _, e := range entries {
+		pattern := strings.Join(e.segments, "/")
+		c, found := clusters[pattern]
+		if !found {
+			c = &URLCluster{Pattern: pattern}
+			clusters[pattern] = c
+			order = append(order, pattern)
+		}
+		c.Count++
+		if len(c.Examples) < maxClusterExamples {
+			c.Examples = append(c.Examples, e.url)
+		}
+	}
+
+	ret := make([]URLCluster, 0, len(order))
+	for _, pattern := range order {
+		ret = append(ret, *clusters[pattern])
+	}
+	sortClusters(ret)
+	return ret
+}
+
+// slugSegment replaces a path segment with "{slug}", keeping any query string
+// that follows it.
+func slugSegment(seg string) string {
+	if idx := strings.IndexByte(seg, '?'); idx >= 0 {
+		return "{slug}" + seg[idx:]
+	}
+	return "{slug}"
+}
+
+func sortClusters(clusters []URLCluster) {
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		return clusters[i].Pattern < clusters[j].Pattern
+	})
+}
+
+// mergeClusters adds the counts and examples of the clusters in b to a.
+func mergeClusters(a, b []URLCluster) []URLCluster {
+	index := map[string]int{}
+	ret := []URLCluster{}
+	for _, list := range [][]URLCluster{a, b} {
+		for _, c := range list {
+			i, found := index[c.Pattern]
+			if !found {
+				index[c.Pattern] = len(ret)
+				ret = append(ret, URLCluster{Pattern: c.Pattern})
+				i = len(ret) - 1
+			}
+			ret[i].Count += c.Count
+			for _, ex := range c.Examples {
+				if len(ret[i].Examples) < maxClusterExamples {
+					ret[i].Examples = append(ret[i].Examples, ex)
+				}
+			}
+		}
+	}
+	sortClusters(ret)
+	return ret
+}
@@ -0,0 +1,45 @@
+package scrape_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLearnURLPatterns(t *testing.T) {
+	urls := []string{"http://example.com/", "http://example.com/about"}
+	for _, slug := range []string{"red-shoe", "blue-hat", "green-scarf", "tan-coat", "odd-sock", "big-bag"} {
+		urls = append(urls, "http://example.com/product/"+slug)
+	}
+	for _, slug := range []string{"shoes", "hats", "coats", "socks", "bags"} {
+		for page := 1; page <= 2; page++ {
+			urls = append(urls, fmt.Sprintf("http://example.com/category/%s?page=%d", slug, page))
+		}
+	}
+
+	clusters := scrape.LearnURLPatterns(urls)
+	assert.Equal(t, []scrape.URLCluster{
+		{
+			Pattern: "example.com/category/{slug}?page",
+			Count:   10,
+			Examples: []string{
+				"http://example.com/category/shoes?page=1",
+				"http://example.com/category/shoes?page=2",
+				"http://example.com/category/hats?page=1",
+			},
+		},
+		{
+			Pattern: "example.com/product/{slug}",
+			Count:   6,
+			Examples: []string{
+				"http://example.com/product/red-shoe",
+				"http://example.com/product/blue-hat",
+				"http://example.com/product/green-scarf",
+			},
+		},
+		{Pattern: "example.com/", Count: 1, Examples: []string{"http://example.com/"}},
+		{Pattern: "example.com/about", Count: 1, Examples: []string{"http://example.com/about"}},
+	}, clusters)
+}
@@ -5,14 +5,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const fetchScript = `
 var system = require('system'),
+    fs = require('fs'),
     page = require("webpage").create();
 
 // Workaround for https://github.com/ariya/phantomjs/issues/12697 since
@@ -22,24 +31,173 @@ var phantomExit = function(exitCode) {
     setTimeout(function() { phantom.exit(exitCode); }, 0);
 };
 
-if( system.args.length !== 2 ) {
-    system.stderr.writeLine("Usage: fetch.js URL");
+if( system.args.length !== 2 && system.args.length !== 3 ) {
+    system.stderr.writeLine("Usage: fetch.js URL [CAPTURE_MS]");
     phantomExit(1);
 }
 
 var resourceWait  = 300,
     maxRenderWait = 10000,
     url           = system.args[1],
+    captureWait   = system.args.length === 3 ? parseInt(system.args[2], 10) : 0,
+    capturing     = false,
     count         = 0,
     forcedRenderTimeout,
     renderTimeout;
 
+// Restore the saved browser session, if given by the scraper.
+var sessionPath = system.args[0].replace(/fetch\.js$/, 'session.json'),
+    session     = fs.exists(sessionPath) ? JSON.parse(fs.read(sessionPath)) : null;
+
+if (session) {
+    (session.Cookies || []).forEach(function(c) {
+        var cookie = {
+            name: c.Name,
+            value: c.Value,
+            domain: c.Domain,
+            path: c.Path || '/',
+            httponly: c.HTTPOnly,
+            secure: c.Secure
+        };
+        if (c.Expires) {
+            cookie.expires = c.Expires * 1000;
+        }
+        phantom.addCookie(cookie);
+    });
+}
+
+// Returns the browser's cookies and the current page's storage.
+var sessionState = function() {
+    if (!session) {
+        return null;
+    }
+
+    var storage = page.evaluate(function() {
+        var dump = function(s) {
+            var ret = {};
+            for (var i = 0; i < s.length; i++) {
+                ret[s.key(i)] = s.getItem(s.key(i));
+            }
+            return ret;
+        };
+        try {
+            return {
+                origin: location.protocol + '//' + location.host,
+                local: dump(window.localStorage),
+                session: dump(window.sessionStorage)
+            };
+        } catch (e) {
+            return null;
+        }
+    });
+
+    return {
+        cookies: phantom.cookies.map(function(c) {
+            return {
+                Name: c.name,
+                Value: c.value,
+                Domain: c.domain,
+                Path: c.path,
+                Expires: c.expiry || 0,
+                HTTPOnly: !!c.httponly,
+                Secure: !!c.secure
+            };
+        }),
+        storage: storage
+    };
+};
+
+// Record every message received on the page's WebSockets, by wrapping the
+// WebSocket constructor before any of the page's scripts run.
+page.onInitialized = function() {
+    if (session) {
+        page.evaluate(function(local, sess) {
+            var origin = location.protocol + '//' + location.host;
+            var restore = function(storage, saved) {
+                var items = saved && saved[origin];
+                for (var k in items || {}) {
+                    storage.setItem(k, items[k]);
+                }
+            };
+            try {
+                restore(window.localStorage, local);
+                restore(window.sessionStorage, sess);
+            } catch (e) {}
+        }, session.LocalStorage, session.SessionStorage);
+    }
+
+    if (captureWait <= 0) {
+        return;
+    }
+    page.evaluate(function() {
+        var Orig = window.WebSocket;
+        window.__goscrapeMessages = [];
+        if (!Orig) {
+            return;
+        }
+
+        var Wrapped = function(url, protocols) {
+            var ws = protocols === undefined ? new Orig(url) : new Orig(url, protocols);
+            ws.addEventListener('message', function(e) {
+                window.__goscrapeMessages.push({
+                    url: url,
+                    data: String(e.data),
+                    time: new Date().toISOString()
+                });
+            });
+            return ws;
+        };
+        Wrapped.prototype = Orig.prototype;
+        Wrapped.CONNECTING = Orig.CONNECTING;
+        Wrapped.OPEN = Orig.OPEN;
+        Wrapped.CLOSING = Orig.CLOSING;
+        Wrapped.CLOSED = Orig.CLOSED;
+        window.WebSocket = Wrapped;
+    });
+};
+
+// Evaluate any scripts given by the scraper.
+var runEvals = function() {
+    var evalsPath = system.args[0].replace(/fetch\.js$/, 'evals.json');
+    if (!fs.exists(evalsPath)) {
+        return [];
+    }
+    return JSON.parse(fs.read(evalsPath)).map(function(src) {
+        return page.evaluate(function(src) {
+            try {
+                var v = (0, eval)(src);
+                return {script: src, value: v === undefined ? null : JSON.parse(JSON.stringify(v))};
+            } catch (e) {
+                return {script: src, error: String(e)};
+            }
+        }, src);
+    });
+};
+
+var doCapture = function() {
+    var m = page.evaluate(function() {
+        return window.__goscrapeMessages || [];
+    });
+
+    system.stdout.write(JSON.stringify({messages: m, evals: runEvals(), session: sessionState()}));
+    phantomExit();
+}
+
 var doRender = function() {
+    if (captureWait > 0) {
+        // Once the page has loaded, keep listening for the capture duration.
+        if (!capturing) {
+            capturing = true;
+            setTimeout(doCapture, captureWait);
+        }
+        return;
+    }
+
     var c = page.evaluate(function() {
         return document.documentElement.outerHTML;
     });
 
-    system.stdout.write(JSON.stringify({contents: c}));
+    system.stdout.write(JSON.stringify({contents: c, evals: runEvals(), session: sessionState()}));
     phantomExit();
 }
 
@@ -113,6 +271,36 @@ type PhantomJSFetcher struct {
 
 	// Arguments to pass to PhantomJS
 	args []string
+
+	// If CaptureWebSockets is greater than 0, then rather than returning each
+	// page's HTML, the fetcher records every message received on the page's
+	// WebSocket connections, from when it starts loading until this long after
+	// it has loaded.  This is useful for live-updating pages (e.g. sports
+	// scores or auctions) that never render their data into HTML.
+	//
+	// The messages are returned as a document converted from JSON (see
+	// JSONToHTML) of the form:
+	//
+	//	{"messages": [{"url": "wss://...", "data": ..., "time": "2006-01-02T15:04:05.000Z"}]}
+	//
+	// where "data" is the message itself, parsed as JSON if possible, or as a
+	// string otherwise.  For example, use the selector ".messages > li" to
+	// divide the page into one block per message.  The results of evaluating
+	// scripts (see ScriptEvaluator) are added to this document as usual.
+	CaptureWebSockets time.Duration
+
+	// If Session is non-nil, then its cookies and storage are loaded into the
+	// browser before each page is fetched, and it is updated with the
+	// browser's cookies and the page's storage afterwards.  Since a
+	// BrowserSession can be saved and loaded as JSON, this allows a session
+	// that was logged in once (e.g. by hand) to be reused by later scrapes.
+	Session *BrowserSession
+
+	// The scripts to evaluate in each page; see ScriptEvaluator.
+	scripts []string
+
+	// Protects Session.
+	sessionMu sync.Mutex
 }
 
 // NewPhantomJSFetcher will create a new instance of PhantomJSFetcher,
@@ -156,7 +344,22 @@ func NewPhantomJSFetcher(binary ...string) (*PhantomJSFetcher, error) {
 	return ret, nil
 }
 
+// SetScripts sets the scripts that are evaluated in each page, which allows
+// extract.BrowserEval to be used with this fetcher.
+func (pf *PhantomJSFetcher) SetScripts(scripts []string) {
+	pf.scripts = scripts
+}
+
 func (pf *PhantomJSFetcher) Prepare() error {
+	data, err := json.Marshal(pf.scripts)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(pf.tempDir, "evals.json"), data, 0600)
+	if err != nil {
+		return err
+	}
+
 	// TODO: configure ssl errors / web security
 	// TODO: cookies file path might break if spaces
 	pf.args = []string{
@@ -173,18 +376,48 @@ func (pf *PhantomJSFetcher) Fetch(method, url string) (io.ReadCloser, error) {
 		return nil, ErrInvalidMethod
 	}
 
+	hasSession, err := pf.writeSession()
+	if err != nil {
+		return nil, err
+	}
+
 	// Call the fetch script with these parameters.
-	cmd := exec.Command(pf.binaryPath, append(pf.args, url)...)
+	args := append(append([]string{}, pf.args...), url)
+	if pf.CaptureWebSockets > 0 {
+		args = append(args, strconv.FormatInt(int64(pf.CaptureWebSockets/time.Millisecond), 10))
+	}
+	cmd := exec.Command(pf.binaryPath, args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		return nil, err
 	}
 
+	if hasSession {
+		var state struct {
+			Session *pageSession `json:"session"`
+		}
+		if err = json.Unmarshal(stdout.Bytes(), &state); err != nil {
+			return nil, err
+		}
+		pf.updateSession(state.Session)
+	}
+
+	if pf.CaptureWebSockets > 0 {
+		var captured struct {
+			Messages []webSocketMessage `json:"messages"`
+			Evals    []interface{}      `json:"evals"`
+		}
+		if err = json.NewDecoder(&stdout).Decode(&captured); err != nil {
+			return nil, err
+		}
+		return webSocketDocument(captured.Messages, captured.Evals)
+	}
+
 	// Load the resulting JSON.
 	results := map[string]interface{}{}
 	err = json.NewDecoder(&stdout).Decode(&results)
@@ -198,6 +431,240 @@ func (pf *PhantomJSFetcher) Fetch(method, url string) (io.ReadCloser, error) {
 		return nil, fmt.Errorf("unknown type for 'contents': %T", results["contents"])
 	}
 
+	evals, _ := results["evals"].([]interface{})
+	contents, err = addEvalResults(contents, evals)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStringReadCloser(contents), nil
+}
+
+// addEvalResults adds the results of evaluating scripts in the page to its
+// contents, in the form described by ScriptEvaluator.
+func addEvalResults(contents string, evals []interface{}) (string, error) {
+	if len(evals) == 0 {
+		return contents, nil
+	}
+
+	var buf bytes.Buffer
+	for _, e := range evals {
+		result, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		script, _ := result["script"].(string)
+		delete(result, "script")
+
+		// Since json.Marshal escapes '<' and '>', the result can't contain
+		// a closing script tag.
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, `<script type="application/json" data-goscrape-eval="%s">%s</script>`,
+			html.EscapeString(script), data)
+	}
+
+	idx := strings.LastIndex(contents, "</body>")
+	if idx < 0 {
+		return contents + buf.String(), nil
+	}
+	return contents[:idx] + buf.String() + contents[idx:], nil
+}
+
+// CookieJar returns a cookie jar that reads and writes the cookies in the
+// fetcher's Session.  Cookies that are added to it create a Session if there
+// isn't one, so that they are used for every later page.
+func (pf *PhantomJSFetcher) CookieJar() http.CookieJar {
+	return phantomJar{pf}
+}
+
+type phantomJar struct {
+	pf *PhantomJSFetcher
+}
+
+// Cookies returns the cookies in the Session that would be sent to the given
+// URL.
+func (j phantomJar) Cookies(u *neturl.URL) []*http.Cookie {
+	j.pf.sessionMu.Lock()
+	defer j.pf.sessionMu.Unlock()
+
+	if j.pf.Session == nil {
+		return nil
+	}
+
+	host := u.Hostname()
+	ret := []*http.Cookie{}
+	for _, c := range j.pf.Session.Cookies {
+		domain := strings.TrimPrefix(c.Domain, ".")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if !strings.HasPrefix(u.Path, c.Path) && !(u.Path == "" && c.Path == "/") {
+			continue
+		}
+		if c.Secure && u.Scheme != "https" {
+			continue
+		}
+		ret = append(ret, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return ret
+}
+
+// SetCookies adds the given cookies to the Session, replacing any with the
+// same name, domain and path.
+func (j phantomJar) SetCookies(u *neturl.URL, cookies []*http.Cookie) {
+	pf := j.pf
+	pf.sessionMu.Lock()
+	defer pf.sessionMu.Unlock()
+
+	if pf.Session == nil {
+		pf.Session = &BrowserSession{}
+	}
+	for _, c := range cookies {
+		bc := BrowserCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if bc.Domain == "" {
+			bc.Domain = u.Hostname()
+		}
+		if bc.Path == "" {
+			bc.Path = "/"
+		}
+		if !c.Expires.IsZero() {
+			bc.Expires = c.Expires.Unix()
+		}
+
+		// Replace any existing cookie with the same name, domain and path.
+		replaced := false
+		for i, existing := range pf.Session.Cookies {
+			if existing.Name == bc.Name && existing.Domain == bc.Domain && existing.Path == bc.Path {
+				pf.Session.Cookies[i] = bc
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			pf.Session.Cookies = append(pf.Session.Cookies, bc)
+		}
+	}
+}
+
+// writeSession writes the current session (if any) for the fetch script, and
+// returns whether there was one.
+func (pf *PhantomJSFetcher) writeSession() (bool, error) {
+	pf.sessionMu.Lock()
+	defer pf.sessionMu.Unlock()
+
+	path := filepath.Join(pf.tempDir, "session.json")
+	if pf.Session == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	data, err := json.Marshal(pf.Session)
+	if err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(path, data, 0600)
+}
+
+// pageSession is the session state returned by the fetch script.
+type pageSession struct {
+	Cookies []BrowserCookie `json:"cookies"`
+	Storage *struct {
+		Origin  string            `json:"origin"`
+		Local   map[string]string `json:"local"`
+		Session map[string]string `json:"session"`
+	} `json:"storage"`
+}
+
+// updateSession updates the Session with the state after fetching a page.
+// Nothing is updated if the Session was removed while the page was fetched.
+func (pf *PhantomJSFetcher) updateSession(state *pageSession) {
+	if state == nil {
+		return
+	}
+
+	pf.sessionMu.Lock()
+	defer pf.sessionMu.Unlock()
+
+	sess := pf.Session
+	if sess == nil {
+		return
+	}
+	if state.Cookies != nil {
+		sess.Cookies = state.Cookies
+	}
+	if state.Storage != nil && state.Storage.Origin != "" {
+		if sess.LocalStorage == nil {
+			sess.LocalStorage = map[string]map[string]string{}
+		}
+		if sess.SessionStorage == nil {
+			sess.SessionStorage = map[string]map[string]string{}
+		}
+		sess.LocalStorage[state.Storage.Origin] = state.Storage.Local
+		sess.SessionStorage[state.Storage.Origin] = state.Storage.Session
+	}
+}
+
+// webSocketMessage is a single message captured by the fetch script.
+type webSocketMessage struct {
+	URL  string `json:"url"`
+	Data string `json:"data"`
+	Time string `json:"time"`
+}
+
+// webSocketDocument converts the captured WebSocket messages into a document,
+// along with the results of evaluating scripts in the page.
+func webSocketDocument(messages []webSocketMessage, evals []interface{}) (io.ReadCloser, error) {
+	type message struct {
+		URL  string          `json:"url"`
+		Data json.RawMessage `json:"data"`
+		Time string          `json:"time"`
+	}
+
+	converted := []message{}
+	for _, m := range messages {
+		data := json.RawMessage(m.Data)
+		if !json.Valid(data) {
+			var err error
+			if data, err = json.Marshal(m.Data); err != nil {
+				return nil, err
+			}
+		}
+		converted = append(converted, message{m.URL, data, m.Time})
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{"messages": converted})
+	if err != nil {
+		return nil, err
+	}
+	doc, err := JSONToHTML(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := renderDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := addEvalResults(string(rendered), evals)
+	if err != nil {
+		return nil, err
+	}
 	return newStringReadCloser(contents), nil
 }
 
@@ -206,4 +673,5 @@ func (pf *PhantomJSFetcher) Close() {
 }
 
 // Static type assertion
-var _ Fetcher = &PhantomJSFetcher{}
+var _ ScriptEvaluator = &PhantomJSFetcher{}
+var _ CookieJarFetcher = &PhantomJSFetcher{}
@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketDocument(t *testing.T) {
+	r, err := webSocketDocument([]webSocketMessage{
+		{URL: "wss://example.com/live", Data: `{"score": "1-0"}`, Time: "2024-01-01T00:00:00.000Z"},
+		{URL: "wss://example.com/live", Data: `ping`, Time: "2024-01-01T00:00:01.000Z"},
+	}, []interface{}{
+		map[string]interface{}{"script": "window.room", "value": "lobby"},
+	})
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `<html><head></head><body><ol class="messages" data-key="messages">`+
+		`<li><span class="url" data-key="url">wss://example.com/live</span>`+
+		`<div class="data" data-key="data"><span class="score" data-key="score">1-0</span></div>`+
+		`<span class="time" data-key="time">2024-01-01T00:00:00.000Z</span></li>`+
+		`<li><span class="url" data-key="url">wss://example.com/live</span>`+
+		`<span class="data" data-key="data">ping</span>`+
+		`<span class="time" data-key="time">2024-01-01T00:00:01.000Z</span></li>`+
+		`</ol><script type="application/json" data-goscrape-eval="window.room">{"value":"lobby"}</script>`+
+		`</body></html>`, string(body))
+}
+
+func TestAddEvalResults(t *testing.T) {
+	contents, err := addEvalResults(`<html><body><p>hi</p></body></html>`, []interface{}{
+		map[string]interface{}{"script": `a["b"] < 2`, "value": "</script>"},
+		map[string]interface{}{"script": "oops()", "error": "ReferenceError"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><body><p>hi</p>`+
+		`<script type="application/json" data-goscrape-eval="a[&#34;b&#34;] &lt; 2">{"value":"\u003c/script\u003e"}</script>`+
+		`<script type="application/json" data-goscrape-eval="oops()">{"error":"ReferenceError"}</script>`+
+		`</body></html>`, contents)
+
+	contents, err = addEvalResults(`<p>hi</p>`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>hi</p>`, contents)
+}
+
+func TestUpdateSession(t *testing.T) {
+	pf := &PhantomJSFetcher{Session: &BrowserSession{
+		Cookies:      []BrowserCookie{{Name: "old", Value: "1"}},
+		LocalStorage: map[string]map[string]string{"https://other.com": {"a": "b"}},
+	}}
+
+	var state pageSession
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"cookies": [{"Name": "sid", "Value": "abc", "Domain": ".example.com", "Path": "/", "Expires": 1700000000, "HTTPOnly": true, "Secure": true}],
+		"storage": {"origin": "https://example.com", "local": {"token": "xyz"}, "session": {}}
+	}`), &state))
+	pf.updateSession(&state)
+
+	assert.Equal(t, &BrowserSession{
+		Cookies: []BrowserCookie{{
+			Name: "sid", Value: "abc", Domain: ".example.com", Path: "/",
+			Expires: 1700000000, HTTPOnly: true, Secure: true,
+		}},
+		LocalStorage: map[string]map[string]string{
+			"https://other.com":   {"a": "b"},
+			"https://example.com": {"token": "xyz"},
+		},
+		SessionStorage: map[string]map[string]string{
+			"https://example.com": {},
+		},
+	}, pf.Session)
+
+	// Missing state leaves the session alone.
+	pf.updateSession(&pageSession{})
+	assert.Equal(t, 1, len(pf.Session.Cookies))
+	assert.Equal(t, "xyz", pf.Session.LocalStorage["https://example.com"]["token"])
+
+	// A session that was removed during the fetch isn't recreated.
+	pf.Session = nil
+	pf.updateSession(&state)
+	assert.Nil(t, pf.Session)
+}
+
+func TestPhantomJar(t *testing.T) {
+	pf := &PhantomJSFetcher{}
+	jar := pf.CookieJar()
+
+	u, _ := url.Parse("https://www.example.com/shop")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "a", Value: "1"},
+		{Name: "b", Value: "2", Domain: ".example.com", Path: "/other"},
+		{Name: "c", Value: "3", Domain: "example.com", Secure: true},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "4"}})
+
+	if assert.NotNil(t, pf.Session) {
+		assert.Equal(t, 3, len(pf.Session.Cookies))
+	}
+	assert.Equal(t, []*http.Cookie{
+		{Name: "a", Value: "4"},
+		{Name: "c", Value: "3"},
+	}, jar.Cookies(u))
+
+	u, _ = url.Parse("http://example.com/other/page")
+	assert.Equal(t, []*http.Cookie{{Name: "b", Value: "2"}}, jar.Cookies(u))
+}
@@ -0,0 +1,123 @@
+package scrape
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+type cachedExtractor struct {
+	store     Store
+	namespace string
+	e         PieceExtractor
+}
+
+// WithCache returns a PieceExtractor that caches the results of the given
+// extractor in a Store, keyed by a hash of the HTML of the selection it is
+// given.  This is intended for expensive extractors (e.g. ones that download
+// files or call other services), so that repeated scrapes of unchanged blocks
+// skip the expensive work.
+//
+// The namespace separates the cached results of different extractors in the
+// same Store, and should be changed whenever the extractor's behaviour
+// changes so that stale results aren't used.  Errors are not cached.
+//
+// Note: since cached results are encoded as JSON, values returned from the
+// cache are decoded as the equivalent JSON types (e.g. numbers become float64,
+// and a []string becomes a []interface{}).  Also, if the underlying extractor
+// is a ContextualExtractor, then its results are cached by content alone, so
+// the same result is returned for identical blocks on different pages.
+func WithCache(store Store, namespace string, e PieceExtractor) PieceExtractor {
+	return &cachedExtractor{
+		store:     store,
+		namespace: namespace,
+		e:         e,
+	}
+}
+
+func (c *cachedExtractor) Validate() error {
+	if c.store == nil {
+		return errors.New("no store provided for cache")
+	}
+	if c.e == nil {
+		return errors.New("no extractor provided for cache")
+	}
+	if v, ok := c.e.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (c *cachedExtractor) Extract(sel *goquery.Selection) (interface{}, error) {
+	return c.extract(sel, func() (interface{}, error) {
+		return c.e.Extract(sel)
+	})
+}
+
+func (c *cachedExtractor) ExtractWithContext(ctx ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	return c.extract(sel, func() (interface{}, error) {
+		if ce, ok := c.e.(ContextualExtractor); ok {
+			return ce.ExtractWithContext(ctx, sel)
+		}
+		return c.e.Extract(sel)
+	})
+}
+
+func (c *cachedExtractor) extract(sel *goquery.Selection, miss func() (interface{}, error)) (interface{}, error) {
+	key, err := c.key(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		var val interface{}
+		if err = json.Unmarshal(data, &val); err != nil {
+			return nil, err
+		}
+		return val, nil
+	}
+
+	val, err := miss()
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = json.Marshal(val); err != nil {
+		return nil, err
+	}
+	if err = c.store.Put(key, data); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// key returns the Store key for the given selection.
+func (c *cachedExtractor) key(sel *goquery.Selection) (string, error) {
+	var buf bytes.Buffer
+	for _, node := range sel.Nodes {
+		if err := html.Render(&buf, node); err != nil {
+			return "", err
+		}
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return "piece:" + c.namespace + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func (c *cachedExtractor) Unwrap() []PieceExtractor {
+	return []PieceExtractor{c.e}
+}
+
+// Static type assertion
+var _ ContextualExtractor = &cachedExtractor{}
+var _ Validator = &cachedExtractor{}
+var _ WrappingExtractor = &cachedExtractor{}
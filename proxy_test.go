@@ -0,0 +1,205 @@
+package scrape
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyPool(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("good " + r.URL.Host))
+	}))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer bad.Close()
+
+	now := time.Unix(1000, 0)
+	pool, err := NewProxyPool([]string{bad.URL, good.URL}, ProxyPoolConfig{
+		MinRequests:        2,
+		QuarantineDuration: time.Minute,
+	})
+	assert.NoError(t, err)
+	pool.now = func() time.Time { return now }
+
+	client := &http.Client{Transport: pool.Transport(nil)}
+	get := func(url string) (int, string) {
+		resp, err := client.Get(url)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Requests alternate between the proxies until the bad one is quarantined.
+	for i := 0; i < 4; i++ {
+		get("http://example.com/")
+	}
+	stats := pool.Stats()
+	assert.Equal(t, 2, stats[0].Blocked)
+	assert.True(t, stats[0].Quarantined)
+	assert.Equal(t, 2, stats[1].Requests)
+	assert.Equal(t, 1.0, stats[1].SuccessRate)
+
+	status, body := get("http://example.com/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "good example.com", body)
+
+	// Once the quarantine is over, the proxy is used again.
+	now = now.Add(time.Minute)
+	stats = pool.Stats()
+	assert.False(t, stats[0].Quarantined)
+	assert.Equal(t, 0, stats[0].Requests)
+}
+
+func TestProxyPoolSticky(t *testing.T) {
+	pool, err := NewProxyPool([]string{"http://a:1", "http://b:2"}, ProxyPoolConfig{Sticky: true})
+	assert.NoError(t, err)
+
+	first, _, err := pool.pick("example.com")
+	assert.NoError(t, err)
+	other, _, err := pool.pick("example.org")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, other)
+
+	again, _, err := pool.pick("example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	// A quarantined proxy is replaced.
+	for i := 0; i < 5; i++ {
+		pool.record(first, 0, nil, true)
+	}
+	again, _, err = pool.pick("example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, other, again)
+
+	for i := 0; i < 5; i++ {
+		pool.record(other, 0, nil, true)
+	}
+	_, _, err = pool.pick("example.com")
+	assert.Equal(t, ErrNoHealthyProxies, err)
+}
+
+func TestProxyPoolTransport(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	pool, err := NewProxyPool([]string{proxy.URL}, ProxyPoolConfig{})
+	assert.NoError(t, err)
+
+	// The proxy's transport is only replaced when the base transport changes.
+	rt := pool.Transport(nil)
+	first := pool.proxies[0].transport
+	pool.Transport(nil)
+	assert.True(t, first == pool.proxies[0].transport)
+	pool.Transport(&http.Transport{})
+	assert.False(t, first == pool.proxies[0].transport)
+
+	// Requests can be made while the transport is being set up again, e.g. by
+	// another scrape preparing its fetcher.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				pool.Transport(nil)
+				return
+			}
+			req, _ := http.NewRequest("GET", "http://example.com/", nil)
+			resp, err := rt.RoundTrip(req)
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestHttpClientFetcherProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	hf, err := NewHttpClientFetcher()
+	assert.NoError(t, err)
+	hf.Proxy = proxy.URL
+	assert.NoError(t, hf.Prepare())
+
+	body, err := hf.Fetch("GET", "http://example.com/page")
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadAll(body)
+		body.Close()
+		assert.Equal(t, "proxied http://example.com/page", string(data))
+	}
+
+	for proxy, msg := range map[string]string{
+		"ftp://proxy.example.com": `unsupported proxy scheme "ftp" in ftp://proxy.example.com`,
+		"socks5://":               "no host in proxy URL socks5://",
+	} {
+		hf, err := NewHttpClientFetcher()
+		assert.NoError(t, err)
+		hf.Proxy = proxy
+		assert.EqualError(t, hf.Prepare(), msg)
+	}
+}
+
+func TestRotatingProxyFetcher(t *testing.T) {
+	var dead []*http.Request
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if name == "dead" {
+				dead = append(dead, r)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(name))
+		}))
+	}
+	a, b, c := newProxy("a"), newProxy("dead"), newProxy("c")
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+
+	fetcher := &RotatingProxyFetcher{
+		Proxies: []string{a.URL, b.URL, c.URL},
+		Config:  ProxyPoolConfig{MaxConsecutiveFailures: 1},
+	}
+	assert.NoError(t, fetcher.Prepare())
+	// Preparing again keeps the same pool.
+	assert.NoError(t, fetcher.Prepare())
+
+	var got []string
+	for i := 0; i < 5; i++ {
+		body, err := fetcher.Fetch("GET", "http://example.com/")
+		if !assert.NoError(t, err) {
+			continue
+		}
+		data, _ := ioutil.ReadAll(body)
+		body.Close()
+		got = append(got, string(data))
+	}
+
+	// The dead proxy is evicted after its first failure.
+	assert.Equal(t, []string{"a", "", "c", "a", "c"}, got)
+	assert.Len(t, dead, 1)
+	stats := fetcher.Stats()
+	assert.True(t, stats[1].Quarantined)
+	assert.Equal(t, 2, stats[2].Requests)
+
+	fetcher = &RotatingProxyFetcher{Fetcher: &HttpClientFetcher{Proxy: a.URL}, Proxies: []string{a.URL}}
+	assert.EqualError(t, fetcher.Prepare(), "RotatingProxyFetcher's fetcher must not have a Proxy")
+}
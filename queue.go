@@ -0,0 +1,259 @@
+package scrape
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// BackpressurePolicy controls what a queued Sink does when its queue is full.
+type BackpressurePolicy int
+
+const (
+	// Block makes Write wait until there is room in the queue.  This slows the
+	// scrape down to the speed of the Sink, but never loses data.
+	Block BackpressurePolicy = iota
+
+	// DropOldest discards the oldest queued page to make room for the new one.
+	// The number of discarded pages can be retrieved with QueuedSink.Dropped.
+	DropOldest
+
+	// SpillToDisk writes pages that don't fit in the queue to a temporary file,
+	// and reads them back once the Sink catches up.  Since spilled pages are
+	// stored as JSON, the values in their blocks are decoded as the equivalent
+	// JSON types (e.g. numbers become float64).
+	SpillToDisk
+)
+
+// QueuedSink is a Sink that places pages in a bounded queue and writes them to
+// an underlying Sink in the background, so that a slow Sink does not stall the
+// scrape.  Create one with the NewQueuedSink function.
+//
+// If the underlying Sink returns an error, then all pages still in the queue
+// are discarded, and the error is returned from the next call to Write or
+// Flush.
+type QueuedSink struct {
+	sink   Sink
+	size   int
+	policy BackpressurePolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*Page
+	running bool
+	err     error
+	dropped int
+
+	// Spill file state, only used with SpillToDisk.
+	spillW     *os.File
+	spillR     *os.File
+	spillEnc   *json.Encoder
+	spillDec   *json.Decoder
+	numSpilled int
+}
+
+// NewQueuedSink wraps the given Sink with a queue that holds at most 'size'
+// pages, using the given policy when the queue is full.
+func NewQueuedSink(sink Sink, size int, policy BackpressurePolicy) *QueuedSink {
+	if size < 1 {
+		size = 1
+	}
+
+	ret := &QueuedSink{
+		sink:   sink,
+		size:   size,
+		policy: policy,
+	}
+	ret.cond = sync.NewCond(&ret.mu)
+	return ret
+}
+
+func (q *QueuedSink) Write(p *Page) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.err != nil {
+		return q.err
+	}
+
+	switch {
+	case q.numSpilled > 0:
+		// Once we've started spilling, everything goes to disk until the spill
+		// file is drained, so that pages stay in order.
+		if err := q.spill(p); err != nil {
+			return err
+		}
+
+	case len(q.queue) < q.size:
+		q.queue = append(q.queue, p)
+
+	case q.policy == DropOldest:
+		q.queue = append(q.queue[1:], p)
+		q.dropped++
+
+	case q.policy == SpillToDisk:
+		if err := q.spill(p); err != nil {
+			return err
+		}
+
+	default:
+		for len(q.queue) >= q.size && q.err == nil {
+			q.cond.Wait()
+		}
+		if q.err != nil {
+			return q.err
+		}
+		q.queue = append(q.queue, p)
+	}
+
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+	return nil
+}
+
+// Flush waits until every queued page has been written to the underlying
+// Sink, and then flushes it.
+func (q *QueuedSink) Flush() error {
+	if err := q.wait(); err != nil {
+		return err
+	}
+	return q.sink.Flush()
+}
+
+// Failed waits until every queued page has been written to the underlying
+// Sink, and then passes the error on to it, if it is a FailureSink.
+func (q *QueuedSink) Failed(err error) {
+	q.wait()
+	if fs, ok := q.sink.(FailureSink); ok {
+		fs.Failed(err)
+	}
+}
+
+// wait blocks until the queue has been written to the underlying Sink, so that
+// it isn't called from two goroutines at once, and returns its error, if any.
+func (q *QueuedSink) wait() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.running {
+		q.cond.Wait()
+	}
+	return q.err
+}
+
+// Dropped returns the number of pages that have been discarded due to the
+// DropOldest policy.
+func (q *QueuedSink) Dropped() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Check returns the error from the underlying Sink, if it has failed.  If the
+// underlying Sink has a "Check() error" method, then that is also called.
+func (q *QueuedSink) Check() error {
+	q.mu.Lock()
+	err := q.err
+	q.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if c, ok := q.sink.(interface {
+		Check() error
+	}); ok {
+		return c.Check()
+	}
+	return nil
+}
+
+// run writes queued pages to the underlying Sink until there are none left.
+func (q *QueuedSink) run() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.err == nil {
+		var p *Page
+		if len(q.queue) > 0 {
+			p = q.queue[0]
+			q.queue = q.queue[1:]
+		} else if q.numSpilled > 0 {
+			p = &Page{}
+			if err := q.spillDec.Decode(p); err != nil {
+				q.err = err
+				break
+			}
+			q.numSpilled--
+			if q.numSpilled == 0 {
+				q.closeSpill()
+			}
+		} else {
+			break
+		}
+
+		// Let any blocked writers proceed while we write this page.
+		q.cond.Broadcast()
+		q.mu.Unlock()
+		err := q.sink.Write(p)
+		q.mu.Lock()
+
+		if err != nil {
+			q.err = err
+		}
+	}
+
+	if q.err != nil {
+		q.queue = nil
+		q.numSpilled = 0
+		q.closeSpill()
+	}
+	q.running = false
+	q.cond.Broadcast()
+}
+
+// spill appends the given page to the spill file, creating it if necessary.
+// Must be called with the lock held.
+func (q *QueuedSink) spill(p *Page) error {
+	if q.spillW == nil {
+		w, err := ioutil.TempFile("", "goscrape-spill-")
+		if err != nil {
+			return err
+		}
+		r, err := os.Open(w.Name())
+		if err != nil {
+			w.Close()
+			os.Remove(w.Name())
+			return err
+		}
+
+		q.spillW, q.spillR = w, r
+		q.spillEnc = json.NewEncoder(w)
+		q.spillDec = json.NewDecoder(r)
+	}
+
+	if err := q.spillEnc.Encode(p); err != nil {
+		return err
+	}
+	q.numSpilled++
+	return nil
+}
+
+// closeSpill closes and removes the spill file, if any.  Must be called with
+// the lock held.
+func (q *QueuedSink) closeSpill() {
+	if q.spillW == nil {
+		return
+	}
+
+	q.spillW.Close()
+	q.spillR.Close()
+	os.Remove(q.spillW.Name())
+	q.spillW, q.spillR = nil, nil
+	q.spillEnc, q.spillDec = nil, nil
+}
+
+// Static type assertion
+var _ FailureSink = &QueuedSink{}
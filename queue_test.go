@@ -0,0 +1,124 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedSink is a Sink that doesn't accept any pages until it's opened.
+type gatedSink struct {
+	gate chan struct{}
+
+	mu      sync.Mutex
+	urls    []string
+	err     error
+	flushed bool
+
+	// The pages written before Failed was called.
+	failedAfter []string
+}
+
+func newGatedSink() *gatedSink {
+	return &gatedSink{gate: make(chan struct{})}
+}
+
+func (s *gatedSink) Write(p *Page) error {
+	<-s.gate
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urls = append(s.urls, p.URL)
+	return s.err
+}
+
+func (s *gatedSink) Flush() error {
+	s.flushed = true
+	return nil
+}
+
+func (s *gatedSink) Failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failedAfter = append([]string{}, s.urls...)
+}
+
+func writePages(t *testing.T, s Sink, n int) {
+	for i := 0; i < n; i++ {
+		assert.NoError(t, s.Write(&Page{URL: fmt.Sprintf("url-%d", i), Index: i}))
+	}
+}
+
+func TestQueuedSinkBlock(t *testing.T) {
+	inner := newGatedSink()
+	q := NewQueuedSink(inner, 2, Block)
+
+	done := make(chan struct{})
+	go func() {
+		writePages(t, q, 5)
+		close(done)
+	}()
+
+	close(inner.gate)
+	<-done
+	assert.NoError(t, q.Flush())
+	assert.True(t, inner.flushed)
+	assert.Equal(t, []string{"url-0", "url-1", "url-2", "url-3", "url-4"}, inner.urls)
+}
+
+func TestQueuedSinkDropOldest(t *testing.T) {
+	inner := newGatedSink()
+	q := NewQueuedSink(inner, 2, DropOldest)
+
+	// Depending on when the background writer picks up the first page, either
+	// two or three pages are dropped - but the newest pages always survive.
+	writePages(t, q, 5)
+	close(inner.gate)
+
+	assert.NoError(t, q.Flush())
+	assert.Equal(t, 5, q.Dropped()+len(inner.urls))
+	assert.True(t, q.Dropped() >= 2)
+	assert.Equal(t, []string{"url-3", "url-4"}, inner.urls[len(inner.urls)-2:])
+}
+
+func TestQueuedSinkSpillToDisk(t *testing.T) {
+	inner := newGatedSink()
+	q := NewQueuedSink(inner, 1, SpillToDisk)
+
+	writePages(t, q, 6)
+	close(inner.gate)
+
+	assert.NoError(t, q.Flush())
+	assert.Equal(t, 0, q.Dropped())
+	assert.Equal(t, []string{"url-0", "url-1", "url-2", "url-3", "url-4", "url-5"}, inner.urls)
+}
+
+func TestQueuedSinkError(t *testing.T) {
+	inner := newGatedSink()
+	inner.err = errors.New("sink failed")
+	close(inner.gate)
+
+	q := NewQueuedSink(inner, 2, Block)
+	assert.NoError(t, q.Write(&Page{URL: "url-0"}))
+	assert.EqualError(t, q.Flush(), "sink failed")
+	assert.EqualError(t, q.Write(&Page{URL: "url-1"}), "sink failed")
+}
+
+func TestQueuedSinkFailed(t *testing.T) {
+	inner := newGatedSink()
+	q := NewQueuedSink(inner, 3, Block)
+	writePages(t, q, 3)
+
+	// The failure is only passed on once the queue has been written.
+	done := make(chan struct{})
+	go func() {
+		q.Failed(errors.New("scrape failed"))
+		close(done)
+	}()
+	close(inner.gate)
+	<-done
+	assert.Equal(t, []string{"url-0", "url-1", "url-2"}, inner.failedAfter)
+}
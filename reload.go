@@ -0,0 +1,143 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reloadable holds a Scraper whose configuration can be replaced while it is
+// in use - e.g. to deploy a fixed selector in a long-running service without
+// restarting it.  Scrapes that are already running when the configuration is
+// replaced continue to use the old configuration until they finish; scrapes
+// started afterwards use the new one.
+//
+// A new configuration can be given directly with Reload - e.g. from an admin
+// API of the service - or loaded whenever the files in a directory change
+// with WatchDir.
+//
+// Reloadable is safe for concurrent use.
+type Reloadable struct {
+	mu      sync.RWMutex
+	scraper *Scraper
+}
+
+// NewReloadable creates a Reloadable with the given initial configuration.
+func NewReloadable(c *ScrapeConfig) (*Reloadable, error) {
+	scraper, err := New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reloadable{scraper: scraper}, nil
+}
+
+// Scraper returns the Scraper for the current configuration.
+func (r *Reloadable) Scraper() *Scraper {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.scraper
+}
+
+// Reload replaces the current configuration with the given one.  If the new
+// configuration is invalid, then an error is returned and the current
+// configuration is kept.
+func (r *Reloadable) Reload(c *ScrapeConfig) error {
+	scraper, err := New(c)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.scraper = scraper
+	r.mu.Unlock()
+	return nil
+}
+
+// Scrape scrapes the given URL using the current configuration and default
+// options.
+func (r *Reloadable) Scrape(url string) (*ScrapeResults, error) {
+	return r.Scraper().Scrape(url)
+}
+
+// ScrapeWithOpts scrapes the given URL using the current configuration.
+func (r *Reloadable) ScrapeWithOpts(url string, opts ScrapeOptions) (*ScrapeResults, error) {
+	return r.Scraper().ScrapeWithOpts(url, opts)
+}
+
+// ConfigLoader builds a ScrapeConfig from the files in the given directory.
+// Since a ScrapeConfig contains code, such as its extractors, goscrape has no
+// configuration file format of its own; the loader decides how the files are
+// turned into a configuration.
+type ConfigLoader func(dir string) (*ScrapeConfig, error)
+
+// WatchDir checks the given directory every interval, and whenever a file in
+// it is added, removed or modified, calls load to build a new configuration
+// and reloads it.  The current configuration is assumed to match the
+// directory's contents when WatchDir is called.
+//
+// If the new configuration can't be loaded or is invalid, then the error is
+// logged to the current configuration's Logger, the current configuration is
+// kept, and watching continues - so a broken configuration can be fixed by
+// changing the files again.  WatchDir returns the context's error once it is
+// done, or an error if the directory can't be read when it is called.
+func (r *Reloadable) WatchDir(ctx context.Context, dir string, interval time.Duration, load ConfigLoader) error {
+	if interval <= 0 {
+		return errors.New("watch interval must be positive")
+	}
+	if load == nil {
+		return errors.New("no config loader provided")
+	}
+
+	last, err := dirState(dir)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		state, err := dirState(dir)
+		if err != nil {
+			r.Scraper().logf("watching %s: %s", dir, err)
+			continue
+		}
+		if state == last {
+			continue
+		}
+		last = state
+
+		c, err := load(dir)
+		if err == nil {
+			err = r.Reload(c)
+		}
+		if err != nil {
+			r.Scraper().logf("reloading config from %s: %s", dir, err)
+		}
+	}
+}
+
+// dirState returns a summary of the names, sizes and modification times of
+// the files in the given directory, which changes whenever any of them do.
+func dirState(dir string) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, info := range infos {
+		fmt.Fprintf(&b, "%s\x00%d\x00%d\n", info.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
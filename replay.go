@@ -0,0 +1,147 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrNotRecorded is returned by a ReplayFetcher for requests that weren't
+// recorded.
+var ErrNotRecorded = errors.New("request was not recorded")
+
+// RecordingFetcher is a Fetcher that wraps another Fetcher, and writes every
+// response that it receives to a directory of fixtures, which a ReplayFetcher
+// can later serve.  This allows a scrape configuration to be tested against a
+// recording of a live site, so that the test is deterministic and doesn't
+// need network access - e.g.:
+//
+//	// Run once, to record the fixtures.
+//	config.Fetcher = &scrape.RecordingFetcher{Fetcher: fetcher, Dir: "testdata/site"}
+//
+//	// In tests.
+//	config.Fetcher = &scrape.ReplayFetcher{Dir: "testdata/site"}
+//
+// Responses are recorded whatever their status code, along with their
+// headers if the wrapped Fetcher reports them with ResponseMetadata.  Requests
+// that fail aren't recorded.  If a request is made more than once, then the
+// last response is kept.
+//
+// A RecordingFetcher is safe to use concurrently if the underlying Fetcher
+// is.
+type RecordingFetcher struct {
+	// The Fetcher to make requests with.
+	Fetcher Fetcher
+
+	// The directory in which the fixtures are stored.  It is created if it
+	// doesn't exist.
+	Dir string
+}
+
+func (f *RecordingFetcher) Prepare() error {
+	if f.Fetcher == nil {
+		return errors.New("no fetcher to record")
+	}
+	if f.Dir == "" {
+		return errors.New("no fixture directory given")
+	}
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	return f.Fetcher.Prepare()
+}
+
+func (f *RecordingFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *RecordingFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	rc, err := fetchFunc(f.Fetcher)(ctx, method, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &Response{StatusCode: http.StatusOK, URL: url}
+	if rm, ok := rc.(ResponseMetadata); ok {
+		resp = rm.Response()
+	}
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cacheEntry{Response: resp, Stored: time.Now()}
+	if err := saveEntry(entryPath(f.Dir, fixtureKey(method, url)), entry, data); err != nil {
+		return nil, err
+	}
+	return entry.body(data), nil
+}
+
+func (f *RecordingFetcher) CookieJar() http.CookieJar {
+	if jf, ok := f.Fetcher.(CookieJarFetcher); ok {
+		return jf.CookieJar()
+	}
+	return nil
+}
+
+func (f *RecordingFetcher) Close() {
+	f.Fetcher.Close()
+}
+
+// SetScripts passes the given scripts to the underlying Fetcher, if it is a
+// ScriptEvaluator.
+func (f *RecordingFetcher) SetScripts(scripts []string) {
+	setScripts(f.Fetcher, scripts)
+}
+
+// Check calls the underlying Fetcher's Check method, if it has one.
+func (f *RecordingFetcher) Check() error {
+	return checkFetcher(f.Fetcher)
+}
+
+// ReplayFetcher is a Fetcher that serves the responses recorded by a
+// RecordingFetcher, without making any requests.  Requests that weren't
+// recorded fail with ErrNotRecorded, so that a test notices when a change to
+// a scrape configuration makes it fetch different pages.
+//
+// A ReplayFetcher is safe to use concurrently.
+type ReplayFetcher struct {
+	// The directory in which the fixtures are stored.
+	Dir string
+}
+
+func (f *ReplayFetcher) Prepare() error {
+	if f.Dir == "" {
+		return errors.New("no fixture directory given")
+	}
+	_, err := os.Stat(f.Dir)
+	return err
+}
+
+func (f *ReplayFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	entry, body := loadEntry(entryPath(f.Dir, fixtureKey(method, url)))
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, method, url)
+	}
+	return entry.body(body), nil
+}
+
+func (f *ReplayFetcher) Close() {}
+
+// fixtureKey returns the key under which the response to the given request is
+// recorded.
+func fixtureKey(method, url string) string {
+	return method + " " + url
+}
+
+// Static type assertion
+var _ ContextFetcher = &RecordingFetcher{}
+var _ CookieJarFetcher = &RecordingFetcher{}
+var _ ScriptEvaluator = &RecordingFetcher{}
+var _ Fetcher = &ReplayFetcher{}
@@ -0,0 +1,69 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("X-Method", r.Method)
+			fmt.Fprint(w, "page "+r.Method)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	hf, err := NewHttpClientFetcher()
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	recorder := &RecordingFetcher{Fetcher: hf, Dir: dir}
+	assert.NoError(t, recorder.Prepare())
+
+	for _, req := range [][2]string{{"GET", "/page"}, {"POST", "/page"}, {"GET", "/missing"}} {
+		body, err := recorder.Fetch(req[0], ts.URL+req[1])
+		if assert.NoError(t, err) {
+			body.Close()
+		}
+	}
+	ts.Close()
+
+	replay := &ReplayFetcher{Dir: dir}
+	assert.NoError(t, replay.Prepare())
+
+	get := func(method, path string) (*Response, string) {
+		body, err := replay.Fetch(method, ts.URL+path)
+		if !assert.NoError(t, err) {
+			return nil, ""
+		}
+		defer body.Close()
+		data, _ := ioutil.ReadAll(body)
+		return body.(ResponseMetadata).Response(), string(data)
+	}
+
+	// Responses are replayed, with their status and headers, without the
+	// server.
+	resp, body := get("GET", "/page")
+	assert.Equal(t, "page GET", body)
+	assert.Equal(t, "GET", resp.Header.Get("X-Method"))
+	_, body = get("POST", "/page")
+	assert.Equal(t, "page POST", body)
+	resp, _ = get("GET", "/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Requests that weren't recorded fail.
+	_, err = replay.Fetch("GET", ts.URL+"/other")
+	assert.True(t, errors.Is(err, ErrNotRecorded), err)
+	_, err = replay.Fetch("PUT", ts.URL+"/page")
+	assert.True(t, errors.Is(err, ErrNotRecorded), err)
+
+	assert.Error(t, (&ReplayFetcher{Dir: dir + "/nonexistent"}).Prepare())
+}
@@ -0,0 +1,43 @@
+package scrape
+
+import (
+	"context"
+	"io"
+)
+
+// RequestInfo describes a request's position in a scrape.  It is passed to
+// Fetchers that implement ContextFetcher.
+type RequestInfo struct {
+	// The index of the page being fetched, starting at 0.
+	PageIndex int
+
+	// The URL of the previous page in the scrape, or of the page on which the
+	// link was found during a crawl.  This is empty for the first page, and
+	// for the first page after resuming from a Checkpoint.
+	PreviousURL string
+
+	// The ScrapeConfig's Tags.
+	Tags map[string]string
+}
+
+type requestInfoKey struct{}
+
+// RequestInfoFromContext returns the RequestInfo stored in the given context,
+// and whether there was one.
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+// fetch fetches the given URL, passing the request information to the Fetcher
+// if it supports it.
+func (s *Scraper) fetch(url string, info RequestInfo) (io.ReadCloser, error) {
+	cf, ok := s.config.Fetcher.(ContextFetcher)
+	if !ok {
+		return s.config.Fetcher.Fetch("GET", url)
+	}
+
+	info.Tags = s.config.Tags
+	ctx := context.WithValue(context.Background(), requestInfoKey{}, info)
+	return cf.FetchContext(ctx, "GET", url)
+}
@@ -2,6 +2,7 @@ package scrape
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -37,3 +38,148 @@ func TestResultsAllBlocks(t *testing.T) {
 		{"baz": 3, "asdf": 4},
 	})
 }
+
+func TestResultsDecode(t *testing.T) {
+	type author struct {
+		Name string `scrape:"name"`
+	}
+	type story struct {
+		Title  string    `scrape:"title"`
+		Points int       `scrape:"score"`
+		Ratio  float64   `scrape:"ratio"`
+		Posted time.Time `scrape:"date,layout=Jan 2 2006"`
+		Tags   []string  `scrape:"tags"`
+		Link   string
+		Author *author `scrape:"author"`
+		Hidden string  `scrape:"-"`
+	}
+
+	r := &ScrapeResults{
+		Results: [][]map[string]interface{}{
+			{{
+				"title":  "First",
+				"score":  " 42 ",
+				"ratio":  3,
+				"date":   "Mar 4 2015",
+				"tags":   "solo",
+				"link":   []string{"http://example.com"},
+				"author": map[string]interface{}{"name": "someone"},
+				"Hidden": "x",
+			}},
+			{{"title": "Second", "score": 7.0, "tags": []string{"a", "b"}}},
+		},
+	}
+
+	var stories []story
+	assert.NoError(t, r.Decode(&stories))
+	assert.Equal(t, []story{
+		{
+			Title:  "First",
+			Points: 42,
+			Ratio:  3,
+			Posted: time.Date(2015, 3, 4, 0, 0, 0, 0, time.UTC),
+			Tags:   []string{"solo"},
+			Link:   "http://example.com",
+			Author: &author{Name: "someone"},
+		},
+		{Title: "Second", Points: 7, Tags: []string{"a", "b"}},
+	}, stories)
+
+	var ptrs []*story
+	assert.NoError(t, r.Decode(&ptrs))
+	assert.Equal(t, "Second", ptrs[1].Title)
+
+	r = &ScrapeResults{
+		Results: [][]map[string]interface{}{{{"score": "many"}}},
+	}
+	assert.Error(t, r.Decode(&stories))
+	assert.Error(t, r.Decode(stories))
+
+	var s story
+	assert.NoError(t, DecodeBlock(map[string]interface{}{"score": 1.0}, &s))
+	assert.Equal(t, 1, s.Points)
+}
+
+func TestMergeResults(t *testing.T) {
+	a := &ScrapeResults{
+		URLs: []string{"p1", "p2"},
+		Results: [][]map[string]interface{}{
+			{{"id": "1", "name": "one", "price": "5"}, {"name": "no id"}},
+			{{"id": "2", "name": "two"}},
+		},
+		Failures: map[string]*PieceFailures{"price": {Count: 1, Samples: []FailureSample{}}},
+	}
+	b := &ScrapeResults{
+		URLs: []string{"p2", "p3"},
+		Results: [][]map[string]interface{}{
+			{{"id": "1", "name": "", "price": "6"}, {"name": "no id"}},
+			{{"id": "3", "name": "three"}},
+		},
+		Failures: map[string]*PieceFailures{"price": {Count: 2, Samples: []FailureSample{}}},
+	}
+
+	res := MergeResults(a, b, "id", MergeUnion)
+	assert.Equal(t, []string{"p1", "p2", "p3"}, res.URLs)
+	assert.Equal(t, [][]map[string]interface{}{
+		{{"id": "1", "name": "one", "price": "5"}, {"name": "no id"}},
+		{{"id": "2", "name": "two"}, {"name": "no id"}},
+		{{"id": "3", "name": "three"}},
+	}, res.Results)
+	assert.Equal(t, 3, res.Failures["price"].Count)
+	assert.Nil(t, res.StartURLs)
+
+	res = MergeResults(a, b, "id", MergePreferNewest)
+	assert.Equal(t, map[string]interface{}{"id": "1", "name": "", "price": "6"}, res.Results[0][0])
+
+	res = MergeResults(a, b, "id", MergePreferNonEmpty)
+	assert.Equal(t, map[string]interface{}{"id": "1", "name": "one", "price": "6"}, res.Results[0][0])
+
+	// The inputs are not modified.
+	assert.Equal(t, "one", a.Results[0][0]["name"])
+	assert.Len(t, a.Results[1], 1)
+}
+
+func TestResultsSummary(t *testing.T) {
+	r := &ScrapeResults{
+		URLs: []string{"http://example.com/1", "http://example.com/2", "http://example.com/3"},
+		Results: [][]map[string]interface{}{
+			{{"title": "a", "price": 1}, {"title": "b"}},
+			{{"title": "c", "price": 3}},
+			{},
+		},
+		Failures: map[string]*PieceFailures{
+			"price": {Count: 1},
+			"sku":   {Count: 3},
+		},
+		Usage: map[string]*HostUsage{
+			"example.com": {Requests: 2, Bytes: 3 << 10, FetchTime: 1500 * time.Millisecond},
+			"cdn.example": {Requests: 1, Bytes: 512, FetchTime: 500 * time.Millisecond},
+		},
+		Duration: 2345 * time.Millisecond,
+	}
+
+	s := r.Summary()
+	assert.Equal(t, &Summary{
+		Pages:     3,
+		Blocks:    3,
+		Filled:    map[string]int{"title": 3, "price": 2, "sku": 0},
+		Duration:  2345 * time.Millisecond,
+		Bytes:     3<<10 + 512,
+		FetchTime: 2 * time.Second,
+	}, s)
+	assert.InDelta(t, 2.0/3, s.FillRate("price"), 0.0001)
+	assert.Equal(t, 0.0, s.FillRate("missing"))
+
+	assert.Equal(t, "pages:    3\n"+
+		"blocks:   3\n"+
+		"duration: 2.35s\n"+
+		"fetched:  3.5 KiB in 2s\n"+
+		"pieces:\n"+
+		"  price  66.7% (2/3)\n"+
+		"  sku     0.0% (0/3)\n"+
+		"  title 100.0% (3/3)\n", s.String())
+
+	empty := (&ScrapeResults{URLs: []string{"http://example.com"}, Results: [][]map[string]interface{}{{}}}).Summary()
+	assert.Equal(t, 0.0, empty.FillRate("title"))
+	assert.Equal(t, "pages:    1\nblocks:   0\nduration: 0s\n", empty.String())
+}
@@ -0,0 +1,185 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how HttpClientFetcher and RetryMiddleware retry
+// requests that fail, so that a single flaky response doesn't abort a long
+// scrape.  The delay before each retry grows exponentially, and is replaced
+// by the server's Retry-After header if it sends one.
+type RetryPolicy struct {
+	// The maximum number of attempts for each request, including the first.
+	// If this is 0, then 3 is used.
+	MaxAttempts int
+
+	// The delay before the first retry, and the maximum delay before any
+	// retry.  Each delay is Multiplier times the one before.  If these are 0,
+	// then an initial delay of 1 second, a maximum of 1 minute, and a
+	// multiplier of 2 are used.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+
+	// Jitter randomly varies each delay by up to this fraction of it (e.g. 0.1
+	// for +/- 10%), so that many scrapers don't retry in lockstep.
+	Jitter float64
+
+	// RetryOn reports whether a request should be retried, given its response
+	// or error.  If this is nil, then requests are retried after network
+	// errors and responses with a 429 or 5xx status code.
+	RetryOn func(resp *http.Response, err error) bool
+}
+
+func (p *RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 {
+		return errors.New("retry attempts must not be negative")
+	}
+	if p.InitialDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("retry delays must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return errors.New("retry multiplier must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts == 0 {
+		return 3
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if p.RetryOn != nil {
+		return p.RetryOn(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5
+}
+
+// delay returns how long to wait before the given retry, starting at 0.
+func (p *RetryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = time.Minute
+	}
+
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > maxDelay {
+				d = maxDelay
+			}
+			return d
+		}
+	}
+
+	delay := float64(p.InitialDelay)
+	if delay == 0 {
+		delay = float64(time.Second)
+	}
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	for i := 0; i < retry && delay < float64(maxDelay); i++ {
+		delay *= multiplier
+	}
+	if delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(val string) (time.Duration, bool) {
+	if val == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// fetch fetches the given URL with the given function, retrying it according
+// to the policy.  This is used by both RetryMiddleware and
+// HttpClientFetcher.Retry.  Responses can only be checked if their bodies
+// implement ResponseMetadata; other bodies are returned as they are.  The
+// body of the last attempt is returned, whether or not it was successful.
+func (p *RetryPolicy) fetch(ctx context.Context, next FetchFunc, method, url string) (io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		body, err := next(ctx, method, url)
+
+		var resp *http.Response
+		if err == nil {
+			resp = httpResponse(body)
+		}
+		if ctx.Err() != nil || attempt >= p.maxAttempts() || (err == nil && resp == nil) || !p.shouldRetry(resp, err) {
+			return body, err
+		}
+
+		delay := p.delay(attempt-1, resp)
+		if body != nil {
+			// Drain the body, so that the connection can be reused.
+			io.CopyN(ioutil.Discard, body, 64*1024)
+			body.Close()
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// httpResponse returns the HTTP response that the given body was read from,
+// or one with its status code and headers if it only implements
+// ResponseMetadata.  It returns nil if neither is known.
+func httpResponse(body io.ReadCloser) *http.Response {
+	if rb, ok := body.(*responseBody); ok && rb.raw != nil {
+		return rb.raw
+	}
+	if rm, ok := body.(ResponseMetadata); ok {
+		r := rm.Response()
+		return &http.Response{StatusCode: r.StatusCode, Header: r.Header}
+	}
+	return nil
+}
+
+// sleepContext waits for the given duration, or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package scrape
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	p := &RetryPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(0, nil))
+	assert.Equal(t, 2*time.Second, p.delay(1, nil))
+	assert.Equal(t, 4*time.Second, p.delay(2, nil))
+	assert.Equal(t, 5*time.Second, p.delay(3, nil))
+	assert.Equal(t, 5*time.Second, p.delay(100, nil))
+
+	p = &RetryPolicy{Multiplier: 3}
+	assert.Equal(t, 9*time.Second, p.delay(2, nil))
+
+	// The Retry-After header takes precedence, up to the maximum delay.
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	assert.Equal(t, 3*time.Second, p.delay(0, resp))
+	resp.Header.Set("Retry-After", "3600")
+	assert.Equal(t, time.Minute, p.delay(0, resp))
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), p.delay(0, resp))
+	resp.Header.Set("Retry-After", "soon")
+	assert.Equal(t, time.Second, p.delay(0, resp))
+
+	p = &RetryPolicy{Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		d := p.delay(0, nil)
+		assert.True(t, d >= 500*time.Millisecond && d <= 1500*time.Millisecond, d)
+	}
+}
+
+func TestHttpClientFetcherRetry(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/limited" && n == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/flaky" && n <= 2:
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, "%s %d", r.URL.Path, n)
+	}))
+	defer ts.Close()
+
+	hf, err := NewHttpClientFetcher()
+	assert.NoError(t, err)
+	hf.Retry = &RetryPolicy{InitialDelay: time.Millisecond}
+	assert.NoError(t, hf.Prepare())
+
+	fetch := func(path string) (string, int) {
+		body, err := hf.Fetch("GET", ts.URL+path)
+		if !assert.NoError(t, err) {
+			return "", 0
+		}
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+		return string(data), body.(ResponseMetadata).Response().StatusCode
+	}
+
+	body, code := fetch("/limited")
+	assert.Equal(t, "/limited 2", body)
+	assert.Equal(t, http.StatusOK, code)
+
+	body, code = fetch("/flaky")
+	assert.Equal(t, "/flaky 3", body)
+	assert.Equal(t, http.StatusOK, code)
+
+	// The last response is returned once the attempts run out.
+	body, code = fetch("/broken")
+	assert.Equal(t, "/broken 3", body)
+	assert.Equal(t, http.StatusInternalServerError, code)
+
+	// Client errors aren't retried.
+	body, code = fetch("/missing")
+	assert.Equal(t, "/missing 1", body)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Neither are other statuses with a custom RetryOn.
+	hf.Retry.RetryOn = func(resp *http.Response, err error) bool {
+		return err == nil && resp.StatusCode == http.StatusNotFound
+	}
+	body, code = fetch("/missing")
+	assert.Equal(t, "/missing 4", body)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Network errors are retried, and returned once the attempts run out.
+	hf.Retry.RetryOn = nil
+	ts.Close()
+	_, err = hf.Fetch("GET", ts.URL+"/flaky")
+	assert.Error(t, err)
+
+	hf.Retry = &RetryPolicy{Jitter: 2}
+	assert.EqualError(t, hf.Prepare(), "retry jitter must be between 0 and 1")
+}
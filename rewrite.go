@@ -0,0 +1,78 @@
+package scrape
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The RewriteFunc type is used to modify the raw HTML of a page before it is
+// parsed.  It is given the URL of the page and its contents, and returns the
+// new contents.  If it returns an error, then the scrape is aborted.
+type RewriteFunc func(url, body string) (string, error)
+
+// RewriteRegex returns a RewriteFunc that replaces all matches of the given
+// regular expression with the replacement string.  Inside the replacement,
+// "$1" and similar are expanded as in regexp.Regexp.ReplaceAllString.
+func RewriteRegex(re *regexp.Regexp, repl string) RewriteFunc {
+	return func(url, body string) (string, error) {
+		return re.ReplaceAllString(body, repl), nil
+	}
+}
+
+// RewriteReplace returns a RewriteFunc that replaces all occurrences of the
+// given string with another.  This can be used, for example, to un-escape HTML
+// that a server has embedded inside a JSON string.
+func RewriteReplace(old, new string) RewriteFunc {
+	return func(url, body string) (string, error) {
+		return strings.Replace(body, old, new, -1), nil
+	}
+}
+
+// parseDocument reads and parses the contents of the given page, applying
+// every configured RewriteFunc first, and promoting <noscript> contents
+// afterwards if requested.
+func (s *Scraper) parseDocument(url string, r io.Reader) (*goquery.Document, error) {
+	if len(s.config.Rewrite) > 0 {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := s.rewrite(url, string(data))
+		if err != nil {
+			return nil, err
+		}
+		r = strings.NewReader(body)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishDocument(doc)
+}
+
+// rewrite applies every configured RewriteFunc to the given page contents.
+func (s *Scraper) rewrite(url, body string) (string, error) {
+	var err error
+	for _, rewrite := range s.config.Rewrite {
+		if body, err = rewrite(url, body); err != nil {
+			return "", err
+		}
+	}
+	return body, nil
+}
+
+// finishDocument applies any post-processing to a parsed document.
+func (s *Scraper) finishDocument(doc *goquery.Document) (*goquery.Document, error) {
+	if s.config.PromoteNoscript {
+		if err := promoteNoscript(doc); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,149 @@
+package scrape
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// The SchemaExtractor interface can optionally be implemented by a
+// PieceExtractor to describe the values that it returns, as a JSON Schema.
+// This is used by ScrapeConfig.JSONSchema.
+type SchemaExtractor interface {
+	// JSONSchema returns the schema of the values returned by Extract.
+	JSONSchema() map[string]interface{}
+}
+
+// JSONSchemaVersion is the JSON Schema dialect of the schemas returned by
+// ScrapeConfig.JSONSchema.
+const JSONSchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema describing the results of a scrape with
+// this configuration, as they are encoded by encoding/json, so that consumers
+// of the results can validate them or generate types for them.  The schema of
+// each block is as described by BlockSchema.
+func (c *ScrapeConfig) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema": JSONSchemaVersion,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"URLs": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+			"Results": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":  "array",
+					"items": c.BlockSchema(),
+				},
+			},
+		},
+		"required": []string{"URLs", "Results"},
+	}
+}
+
+// BlockSchema returns a JSON Schema describing a single block of the results
+// of a scrape with this configuration: an object with a property for each
+// Piece.  None of the properties are required, since a Piece is omitted from
+// a block when its value is nil.  If BlockID is set, then the block's ID is
+// also a property, which is a string.
+//
+// The schema of each Piece is its Schema field, if given.  Otherwise, it is
+// taken from its extractor, if the extractor implements SchemaExtractor or
+// was made by Untyped (or PieceT), and Transform and Compute are nil.  Pieces
+// whose values aren't described in any of these ways can have any value.
+func (c *ScrapeConfig) BlockSchema() map[string]interface{} {
+	props := map[string]interface{}{}
+	for _, piece := range c.Pieces {
+		props[piece.Name] = pieceSchema(piece)
+	}
+	if c.BlockID != nil {
+		props[c.BlockID.name()] = map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+}
+
+func pieceSchema(piece Piece) map[string]interface{} {
+	if piece.Schema != nil {
+		return piece.Schema
+	}
+	if piece.Transform == nil && piece.Compute == nil {
+		if se, ok := piece.Extractor.(SchemaExtractor); ok {
+			return se.JSONSchema()
+		}
+	}
+	return map[string]interface{}{}
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// TypeSchema returns a JSON Schema describing the values of the given Go type
+// as they are encoded by encoding/json - e.g. a []string is an array of
+// strings.  It is useful for implementing SchemaExtractor.  Types that encode
+// themselves (other than time.Time), and interface types, can have any value.
+func TypeSchema(t reflect.Type) map[string]interface{} {
+	return typeSchema(t, map[reflect.Type]bool{})
+}
+
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t.Implements(marshalerType) {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes byte slices as base64 strings.
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		// Recursive types are described only down to their first repetition.
+		if seen[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		props := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Anonymous {
+				continue
+			}
+			name := f.Name
+			if tag := f.Tag.Get("json"); tag != "" {
+				if tag == "-" {
+					continue
+				}
+				if n := strings.Split(tag, ",")[0]; n != "" {
+					name = n
+				}
+			}
+			props[name] = typeSchema(f.Type, seen)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	default:
+		return map[string]interface{}{}
+	}
+}
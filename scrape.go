@@ -1,20 +1,45 @@
 package scrape
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 var (
 	ErrNoPieces = errors.New("no pieces in the config")
+
+	// This error is returned when a scrape is stopped because the Scraper is
+	// being drained.  See the Drain method for more information.
+	ErrDrained = errors.New("scraper is draining")
 )
 
 // The DividePageFunc type is used to extract a page's blocks during a scrape.
 // For more information, please see the documentation on the ScrapeConfig type.
 type DividePageFunc func(*goquery.Selection) []*goquery.Selection
 
+// The StopConditionFunc type is used to end a scrape early.  For more
+// information, please see the documentation on the ScrapeConfig type.
+type StopConditionFunc func(*Page) bool
+
+// A Page contains the results of scraping a single page.
+type Page struct {
+	// The URL of this page.
+	URL string
+
+	// The index of this page in the scrape, starting from 0.
+	Index int
+
+	// The results from each block on this page.  Each entry is the mapping of
+	// Piece.Name to results for a single block.
+	Blocks []map[string]interface{}
+}
+
 // The PieceExtractor interface represents something that can extract data from
 // a selection.
 type PieceExtractor interface {
@@ -27,6 +52,22 @@ type PieceExtractor interface {
 	Extract(*goquery.Selection) (interface{}, error)
 }
 
+// The ExtractorFunc type is an adapter to allow the use of ordinary functions
+// as PieceExtractors - e.g.:
+//
+//	Extractor: scrape.ExtractorFunc(func(sel *goquery.Selection) (interface{}, error) {
+//		return strings.ToUpper(sel.Text()), nil
+//	}),
+type ExtractorFunc func(*goquery.Selection) (interface{}, error)
+
+// Extract calls f(sel).
+func (f ExtractorFunc) Extract(sel *goquery.Selection) (interface{}, error) {
+	return f(sel)
+}
+
+// Static type assertion
+var _ PieceExtractor = ExtractorFunc(nil)
+
 // The Paginator interface should be implemented by things that can retrieve the
 // next page from the current one.
 type Paginator interface {
@@ -53,6 +94,32 @@ type Piece struct {
 	// Extractor contains the logic on how to extract some results from the
 	// selector that is provided to this Piece.
 	Extractor PieceExtractor
+
+	// Transform, if given, is called with the value returned by the Extractor
+	// and returns the value that is stored in the results instead.  This can
+	// be used to trim, parse or reshape a value without writing a new
+	// PieceExtractor.  It is not called if the Extractor returns nil, and may
+	// itself return nil to omit the value from the results.
+	Transform func(interface{}) (interface{}, error)
+
+	// Compute, if given, makes this a computed Piece: rather than being
+	// extracted from the block, its value is computed from the values of the
+	// Pieces before it in the same block (e.g. combining a base URL with a
+	// link, or subtracting two scores).  The map contains only those Pieces
+	// that were present in the results.  Computed Pieces must not have a
+	// Selector or Extractor.
+	Compute func(block map[string]interface{}) (interface{}, error)
+
+	// Schema, if given, is the JSON Schema of this Piece's values, for use by
+	// ScrapeConfig.JSONSchema and strict mode - e.g. {"type": "number"}.  This is only needed
+	// if the Piece has a Transform or Compute function, or its extractor
+	// doesn't describe its own values.
+	Schema map[string]interface{}
+
+	// Limits, if given, bounds the size of this Piece's values (after
+	// Transform or Compute), truncating them or aborting the scrape if they
+	// are too large.  See PieceLimits for more information.
+	Limits *PieceLimits
 }
 
 // The main configuration for a scrape.  Pass this to the New() function.
@@ -62,6 +129,11 @@ type ScrapeConfig struct {
 	// will be created and used.
 	Fetcher Fetcher
 
+	// Middleware wraps the Fetcher (or the default one) in each of the given
+	// FetcherMiddlewares, as ChainFetcher does - e.g. to retry or throttle
+	// requests.
+	Middleware []FetcherMiddleware
+
 	// Paginator is the Paginator to use for this current scrape.
 	//
 	// If Paginator is nil, then no pagination is performed and it is assumed that
@@ -87,14 +159,123 @@ type ScrapeConfig struct {
 	// being aborted - this can be useful if you need to ensure that a given Piece
 	// is required, for example.
 	Pieces []Piece
+
+	// StopCondition is called after each page has been processed.  If it
+	// returns true, then the scrape stops without fetching any further pages.
+	// The results from the page that triggered the condition are still
+	// included in the scrape results.
+	//
+	// This is useful for incremental scrapes - e.g. stopping as soon as a page
+	// contains an item that is older than the last scrape.  If StopCondition is
+	// nil, then the scrape continues until the Paginator returns no more pages.
+	StopCondition StopConditionFunc
+
+	// Dedup, if non-nil, enables deduplication of blocks across scrapes.  Each
+	// block is identified by the value of a single Piece, and blocks that were
+	// seen in a previous scrape are omitted from the results.  The keys of all
+	// new blocks are recorded in the Store once the scrape finishes
+	// successfully.
+	Dedup *DedupConfig
+
+	// ConcurrentPieces is the maximum number of Pieces that will be extracted
+	// from a single block at the same time.  This is useful when some Pieces'
+	// extractors perform I/O.  Results are the same as when extracting serially.
+	// If this is 0 or 1, then Pieces are extracted one after the other.
+	//
+	// To limit how many calls to a particular extractor may run at once, see
+	// the WithConcurrencyLimit function.
+	ConcurrentPieces int
+
+	// Sink, if non-nil, receives each page as soon as it has been scraped.  A
+	// slow Sink slows down the scrape; to decouple the two, wrap the Sink with
+	// NewQueuedSink.
+	Sink Sink
+
+	// Checkpoint, if non-nil, enables periodically saving the progress of a
+	// scrape, so that an interrupted scrape can be resumed.  See the
+	// CheckpointConfig type for more information.
+	Checkpoint *CheckpointConfig
+
+	// Logger, if non-nil, is used to log the progress of each scrape.
+	Logger *log.Logger
+
+	// FailureSamples is the maximum number of blocks that are recorded for each
+	// Piece that is empty - i.e. whose extractor returned nil, an empty string,
+	// or an empty list or map.  The total number of such blocks is always
+	// counted.  These are reported in the Failures field of the ScrapeResults,
+	// which helps diagnose broken selectors without having to inspect every
+	// block of a large scrape.  If this is 0, then nothing is recorded.
+	FailureSamples int
+
+	// Rewrite contains functions that modify the raw HTML of each page before
+	// it's parsed, in order - e.g. to remove markup that hides the real
+	// content, or to un-escape HTML that's embedded in a script.
+	Rewrite []RewriteFunc
+
+	// If PromoteNoscript is true, then every <noscript> element in each page is
+	// replaced with its contents before the page is divided into blocks.  Many
+	// sites that lazy-load images only include the real <img> tags inside
+	// <noscript> elements, which are otherwise treated as plain text.
+	PromoteNoscript bool
+
+	// Tags are arbitrary values that identify this scrape.  They are passed to
+	// Fetchers that implement ContextFetcher as part of each request's
+	// RequestInfo.
+	Tags map[string]string
+
+	// Usage, if given, enables accounting of the requests, bytes and time
+	// used to fetch pages from each host.  See UsageConfig for more
+	// information.
+	Usage *UsageConfig
+
+	// Challenge, if given, enables handling of captchas and other anti-bot
+	// challenges, by pausing the scrape until a callback has dealt with them.
+	// See ChallengeConfig for more information.
+	Challenge *ChallengeConfig
+
+	// StatusPolicy, if given, controls what happens to pages whose responses
+	// have a non-2xx status code - e.g. aborting the scrape, or retrying.  If
+	// this is nil, then such pages are scraped like any other.  See
+	// StatusPolicy for more information.
+	StatusPolicy *StatusPolicy
+
+	// If Strict is true, then every Piece's value is checked against its
+	// declared type - i.e. its JSON Schema, as described by BlockSchema - and
+	// a value of the wrong type aborts the scrape with a *PieceTypeError.
+	// This catches bugs in custom extractors and Transform functions early.
+	// Pieces without a declared type can have any value.
+	Strict bool
+
+	// BlockID, if given, adds an ID to every block, so that it can be
+	// identified consistently by sinks, deduplication and merging.  See
+	// BlockIDConfig for more information.
+	BlockID *BlockIDConfig
 }
 
 func (c *ScrapeConfig) clone() *ScrapeConfig {
 	ret := &ScrapeConfig{
 		Fetcher:    c.Fetcher,
+		Middleware: c.Middleware,
 		Paginator:  c.Paginator,
 		DividePage: c.DividePage,
 		Pieces:     c.Pieces,
+
+		StopCondition: c.StopCondition,
+		Dedup:         c.Dedup,
+
+		ConcurrentPieces: c.ConcurrentPieces,
+		Sink:             c.Sink,
+		Checkpoint:       c.Checkpoint,
+		Logger:           c.Logger,
+		FailureSamples:   c.FailureSamples,
+		Rewrite:          c.Rewrite,
+		PromoteNoscript:  c.PromoteNoscript,
+		Tags:             c.Tags,
+		Usage:            c.Usage,
+		Challenge:        c.Challenge,
+		StatusPolicy:     c.StatusPolicy,
+		Strict:           c.Strict,
+		BlockID:          c.BlockID,
 	}
 	return ret
 }
@@ -111,6 +292,35 @@ type ScrapeResults struct {
 	// is for each page, the second-level array is for each block in a page, and
 	// the final map[string]interface{} is the mapping of Piece.Name to results.
 	Results [][]map[string]interface{}
+
+	// The start URL of the scrape that visited each page, in the same order as
+	// URLs.  This is only set by ScrapeAll, which combines the results of
+	// multiple scrapes.
+	StartURLs []string `json:",omitempty"`
+
+	// Failures records the blocks in which each Piece was empty, keyed by
+	// Piece.Name.  This is only set if ScrapeConfig.FailureSamples is greater
+	// than 0.
+	Failures map[string]*PieceFailures `json:",omitempty"`
+
+	// Usage records the resources used to fetch pages from each host, keyed
+	// by host.  This is only set if ScrapeConfig.Usage is set.
+	Usage map[string]*HostUsage `json:",omitempty"`
+
+	// URLPatterns groups every URL discovered during a crawl (whether or not
+	// it was fetched) by its general shape; see LearnURLPatterns.  This is
+	// only set by Crawl.
+	URLPatterns []URLCluster `json:",omitempty"`
+
+	// Responses records the response that each page was read from, keyed by
+	// the page's URL in URLs.  This is only set for pages whose Fetcher
+	// returns this metadata (see ResponseMetadata), such as
+	// HttpClientFetcher.
+	Responses map[string]*Response `json:",omitempty"`
+
+	// The time taken by the scrape, from start to finish.  When resuming from
+	// a Checkpoint, this includes the time taken before the checkpoint.
+	Duration time.Duration `json:",omitempty"`
 }
 
 // First returns the first set of results - i.e. the results from the first
@@ -142,6 +352,20 @@ func (r *ScrapeResults) AllBlocks() []map[string]interface{} {
 
 type Scraper struct {
 	config *ScrapeConfig
+
+	// The options used by Scrape, Resume and ScrapeAll.
+	opts ScrapeOptions
+
+	// Used to implement Drain.
+	mu       sync.Mutex
+	draining chan struct{}
+	inFlight sync.WaitGroup
+
+	// Held for writing while a challenge is handled, to pause every fetch.
+	challengeMu sync.RWMutex
+
+	// The schema of each Piece, in strict mode.
+	schemas []map[string]interface{}
 }
 
 // Create a new scraper with the provided configuration.
@@ -149,23 +373,8 @@ func New(c *ScrapeConfig) (*Scraper, error) {
 	var err error
 
 	// Validate config
-	if len(c.Pieces) == 0 {
-		return nil, ErrNoPieces
-	}
-
-	seenNames := map[string]struct{}{}
-	for i, piece := range c.Pieces {
-		if len(piece.Name) == 0 {
-			return nil, fmt.Errorf("no name provided for piece %d", i)
-		}
-		if _, seen := seenNames[piece.Name]; seen {
-			return nil, fmt.Errorf("piece %d has a duplicate name", i)
-		}
-		seenNames[piece.Name] = struct{}{}
-
-		if len(piece.Selector) == 0 {
-			return nil, fmt.Errorf("no selector provided for piece %d", i)
-		}
+	if err = c.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Clone the configuration and fill in the defaults.
@@ -183,18 +392,27 @@ func New(c *ScrapeConfig) (*Scraper, error) {
 			return nil, err
 		}
 	}
+	config.Fetcher = ChainFetcher(config.Fetcher, config.Middleware...)
 
 	// All set!
 	ret := &Scraper{
-		config: config,
+		config:   config,
+		opts:     DefaultOptions,
+		draining: make(chan struct{}),
+	}
+	if config.Strict {
+		ret.schemas = pieceSchemas(config.Pieces)
 	}
 	return ret, nil
 }
 
 // Scrape a given URL with default options.  See 'ScrapeWithOpts' for more
 // information.
+//
+// The default options are DefaultOptions, unless the Scraper was created with
+// NewScraper.
 func (s *Scraper) Scrape(url string) (*ScrapeResults, error) {
-	return s.ScrapeWithOpts(url, DefaultOptions)
+	return s.ScrapeWithOpts(url, s.opts)
 }
 
 // Actually start scraping at the given URL.
@@ -205,83 +423,526 @@ func (s *Scraper) Scrape(url string) (*ScrapeResults, error) {
 // Please be careful when running multiple scrapes at a time, unless you know
 // that it's safe.
 func (s *Scraper) ScrapeWithOpts(url string, opts ScrapeOptions) (*ScrapeResults, error) {
+	return s.scrapeURL(url, opts, true)
+}
+
+// scrapeURL scrapes starting at the given URL.  If prepare is false, then the
+// Fetcher must already have been prepared - e.g. once for several scrapes
+// running in parallel.
+func (s *Scraper) scrapeURL(url string, opts ScrapeOptions, prepare bool) (*ScrapeResults, error) {
 	if len(url) == 0 {
 		return nil, errors.New("no URL provided")
 	}
 
-	// Prepare the fetcher.
-	err := s.config.Fetcher.Prepare()
-	if err != nil {
+	start := &Checkpoint{
+		NextURL: url,
+		Results: &ScrapeResults{
+			URLs:    []string{},
+			Results: [][]map[string]interface{}{},
+		},
+	}
+	return s.run(start, opts, prepare)
+}
+
+// run performs a scrape starting from the given state, and then flushes the
+// sink.  The Fetcher is prepared first if prepare is true.
+func (s *Scraper) run(start *Checkpoint, opts ScrapeOptions, prepare bool) (*ScrapeResults, error) {
+	if err := s.begin(); err != nil {
 		return nil, err
 	}
+	defer s.end()
+
+	var res *ScrapeResults
+	var err error
+	if prepare {
+		err = s.prepareFetcher()
+	}
+	if err == nil {
+		res, err = s.scrape(context.Background(), start, opts, nil)
+	}
+
+	// Ensure the sink has handled everything we've sent, even on failure.
+	if serr := s.flushSink(err); serr != nil && err == nil {
+		return nil, serr
+	}
+
+	return res, err
+}
+
+// begin registers the start of a scrape, failing if the Scraper is being
+// drained.  Every successful call to begin must be paired with a call to end.
+func (s *Scraper) begin() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isDraining() {
+		return ErrDrained
+	}
+	s.inFlight.Add(1)
+	return nil
+}
+
+// end registers the end of a scrape.
+func (s *Scraper) end() {
+	s.inFlight.Done()
+}
+
+// flushSink flushes the sink at the end of a scrape, first telling it about
+// the scrape's error, if any.
+func (s *Scraper) flushSink(err error) error {
+	if s.config.Sink == nil {
+		return nil
+	}
+	if fs, ok := s.config.Sink.(FailureSink); ok && err != nil {
+		fs.Failed(err)
+	}
+	return s.config.Sink.Flush()
+}
 
-	res := &ScrapeResults{
-		URLs:    []string{},
-		Results: [][]map[string]interface{}{},
+// scrape runs a scrape starting from the given state, until there are no more
+// pages, the scrape is stopped early or the context is done.  The Fetcher
+// must already have been prepared.
+//
+// If onPage is nil, then the results of each page are collected in the
+// returned results.  Otherwise, onPage is called with each page instead, and
+// the scrape stops if it returns false; the pages aren't collected, and no
+// checkpoints are saved.
+func (s *Scraper) scrape(ctx context.Context, start *Checkpoint, opts ScrapeOptions, onPage func(*Page) bool) (*ScrapeResults, error) {
+	var err error
+	checkpoint := s.config.Checkpoint
+	if onPage != nil {
+		checkpoint = nil
 	}
 
-	var numPages int
+	url := start.NextURL
+	res := start.Results
+	dedup := newDedupState(s.config.Dedup, start.DedupKeys)
+
+	// The Duration of the results includes the time taken before the
+	// checkpoint that we started from, if any.  It is updated whenever the
+	// results are saved or returned.
+	started, before := time.Now(), res.Duration
+	updateDuration := func() {
+		res.Duration = before + time.Since(started)
+	}
+
+	// The URL of the page before the current one, if any.  This isn't known
+	// when resuming from a checkpoint.
+	var prevURL string
+
+	// The error with which the scrape ended early, if any.
+	var stopErr error
+	stopLoss := &stopLoss{opts: opts}
+
+	numPages := start.PagesDone
 	for {
 		// Repeat until we don't have any more URLs, or until we hit our page limit.
 		if len(url) == 0 || (opts.MaxPages > 0 && numPages >= opts.MaxPages) {
 			break
 		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Stop before fetching another page if we're being drained.
+		if s.isDraining() {
+			updateDuration()
+			if checkpoint != nil {
+				err = checkpoint.save(&Checkpoint{
+					NextURL:   url,
+					PagesDone: numPages,
+					Results:   res,
+					DedupKeys: dedup.newKeys,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return res, ErrDrained
+		}
+
+		doc, err := s.fetchDocument(url, RequestInfo{
+			PageIndex:   numPages,
+			PreviousURL: prevURL,
+		})
+		if err != nil {
+			if opts.MaxConsecutiveErrors <= 0 {
+				return nil, err
+			}
+			if stopErr = stopLoss.fetchFailed(err); stopErr != nil {
+				s.logf("stopping after %d failed pages", opts.MaxConsecutiveErrors)
+				break
+			}
+
+			// Skip this page, and carry on from the URL alone.
+			numPages++
+			prevURL = url
+			url, err = s.config.Paginator.NextPage(url, blankDocument().Selection)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		s.recordUsage(res, url, doc)
+
+		page, foundSeen, err := s.processPage(url, numPages, doc, dedup, res)
+		if err != nil {
+			return nil, err
+		}
+
+		// Append the results from this page.
+		numPages++
+		if onPage == nil {
+			res.URLs = append(res.URLs, url)
+			res.Results = append(res.Results, page.Blocks)
+		} else if !onPage(page) {
+			break
+		}
 
-		resp, err := s.config.Fetcher.Fetch("GET", url)
+		if stopErr = stopLoss.fetched(len(page.Blocks)); stopErr != nil {
+			s.logf("stopping after %d empty pages", opts.MaxConsecutiveEmptyPages)
+			break
+		}
+
+		// Check whether we should stop here.
+		if s.config.StopCondition != nil && s.config.StopCondition(page) {
+			break
+		}
+
+		if foundSeen && s.config.Dedup.StopOnSeen {
+			break
+		}
+
+		// Get the next page.
+		prevURL = url
+		url, err = s.config.Paginator.NextPage(url, doc.Selection)
 		if err != nil {
 			return nil, err
 		}
 
-		// Create a goquery document.
-		doc, err := goquery.NewDocumentFromReader(resp)
-		resp.Close()
+		// Save our progress, if requested.
+		if checkpoint != nil && checkpoint.shouldSave(numPages) {
+			updateDuration()
+			err = checkpoint.save(&Checkpoint{
+				NextURL:   url,
+				PagesDone: numPages,
+				Results:   res,
+				DedupKeys: dedup.newKeys,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Record the blocks we've seen, now that the scrape has succeeded.
+	if err = dedup.commit(); err != nil {
+		return nil, err
+	}
+	if s.config.Usage != nil {
+		if err = s.config.Usage.save(res.Usage); err != nil {
+			return nil, err
+		}
+	}
+
+	// The scrape is finished, so there's nothing left to resume.
+	if checkpoint != nil {
+		err = checkpoint.Store.Delete(checkpoint.storeKey())
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		res.URLs = append(res.URLs, url)
-		results := []map[string]interface{}{}
+	// All good!
+	updateDuration()
+	return res, stopErr
+}
 
-		// Divide this page into blocks
-		for _, block := range s.config.DividePage(doc.Selection) {
-			blockResults := map[string]interface{}{}
+// fetchedDoc is a parsed page, along with information about how it was
+// fetched.
+type fetchedDoc struct {
+	*goquery.Document
 
-			// Process each piece of this block
-			for _, piece := range s.config.Pieces {
-				sel := block
-				if piece.Selector != "." {
-					sel = sel.Find(piece.Selector)
-				}
+	// When the page was fetched.
+	fetchedAt time.Time
 
-				pieceResults, err := piece.Extractor.Extract(sel)
-				if err != nil {
-					return nil, err
-				}
+	// The time spent in the Fetcher, and the size of the page's body.
+	fetchTime time.Duration
+	bytes     int64
+
+	// The response headers and metadata, if the Fetcher returned them.
+	header   http.Header
+	response *Response
+
+	// Whether the page was skipped because of its status code, in which case
+	// the document is empty.
+	skipped bool
+}
 
-				// A nil response from an extractor means that we don't even include it in
-				// the results.
-				if pieceResults == nil {
-					continue
+// fetchDocument fetches the given URL and parses it.  The given information
+// about the request is passed to the Fetcher if it is a ContextFetcher.
+func (s *Scraper) fetchDocument(url string, info RequestInfo) (*fetchedDoc, error) {
+	retries := 0
+	for attempt := 0; ; {
+		doc, err := s.fetchOnce(url, info)
+		if err == nil && doc.response != nil && s.config.StatusPolicy != nil {
+			policy := s.config.StatusPolicy
+			code := doc.response.StatusCode
+			switch policy.action(code) {
+			case StatusAbort:
+				return nil, &StatusError{URL: url, StatusCode: code}
+
+			case StatusSkip:
+				s.logf("skipping %s with status %d", url, code)
+				doc.Document = blankDocument()
+				doc.skipped = true
+				return doc, nil
+
+			case StatusRetry:
+				if retries >= policy.maxRetries() {
+					return nil, &StatusError{URL: url, StatusCode: code}
 				}
+				delay := policy.retryDelay(retries)
+				s.logf("retrying %s with status %d in %s", url, code, delay)
+				time.Sleep(delay)
+				retries++
+				continue
+			}
+		}
+		if err != nil || s.config.Challenge == nil || !s.config.Challenge.detect(url, doc.Selection) {
+			return doc, err
+		}
+
+		if attempt >= s.config.Challenge.maxAttempts() {
+			return nil, ErrChallengeUnsolved
+		}
+		html, err := goquery.OuterHtml(doc.Selection)
+		if err != nil {
+			return nil, err
+		}
+		err = s.handleChallenge(&Challenge{URL: url, HTML: html, Attempt: attempt})
+		if err != nil {
+			return nil, err
+		}
+		attempt++
+	}
+}
+
+// fetchOnce fetches and parses the given page, waiting for any challenge that
+// is being handled first.
+func (s *Scraper) fetchOnce(url string, info RequestInfo) (*fetchedDoc, error) {
+	s.challengeMu.RLock()
+	defer s.challengeMu.RUnlock()
+
+	s.logf("fetching %s", url)
+	fetchedAt := time.Now()
+	resp, err := s.fetch(url, info)
+	fetchTime := time.Since(fetchedAt)
+	if err != nil {
+		s.logf("error fetching %s: %s", url, err)
+		return nil, err
+	}
+
+	// Create a goquery document.
+	body := &countingReader{r: resp}
+	doc, err := s.parseDocument(url, body)
+	resp.Close()
+	if err != nil {
+		return nil, err
+	}
+	ret := &fetchedDoc{
+		Document:  doc,
+		fetchedAt: fetchedAt,
+		fetchTime: fetchTime,
+		bytes:     body.n,
+	}
+	if rb, ok := resp.(ResponseBody); ok {
+		ret.header = rb.Header()
+	}
+	if rm, ok := resp.(ResponseMetadata); ok {
+		ret.response = rm.Response()
+		if ret.header == nil {
+			ret.header = ret.response.Header
+		}
+		if code := ret.response.StatusCode; code != 0 && code/100 != 2 {
+			s.logf("%s returned status %d", url, code)
+		}
+	}
+	return ret, nil
+}
+
+// processPage extracts the results from every block of the given document,
+// and sends the resulting page to the sink.  Any empty Pieces are recorded in
+// the failure report of the given results.  It also returns whether the page
+// contains a block that was seen in a previous scrape.
+func (s *Scraper) processPage(url string, index int, doc *fetchedDoc, dedup *dedupState, res *ScrapeResults) (*Page, bool, error) {
+	results := []map[string]interface{}{}
+
+	// Whether this page contains a block seen in a previous scrape.
+	var foundSeen bool
+
+	var blocks []*goquery.Selection
+	if !doc.skipped {
+		blocks = s.config.DividePage(doc.Selection)
+	}
 
-				blockResults[piece.Name] = pieceResults
+	// Divide this page into blocks
+	for i, block := range blocks {
+		blockResults, err := s.extractBlock(block, ExtractContext{
+			URL:        url,
+			FetchedAt:  doc.fetchedAt,
+			PageIndex:  index,
+			BlockIndex: i,
+			Header:     doc.header,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		// Skip this block if it's a duplicate.
+		keep, seen, err := dedup.check(blockResults)
+		if err != nil {
+			return nil, false, err
+		}
+		foundSeen = foundSeen || seen
+		if !keep {
+			continue
+		}
+
+		if s.config.FailureSamples > 0 {
+			recordFailures(res, s.config.Pieces, s.config.FailureSamples,
+				url, len(results), block, blockResults)
+		}
+
+		// Append the results from this block.
+		results = append(results, blockResults)
+	}
+
+	page := &Page{
+		URL:    url,
+		Index:  index,
+		Blocks: results,
+	}
+	if doc.response != nil {
+		if res.Responses == nil {
+			res.Responses = map[string]*Response{}
+		}
+		res.Responses[url] = doc.response
+	}
+
+	// Send this page to the sink.
+	if s.config.Sink != nil {
+		if err := s.config.Sink.Write(page); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return page, foundSeen, nil
+}
+
+// extractBlock runs every Piece's extractor over the given block, and returns
+// the mapping of Piece.Name to results.  The context is passed to any
+// ContextualExtractors.
+func (s *Scraper) extractBlock(block *goquery.Selection, ctx ExtractContext) (map[string]interface{}, error) {
+	pieces := s.config.Pieces
+	values := make([]interface{}, len(pieces))
+	errs := make([]error, len(pieces))
+
+	extractPiece := func(i int) {
+		if pieces[i].Compute != nil {
+			// Computed once all other pieces have been extracted.
+			return
+		}
+
+		sel := block
+		if pieces[i].Selector != "." {
+			sel = sel.Find(pieces[i].Selector)
+		}
+		if ce, ok := pieces[i].Extractor.(ContextualExtractor); ok {
+			values[i], errs[i] = ce.ExtractWithContext(ctx, sel)
+		} else {
+			values[i], errs[i] = pieces[i].Extractor.Extract(sel)
+		}
+		if errs[i] == nil && values[i] != nil && pieces[i].Transform != nil {
+			values[i], errs[i] = pieces[i].Transform(values[i])
+		}
+		if errs[i] == nil && values[i] != nil && pieces[i].Limits != nil {
+			values[i], errs[i] = pieces[i].Limits.apply(pieces[i].Name, values[i])
+		}
+		if errs[i] == nil && values[i] != nil && s.schemas != nil {
+			errs[i] = checkSchema(pieces[i].Name, s.schemas[i], values[i])
+		}
+	}
+
+	if s.config.ConcurrentPieces > 1 {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, s.config.ConcurrentPieces)
+		for i := range pieces {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				extractPiece(i)
+				<-sem
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range pieces {
+			if extractPiece(i); errs[i] != nil {
+				break
 			}
+		}
+	}
 
-			// Append the results from this block.
-			results = append(results, blockResults)
+	blockResults := map[string]interface{}{}
+	for i, piece := range pieces {
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
 
-		// Append the results from this page.
-		res.Results = append(res.Results, results)
-		numPages++
+		if piece.Compute != nil {
+			val, err := piece.Compute(blockResults)
+			if err == nil && val != nil && piece.Transform != nil {
+				val, err = piece.Transform(val)
+			}
+			if err == nil && val != nil && piece.Limits != nil {
+				val, err = piece.Limits.apply(piece.Name, val)
+			}
+			if err == nil && val != nil && s.schemas != nil {
+				err = checkSchema(piece.Name, s.schemas[i], val)
+			}
+			if err != nil {
+				return nil, err
+			}
+			values[i] = val
+		}
 
-		// Get the next page.
-		url, err = s.config.Paginator.NextPage(url, doc.Selection)
+		// A nil response from an extractor means that we don't even include it in
+		// the results.
+		if values[i] == nil {
+			continue
+		}
+
+		blockResults[piece.Name] = values[i]
+	}
+
+	if s.config.BlockID != nil {
+		id, err := s.config.BlockID.Generator.BlockID(ctx, blockResults)
 		if err != nil {
 			return nil, err
 		}
+		if id != "" {
+			blockResults[s.config.BlockID.name()] = id
+		}
 	}
 
-	// All good!
-	return res, nil
+	return blockResults, nil
+}
+
+// logf logs a message to the configured Logger, if any.
+func (s *Scraper) logf(format string, args ...interface{}) {
+	if s.config.Logger != nil {
+		s.config.Logger.Printf(format, args...)
+	}
 }
@@ -0,0 +1,60 @@
+package scrape
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// ShardFor returns the index of the shard, from 0 to count-1, that the given
+// URL belongs to when URLs are partitioned across 'count' workers.  It uses
+// rendezvous hashing, so every worker computes the same assignment without
+// coordination, and changing the number of shards only moves the URLs that
+// must move.
+func ShardFor(url string, count int) int {
+	if count <= 1 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(url))
+	sum := h.Sum64()
+
+	best, bestScore := 0, uint64(0)
+	for i := 0; i < count; i++ {
+		if score := mix64(sum ^ mix64(uint64(i)+1)); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// mix64 is the finalizer from the SplitMix64 generator, which spreads small
+// differences in its input across all bits of the output.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// ShardURLs returns the URLs from the given list that belong to the shard with
+// the given index, out of 'count' shards, in their original order.  See
+// ShardFor for more information.
+func ShardURLs(urls []string, index, count int) []string {
+	ret := []string{}
+	for _, url := range urls {
+		if ShardFor(url, count) == index {
+			ret = append(ret, url)
+		}
+	}
+	return ret
+}
+
+func validateShard(index, count int) error {
+	if count < 0 || (count > 0 && (index < 0 || index >= count)) {
+		return fmt.Errorf("invalid shard %d of %d", index, count)
+	}
+	return nil
+}
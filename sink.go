@@ -0,0 +1,24 @@
+package scrape
+
+// Sink is the interface that must be satisfied by things that consume the
+// results of a scrape as it progresses - e.g. by writing them to a database.
+//
+// Note: the Scraper calls a Sink's methods from a single goroutine, but a Sink
+// that is shared between multiple Scrapers must be safe for concurrent use.
+type Sink interface {
+	// Write is called with each page once it has been scraped.  If it returns
+	// an error, then the scrape is aborted.
+	Write(*Page) error
+
+	// Flush is called when a scrape finishes (whether successfully or not), and
+	// should not return until all pages passed to Write have been handled.
+	Flush() error
+}
+
+// FailureSink is an optional interface for Sinks that need to know whether a
+// scrape failed - e.g. to send a notification.  If a Sink implements it, then
+// Failed is called with the error that aborted a scrape, just before Flush.
+type FailureSink interface {
+	Sink
+	Failed(error)
+}
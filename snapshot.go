@@ -0,0 +1,165 @@
+package scrape
+
+import (
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// SelectorReport describes how a single Piece's selector behaves on two
+// snapshots of the same page.
+type SelectorReport struct {
+	// The name of the Piece that this report is for.
+	Piece string
+
+	// The selector of the Piece.
+	Selector string
+
+	// The number of blocks in the old and new snapshots, respectively, in which
+	// the selector matched at least one element.
+	OldMatches int
+	NewMatches int
+}
+
+// Broken returns whether the selector matched something in the old snapshot,
+// but no longer matches anything in the new one.
+func (r SelectorReport) Broken() bool {
+	return r.OldMatches > 0 && r.NewMatches == 0
+}
+
+// SnapshotDiff is the result of comparing two HTML snapshots of the same URL.
+type SnapshotDiff struct {
+	// Similarity is a measure of how similar the tag trees of the two snapshots
+	// are, from 0 (nothing in common) to 1 (identical structure).  Text and
+	// attributes are not considered.
+	Similarity float64
+
+	// The number of blocks that the DividePage function found in the old and new
+	// snapshots, respectively.
+	OldBlocks int
+	NewBlocks int
+
+	// A report for each configured Piece (except computed Pieces), in the same
+	// order as in the config.
+	Selectors []SelectorReport
+}
+
+// Broken returns the reports for all selectors that would break if the new
+// snapshot were to be scraped.
+func (d *SnapshotDiff) Broken() []SelectorReport {
+	ret := []SelectorReport{}
+	for _, r := range d.Selectors {
+		if r.Broken() {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// CompareSnapshots compares two HTML snapshots of the same page, and reports
+// how the structure has changed and which of the configured selectors would
+// stop matching.  This can be used to detect that a site's layout has changed
+// before a scrape silently starts returning empty results.
+//
+// The given config does not need to be valid for scraping - only the
+// DividePage function and the Pieces' names and selectors are used.
+func CompareSnapshots(c *ScrapeConfig, oldDoc, newDoc io.Reader) (*SnapshotDiff, error) {
+	oldSel, err := goquery.NewDocumentFromReader(oldDoc)
+	if err != nil {
+		return nil, err
+	}
+	newSel, err := goquery.NewDocumentFromReader(newDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	divide := c.DividePage
+	if divide == nil {
+		divide = DividePageBySelector("body")
+	}
+	oldBlocks := divide(oldSel.Selection)
+	newBlocks := divide(newSel.Selection)
+
+	ret := &SnapshotDiff{
+		Similarity: treeSimilarity(oldSel.Selection, newSel.Selection),
+		OldBlocks:  len(oldBlocks),
+		NewBlocks:  len(newBlocks),
+		Selectors:  []SelectorReport{},
+	}
+
+	for _, piece := range c.Pieces {
+		if piece.Compute != nil {
+			continue
+		}
+		ret.Selectors = append(ret.Selectors, SelectorReport{
+			Piece:      piece.Name,
+			Selector:   piece.Selector,
+			OldMatches: countMatches(oldBlocks, piece.Selector),
+			NewMatches: countMatches(newBlocks, piece.Selector),
+		})
+	}
+
+	return ret, nil
+}
+
+func countMatches(blocks []*goquery.Selection, selector string) int {
+	var count int
+	for _, block := range blocks {
+		if selector == "." || block.Find(selector).Length() > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// treeSimilarity compares the tag paths (e.g. "html>body>div>p") of every
+// element in the two documents, and returns the ratio of shared paths to total
+// paths.
+func treeSimilarity(a, b *goquery.Selection) float64 {
+	pathsA := tagPaths(a)
+	pathsB := tagPaths(b)
+
+	var shared, total int
+	for path, countA := range pathsA {
+		countB := pathsB[path]
+		if countA < countB {
+			shared += countA
+			total += countB
+		} else {
+			shared += countB
+			total += countA
+		}
+	}
+	for path, countB := range pathsB {
+		if _, found := pathsA[path]; !found {
+			total += countB
+		}
+	}
+
+	if total == 0 {
+		return 1
+	}
+	return float64(shared) / float64(total)
+}
+
+func tagPaths(sel *goquery.Selection) map[string]int {
+	ret := map[string]int{}
+
+	var walk func(n *html.Node, parents []string)
+	walk = func(n *html.Node, parents []string) {
+		if n.Type == html.ElementNode {
+			parents = append(parents, n.Data)
+			ret[strings.Join(parents, ">")]++
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, parents)
+		}
+	}
+	for _, n := range sel.Nodes {
+		walk(n, nil)
+	}
+
+	return ret
+}
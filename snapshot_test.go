@@ -0,0 +1,41 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	config := &ScrapeConfig{
+		DividePage: DividePageBySelector("li"),
+		Pieces: []Piece{
+			{Name: "title", Selector: "h2"},
+			{Name: "link", Selector: "a.link"},
+			{Name: "self", Selector: "."},
+		},
+	}
+
+	oldDoc := `<ul><li><h2>One</h2><a class="link">x</a></li><li><h2>Two</h2></li></ul>`
+	newDoc := `<ul><li><h3>One</h3><a class="link">x</a></li><li><h3>Two</h3></li></ul>`
+
+	diff, err := CompareSnapshots(config, strings.NewReader(oldDoc), strings.NewReader(newDoc))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, diff.OldBlocks)
+	assert.Equal(t, 2, diff.NewBlocks)
+	assert.True(t, diff.Similarity > 0 && diff.Similarity < 1)
+
+	broken := diff.Broken()
+	if assert.Equal(t, 1, len(broken)) {
+		assert.Equal(t, "title", broken[0].Piece)
+		assert.Equal(t, 2, broken[0].OldMatches)
+	}
+	assert.Equal(t, 1, diff.Selectors[1].NewMatches)
+	assert.Equal(t, 2, diff.Selectors[2].NewMatches)
+
+	diff, err = CompareSnapshots(config, strings.NewReader(oldDoc), strings.NewReader(oldDoc))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, diff.Similarity)
+	assert.Empty(t, diff.Broken())
+}
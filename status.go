@@ -0,0 +1,103 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StatusAction is what a Scraper does with a page whose response has a
+// certain status code.
+type StatusAction int
+
+const (
+	// StatusParse scrapes the page as usual, whatever its status code.
+	StatusParse StatusAction = iota
+
+	// StatusAbort aborts the scrape with a *StatusError.
+	StatusAbort
+
+	// StatusSkip records the page without any blocks, and carries on with
+	// the scrape.  The Paginator is given an empty document, so the scrape
+	// only continues if the next page can be found from the URL alone (e.g.
+	// with paginate.ByQueryParam).
+	StatusSkip
+
+	// StatusRetry fetches the page again after a delay.  If the page still
+	// has the same status after StatusPolicy.MaxRetries retries, then the
+	// scrape is aborted with a *StatusError.
+	StatusRetry
+)
+
+// StatusPolicy controls what a Scraper does with pages whose responses don't
+// have a successful (2xx) status code, so that e.g. an error page isn't
+// scraped as if it were content.  It only applies to Fetchers that report
+// status codes (see ResponseMetadata), such as HttpClientFetcher.
+type StatusPolicy struct {
+	// The action for responses with a non-2xx status code that isn't in
+	// Codes.  The default is StatusParse.
+	Default StatusAction
+
+	// Actions for specific status codes, which take precedence over Default
+	// - e.g. {404: StatusSkip, 503: StatusRetry}.
+	Codes map[int]StatusAction
+
+	// The maximum number of times a page is retried, and the delay before
+	// the first retry, which doubles with each retry.  If these are 0, then
+	// 3 retries and a delay of 1 second are used.
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+const (
+	defaultStatusRetries = 3
+	defaultStatusDelay   = time.Second
+)
+
+// StatusError is returned when a scrape is aborted because of the status code
+// of a page's response.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.URL, e.StatusCode)
+}
+
+func (p *StatusPolicy) validate() error {
+	if p.MaxRetries < 0 {
+		return errors.New("status retries must not be negative")
+	}
+	if p.RetryDelay < 0 {
+		return errors.New("status retry delay must not be negative")
+	}
+	return nil
+}
+
+// action returns the action for the given status code.
+func (p *StatusPolicy) action(code int) StatusAction {
+	if action, found := p.Codes[code]; found {
+		return action
+	}
+	if code == 0 || code/100 == 2 {
+		return StatusParse
+	}
+	return p.Default
+}
+
+func (p *StatusPolicy) maxRetries() int {
+	if p.MaxRetries == 0 {
+		return defaultStatusRetries
+	}
+	return p.MaxRetries
+}
+
+// retryDelay returns the delay before the given retry, starting at 0.
+func (p *StatusPolicy) retryDelay(retry int) time.Duration {
+	delay := p.RetryDelay
+	if delay == 0 {
+		delay = defaultStatusDelay
+	}
+	return delay << uint(retry)
+}
@@ -0,0 +1,66 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	// This error is returned, along with the results so far, when a scrape
+	// is stopped because ScrapeOptions.MaxConsecutiveEmptyPages pages in a row
+	// had no blocks.
+	ErrTooManyEmptyPages = errors.New("too many consecutive empty pages")
+
+	// This error is returned, along with the results so far, when a scrape
+	// is stopped because ScrapeOptions.MaxConsecutiveErrors pages in a row
+	// couldn't be fetched.  It is wrapped with the last fetch error, so check
+	// for it with errors.Is.
+	ErrTooManyErrors = errors.New("too many consecutive errors")
+)
+
+// stopLoss counts consecutive empty and failed pages during a scrape.
+type stopLoss struct {
+	opts   ScrapeOptions
+	empty  int
+	errors int
+}
+
+// fetchFailed records a page that couldn't be fetched with the given error.
+// It returns the error that should end the scrape, or nil if the scrape should
+// carry on with the next page.
+func (sl *stopLoss) fetchFailed(err error) error {
+	if sl.opts.MaxConsecutiveErrors <= 0 {
+		return err
+	}
+	sl.errors++
+	if sl.errors >= sl.opts.MaxConsecutiveErrors {
+		return fmt.Errorf("%w: %s", ErrTooManyErrors, err)
+	}
+	return nil
+}
+
+// fetched records a page that was fetched and had the given number of
+// blocks.  It returns ErrTooManyEmptyPages if the scrape should stop, or nil
+// otherwise.
+func (sl *stopLoss) fetched(blocks int) error {
+	sl.errors = 0
+	if blocks > 0 {
+		sl.empty = 0
+		return nil
+	}
+
+	sl.empty++
+	if sl.opts.MaxConsecutiveEmptyPages > 0 && sl.empty >= sl.opts.MaxConsecutiveEmptyPages {
+		return ErrTooManyEmptyPages
+	}
+	return nil
+}
+
+// blankDocument returns a document with no content at all, which is given to
+// the Paginator in place of a page that couldn't be fetched.
+func blankDocument() *goquery.Document {
+	return goquery.NewDocumentFromNode(&html.Node{Type: html.DocumentNode})
+}
@@ -0,0 +1,169 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Store is the interface that must be satisfied by things that can persist
+// data between scrapes.  It is a simple key-value store; callers are expected
+// to namespace their keys (e.g. "seen:...") so that a single Store can be
+// shared between multiple users.
+//
+// Implementations of Store must be safe for concurrent use.  Some
+// implementations can be found in the "store" subpackage.
+type Store interface {
+	// Get retrieves the value for the given key.  If the key does not exist,
+	// Get returns a nil slice and a nil error.
+	Get(key string) ([]byte, error)
+
+	// Put stores the given value under the given key, overwriting any existing
+	// value.
+	Put(key string, value []byte) error
+
+	// Delete removes the given key.  Deleting a key that does not exist is not
+	// an error.
+	Delete(key string) error
+}
+
+// The BatchStore interface can optionally be implemented by a Store that can
+// store many values at once more efficiently than one at a time - e.g. one
+// that rewrites a file on every change.  Deduplication uses it to record the
+// keys of all new blocks at the end of a scrape.
+type BatchStore interface {
+	Store
+
+	// PutAll stores each of the given values under its key, as if Put were
+	// called for each of them.
+	PutAll(values map[string][]byte) error
+}
+
+// DedupConfig controls deduplication of blocks across multiple scrapes.  For
+// more information, please see the documentation on the ScrapeConfig type.
+type DedupConfig struct {
+	// Store is where the keys of previously-seen blocks are persisted.
+	// Required.
+	Store Store
+
+	// Key is the name of the Piece whose value uniquely identifies a block -
+	// e.g. a link or an ID - or the name of the block's ID, if
+	// ScrapeConfig.BlockID is set.  Blocks for which this has no value are
+	// never considered duplicates.  Required.
+	Key string
+
+	// If StopOnSeen is true, then the scrape will stop paginating after the
+	// first page that contains a block that was seen in a previous scrape.
+	// This is useful for sites that list the newest items first.
+	StopOnSeen bool
+}
+
+func (d *DedupConfig) validate(c *ScrapeConfig) error {
+	if d.Store == nil {
+		return errors.New("no store provided for dedup")
+	}
+	if c.BlockID != nil && c.BlockID.name() == d.Key {
+		return nil
+	}
+	for _, piece := range c.Pieces {
+		if piece.Name == d.Key {
+			return nil
+		}
+	}
+	return fmt.Errorf("dedup key %q is not the name of a piece", d.Key)
+}
+
+// dedupKey returns the store key for the given block, or an empty string if
+// the block has no value for the configured Piece.
+func (d *DedupConfig) dedupKey(block map[string]interface{}) string {
+	val, found := block[d.Key]
+	if !found {
+		return ""
+	}
+	return "seen:" + fmt.Sprint(val)
+}
+
+// isSeen returns whether the given key was recorded by a previous scrape.
+func (d *DedupConfig) isSeen(key string) (bool, error) {
+	val, err := d.Store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return val != nil, nil
+}
+
+// markSeen records the given keys in the store.
+func (d *DedupConfig) markSeen(keys []string) error {
+	now := []byte(time.Now().UTC().Format(time.RFC3339))
+	if bs, ok := d.Store.(BatchStore); ok {
+		values := make(map[string][]byte, len(keys))
+		for _, key := range keys {
+			values[key] = now
+		}
+		return bs.PutAll(values)
+	}
+	for _, key := range keys {
+		if err := d.Store.Put(key, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dedupState tracks which blocks have been seen during a single scrape.  A nil
+// *DedupConfig results in a state that keeps every block.
+type dedupState struct {
+	config *DedupConfig
+
+	// Keys of blocks that are new in this scrape.
+	newKeys  []string
+	seenKeys map[string]struct{}
+}
+
+func newDedupState(config *DedupConfig, newKeys []string) *dedupState {
+	ret := &dedupState{
+		config:   config,
+		newKeys:  newKeys,
+		seenKeys: map[string]struct{}{},
+	}
+	for _, key := range newKeys {
+		ret.seenKeys[key] = struct{}{}
+	}
+	return ret
+}
+
+// check returns whether the given block should be kept, and whether it was
+// seen in a previous scrape.
+func (d *dedupState) check(block map[string]interface{}) (keep bool, seenBefore bool, err error) {
+	if d.config == nil {
+		return true, false, nil
+	}
+
+	key := d.config.dedupKey(block)
+	if key == "" {
+		return true, false, nil
+	}
+	if _, seen := d.seenKeys[key]; seen {
+		return false, false, nil
+	}
+	d.seenKeys[key] = struct{}{}
+
+	seen, err := d.config.isSeen(key)
+	if err != nil {
+		return false, false, err
+	}
+	if seen {
+		return false, true, nil
+	}
+
+	d.newKeys = append(d.newKeys, key)
+	return true, false, nil
+}
+
+// commit records every new block in the store.
+func (d *dedupState) commit() error {
+	if d.config == nil {
+		return nil
+	}
+	return d.config.markSeen(d.newKeys)
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// File is a Store that persists all data to a single JSON file on disk.  The
+// entire contents of the store are kept in memory, and the file is rewritten
+// on every modification, so this is best suited to small or medium-sized
+// stores (e.g. tens of thousands of keys).  Use PutAll to store many values
+// with a single write, as deduplication does.
+type File struct {
+	path string
+
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewFile opens the file-backed Store at the given path, loading any existing
+// data.  If the file does not exist, it will be created on the first write.
+func NewFile(path string) (*File, error) {
+	ret := &File{
+		path: path,
+		data: map[string][]byte{},
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err = json.NewDecoder(f).Decode(&ret.data); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *File) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data[key], nil
+}
+
+func (s *File) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = append([]byte{}, value...)
+	return s.save()
+}
+
+// PutAll stores all of the given values, and then rewrites the file once.
+func (s *File) PutAll(values map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range values {
+		s.data[key] = append([]byte{}, value...)
+	}
+	return s.save()
+}
+
+func (s *File) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.data[key]; !found {
+		return nil
+	}
+	delete(s.data, key)
+	return s.save()
+}
+
+// save writes the store to a temporary file and then renames it over the
+// original, so that a crash never leaves a partially-written store behind.
+// Must be called with the lock held.
+func (s *File) save() error {
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if err = json.NewEncoder(tmp).Encode(s.data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Static type assertion
+var _ scrape.BatchStore = &File{}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/andrew-d/goscrape"
+)
+
+// Memory is a Store that keeps all data in memory.  Data is not persisted
+// between runs of a program, which makes this mostly useful for testing, or
+// for deduplicating across multiple scrapes in a single process.
+type Memory struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemory creates a new, empty in-memory Store.
+func NewMemory() *Memory {
+	return &Memory{
+		data: map[string][]byte{},
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.data[key], nil
+}
+
+func (m *Memory) Put(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data, key)
+	return nil
+}
+
+// Static type assertion
+var _ scrape.Store = &Memory{}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	s := NewMemory()
+
+	val, err := s.Get("foo")
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	assert.NoError(t, s.Put("foo", []byte("bar")))
+	val, err = s.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+
+	assert.NoError(t, s.Delete("foo"))
+	val, err = s.Get("foo")
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goscrape-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "store.json")
+	s, err := NewFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Put("foo", []byte("bar")))
+	assert.NoError(t, s.Put("baz", []byte("asdf")))
+	assert.NoError(t, s.Delete("baz"))
+
+	// Re-open and ensure the data was persisted.
+	s, err = NewFile(path)
+	assert.NoError(t, err)
+
+	val, err := s.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+
+	val, err = s.Get("baz")
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	// PutAll stores every value with a single write.
+	assert.NoError(t, s.PutAll(map[string][]byte{"a": []byte("1"), "b": []byte("2")}))
+	s, err = NewFile(path)
+	assert.NoError(t, err)
+	for key, want := range map[string]string{"foo": "bar", "a": "1", "b": "2"} {
+		val, err = s.Get(key)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(want), val)
+	}
+}
@@ -0,0 +1,161 @@
+package scrape
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// PieceTypeError is returned in strict mode (see ScrapeConfig.Strict) when a
+// Piece's value doesn't match its declared JSON Schema.
+type PieceTypeError struct {
+	// The name of the Piece.
+	Piece string
+
+	// Where in the value the mismatch was found - e.g. "[2]" for the third
+	// item of a list, or ".name" for an entry of a map.  This is empty if the
+	// value itself has the wrong type.
+	Path string
+
+	// The type that the schema expects, and the Go type of the value.
+	Expected string
+	Got      string
+}
+
+func (e *PieceTypeError) Error() string {
+	return fmt.Sprintf("piece %q%s: expected %s, got %s", e.Piece, e.Path, e.Expected, e.Got)
+}
+
+// pieceSchemas returns the schema of each Piece, for checking values in
+// strict mode.
+func pieceSchemas(pieces []Piece) []map[string]interface{} {
+	ret := make([]map[string]interface{}, len(pieces))
+	for i, piece := range pieces {
+		ret[i] = pieceSchema(piece)
+	}
+	return ret
+}
+
+// checkSchema checks that the given value of the named Piece matches the
+// schema.  Only the keywords produced by TypeSchema and the extractors in
+// this module - "type", "items", "properties", "additionalProperties" and
+// "anyOf" - are checked.
+func checkSchema(piece string, schema map[string]interface{}, val interface{}) error {
+	if err := matchSchema(schema, reflect.ValueOf(val), ""); err != nil {
+		err.Piece = piece
+		return err
+	}
+	return nil
+}
+
+func matchSchema(schema map[string]interface{}, v reflect.Value, path string) *PieceTypeError {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var first *PieceTypeError
+		for _, s := range anyOf {
+			sub, _ := s.(map[string]interface{})
+			err := matchSchema(sub, v, path)
+			if err == nil {
+				return nil
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return first
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	if typ == "" {
+		return nil
+	}
+	mismatch := func() *PieceTypeError {
+		got := "nil"
+		if v.IsValid() {
+			got = v.Type().String()
+		}
+		return &PieceTypeError{Path: path, Expected: typ, Got: got}
+	}
+	if !v.IsValid() {
+		return mismatch()
+	}
+
+	switch typ {
+	case "string":
+		if v.Kind() != reflect.String && !(v.Type() == timeType && schema["format"] == "date-time") {
+			return mismatch()
+		}
+
+	case "integer":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		case reflect.Float32, reflect.Float64:
+			if f := v.Float(); f != math.Trunc(f) {
+				return mismatch()
+			}
+		default:
+			return mismatch()
+		}
+
+	case "number":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+		default:
+			return mismatch()
+		}
+
+	case "boolean":
+		if v.Kind() != reflect.Bool {
+			return mismatch()
+		}
+
+	case "array":
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return mismatch()
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i := 0; i < v.Len(); i++ {
+				if err := matchSchema(items, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "object":
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return mismatch()
+			}
+			props, _ := schema["properties"].(map[string]interface{})
+			values, _ := schema["additionalProperties"].(map[string]interface{})
+			iter := v.MapRange()
+			for iter.Next() {
+				key := iter.Key().String()
+				sub, ok := props[key].(map[string]interface{})
+				if !ok {
+					sub = values
+				}
+				if err := matchSchema(sub, iter.Value(), path+"."+key); err != nil {
+					return err
+				}
+			}
+		case reflect.Struct:
+			// Structs are checked by their type, rather than their fields.
+		default:
+			return mismatch()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package scrape
+
+import (
+	"strings"
+)
+
+// StripFlags selects the kinds of content that are removed by RewriteStrip.
+type StripFlags int
+
+const (
+	// StripScripts removes <script> elements and their contents.
+	StripScripts StripFlags = 1 << iota
+
+	// StripStyles removes <style> elements and their contents.
+	StripStyles
+
+	// StripComments removes HTML comments.
+	StripComments
+
+	// StripAll removes all of the above.
+	StripAll = StripScripts | StripStyles | StripComments
+)
+
+// RewriteStrip returns a RewriteFunc that removes the given kinds of content
+// from each page before it's parsed.  On many pages, most of the HTML consists
+// of inline scripts and styles that are never needed for extraction, so
+// removing them first can greatly reduce the time and memory used to parse
+// the page.
+//
+// This uses a simple scan of the raw HTML rather than a full parse, so it does
+// not handle every edge case that a browser would (e.g. a "</script>" string
+// inside a script's contents ends the script early, just as it does in a
+// browser).
+func RewriteStrip(what StripFlags) RewriteFunc {
+	return func(url, body string) (string, error) {
+		return stripHTML(body, what), nil
+	}
+}
+
+func stripHTML(body string, what StripFlags) string {
+	lower := asciiLower(body)
+
+	var buf strings.Builder
+	buf.Grow(len(body))
+
+	pos := 0
+	for {
+		idx := strings.IndexByte(body[pos:], '<')
+		if idx < 0 {
+			break
+		}
+		start := pos + idx
+
+		// Comments are always skipped over, so that a tag inside a comment
+		// isn't mistaken for a real one.
+		if strings.HasPrefix(body[start:], "<!--") {
+			end := strings.Index(body[start+4:], "-->")
+			if end < 0 {
+				end = len(body)
+			} else {
+				end = start + 4 + end + 3
+			}
+			if what&StripComments != 0 {
+				buf.WriteString(body[pos:start])
+			} else {
+				buf.WriteString(body[pos:end])
+			}
+			pos = end
+			continue
+		}
+
+		tag := ""
+		switch {
+		case what&StripScripts != 0 && isTagStart(lower[start:], "script"):
+			tag = "script"
+		case what&StripStyles != 0 && isTagStart(lower[start:], "style"):
+			tag = "style"
+		}
+		if tag == "" {
+			buf.WriteString(body[pos : start+1])
+			pos = start + 1
+			continue
+		}
+
+		// Skip everything up to and including the closing tag.
+		end := len(body)
+		if close := strings.Index(lower[start:], "</"+tag); close >= 0 {
+			close += start
+			if gt := strings.IndexByte(body[close:], '>'); gt >= 0 {
+				end = close + gt + 1
+			}
+		}
+		buf.WriteString(body[pos:start])
+		pos = end
+	}
+
+	buf.WriteString(body[pos:])
+	return buf.String()
+}
+
+// isTagStart returns whether s starts with an opening tag with the given
+// (lowercase) name.
+func isTagStart(s, name string) bool {
+	if !strings.HasPrefix(s, "<"+name) {
+		return false
+	}
+	if len(s) == len(name)+1 {
+		return true
+	}
+	switch s[len(name)+1] {
+	case '>', '/', ' ', '\t', '\n', '\r', '\f':
+		return true
+	}
+	return false
+}
+
+// asciiLower lowercases only ASCII letters, so that byte offsets in the result
+// match those in the original string.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,127 @@
+package scrape
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Summary is a short overview of a scrape, as returned by
+// ScrapeResults.Summary.
+type Summary struct {
+	// The number of pages visited.
+	Pages int
+
+	// The number of blocks extracted from all pages.
+	Blocks int
+
+	// The number of blocks in which each Piece had a value, keyed by
+	// Piece.Name.  Pieces that were recorded in ScrapeResults.Failures but
+	// never had a value are included with a count of 0.
+	Filled map[string]int
+
+	// The time taken by the scrape; see ScrapeResults.Duration.
+	Duration time.Duration
+
+	// The number of bytes downloaded and the time spent in the Fetcher,
+	// across all hosts.  These are only known if ScrapeConfig.Usage was set.
+	Bytes     int64
+	FetchTime time.Duration
+}
+
+// Summary returns a Summary of the results.
+func (r *ScrapeResults) Summary() *Summary {
+	ret := &Summary{
+		Pages:    len(r.URLs),
+		Filled:   map[string]int{},
+		Duration: r.Duration,
+	}
+	for _, page := range r.Results {
+		ret.Blocks += len(page)
+		for _, block := range page {
+			for name := range block {
+				ret.Filled[name]++
+			}
+		}
+	}
+	for name := range r.Failures {
+		if _, found := ret.Filled[name]; !found {
+			ret.Filled[name] = 0
+		}
+	}
+	for _, usage := range r.Usage {
+		ret.Bytes += usage.Bytes
+		ret.FetchTime += usage.FetchTime
+	}
+	return ret
+}
+
+// FillRate returns the fraction of blocks in which the given Piece had a
+// value, between 0 and 1.  If there were no blocks, then it returns 0.
+func (s *Summary) FillRate(piece string) float64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+	return float64(s.Filled[piece]) / float64(s.Blocks)
+}
+
+// String formats the summary for humans, over several lines - e.g.
+//
+//	pages:    3
+//	blocks:   42
+//	duration: 1.52s
+//	fetched:  120.5 KiB in 1.2s
+//	pieces:
+//	  price  95.2% (40/42)
+//	  title 100.0% (42/42)
+func (s *Summary) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "pages:    %d\n", s.Pages)
+	fmt.Fprintf(&buf, "blocks:   %d\n", s.Blocks)
+	fmt.Fprintf(&buf, "duration: %s\n", s.Duration.Round(10*time.Millisecond))
+	if s.Bytes > 0 || s.FetchTime > 0 {
+		fmt.Fprintf(&buf, "fetched:  %s in %s\n", formatBytes(s.Bytes), s.FetchTime.Round(10*time.Millisecond))
+	}
+
+	if len(s.Filled) > 0 {
+		names := make([]string, 0, len(s.Filled))
+		width := 0
+		for name := range s.Filled {
+			names = append(names, name)
+			if len(name) > width {
+				width = len(name)
+			}
+		}
+		sort.Strings(names)
+
+		buf.WriteString("pieces:\n")
+		for _, name := range names {
+			fmt.Fprintf(&buf, "  %-*s %5.1f%% (%d/%d)\n",
+				width, name, 100*s.FillRate(name), s.Filled[name], s.Blocks)
+		}
+	}
+	return buf.String()
+}
+
+// PrintSummary writes a human-readable Summary of the results to w - e.g. at
+// the end of a command-line tool or cron job.
+func (r *ScrapeResults) PrintSummary(w io.Writer) error {
+	_, err := io.WriteString(w, r.Summary().String())
+	return err
+}
+
+// formatBytes formats a number of bytes using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,158 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	neturl "net/url"
+	"sync"
+	"time"
+)
+
+// ThrottledFetcher is a Fetcher that wraps another Fetcher, and enforces a
+// minimum delay between requests to the same host.  Unlike
+// paginate.WithDelay, this applies to every request made through the
+// fetcher - including those made by concurrent scrapes, crawls and ScrapeAll
+// - so sharing a single ThrottledFetcher between Scrapers keeps all of them
+// polite.  Requests to different hosts aren't delayed by each other.
+//
+// Requests that are made outside of the Fetcher - e.g. by a custom extractor
+// or in HttpClientFetcher.PrepareClient - can share the same limits by using
+// an http.Client whose transport is returned by Transport.
+//
+// A ThrottledFetcher is safe to use concurrently if the underlying Fetcher
+// is.
+type ThrottledFetcher struct {
+	// The Fetcher to make requests with.
+	Fetcher Fetcher
+
+	// Delay is the minimum time between the start of each request to the same
+	// host.  HostDelays overrides this for specific hosts (e.g.
+	// "www.example.com").
+	Delay      time.Duration
+	HostDelays map[string]time.Duration
+
+	// Jitter randomly increases each delay by up to this fraction of it (e.g.
+	// 0.5 for up to 50% longer), so that requests don't arrive at perfectly
+	// regular intervals.
+	Jitter float64
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func (f *ThrottledFetcher) Prepare() error {
+	if f.Fetcher == nil {
+		return errors.New("no fetcher to throttle")
+	}
+	if f.Jitter < 0 {
+		return errors.New("throttle jitter must not be negative")
+	}
+	return f.Fetcher.Prepare()
+}
+
+func (f *ThrottledFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *ThrottledFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	if err := f.wait(ctx, url); err != nil {
+		return nil, err
+	}
+	if cf, ok := f.Fetcher.(ContextFetcher); ok {
+		return cf.FetchContext(ctx, method, url)
+	}
+	return f.Fetcher.Fetch(method, url)
+}
+
+func (f *ThrottledFetcher) Close() {
+	f.Fetcher.Close()
+}
+
+// SetScripts passes the given scripts to the underlying Fetcher, if it is a
+// ScriptEvaluator.
+func (f *ThrottledFetcher) SetScripts(scripts []string) {
+	setScripts(f.Fetcher, scripts)
+}
+
+// Check calls the underlying Fetcher's Check method, if it has one.
+func (f *ThrottledFetcher) Check() error {
+	return checkFetcher(f.Fetcher)
+}
+
+// CookieJar returns the cookie jar of the underlying Fetcher, or nil if it
+// isn't a CookieJarFetcher.
+func (f *ThrottledFetcher) CookieJar() http.CookieJar {
+	if jf, ok := f.Fetcher.(CookieJarFetcher); ok {
+		return jf.CookieJar()
+	}
+	return nil
+}
+
+// Transport returns an http.RoundTripper that sends requests with the given
+// RoundTripper (or http.DefaultTransport, if it is nil), subject to the same
+// per-host delays as the fetcher.
+func (f *ThrottledFetcher) Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &throttledTransport{f, rt}
+}
+
+type throttledTransport struct {
+	f  *ThrottledFetcher
+	rt http.RoundTripper
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.f.wait(req.Context(), req.URL.String()); err != nil {
+		return nil, err
+	}
+	return t.rt.RoundTrip(req)
+}
+
+// wait blocks until a request to the given URL's host may be made.
+func (f *ThrottledFetcher) wait(ctx context.Context, url string) error {
+	host := url
+	if u, err := neturl.Parse(url); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	delay, found := f.HostDelays[host]
+	if !found {
+		delay = f.Delay
+	}
+	if delay <= 0 {
+		return nil
+	}
+	if f.Jitter > 0 {
+		delay += time.Duration(f.Jitter * rand.Float64() * float64(delay))
+	}
+
+	f.mu.Lock()
+	if f.next == nil {
+		f.next = map[string]time.Time{}
+	}
+	now := time.Now()
+	start := f.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	f.next[host] = start.Add(delay)
+	f.mu.Unlock()
+
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Static type assertion
+var _ ContextFetcher = &ThrottledFetcher{}
+var _ CookieJarFetcher = &ThrottledFetcher{}
+var _ ScriptEvaluator = &ThrottledFetcher{}
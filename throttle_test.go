@@ -0,0 +1,114 @@
+package scrape_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/stretchr/testify/assert"
+)
+
+// timingFetcher records when each URL is fetched.
+type timingFetcher struct {
+	mapFetcher
+
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+func (f *timingFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	f.times[url] = time.Now()
+	f.mu.Unlock()
+	return f.mapFetcher.Fetch(method, url)
+}
+
+func TestThrottledFetcher(t *testing.T) {
+	inner := &timingFetcher{mapFetcher: mapFetcher{}, times: map[string]time.Time{}}
+	for _, url := range []string{"http://a/1", "http://a/2", "http://a/3", "http://b/1", "http://c/1", "http://c/2"} {
+		inner.mapFetcher[url] = "<p>ok</p>"
+	}
+	fetcher := &scrape.ThrottledFetcher{
+		Fetcher:    inner,
+		Delay:      50 * time.Millisecond,
+		HostDelays: map[string]time.Duration{"c": 0},
+	}
+	assert.NoError(t, fetcher.Prepare())
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	for _, url := range []string{"http://a/1", "http://a/2", "http://a/3", "http://b/1", "http://c/1", "http://c/2"} {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			body, err := fetcher.Fetch("GET", url)
+			if assert.NoError(t, err) {
+				body.Close()
+			}
+		}(url)
+	}
+	wg.Wait()
+
+	// Requests to the same host are spaced out.
+	a := []time.Time{inner.times["http://a/1"], inner.times["http://a/2"], inner.times["http://a/3"]}
+	sort.Slice(a, func(i, j int) bool { return a[i].Before(a[j]) })
+	assert.True(t, a[1].Sub(a[0]) >= 45*time.Millisecond, a[1].Sub(a[0]))
+	assert.True(t, a[2].Sub(a[1]) >= 45*time.Millisecond, a[2].Sub(a[1]))
+
+	// Other hosts aren't held up by them.
+	for _, url := range []string{"http://b/1", "http://c/1", "http://c/2"} {
+		assert.True(t, inner.times[url].Sub(started) < 45*time.Millisecond, url)
+	}
+
+	// Waiting can be cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := fetcher.FetchContext(ctx, "GET", "http://a/4")
+	assert.Equal(t, context.Canceled, err)
+
+	assert.EqualError(t, (&scrape.ThrottledFetcher{}).Prepare(), "no fetcher to throttle")
+}
+
+func TestThrottledTransport(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		fmt.Fprint(w, "<p>ok</p>")
+	}))
+	defer ts.Close()
+
+	fetcher := &scrape.ThrottledFetcher{Delay: 50 * time.Millisecond}
+	hf, err := scrape.NewHttpClientFetcher()
+	assert.NoError(t, err)
+	fetcher.Fetcher = hf
+	assert.NoError(t, fetcher.Prepare())
+
+	// A request through the transport and one through the fetcher share the
+	// same limit.
+	client := &http.Client{Transport: fetcher.Transport(nil)}
+	resp, err := client.Get(ts.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+	body, err := fetcher.Fetch("GET", ts.URL)
+	if assert.NoError(t, err) {
+		body.Close()
+	}
+
+	if assert.Len(t, times, 2) {
+		assert.True(t, times[1].Sub(times[0]) >= 45*time.Millisecond, times[1].Sub(times[0]))
+	}
+	assert.NotNil(t, fetcher.CookieJar())
+}
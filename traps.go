@@ -0,0 +1,90 @@
+package scrape
+
+import (
+	neturl "net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	reDigits = regexp.MustCompile(`[0-9]+`)
+	reID     = regexp.MustCompile(`^(?:[0-9a-fA-F-]{16,}|[0-9A-Za-z_-]{24,})$`)
+)
+
+// URLPattern returns the general shape of the given URL, which is used to
+// group together URLs that point to similar pages.  The scheme and fragment
+// are removed, runs of digits in the path are replaced by "{n}", path segments
+// that look like IDs or session tokens are replaced by "{id}", and the query
+// string is replaced by its sorted, unique parameter names.  For example,
+// "http://example.com/cal/2024/05?sid=abc&view=month" has the pattern
+// "example.com/cal/{n}/{n}?sid&view".
+//
+// If the URL cannot be parsed, it is returned unchanged.
+func URLPattern(url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return url
+	}
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, seg := range segments {
+		// Remove path parameters, such as ";jsessionid=...".
+		if idx := strings.IndexByte(seg, ';'); idx >= 0 {
+			seg = seg[:idx]
+		}
+
+		if reID.MatchString(seg) && reDigits.MatchString(seg) {
+			seg = "{id}"
+		} else {
+			seg = reDigits.ReplaceAllString(seg, "{n}")
+		}
+		segments[i] = seg
+	}
+	pattern := u.Host + strings.Join(segments, "/")
+
+	if u.RawQuery != "" {
+		keys := []string{}
+		for key := range u.Query() {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pattern += "?" + strings.Join(keys, "&")
+	}
+	return pattern
+}
+
+// trapDetector keeps track of how many URLs have been queued for each URL
+// pattern during a crawl, and reports patterns that have been queued too often.
+type trapDetector struct {
+	threshold int
+	counts    map[string]int
+}
+
+func newTrapDetector(threshold int) *trapDetector {
+	return &trapDetector{
+		threshold: threshold,
+		counts:    make(map[string]int),
+	}
+}
+
+// allow records that the given URL is about to be queued, and returns whether
+// it should be.  It also returns the URL's pattern, and whether this URL is the
+// one that caused the pattern to be treated as a trap.
+func (t *trapDetector) allow(url string) (ok bool, pattern string, tripped bool) {
+	if t.threshold <= 0 {
+		return true, "", false
+	}
+
+	pattern = URLPattern(url)
+	count := t.counts[pattern]
+	if count > t.threshold {
+		return false, pattern, false
+	}
+
+	t.counts[pattern] = count + 1
+	if count == t.threshold {
+		return false, pattern, true
+	}
+	return true, pattern, false
+}
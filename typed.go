@@ -0,0 +1,229 @@
+//go:build go1.18
+// +build go1.18
+
+package scrape
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PieceExtractorT is the typed equivalent of PieceExtractor: it extracts a
+// value of type T from a selection.  Use the Untyped function to convert one
+// into a PieceExtractor, or PieceT to use it in a ScrapeConfig directly.
+//
+// This type requires Go 1.18 or later.
+type PieceExtractorT[T any] interface {
+	Extract(sel *goquery.Selection) (T, error)
+}
+
+// ExtractorFuncT adapts a function into a PieceExtractorT.
+type ExtractorFuncT[T any] func(sel *goquery.Selection) (T, error)
+
+func (f ExtractorFuncT[T]) Extract(sel *goquery.Selection) (T, error) {
+	return f(sel)
+}
+
+// ContextualExtractorT is the typed equivalent of ContextualExtractor.  If a
+// PieceExtractorT given to Untyped implements it, then ExtractWithContext is
+// called instead of Extract during a scrape.
+//
+// This type requires Go 1.18 or later.
+type ContextualExtractorT[T any] interface {
+	PieceExtractorT[T]
+
+	ExtractWithContext(ctx ExtractContext, sel *goquery.Selection) (T, error)
+}
+
+type untyped[T any] struct {
+	e PieceExtractorT[T]
+}
+
+func (u untyped[T]) Extract(sel *goquery.Selection) (interface{}, error) {
+	return u.extract(nil, sel)
+}
+
+func (u untyped[T]) ExtractWithContext(ctx ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	return u.extract(&ctx, sel)
+}
+
+// extract runs the wrapped extractor, with the given context if there is one
+// and the extractor implements ContextualExtractorT.
+func (u untyped[T]) extract(ctx *ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	// An extractor converted with Typed can tell us whether the Piece was
+	// omitted, rather than returning T's zero value.
+	if t, ok := u.e.(typed[T]); ok {
+		return t.extract(ctx, sel)
+	}
+
+	var val T
+	var err error
+	if ce, ok := u.e.(ContextualExtractorT[T]); ok && ctx != nil {
+		val, err = ce.ExtractWithContext(*ctx, sel)
+	} else {
+		val, err = u.e.Extract(sel)
+	}
+	if err != nil || isNilValue(val) {
+		return nil, err
+	}
+	return val, nil
+}
+
+func (u untyped[T]) Unwrap() []PieceExtractor {
+	if t, ok := u.e.(typed[T]); ok {
+		return []PieceExtractor{t.e}
+	}
+	return nil
+}
+
+func (u untyped[T]) Validate() error {
+	if v, ok := u.e.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// isNilValue returns whether the given value is nil, or a nil pointer, slice,
+// map or interface.
+func isNilValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(val); v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (u untyped[T]) JSONSchema() map[string]interface{} {
+	return TypeSchema(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Untyped converts a PieceExtractorT into a PieceExtractor.  A nil pointer,
+// slice or map is omitted from the results, as is a value that the wrapped
+// extractor of Typed omitted, so Untyped(Typed(e)) behaves just like e.  Any
+// other value is always included, even if it is T's zero value.
+//
+// The returned extractor implements ContextualExtractor and Validator, and
+// passes them through to the given extractor if it implements
+// ContextualExtractorT or Validator.
+func Untyped[T any](e PieceExtractorT[T]) PieceExtractor {
+	return untyped[T]{e}
+}
+
+type typed[T any] struct {
+	e PieceExtractor
+}
+
+func (t typed[T]) Extract(sel *goquery.Selection) (T, error) {
+	return t.typedExtract(nil, sel)
+}
+
+func (t typed[T]) ExtractWithContext(ctx ExtractContext, sel *goquery.Selection) (T, error) {
+	return t.typedExtract(&ctx, sel)
+}
+
+func (t typed[T]) typedExtract(ctx *ExtractContext, sel *goquery.Selection) (T, error) {
+	var zero T
+
+	val, err := t.extract(ctx, sel)
+	if err != nil || val == nil {
+		return zero, err
+	}
+	return val.(T), nil
+}
+
+// extract returns the value of the wrapped extractor, which is nil if it was
+// omitted or of type T otherwise.  The extractor is given the context, if
+// there is one and it implements ContextualExtractor.
+func (t typed[T]) extract(ctx *ExtractContext, sel *goquery.Selection) (interface{}, error) {
+	var val interface{}
+	var err error
+	if ce, ok := t.e.(ContextualExtractor); ok && ctx != nil {
+		val, err = ce.ExtractWithContext(*ctx, sel)
+	} else {
+		val, err = t.e.Extract(sel)
+	}
+	if err != nil || val == nil {
+		return nil, err
+	}
+	if _, ok := val.(T); !ok {
+		var zero T
+		return nil, fmt.Errorf("extractor returned %T, not %T", val, zero)
+	}
+	return val, nil
+}
+
+func (t typed[T]) Validate() error {
+	if v, ok := t.e.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Typed converts a PieceExtractor into a PieceExtractorT.  The extractor must
+// return values of type T (or nil, which becomes T's zero value); any other
+// value results in an error.  The returned extractor implements
+// ContextualExtractorT and Validator, and passes them through to the given
+// extractor.
+func Typed[T any](e PieceExtractor) PieceExtractorT[T] {
+	return typed[T]{e}
+}
+
+// PieceT is the typed equivalent of Piece.  Its Piece method converts it for
+// use in a ScrapeConfig, and its Get method retrieves its typed value from a
+// block of the results - e.g.:
+//
+//	var title = scrape.PieceT[string]{
+//		Name: "title", Selector: "h1", Extractor: scrape.Typed[string](extract.Text{}),
+//	}
+//
+//	config := &scrape.ScrapeConfig{Pieces: []scrape.Piece{title.Piece()}}
+//	...
+//	for _, block := range results.AllBlocks() {
+//		t, ok := title.Get(block)
+//	}
+type PieceT[T any] struct {
+	// The name of this piece.  Required, and will be used to aggregate results.
+	Name string
+
+	// A sub-selector within the given block to process.  Pass in "." to use
+	// the root block's selector with no modification.
+	Selector string
+
+	// Extractor contains the logic on how to extract the value from the
+	// selector that is provided to this Piece.
+	Extractor PieceExtractorT[T]
+}
+
+// Piece returns the untyped Piece for use in a ScrapeConfig.
+func (p PieceT[T]) Piece() Piece {
+	var e PieceExtractor
+	if p.Extractor != nil {
+		e = Untyped[T](p.Extractor)
+	}
+	return Piece{Name: p.Name, Selector: p.Selector, Extractor: e}
+}
+
+// Get returns the value of this Piece in the given block, and whether it was
+// present with the expected type.
+func (p PieceT[T]) Get(block map[string]interface{}) (T, bool) {
+	return Get[T](block, p.Name)
+}
+
+// Get returns the value of the named Piece in the given block, and whether it
+// was present with type T.
+func Get[T any](block map[string]interface{}, name string) (T, bool) {
+	ret, ok := block[name].(T)
+	return ret, ok
+}
+
+// Static type assertion
+var _ ContextualExtractor = untyped[string]{}
+var _ Validator = untyped[string]{}
+var _ WrappingExtractor = untyped[string]{}
+var _ ContextualExtractorT[string] = typed[string]{}
+var _ Validator = typed[string]{}
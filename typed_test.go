@@ -0,0 +1,118 @@
+//go:build go1.18
+// +build go1.18
+
+package scrape_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/extract/extracttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedPieces(t *testing.T) {
+	name := scrape.PieceT[string]{
+		Name: "name", Selector: "b", Extractor: scrape.Typed[string](extract.Text{}),
+	}
+	count := scrape.PieceT[int]{
+		Name: "count", Selector: "i", Extractor: scrape.ExtractorFuncT[int](func(sel *goquery.Selection) (int, error) {
+			return strconv.Atoi(strings.TrimSpace(sel.Text()))
+		}),
+	}
+	wrong := scrape.PieceT[int]{
+		Name: "wrong", Selector: "b", Extractor: scrape.Typed[int](extract.Text{}),
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    mapFetcher{"a": `<ul><li><b>one</b> <i>1</i></li><li><b>two</b> <i>2</i></li></ul>`},
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces:     []scrape.Piece{name.Piece(), count.Piece()},
+	})
+
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+
+	blocks := results.AllBlocks()
+	assert.Len(t, blocks, 2)
+
+	n, ok := name.Get(blocks[1])
+	assert.True(t, ok)
+	assert.Equal(t, "two", n)
+
+	c, ok := count.Get(blocks[1])
+	assert.True(t, ok)
+	assert.Equal(t, 2, c)
+
+	_, ok = scrape.Get[string](blocks[1], "count")
+	assert.False(t, ok)
+
+	sc = mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<b>one</b>`},
+		Pieces:  []scrape.Piece{wrong.Piece()},
+	})
+	_, err = sc.Scrape("a")
+	assert.EqualError(t, err, "extractor returned string, not int")
+}
+
+func TestTypedPassThrough(t *testing.T) {
+	// Validation is passed through both wrappers.
+	_, err := scrape.New(&scrape.ScrapeConfig{
+		Pieces: []scrape.Piece{{
+			Name: "modified", Selector: ".",
+			Extractor: scrape.Untyped(scrape.Typed[string](extract.Header{})),
+		}},
+	})
+	assert.EqualError(t, err, "invalid extractor for piece 0: no header name provided")
+
+	// So is the context.
+	e := scrape.Untyped(scrape.Typed[string](extract.Header{Name: "ETag"})).(scrape.ContextualExtractor)
+	ctx := scrape.ExtractContext{Header: http.Header{"Etag": {`"abc"`}}}
+	got, err := e.ExtractWithContext(ctx, extracttest.Selection(t, `<p></p>`))
+	assert.NoError(t, err)
+	assert.Equal(t, `"abc"`, got)
+}
+
+func TestUntypedOmitted(t *testing.T) {
+	// Untyped(Typed(e)) omits the same Pieces as e.
+	header := scrape.PieceT[string]{
+		Name: "modified", Selector: ".",
+		Extractor: scrape.Typed[string](extract.Header{Name: "Last-Modified"}),
+	}
+	tags := scrape.PieceT[[]string]{
+		Name: "tags", Selector: ".", Extractor: scrape.ExtractorFuncT[[]string](func(sel *goquery.Selection) ([]string, error) {
+			return nil, nil
+		}),
+	}
+	empty := scrape.PieceT[string]{
+		Name: "empty", Selector: "i", Extractor: scrape.Typed[string](extract.Text{}),
+	}
+
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher: mapFetcher{"a": `<b>one</b>`},
+		Pieces:  []scrape.Piece{header.Piece(), tags.Piece(), empty.Piece()},
+	})
+	results, err := sc.Scrape("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"empty": ""},
+	}, results.AllBlocks())
+}
+
+func TestTypedPieceSchema(t *testing.T) {
+	prices := scrape.PieceT[[]float64]{
+		Name: "prices", Selector: "i", Extractor: scrape.ExtractorFuncT[[]float64](func(sel *goquery.Selection) ([]float64, error) {
+			return nil, nil
+		}),
+	}
+	config := &scrape.ScrapeConfig{Pieces: []scrape.Piece{prices.Piece()}}
+	assert.Equal(t, map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "number"},
+	}, config.BlockSchema()["properties"].(map[string]interface{})["prices"])
+}
@@ -0,0 +1,139 @@
+package scrape
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	neturl "net/url"
+	"time"
+)
+
+// HostUsage records the resources used to fetch pages from a single host.
+type HostUsage struct {
+	// The number of pages fetched.
+	Requests int
+
+	// The number of bytes downloaded - i.e. the size of each page's body
+	// after any decoding done by the Fetcher.
+	Bytes int64
+
+	// The total time spent in the Fetcher.  For browser-based fetchers such as
+	// PhantomJSFetcher, this includes the time spent rendering each page.
+	FetchTime time.Duration
+}
+
+func (u *HostUsage) add(other *HostUsage) {
+	u.Requests += other.Requests
+	u.Bytes += other.Bytes
+	u.FetchTime += other.FetchTime
+}
+
+// UsageConfig enables accounting of the resources used to fetch pages, per
+// host.  The usage for each scrape is recorded in ScrapeResults.Usage; if a
+// Store is given, then it is also added to running totals in the Store once
+// each scrape finishes successfully, so that usage can be budgeted or billed
+// across many runs.  The totals can be retrieved with LoadUsage.
+//
+// Note: the totals are updated by reading, modifying and writing a single
+// value, so scrapes in separate processes that share a Store and Key may lose
+// each other's updates.
+type UsageConfig struct {
+	// Store is where the running totals are kept.  If this is nil, then usage
+	// is only recorded in the results.
+	Store Store
+
+	// Key identifies the running totals in the Store.  Required if Store is
+	// set.
+	Key string
+}
+
+func (c *UsageConfig) validate() error {
+	if c.Store != nil && len(c.Key) == 0 {
+		return errors.New("no key provided for usage totals")
+	}
+	return nil
+}
+
+func usageStoreKey(key string) string {
+	return "usage:" + key
+}
+
+// LoadUsage returns the running usage totals with the given key from the
+// given Store, keyed by host.  It returns an empty map if there are none.
+func LoadUsage(store Store, key string) (map[string]*HostUsage, error) {
+	ret := map[string]*HostUsage{}
+
+	data, err := store.Get(usageStoreKey(key))
+	if err != nil || data == nil {
+		return ret, err
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// save adds the given usage to the totals in the Store.
+func (c *UsageConfig) save(usage map[string]*HostUsage) error {
+	if c.Store == nil || len(usage) == 0 {
+		return nil
+	}
+
+	totals, err := LoadUsage(c.Store, c.Key)
+	if err != nil {
+		return err
+	}
+	mergeUsage(totals, usage)
+
+	data, err := json.Marshal(totals)
+	if err != nil {
+		return err
+	}
+	return c.Store.Put(usageStoreKey(c.Key), data)
+}
+
+// mergeUsage adds the usage in 'from' to 'into'.
+func mergeUsage(into, from map[string]*HostUsage) {
+	for host, u := range from {
+		existing, found := into[host]
+		if !found {
+			existing = &HostUsage{}
+			into[host] = existing
+		}
+		existing.add(u)
+	}
+}
+
+// recordUsage adds the usage for the given fetched page to the results, if
+// usage accounting is enabled.
+func (s *Scraper) recordUsage(res *ScrapeResults, url string, doc *fetchedDoc) {
+	if s.config.Usage == nil {
+		return
+	}
+
+	host := url
+	if u, err := neturl.Parse(url); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	if res.Usage == nil {
+		res.Usage = map[string]*HostUsage{}
+	}
+	mergeUsage(res.Usage, map[string]*HostUsage{host: {
+		Requests:  1,
+		Bytes:     doc.bytes,
+		FetchTime: doc.fetchTime,
+	}})
+}
+
+// countingReader counts the bytes read from an io.Reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,131 @@
+package scrape
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// The Validator interface can optionally be implemented by a PieceExtractor to
+// check its options when a ScrapeConfig is validated, rather than failing
+// during the scrape.
+type Validator interface {
+	// Validate returns an error if the extractor's options are invalid.
+	Validate() error
+}
+
+// ValidationError is returned by ScrapeConfig.Validate, and contains every
+// problem that was found with the configuration.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+
+	msgs := []string{}
+	for _, p := range e.Problems {
+		msgs = append(msgs, p.Error())
+	}
+	return fmt.Sprintf("%d problems with config: %s",
+		len(e.Problems), strings.Join(msgs, "; "))
+}
+
+// Validate checks this configuration for problems, and returns a
+// *ValidationError describing all of them, or nil if there are none.  The
+// checks include that each Piece has a unique name, and either a Compute
+// function or a selector that is valid CSS and an extractor whose options are
+// valid (if the extractor implements the Validator interface).
+//
+// As a special case, if there are no Pieces at all then Validate returns
+// ErrNoPieces.
+//
+// New calls Validate, so it's not necessary to call it before creating a
+// Scraper.
+func (c *ScrapeConfig) Validate() error {
+	if len(c.Pieces) == 0 {
+		return ErrNoPieces
+	}
+
+	problems := []error{}
+	seenNames := map[string]struct{}{}
+	for i, piece := range c.Pieces {
+		if len(piece.Name) == 0 {
+			problems = append(problems, fmt.Errorf("no name provided for piece %d", i))
+		} else if _, seen := seenNames[piece.Name]; seen {
+			problems = append(problems, fmt.Errorf("piece %d has a duplicate name", i))
+		}
+		seenNames[piece.Name] = struct{}{}
+
+		if piece.Limits != nil {
+			if err := piece.Limits.validate(i); err != nil {
+				problems = append(problems, err)
+			}
+		}
+
+		if piece.Compute != nil {
+			if len(piece.Selector) > 0 || piece.Extractor != nil {
+				problems = append(problems,
+					fmt.Errorf("computed piece %d must not have a selector or extractor", i))
+			}
+			continue
+		}
+
+		if len(piece.Selector) == 0 {
+			problems = append(problems, fmt.Errorf("no selector provided for piece %d", i))
+		} else if piece.Selector != "." {
+			if _, err := cascadia.Compile(piece.Selector); err != nil {
+				problems = append(problems,
+					fmt.Errorf("invalid selector for piece %d (%q): %s", i, piece.Selector, err))
+			}
+		}
+
+		if piece.Extractor == nil {
+			problems = append(problems, fmt.Errorf("no extractor provided for piece %d", i))
+		} else if v, ok := piece.Extractor.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				problems = append(problems,
+					fmt.Errorf("invalid extractor for piece %d: %s", i, err))
+			}
+		}
+	}
+
+	if c.BlockID != nil {
+		if err := c.BlockID.validate(c.Pieces); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Dedup != nil {
+		if err := c.Dedup.validate(c); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Checkpoint != nil {
+		if err := c.Checkpoint.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Usage != nil {
+		if err := c.Usage.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.Challenge != nil {
+		if err := c.Challenge.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.StatusPolicy != nil {
+		if err := c.StatusPolicy.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
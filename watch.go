@@ -0,0 +1,51 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Watch scrapes the given URL with the Scraper's default options, and then
+// again every interval, until the context is done or the Scraper is drained.
+// It returns the context's error, or ErrDrained.
+//
+// Watch doesn't report results itself: combined with a Sink that compares
+// each scrape with the previous one, such as WebhookSink or EmailSink, it
+// implements the common "watch this page for changes" workflow - e.g.:
+//
+//	sc, err := scrape.New(&scrape.ScrapeConfig{
+//		Pieces: pieces,
+//		Sink:   &scrape.WebhookSink{URL: hookURL, OnChange: true, StateFile: "state.json"},
+//	})
+//	...
+//	err = sc.Watch(ctx, "https://example.com/products", 10*time.Minute)
+//
+// Scrapes that fail are logged to the Logger, and passed to the Sink if it is
+// a FailureSink, but don't stop the watch.  A scrape that is in progress when
+// the context is done is allowed to finish; use Drain to stop it sooner.
+func (s *Scraper) Watch(ctx context.Context, url string, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("watch interval must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.Scrape(url); err != nil {
+			if errors.Is(err, ErrDrained) {
+				return err
+			}
+			s.logf("watching %s: %s", url, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
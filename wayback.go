@@ -0,0 +1,205 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The default WaybackFetcher.Endpoint.
+const defaultWaybackEndpoint = "https://web.archive.org"
+
+// The format of timestamps in the Wayback Machine.
+const waybackTimeFormat = "20060102150405"
+
+// WaybackSnapshot is a single capture of a URL in the Internet Archive's
+// Wayback Machine.
+type WaybackSnapshot struct {
+	// The time at which the URL was captured.
+	Time time.Time
+
+	// The URL that was captured.
+	URL string
+
+	// The HTTP status code of the captured response.
+	StatusCode int
+}
+
+// WaybackFetcher is a Fetcher that retrieves historical snapshots of pages
+// from the Internet Archive's Wayback Machine, instead of the live site, so
+// that the same ScrapeConfig can be used to backfill data from the past.  Each
+// URL is looked up with the Wayback Machine's CDX API, and the original
+// content of the chosen snapshot is fetched (without the Wayback Machine's
+// toolbar, and with links left pointing at the original site, so Paginators
+// work as usual).
+//
+// Only snapshots with a 200 status code are used.  If a URL has no snapshot
+// between From and To, then fetching it fails.
+//
+// To backfill a series of dates, list the snapshots of the start URL with
+// Snapshots, and run a scrape with At set to the time of each one.
+//
+// A WaybackFetcher is safe to use concurrently, as long as its fields aren't
+// changed during a scrape.
+type WaybackFetcher struct {
+	// Client is the http.Client used to make requests.  If this is nil, then
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// From and To limit the snapshots that are used to those taken within
+	// this range, inclusive.  A zero value leaves that end of the range open.
+	From, To time.Time
+
+	// If At is set, then the snapshot closest to this time (within the range
+	// above) is used.  Otherwise, the most recent snapshot in the range is
+	// used.
+	At time.Time
+
+	// The base URL of the Wayback Machine.  If this is empty, then
+	// "https://web.archive.org" is used.
+	Endpoint string
+}
+
+func (f *WaybackFetcher) Prepare() error {
+	return nil
+}
+
+func (f *WaybackFetcher) Fetch(method, url string) (io.ReadCloser, error) {
+	return f.FetchContext(context.Background(), method, url)
+}
+
+func (f *WaybackFetcher) FetchContext(ctx context.Context, method, url string) (io.ReadCloser, error) {
+	if method != "GET" {
+		return nil, fmt.Errorf("the Wayback Machine only supports GET requests, not %s", method)
+	}
+
+	params := neturl.Values{}
+	if f.At.IsZero() {
+		// A negative limit returns the last snapshots.
+		params.Set("limit", "-1")
+	} else {
+		params.Set("closest", f.At.UTC().Format(waybackTimeFormat))
+		params.Set("sort", "closest")
+		params.Set("limit", "1")
+	}
+	snapshots, err := f.query(ctx, url, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshot of %s found in the Wayback Machine", url)
+	}
+	snapshot := snapshots[0]
+
+	archived := f.endpoint() + "/web/" + snapshot.Time.Format(waybackTimeFormat) + "id_/" + snapshot.URL
+	req, err := http.NewRequest("GET", archived, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request to %s failed: %s", archived, resp.Status)
+	}
+	return newResponseBody(resp, snapshot.URL), nil
+}
+
+func (f *WaybackFetcher) Close() {
+	return
+}
+
+// Snapshots returns the snapshots of the given URL between From and To, in
+// chronological order.  Only the first snapshot on each day is returned.
+func (f *WaybackFetcher) Snapshots(url string) ([]WaybackSnapshot, error) {
+	params := neturl.Values{}
+	params.Set("collapse", "timestamp:8")
+	return f.query(context.Background(), url, params)
+}
+
+// query runs a query against the CDX API for successful snapshots of the
+// given URL within the fetcher's range.
+func (f *WaybackFetcher) query(ctx context.Context, url string, params neturl.Values) ([]WaybackSnapshot, error) {
+	params.Set("url", url)
+	params.Set("output", "json")
+	params.Set("fl", "timestamp,original,statuscode")
+	params.Set("filter", "statuscode:200")
+	if !f.From.IsZero() {
+		params.Set("from", f.From.UTC().Format(waybackTimeFormat))
+	}
+	if !f.To.IsZero() {
+		params.Set("to", f.To.UTC().Format(waybackTimeFormat))
+	}
+
+	cdx := f.endpoint() + "/cdx/search/cdx?" + params.Encode()
+	req, err := http.NewRequest("GET", cdx, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %s failed: %s", cdx, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		// The CDX API returns nothing at all if there are no results.
+		return nil, nil
+	}
+
+	// The first row holds the names of the fields.
+	var rows [][]string
+	if err = json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("invalid response from the CDX API: %s", err)
+	}
+	ret := []WaybackSnapshot{}
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		if len(row) != 3 {
+			return nil, fmt.Errorf("invalid row in response from the CDX API: %v", row)
+		}
+
+		t, err := time.Parse(waybackTimeFormat, row[0])
+		if err != nil {
+			return nil, err
+		}
+		status, _ := strconv.Atoi(row[2])
+		ret = append(ret, WaybackSnapshot{Time: t, URL: row[1], StatusCode: status})
+	}
+	return ret, nil
+}
+
+func (f *WaybackFetcher) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return http.DefaultClient
+}
+
+func (f *WaybackFetcher) endpoint() string {
+	if f.Endpoint != "" {
+		return strings.TrimSuffix(f.Endpoint, "/")
+	}
+	return defaultWaybackEndpoint
+}
+
+// Static type assertion
+var _ ContextFetcher = &WaybackFetcher{}
@@ -0,0 +1,147 @@
+package scrape_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/andrew-d/goscrape/paginate"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWayback serves a minimal version of the Wayback Machine's CDX API and
+// archived pages.
+func fakeWayback(t *testing.T, archive map[string]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cdx/search/cdx" {
+			q := r.URL.Query()
+			assert.Equal(t, "json", q.Get("output"))
+			assert.Equal(t, "statuscode:200", q.Get("filter"))
+
+			var stamps []string
+			for ts := range archive[q.Get("url")] {
+				if (q.Get("from") == "" || ts >= q.Get("from")) && (q.Get("to") == "" || ts <= q.Get("to")) {
+					stamps = append(stamps, ts)
+				}
+			}
+			if len(stamps) == 0 {
+				return
+			}
+			sort.Strings(stamps)
+
+			if closest := q.Get("closest"); closest != "" {
+				target, _ := strconv.ParseInt(closest, 10, 64)
+				dist := func(ts string) int64 {
+					n, _ := strconv.ParseInt(ts, 10, 64)
+					if n > target {
+						return n - target
+					}
+					return target - n
+				}
+				sort.SliceStable(stamps, func(i, j int) bool { return dist(stamps[i]) < dist(stamps[j]) })
+			}
+			if limit, _ := strconv.Atoi(q.Get("limit")); limit < 0 {
+				stamps = stamps[len(stamps)+limit:]
+			} else if limit > 0 && limit < len(stamps) {
+				stamps = stamps[:limit]
+			}
+
+			rows := [][]string{{"timestamp", "original", "statuscode"}}
+			for _, ts := range stamps {
+				rows = append(rows, []string{ts, q.Get("url"), "200"})
+			}
+			json.NewEncoder(w).Encode(rows)
+			return
+		}
+
+		// /web/<timestamp>id_/<url>
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/web/"), "id_/", 2)
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		original := parts[1]
+		if r.URL.RawQuery != "" {
+			original += "?" + r.URL.RawQuery
+		}
+		page, found := archive[original][parts[0]]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Archive-Orig-Last-Modified", "then")
+		fmt.Fprint(w, page)
+	}))
+}
+
+func TestWaybackFetcher(t *testing.T) {
+	archive := map[string]map[string]string{
+		"http://example.com/list?page=1": {
+			"20190105120000": `<b>2019 a</b><a class="next" href="http://example.com/list?page=2">next</a>`,
+			"20200301080000": `<b>2020 a</b><a class="next" href="http://example.com/list?page=2">next</a>`,
+			"20200302080000": `<b>2020 a'</b><a class="next" href="http://example.com/list?page=2">next</a>`,
+			"20210710000000": `<b>2021 a</b>`,
+		},
+		"http://example.com/list?page=2": {
+			"20190106000000": `<b>2019 b</b>`,
+			"20200401000000": `<b>2020 b</b>`,
+		},
+	}
+	ts := fakeWayback(t, archive)
+	defer ts.Close()
+
+	fetcher := &scrape.WaybackFetcher{
+		Endpoint: ts.URL + "/",
+		From:     time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    fetcher,
+		Paginator:  paginate.BySelector("a.next", "href"),
+		DividePage: scrape.DividePageBySelector("b"),
+		Pieces: []scrape.Piece{
+			{Name: "item", Selector: ".", Extractor: extract.Text{}},
+			{Name: "modified", Selector: ".", Extractor: extract.Header{Name: "X-Archive-Orig-Last-Modified"}},
+		},
+	})
+	items := func() []interface{} {
+		res, err := sc.Scrape("http://example.com/list?page=1")
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var ret []interface{}
+		for _, block := range res.AllBlocks() {
+			ret = append(ret, block["item"])
+			assert.Equal(t, "then", block["modified"])
+		}
+		return ret
+	}
+
+	// The latest snapshot in the range.
+	assert.Equal(t, []interface{}{"2020 a'", "2020 b"}, items())
+
+	// The closest snapshot to a time.
+	fetcher.At = time.Date(2019, 1, 5, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []interface{}{"2019 a", "2019 b"}, items())
+
+	snapshots, err := fetcher.Snapshots("http://example.com/list?page=1")
+	assert.NoError(t, err)
+	assert.Equal(t, []scrape.WaybackSnapshot{
+		{Time: time.Date(2019, 1, 5, 12, 0, 0, 0, time.UTC), URL: "http://example.com/list?page=1", StatusCode: 200},
+		{Time: time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), URL: "http://example.com/list?page=1", StatusCode: 200},
+		{Time: time.Date(2020, 3, 2, 8, 0, 0, 0, time.UTC), URL: "http://example.com/list?page=1", StatusCode: 200},
+	}, snapshots)
+
+	_, err = fetcher.Fetch("GET", "http://example.com/missing")
+	assert.EqualError(t, err, "no snapshot of http://example.com/missing found in the Wayback Machine")
+	_, err = fetcher.Fetch("POST", "http://example.com/list?page=1")
+	assert.Error(t, err)
+}
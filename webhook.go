@@ -0,0 +1,154 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// WebhookFormat is the chat service that a WebhookSink posts to.
+type WebhookFormat int
+
+const (
+	// SlackWebhook posts to a Slack incoming webhook.
+	SlackWebhook WebhookFormat = iota
+
+	// DiscordWebhook posts to a Discord webhook.
+	DiscordWebhook
+)
+
+// The maximum length of a Discord message.
+const maxDiscordMessage = 2000
+
+// DefaultWebhookTemplate is the template used by a WebhookSink if none is
+// given.  It lists the scrape's Summary and at most 10 added and removed
+// blocks.
+var DefaultWebhookTemplate = template.Must(template.New("webhook").Parse(
+	"*{{.Title}}*\n" +
+		"{{if .Err}}error: {{.Err}}\n{{end}}" +
+		"```\n{{.Summary}}```\n" +
+		"{{range $i, $b := .Added}}{{if lt $i 10}}+ {{$b}}\n{{end}}{{end}}" +
+		"{{range $i, $b := .Removed}}{{if lt $i 10}}- {{$b}}\n{{end}}{{end}}",
+))
+
+// WebhookSink is a Sink that posts a message to a Slack or Discord webhook at
+// the end of a scrape, to bring monitoring scrapes into a team's chat without
+// any glue code.  It decides when to post, and keeps track of the previous
+// scrape, in the same way as EmailSink.
+//
+// The message is rendered from a Report with Template - e.g.
+//
+//	tmpl := template.Must(template.New("").Parse(
+//		"{{.Summary.Blocks}} products, {{len .Added}} new"))
+//	sink := &scrape.WebhookSink{URL: hookURL, Template: tmpl, Always: true}
+//
+// A WebhookSink is safe to share between Scrapers, but scrapes that overlap
+// in time are reported together.
+type WebhookSink struct {
+	// The URL of the webhook, and the service that it belongs to.
+	URL    string
+	Format WebhookFormat
+
+	// Client is the http.Client used to post messages.  If this is nil, then
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// Template renders the message from a *Report.  If this is nil, then
+	// DefaultWebhookTemplate is used.
+	Template *template.Template
+
+	// Which scrapes to post a message for, as for EmailSink.
+	OnFailure bool
+	OnDrift   bool
+	OnChange  bool
+	Always    bool
+
+	MinBlocks  int
+	MinChanges int
+
+	// If StateFile is non-empty, then the blocks of each scrape are saved to
+	// this file as JSON, and compared with those of the next scrape.
+	StateFile string
+
+	tracker reportTracker
+}
+
+func (s *WebhookSink) Write(p *Page) error {
+	s.tracker.write(p)
+	return nil
+}
+
+func (s *WebhookSink) Failed(err error) {
+	s.tracker.failed(err)
+}
+
+// Flush compares the scrape with the previous one, and posts a message if
+// needed.
+func (s *WebhookSink) Flush() error {
+	report, notify, err := s.tracker.finish(notifyConfig{
+		onFailure:  s.OnFailure,
+		onDrift:    s.OnDrift,
+		onChange:   s.OnChange,
+		always:     s.Always,
+		minBlocks:  s.MinBlocks,
+		minChanges: s.MinChanges,
+		stateFile:  s.StateFile,
+	})
+	if err != nil || !notify {
+		return err
+	}
+
+	tmpl := s.Template
+	if tmpl == nil {
+		tmpl = DefaultWebhookTemplate
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, report); err != nil {
+		return err
+	}
+	return s.post(strings.TrimSpace(buf.String()))
+}
+
+func (s *WebhookSink) post(msg string) error {
+	if s.URL == "" {
+		return errors.New("no webhook URL given")
+	}
+
+	var payload interface{}
+	switch s.Format {
+	case SlackWebhook:
+		payload = map[string]string{"text": msg}
+	case DiscordWebhook:
+		if runes := []rune(msg); len(runes) > maxDiscordMessage {
+			msg = string(runes[:maxDiscordMessage-3]) + "..."
+		}
+		payload = map[string]string{"content": msg}
+	default:
+		return fmt.Errorf("unknown webhook format %d", s.Format)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("request to %s failed: %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// Static type assertion
+var _ FailureSink = &WebhookSink{}
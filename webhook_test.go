@@ -0,0 +1,109 @@
+package scrape_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/andrew-d/goscrape"
+	"github.com/andrew-d/goscrape/extract"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []map[string]string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var msg map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	take := func() []map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		ret := messages
+		messages = nil
+		return ret
+	}
+
+	pages := mapFetcher{
+		"http://example.com": `<li><b>one</b> <i>1</i></li><li><b>two</b> <i>2</i></li>`,
+	}
+	sink := &scrape.WebhookSink{URL: ts.URL, OnDrift: true, OnChange: true}
+	sc := mustNew(&scrape.ScrapeConfig{
+		Fetcher:    pages,
+		DividePage: scrape.DividePageBySelector("li"),
+		Pieces: []scrape.Piece{
+			{Name: "name", Selector: "b", Extractor: extract.Text{}},
+			{Name: "price", Selector: "i", Extractor: extract.Text{}},
+		},
+		Sink: sink,
+	})
+
+	_, err := sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, take())
+
+	pages["http://example.com"] = `<li><b>one</b> <i>1</i></li><li><b>three</b> <i>3</i></li>`
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	msgs := take()
+	if assert.Len(t, msgs, 1) {
+		text := msgs[0]["text"]
+		assert.True(t, strings.HasPrefix(text, "*1 blocks added, 1 removed*\n```\npages:    1\nblocks:   2\n"), text)
+		assert.True(t, strings.HasSuffix(text, "  price 100.0% (2/2)\n```\n"+
+			`+ {"name":"three","price":"3"}`+"\n"+
+			`- {"name":"two","price":"2"}`), text)
+	}
+
+	// The price's selector stops matching.
+	pages["http://example.com"] = `<li><b>one</b> <span>1</span></li><li><b>three</b> <span>3</span></li>`
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	msgs = take()
+	if assert.Len(t, msgs, 1) {
+		assert.True(t, strings.HasPrefix(msgs[0]["text"], "*pieces stopped matching: price*\n"), msgs[0]["text"])
+		assert.NotContains(t, msgs[0]["text"], "+ ")
+	}
+
+	// Discord, with a custom template.
+	sink.Format = scrape.DiscordWebhook
+	sink.Always = true
+	sink.Template = template.Must(template.New("").Parse(
+		"{{.Summary.Blocks}} blocks, {{.Changes}} changes, broken: {{.Broken}}"))
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"content": "2 blocks, 0 changes, broken: [price]"}}, take())
+
+	// Long messages are truncated for Discord.
+	sink.Template = template.Must(template.New("").Parse(strings.Repeat("x", 3000)))
+	_, err = sc.Scrape("http://example.com")
+	assert.NoError(t, err)
+	msgs = take()
+	if assert.Len(t, msgs, 1) {
+		assert.Len(t, msgs[0]["content"], 2000)
+	}
+}
+
+func TestWebhookSinkErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	sink := &scrape.WebhookSink{URL: ts.URL, Always: true}
+	assert.EqualError(t, sink.Flush(), "request to "+ts.URL+" failed: 403 Forbidden")
+
+	sink = &scrape.WebhookSink{Always: true}
+	assert.EqualError(t, sink.Flush(), "no webhook URL given")
+}